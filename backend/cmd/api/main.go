@@ -18,32 +18,63 @@ import (
 	"github.com/temo/pack-optimizer/backend/internal/platform"
 )
 
+// Build information, set at build time with
+// -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse --short HEAD)".
+var (
+	version = "dev"
+	commit  = "unknown"
+)
+
 // main initializes and starts the HTTP server.
 // It performs the following steps:
-// 1. Configure structured logging with slog
-// 2. Load configuration from environment variables
+// 1. Load configuration from environment variables and the optional CONFIG_FILE
+// 2. Configure structured logging with slog
 // 3. Create HTTP router with CORS middleware
 // 4. Bootstrap application (connect to DB, Redis, wire dependencies)
 // 5. Mount API routes
 // 6. Start HTTP server in a goroutine
 // 7. Wait for shutdown signal and perform graceful shutdown
 func main() {
+	startedAt := time.Now() // Reported as uptime by GET /version
+
+	// Load configuration from environment variables, backed by CONFIG_FILE if set.
+	// This comes first so the file can also set LOG_LEVEL/LOG_FORMAT; errors are logged below.
+	cfg, cfgErr := platform.Load()
+
 	// Configure structured logging with slog
-	// Use JSON handler for production, text handler for development
-	var logger *slog.Logger
-	if os.Getenv("ENVIRONMENT") == "production" {
-		logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelInfo,
-		}))
-	} else {
-		logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		}))
-	}
+	// LOG_LEVEL/LOG_FORMAT override the defaults (JSON+info for production, text+debug otherwise)
+	logger := cfg.NewLogger(os.Stdout)
 	slog.SetDefault(logger)
 
-	// Load configuration from environment variables
-	cfg := platform.LoadConfig()
+	if cfgErr != nil {
+		logger.Error("invalid configuration", "error", cfgErr)
+		os.Exit(1)
+	}
+	tlsEnabled, err := cfg.TLSEnabled()
+	if err != nil {
+		logger.Error("invalid TLS configuration", "error", err)
+		os.Exit(1)
+	}
+	requestIDs, err := cfg.RequestIDGenerator()
+	if err != nil {
+		logger.Error("invalid request ID configuration", "error", err)
+		os.Exit(1)
+	}
+	ipFilter, err := cfg.IPFilter()
+	if err != nil {
+		logger.Error("invalid IP filter configuration", "error", err)
+		os.Exit(1)
+	}
+	clientIPs, err := cfg.ClientIPResolver()
+	if err != nil {
+		logger.Error("invalid trusted proxy configuration", "error", err)
+		os.Exit(1)
+	}
+	redactor, err := cfg.Redactor()
+	if err != nil {
+		logger.Error("invalid log redaction configuration", "error", err)
+		os.Exit(1)
+	}
 
 	// Create HTTP router
 	r := chi.NewRouter()
@@ -57,14 +88,20 @@ func main() {
 		DDoSProtectionEnabled: cfg.DDoSProtectionEnabled,
 		MaxRequestSize:        cfg.MaxRequestSize,
 		MaxHeaderSize:         cfg.MaxHeaderSize,
+		MaxConcurrentReqs:     cfg.MaxConcurrentReqs,
+
+		SuspiciousRequestBlockingEnabled: cfg.SuspiciousRequestBlockingEnabled,
+		IPFilter:                         ipFilter,
+		ClientIPs:                        clientIPs,
+		Redactor:                         redactor,
 	})
 	
 	// CORS middleware - allow frontend access
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"}, // Allow all origins (configure for production)
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key", "If-None-Match", "If-Match"},
+		ExposedHeaders:   []string{"Link", "X-Request-ID", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300, // Cache preflight requests for 5 minutes
 	}))
@@ -87,24 +124,55 @@ func main() {
 	errorHandler := httpad.NewErrorHandler(
 		logger,
 		cfg.Environment == "development",
-	)
+	).WithRedactor(redactor)
 
 	// Mount all API routes under /api/v1 with error handling
-	platform.MountRoutes(r, app, errorHandler)
-
-	// Configure HTTP server with timeouts
-	srv := &http.Server{
-		Addr:         ":" + cfg.HTTPPort,
-		Handler:      r,
-		ReadTimeout:  15 * time.Second,  // Maximum time to read request
-		WriteTimeout: 15 * time.Second,  // Maximum time to write response
-		IdleTimeout:  60 * time.Second,  // Maximum time to wait for next request
-	}
+	platform.MountRoutes(r, app, errorHandler, httpad.HandlerConfig{
+		MinOrderAmount: cfg.MinOrderAmount,
+		MaxBatchSize:   cfg.MaxBatchSize,
+		Validator:      cfg.Validator(),
+
+		StrictPackSizes: cfg.StrictPackSizes,
+		MaxPacksWarn:    cfg.MaxPacksWarn,
+		StrictJSON:      cfg.StrictJSON,
+
+		UnitConversions: cfg.UnitConversions,
+		UnitRounding:    cfg.UnitRounding,
+
+		DefaultPackSizes: cfg.DefaultPackSizes,
+
+		ElevatedAPIKeys:   cfg.ElevatedAPIKeys,
+		ElevatedMaxAmount: cfg.ElevatedMaxOrderAmount,
+
+		MaxBodyBytes:      int64(cfg.MaxBodyBytes),
+		MaxBatchBodyBytes: int64(cfg.MaxBatchBodyBytes),
+
+		EventsHeartbeat: cfg.EventsHeartbeat,
+
+		RequestTimeout:   cfg.Server.RequestTimeout,
+		CalculateTimeout: cfg.Server.CalculateTimeout,
+
+		Build: httpad.BuildInfo{Version: version, Commit: commit, StartedAt: startedAt},
+
+		RequestIDs: requestIDs,
+	})
+
+	// Configure HTTP server with timeouts, keep-alive and HTTP/2 (h2 over TLS, h2c otherwise)
+	srv := platform.NewHTTPServer(":"+cfg.HTTPPort, r, cfg.Server)
+	conns := platform.TrackConnections(srv) // Counts open connections for shutdown logging
+	srv.RegisterOnShutdown(app.CloseStreams) // Event streams never finish by themselves
 
 	// Start server in a goroutine to allow graceful shutdown handling
+	// Serves HTTPS when a certificate and key are configured, plain HTTP otherwise
 	go func() {
-		logger.Info("HTTP server starting", "port", cfg.HTTPPort)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Info("HTTP server starting", "port", cfg.HTTPPort, "tls", tlsEnabled, "http2", cfg.Server.HTTP2)
+		var err error
+		if tlsEnabled {
+			err = srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Error("server crashed", "error", err)
 			os.Exit(1)
 		}
@@ -113,12 +181,10 @@ func main() {
 	// Graceful shutdown: wait for interrupt signal
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-	<-stop // Block until signal received
+	sig := <-stop // Block until signal received
+	logger.Info("shutdown signal received", "signal", sig.String())
 	
-	// Shutdown server with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.Error("server shutdown error", "error", err)
-	}
+	// Shutdown server with timeout, logging in-flight connections and how long they took to drain
+	// (errors are logged by Shutdown)
+	_ = platform.Shutdown(srv, conns, 10*time.Second, logger)
 }
@@ -0,0 +1,115 @@
+// Package main is a small offline benchmark for the pack calculator.
+// It runs calculator.Compute over random amounts and reports throughput, latency percentiles
+// and average overage, which helps when tuning pack-size catalogs without the HTTP server.
+//
+// Usage:
+//
+//	go run ./cmd/bench -sizes 250,500,1000,2000,5000 -min 1 -max 100000 -n 2000
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+)
+
+// main parses flags, runs the warmup and measured iterations, and prints a summary.
+func main() {
+	sizesFlag := flag.String("sizes", "250,500,1000,2000,5000", "comma-separated pack sizes")
+	minAmount := flag.Int("min", 1, "smallest amount to calculate")
+	maxAmount := flag.Int("max", 100_000, "largest amount to calculate")
+	n := flag.Int("n", 1000, "number of measured calculations")
+	warmup := flag.Int("warmup", 100, "number of unmeasured warmup calculations")
+	seed := flag.Int64("seed", 1, "random seed for the amount distribution")
+	flag.Parse()
+
+	sizes, err := parseSizes(*sizesFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid -sizes:", err)
+		os.Exit(2)
+	}
+	if *minAmount <= 0 || *maxAmount < *minAmount || *n <= 0 || *warmup < 0 {
+		fmt.Fprintln(os.Stderr, "require 0 < min <= max, n > 0 and warmup >= 0")
+		os.Exit(2)
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	nextAmount := func() int { return *minAmount + rng.Intn(*maxAmount-*minAmount+1) }
+
+	// Warm up caches and the allocator so the first measured runs aren't outliers
+	for i := 0; i < *warmup; i++ {
+		calculator.Compute(nextAmount(), append([]int(nil), sizes...))
+	}
+
+	latencies := make([]time.Duration, *n)
+	totalOverage := 0
+	unsolved := 0
+	start := time.Now()
+	for i := 0; i < *n; i++ {
+		amount := nextAmount()
+		t0 := time.Now()
+		res := calculator.Compute(amount, append([]int(nil), sizes...))
+		latencies[i] = time.Since(t0)
+
+		if res.TotalItems == 0 {
+			unsolved++
+			continue
+		}
+		totalOverage += res.TotalItems - amount
+	}
+	elapsed := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	solved := *n - unsolved
+	avgOverage := 0.0
+	if solved > 0 {
+		avgOverage = float64(totalOverage) / float64(solved)
+	}
+
+	fmt.Printf("sizes:        %v\n", sizes)
+	fmt.Printf("amounts:      %d..%d (seed %d)\n", *minAmount, *maxAmount, *seed)
+	fmt.Printf("calculations: %d (%d unsolved)\n", *n, unsolved)
+	fmt.Printf("throughput:   %.1f calc/s\n", float64(*n)/elapsed.Seconds())
+	fmt.Printf("latency p50:  %v\n", percentile(latencies, 50))
+	fmt.Printf("latency p99:  %v\n", percentile(latencies, 99))
+	fmt.Printf("avg overage:  %.2f items\n", avgOverage)
+}
+
+// parseSizes parses a comma-separated list of positive pack sizes.
+func parseSizes(s string) ([]int, error) {
+	var sizes []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("%q is not a positive integer", part)
+		}
+		sizes = append(sizes, v)
+	}
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("at least one pack size is required")
+	}
+	return sizes, nil
+}
+
+// percentile returns the p-th percentile (nearest rank) of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
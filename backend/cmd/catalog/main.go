@@ -0,0 +1,96 @@
+// Package main is an offline catalog-design tool.
+// Given candidate pack sizes and a distribution of order amounts, it finds the subset of K sizes
+// with the lowest average overage, which helps decide which pack sizes to stock.
+//
+// Usage:
+//
+//	go run ./cmd/catalog -candidates 100,250,500,750,1000,2000,5000 -k 3 -min 1 -max 20000 -n 2000
+//	go run ./cmd/catalog -candidates 250,500,1000,2000 -k 2 -amounts 480,1200,2600
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/catalog"
+)
+
+// main parses flags, builds the amount distribution, runs the search, and prints the best subset.
+func main() {
+	candidatesFlag := flag.String("candidates", "100,250,500,750,1000,2000,5000", "comma-separated candidate pack sizes")
+	k := flag.Int("k", 3, "number of pack sizes to choose")
+	amountsFlag := flag.String("amounts", "", "comma-separated order amounts (overrides the random distribution)")
+	minAmount := flag.Int("min", 1, "smallest random amount")
+	maxAmount := flag.Int("max", 20_000, "largest random amount")
+	n := flag.Int("n", 1000, "number of random amounts")
+	seed := flag.Int64("seed", 1, "random seed for the amount distribution")
+	flag.Parse()
+
+	candidates, err := parseInts(*candidatesFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid -candidates:", err)
+		os.Exit(2)
+	}
+
+	var amounts []int
+	if *amountsFlag != "" {
+		if amounts, err = parseInts(*amountsFlag); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid -amounts:", err)
+			os.Exit(2)
+		}
+	} else {
+		if *minAmount <= 0 || *maxAmount < *minAmount || *n <= 0 {
+			fmt.Fprintln(os.Stderr, "require 0 < min <= max and n > 0")
+			os.Exit(2)
+		}
+		rng := rand.New(rand.NewSource(*seed))
+		amounts = make([]int, *n)
+		for i := range amounts {
+			amounts[i] = *minAmount + rng.Intn(*maxAmount-*minAmount+1)
+		}
+	}
+
+	start := time.Now()
+	res, err := catalog.BestSubset(candidates, *k, amounts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "search failed:", err)
+		if errors.Is(err, catalog.ErrSearchTooLarge) {
+			fmt.Fprintf(os.Stderr, "limits: %d candidates, k <= %d, %d subsets, %d amounts, %.3g DP cells\n",
+				catalog.MaxUniverse, catalog.MaxK, catalog.MaxSubsets, catalog.MaxAmounts, float64(catalog.MaxCells))
+		}
+		os.Exit(1)
+	}
+
+	fmt.Printf("best sizes:   %v\n", res.Sizes)
+	fmt.Printf("avg overage:  %.2f items\n", res.AverageOverage)
+	fmt.Printf("avg packs:    %.2f\n", res.AveragePacks)
+	fmt.Printf("orders:       %d\n", len(amounts))
+	fmt.Printf("subsets:      %d (%d scored, %d branches pruned)\n", res.Subsets, res.Evaluated, res.Pruned)
+	fmt.Printf("elapsed:      %v\n", time.Since(start).Round(time.Millisecond))
+}
+
+// parseInts parses a comma-separated list of positive integers.
+func parseInts(s string) ([]int, error) {
+	var out []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("%q is not a positive integer", part)
+		}
+		out = append(out, v)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("at least one value is required")
+	}
+	return out, nil
+}
@@ -0,0 +1,76 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains API-key authentication for elevated request limits.
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+)
+
+// apiKeyHeader carries the API key that grants elevated limits.
+const apiKeyHeader = "X-API-Key"
+
+// elevatedKey marks a request context as authenticated with an elevated API key.
+type elevatedKey struct{}
+
+// isElevated reports whether the request was authenticated with an elevated API key.
+func isElevated(ctx context.Context) bool {
+	elevated, _ := ctx.Value(elevatedKey{}).(bool)
+	return elevated
+}
+
+// authenticate checks the X-API-Key header against the configured elevated keys. Requests without
+// the header continue with default limits; a key that doesn't match is rejected with 401 rather
+// than silently downgraded, so a misconfigured client notices.
+func (a *packSvcAdapter) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(apiKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, k := range a.cfg.ElevatedAPIKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), elevatedKey{}, true)))
+				return
+			}
+		}
+		a.errorHandler.HandleAPIError(w, r, ErrUnauthorized.WithDetails("header", apiKeyHeader))
+	})
+}
+
+// requireElevated writes a 401 and returns false unless the request was authenticated with an
+// elevated API key. Admin operations call it first; with no keys configured they're refused outright.
+func (a *packSvcAdapter) requireElevated(w http.ResponseWriter, r *http.Request) bool {
+	if isElevated(r.Context()) {
+		return true
+	}
+	a.errorHandler.HandleAPIError(w, r, ErrUnauthorized.
+		WithDetails("header", apiKeyHeader).
+		WithDetails("reason", "an elevated API key is required"))
+	return false
+}
+
+// validateOrderAmount checks an amount for POST /calculate. Requests authenticated with an elevated
+// API key may go up to ElevatedMaxAmount instead of the validator's maximum; that hard maximum
+// applies to every request, authenticated or not.
+func (a *packSvcAdapter) validateOrderAmount(r *http.Request, amount int) *APIError {
+	limits := a.cfg.Validator.Limits()
+	if hardMax := a.cfg.ElevatedMaxAmount; amount > limits.MaxAmount && hardMax > limits.MaxAmount {
+		if amount > hardMax {
+			return ErrValidationFailed.
+				WithDetails("field", "amount").
+				WithDetails("value", amount).
+				WithDetails("maximum", hardMax).
+				WithDetails("reason", "amount exceeds the hard maximum, which no API key can raise")
+		}
+		if isElevated(r.Context()) {
+			return nil
+		}
+	}
+	if err := a.cfg.Validator.ValidateAmount(amount); err != nil {
+		return validationError(err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+func TestCalculate_ElevatedAmountLimit(t *testing.T) {
+	router := NewRouter(&mockPacksService{sizes: []int{250, 500}}, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{
+		ElevatedAPIKeys:   []string{"power-user-key"},
+		ElevatedMaxAmount: 5_000_000,
+	})
+
+	tests := []struct {
+		name      string
+		amount    int
+		key       string
+		wantCode  int
+		wantLimit int // Expected details.maximum on a 400, 0 to skip
+	}{
+		{"default limit without a key", 1_000_000, "", http.StatusOK, 0},
+		{"above default without a key", 2_000_000, "", http.StatusBadRequest, 0},
+		{"above default with a key", 2_000_000, "power-user-key", http.StatusOK, 0},
+		{"above hard maximum with a key", 5_000_001, "power-user-key", http.StatusBadRequest, 5_000_000},
+		{"above hard maximum without a key", 5_000_001, "", http.StatusBadRequest, 5_000_000},
+		{"wrong key", 10, "guess", http.StatusUnauthorized, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newTestRequest("POST", "/calculate", map[string]int{"amount": tt.amount})
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantLimit == 0 {
+				return
+			}
+			var errResp APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("Failed to parse error response: %v", err)
+			}
+			if errResp.Details["maximum"] != float64(tt.wantLimit) {
+				t.Errorf("Expected maximum %d, got %v", tt.wantLimit, errResp.Details["maximum"])
+			}
+		})
+	}
+}
+
+func TestCalculate_ElevatedAmountLimitWithUnit(t *testing.T) {
+	router := NewRouter(&mockPacksService{sizes: []int{250, 500}}, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{
+		ElevatedAPIKeys:   []string{"power-user-key"},
+		ElevatedMaxAmount: 5_000_000,
+		UnitConversions:   map[string]float64{"cases": 12},
+	})
+
+	tests := []struct {
+		name     string
+		amount   int
+		key      string
+		wantCode int
+	}{
+		{"above default without a key", 100_000, "", http.StatusBadRequest},
+		{"above default with a key", 100_000, "power-user-key", http.StatusOK},
+		{"above hard maximum with a key", 500_000, "power-user-key", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newTestRequest("POST", "/calculate", map[string]any{"amount": tt.amount, "unit": "cases"})
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCalculate_APIKeysIgnoredWhenUnconfigured(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500}}, &mockCalculator{})
+
+	req := newTestRequest("POST", "/calculate", map[string]int{"amount": 2_000_000})
+	req.Header.Set("X-API-Key", "anything")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected the default limit to apply, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCacheFlush_RequiresElevatedKey(t *testing.T) {
+	router := NewRouter(&mockPacksService{flushed: 4}, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{
+		ElevatedAPIKeys: []string{"admin-key"},
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/cache/flush", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401 without a key, got %d", w.Code)
+	}
+
+	req := newTestRequest("POST", "/cache/flush", nil)
+	req.Header.Set("X-API-Key", "admin-key")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 with an elevated key, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]int
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp["removed"] != 4 {
+		t.Errorf("Expected 4 keys removed, got %v", resp)
+	}
+
+	// Without configured keys nobody can flush
+	router = newTestRouter(&mockPacksService{}, &mockCalculator{})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/cache/flush", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without configured keys, got %d", w.Code)
+	}
+}
+
+func TestCalculate_ElevatedAmountLimitCapped(t *testing.T) {
+	// A hard maximum beyond what one DP table can hold in memory is lowered to the cap
+	router := NewRouter(&mockPacksService{sizes: []int{250, 500}}, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{
+		ElevatedAPIKeys:   []string{"power-user-key"},
+		ElevatedMaxAmount: domain.MaxLimit,
+	})
+
+	for amount, wantCode := range map[int]int{domain.MaxElevatedAmount: http.StatusOK, domain.MaxElevatedAmount + 1: http.StatusBadRequest} {
+		req := newTestRequest("POST", "/calculate", map[string]int{"amount": amount})
+		req.Header.Set("X-API-Key", "power-user-key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != wantCode {
+			t.Errorf("amount %d: expected status %d, got %d: %s", amount, wantCode, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains the handler that packs a shopping cart line by line.
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// Cart limits: each line is a full calculation, so a cart holds as many lines as a
+// consolidation request holds orders.
+const maxCartLines = 100 // Line items per cart
+
+// cartLine is one line item of a cart, e.g. {"sku":"A","amount":263}.
+type cartLine struct {
+	SKU    string `json:"sku"`    // Identifies the line item; unique within the cart
+	Amount int    `json:"amount"` // Items ordered of this SKU
+}
+
+// validateCart checks that there are 1 to maxCartLines lines, each with a unique non-empty SKU
+// no longer than the validator's maximum SKU length, and an amount the requester may order.
+func (a *packSvcAdapter) validateCart(r *http.Request, lines []cartLine) *APIError {
+	if len(lines) == 0 {
+		return ErrValidationFailed.WithDetails("field", "lines").WithDetails("reason", "at least one line item is required")
+	}
+	if len(lines) > maxCartLines {
+		return ErrValidationFailed.WithDetails("field", "lines").WithDetails("count", len(lines)).WithDetails("maximum", maxCartLines).WithDetails("reason", "too many line items")
+	}
+	maxSKU := a.cfg.Validator.Limits().MaxSKULength
+	seen := make(map[string]bool, len(lines))
+	for i, line := range lines {
+		sku := strings.TrimSpace(line.SKU)
+		switch {
+		case sku == "":
+			return ErrValidationFailed.WithDetails("field", "sku").WithDetails("index", i).WithDetails("reason", "every line item needs a SKU")
+		case len(sku) > maxSKU:
+			return ErrValidationFailed.WithDetails("field", "sku").WithDetails("index", i).WithDetails("reason", "SKUs cannot exceed "+strconv.Itoa(maxSKU)+" characters")
+		case seen[sku]:
+			return ErrValidationFailed.WithDetails("field", "sku").WithDetails("index", i).WithDetails("value", sku).WithDetails("reason", "SKUs must be unique within a cart")
+		}
+		seen[sku] = true
+
+		if apiErr := a.validateOrderAmount(r, line.Amount); apiErr != nil {
+			return apiErr.WithDetails("index", i).WithDetails("sku", sku)
+		}
+	}
+	return nil
+}
+
+// postCart packs each line item of a cart separately with the active pack sizes, since every
+// SKU ships in its own packs, and totals the lines into one summary. For comparison the summed
+// amount is also packed as if it were a single order, showing what mixing the lines would save;
+// when the sum exceeds the maximum order amount that comparison is left out.
+// The body is a JSON array of line items: [{"sku":"A","amount":263},{"sku":"B","amount":500}].
+func (a *packSvcAdapter) postCart(w http.ResponseWriter, r *http.Request) {
+	var lines []cartLine
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBodyBytes, &lines); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	if apiErr := a.validateCart(r, lines); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+
+	sizes, skus, err := a.resolveSizes(r, nil)
+	if err != nil {
+		a.handleReadError(w, r, err, "get_pack_sizes")
+		return
+	}
+	if err := a.cfg.Validator.ValidatePackSet(sizes); err != nil {
+		a.errorHandler.HandleAPIError(w, r, validationError(err))
+		return
+	}
+
+	// Pack each line on its own, totaling as we go
+	perLine := make([]domain.CalculationResult, 0, len(lines))
+	lineResps := make([]map[string]any, 0, len(lines))
+	// int64, so a cart of lines near the maximum amount can't overflow the totals on 32-bit builds
+	var total, totalItems, totalPacks, totalOverage int64
+	for i, line := range lines {
+		res, err := a.calc.Compute(r.Context(), line.Amount, sizes)
+		if err != nil {
+			a.errorHandler.HandleError(w, r, calculationError(err).WithDetails("index", i).WithDetails("amount", line.Amount))
+			return
+		}
+		perLine = append(perLine, res)
+		total += int64(line.Amount)
+		totalItems += int64(res.TotalItems)
+		totalPacks += int64(res.TotalPacks)
+		totalOverage += int64(res.Overage)
+
+		lineResp := calcResponse(line.Amount, res, skus)
+		lineResp["sku"] = strings.TrimSpace(line.SKU)
+		lineResps = append(lineResps, lineResp)
+	}
+
+	resp := map[string]any{
+		"lines": lineResps,
+		"total": map[string]any{
+			"amount":     total,
+			"totalItems": totalItems,
+			"totalPacks": totalPacks,
+			"overage":    totalOverage,
+		},
+	}
+
+	// Pack the summed amount as one order for comparison, if it's an amount that could be ordered
+	if total <= domain.MaxLimit && a.validateOrderAmount(r, int(total)) == nil {
+		summed, err := a.calc.Compute(r.Context(), int(total), sizes)
+		if err != nil {
+			a.errorHandler.HandleError(w, r, calculationError(err).WithDetails("amount", total))
+			return
+		}
+		cmp := compareConsolidation(summed, perLine)
+		resp["summed"] = map[string]any{
+			"amount":     total,
+			"totalItems": summed.TotalItems,
+			"totalPacks": summed.TotalPacks,
+			"overage":    summed.Overage,
+			"shortfall":  summed.Shortfall,
+		}
+		resp["savings"] = map[string]any{
+			"items": cmp.itemsSaved,
+			"packs": cmp.packsSaved,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
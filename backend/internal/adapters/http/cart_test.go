@@ -0,0 +1,170 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+func TestPostCart(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500, 1000}, skus: map[int]string{500: "BOX-500"}}
+	router := newTestRouter(svc, calculator.NewService())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate/cart", []map[string]any{
+		{"sku": "A", "amount": 263},
+		{"sku": "B", "amount": 500},
+		{"sku": "C", "amount": 251},
+	}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	type totals struct {
+		Amount     int `json:"amount"`
+		TotalItems int `json:"totalItems"`
+		TotalPacks int `json:"totalPacks"`
+		Overage    int `json:"overage"`
+	}
+	var resp struct {
+		Lines []struct {
+			SKU       string         `json:"sku"`
+			Amount    int            `json:"amount"`
+			Breakdown map[string]int `json:"breakdown"`
+			Packs     []packCount    `json:"packs"`
+			totals
+		} `json:"lines"`
+		Total   totals `json:"total"`
+		Summed  totals `json:"summed"`
+		Savings struct {
+			Items int `json:"items"`
+			Packs int `json:"packs"`
+		} `json:"savings"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// Each line is packed on its own: 263 -> 500, 500 -> 500, 251 -> 500
+	if len(resp.Lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(resp.Lines))
+	}
+	for i, want := range []struct {
+		sku     string
+		amount  int
+		overage int
+	}{{"A", 263, 237}, {"B", 500, 0}, {"C", 251, 249}} {
+		line := resp.Lines[i]
+		if line.SKU != want.sku || line.Amount != want.amount || line.TotalItems != 500 || line.Overage != want.overage {
+			t.Errorf("Line %d: expected %s %d -> 500 items with overage %d, got %+v", i, want.sku, want.amount, want.overage, line)
+		}
+		if line.Breakdown["500"] != 1 || len(line.Packs) != 1 || line.Packs[0].SKU != "BOX-500" {
+			t.Errorf("Line %d: expected one BOX-500, got %v / %+v", i, line.Breakdown, line.Packs)
+		}
+	}
+	if resp.Total != (totals{Amount: 1014, TotalItems: 1500, TotalPacks: 3, Overage: 486}) {
+		t.Errorf("Unexpected cart total: %+v", resp.Total)
+	}
+
+	// Packed as one order, 1014 needs 1000 + 250
+	if resp.Summed != (totals{Amount: 1014, TotalItems: 1250, TotalPacks: 2, Overage: 236}) {
+		t.Errorf("Unexpected summed order: %+v", resp.Summed)
+	}
+	if resp.Savings.Items != 250 || resp.Savings.Packs != 1 {
+		t.Errorf("Expected savings of 250 items / 1 pack, got %+v", resp.Savings)
+	}
+}
+
+func TestPostCart_SummedAboveMaximum(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500, 1000}}, calculator.NewService())
+
+	// Each line is within the limit, but together they are not
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate/cart", []map[string]any{
+		{"sku": "A", "amount": 600000},
+		{"sku": "B", "amount": 600000},
+	}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if _, ok := resp["summed"]; ok {
+		t.Errorf("Expected no summed comparison above the maximum, got %v", resp["summed"])
+	}
+	if _, ok := resp["savings"]; ok {
+		t.Errorf("Expected no savings above the maximum, got %v", resp["savings"])
+	}
+	if total, _ := resp["total"].(map[string]any); total["amount"] != float64(1200000) {
+		t.Errorf("Expected a cart total of 1200000, got %v", resp["total"])
+	}
+}
+
+func TestPostCart_TotalsBeyond32Bits(t *testing.T) {
+	validator := domain.NewValidator(domain.ValidationLimits{MaxAmount: domain.MaxLimit})
+	calc := &mockCalculator{result: domain.CalculationResult{Amount: domain.MaxLimit, TotalItems: domain.MaxLimit + 250, TotalPacks: 3, Overage: 250}}
+	router := NewRouter(&mockPacksService{sizes: []int{250}}, calc, nil, newTestErrorHandler(), HandlerConfig{Validator: validator})
+
+	// Three lines at the hard limit sum past what 32 bits hold
+	lines := []map[string]any{
+		{"sku": "A", "amount": domain.MaxLimit},
+		{"sku": "B", "amount": domain.MaxLimit},
+		{"sku": "C", "amount": domain.MaxLimit},
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate/cart", lines))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Total struct {
+			Amount     int64 `json:"amount"`
+			TotalItems int64 `json:"totalItems"`
+			TotalPacks int64 `json:"totalPacks"`
+			Overage    int64 `json:"overage"`
+		} `json:"total"`
+		Summed map[string]any `json:"summed"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	limit := int64(domain.MaxLimit)
+	if resp.Total.Amount != 3*limit || resp.Total.TotalItems != 3*(limit+250) || resp.Total.TotalPacks != 9 || resp.Total.Overage != 750 {
+		t.Errorf("Expected exact totals for three lines at the limit, got %+v", resp.Total)
+	}
+	if resp.Summed != nil {
+		t.Errorf("Expected no summed comparison beyond the hard limit, got %v", resp.Summed)
+	}
+}
+
+func TestPostCart_Validation(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500}}, calculator.NewService())
+
+	tooMany := make([]map[string]any, maxCartLines+1)
+	for i := range tooMany {
+		tooMany[i] = map[string]any{"sku": "SKU-" + strings.Repeat("x", i), "amount": 1}
+	}
+	for name, body := range map[string]any{
+		"empty cart":      []map[string]any{},
+		"too many lines":  tooMany,
+		"missing sku":     []map[string]any{{"sku": " ", "amount": 10}},
+		"long sku":        []map[string]any{{"sku": strings.Repeat("x", domain.DefaultMaxSKULength+1), "amount": 10}},
+		"duplicate sku":   []map[string]any{{"sku": "A", "amount": 10}, {"sku": "A", "amount": 20}},
+		"zero amount":     []map[string]any{{"sku": "A", "amount": 0}},
+		"amount too big":  []map[string]any{{"sku": "A", "amount": 2000000}},
+		"object not list": map[string]any{"sku": "A", "amount": 10},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", "/calculate/cart", body))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d: %s", name, w.Code, w.Body.String())
+		}
+	}
+}
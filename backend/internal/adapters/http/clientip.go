@@ -0,0 +1,100 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains client IP resolution behind trusted reverse proxies.
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPResolver finds the IP address of the client behind a request. Forwarding headers
+// (X-Forwarded-For, X-Real-IP) are anyone's to set, so they're only believed when the direct
+// peer is one of the trusted proxies; otherwise the peer itself is the client.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+}
+
+// NewClientIPResolver parses the trusted proxy entries, each a CIDR range or a single address as
+// in NewIPFilter. With no entries no proxy is trusted and every client is its direct peer.
+// Returns an error naming the first entry that doesn't parse.
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	trusted, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
+	return &ClientIPResolver{trusted: trusted}, nil
+}
+
+// ClientIP returns the client IP for r. From a trusted proxy, X-Forwarded-For is read from the
+// right, skipping trusted hops, and the first untrusted hop is the client (the leftmost hop when
+// every one is trusted); without X-Forwarded-For, X-Real-IP is used. Otherwise, and for a nil
+// resolver, the client is the peer address of the connection.
+func (c *ClientIPResolver) ClientIP(r *http.Request) string {
+	peer := remoteIP(r)
+	if c == nil || !c.isTrusted(peer) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !c.isTrusted(hops[i]) || i == 0 {
+			return hops[i]
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	return peer
+}
+
+// isTrusted reports whether ip is one of the trusted proxies.
+func (c *ClientIPResolver) isTrusted(ip string) bool {
+	addr, err := netip.ParseAddr(strings.Trim(ip, "[]"))
+	if err != nil {
+		return false
+	}
+	return containsAddr(c.trusted, addr.Unmap().WithZone(""))
+}
+
+// remoteIP returns the address of the connection's peer, without the port.
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// clientIPKey carries the resolved client IP in a request context.
+type clientIPKey struct{}
+
+// resolveClientIP creates middleware that resolves each request's client IP once with resolver,
+// so every later middleware and handler sees the same address through getClientIP.
+func resolveClientIP(resolver *ClientIPResolver) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPKey{}, resolver.ClientIP(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// getClientIP returns the client IP resolved for the request by resolveClientIP, or the
+// connection's peer address for a request that didn't pass through it.
+func getClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// mustClientIPResolver builds a ClientIPResolver, failing the test on a parse error.
+func mustClientIPResolver(t *testing.T, trusted []string) *ClientIPResolver {
+	t.Helper()
+	c, err := NewClientIPResolver(trusted)
+	if err != nil {
+		t.Fatalf("NewClientIPResolver: %v", err)
+	}
+	return c
+}
+
+func TestClientIPResolver_ClientIP(t *testing.T) {
+	resolver := mustClientIPResolver(t, []string{"10.0.0.0/8", "2001:db8::1"})
+
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string // X-Forwarded-For header lines
+		realIP     string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:4000", nil, "", "203.0.113.7"},
+		{"direct IPv6 client", "[2001:db8::9]:4000", nil, "", "2001:db8::9"},
+		{"untrusted peer's headers ignored", "203.0.113.7:4000", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.7"},
+		{"single proxy", "10.0.0.1:4000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"rightmost untrusted hop", "10.0.0.1:4000", []string{"192.0.2.66, 198.51.100.1, 10.0.0.2"}, "", "198.51.100.1"},
+		{"hops across header lines", "10.0.0.1:4000", []string{"192.0.2.66", "198.51.100.1"}, "", "198.51.100.1"},
+		{"every hop trusted", "10.0.0.1:4000", []string{"10.0.0.3, 10.0.0.2"}, "", "10.0.0.3"},
+		{"trusted IPv6 proxy", "[2001:db8::1]:4000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"X-Real-IP from proxy", "10.0.0.1:4000", nil, "198.51.100.2", "198.51.100.2"},
+		{"X-Forwarded-For wins over X-Real-IP", "10.0.0.1:4000", []string{"198.51.100.1"}, "198.51.100.2", "198.51.100.1"},
+		{"proxy without headers", "10.0.0.1:4000", nil, "", "10.0.0.1"},
+	} {
+		req := httptest.NewRequest("GET", "/api/v1/packs", nil)
+		req.RemoteAddr = tc.remoteAddr
+		for _, v := range tc.forwarded {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		if tc.realIP != "" {
+			req.Header.Set("X-Real-IP", tc.realIP)
+		}
+		if got := resolver.ClientIP(req); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestClientIPResolver_NilTrustsNoProxy(t *testing.T) {
+	var resolver *ClientIPResolver
+	req := httptest.NewRequest("GET", "/api/v1/packs", nil)
+	req.RemoteAddr = "10.0.0.1:4000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	if got := resolver.ClientIP(req); got != "10.0.0.1" {
+		t.Errorf("Expected the peer address, got %s", got)
+	}
+	if _, err := NewClientIPResolver([]string{"10.0.0.0/8", "proxy.internal"}); err == nil {
+		t.Errorf("Expected an error for an entry that isn't an IP or CIDR range")
+	}
+}
@@ -0,0 +1,66 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains conditional GET support (ETag and If-None-Match) for calculation results.
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// calcCacheControl lets browsers and CDNs store calculation results but revalidate them on every
+// use: the URL names the active set, which can change at any time, while the ETag names the
+// exact version the result was computed for.
+const calcCacheControl = "public, no-cache"
+
+// calcETag returns a strong ETag for a calculation result of kind (e.g. "exact") for amount with
+// sizes taken from pack set version. The result of such a calculation never changes, so the tag
+// stays valid until the active set does. It returns "" when version is 0, i.e. when the sizes
+// don't come from a stored pack set and nothing ties the result to a version.
+func calcETag(kind string, version int64, amount int, sizes []int) string {
+	if version <= 0 {
+		return ""
+	}
+	sorted := append([]int(nil), sizes...)
+	sort.Ints(sorted)
+
+	var sb strings.Builder
+	sb.WriteString(kind + "|" + strconv.FormatInt(version, 10) + "|" + strconv.Itoa(amount) + "|")
+	for _, s := range sorted {
+		sb.WriteString(strconv.Itoa(s))
+		sb.WriteByte(',')
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return `"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// notModified sets the caching headers for etag and reports whether the request's
+// If-None-Match already names it, in which case a 304 has been written and the handler is done.
+// An empty etag (a result that can't be cached) sets no headers.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", calcCacheControl)
+	if !etagMatches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag. GET requests use weak
+// comparison (RFC 9110 13.1.2), so a W/ prefix is ignored; "*" matches any current result.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
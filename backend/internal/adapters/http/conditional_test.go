@@ -0,0 +1,81 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+func TestGetExactFit_ConditionalGet(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500, 1000}, meta: domain.PackSetMeta{Version: 5}}
+	calc := &countingCalculator{calls: map[int]int{}}
+	router := newTestRouter(svc, calc)
+
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := newTestRequest("GET", path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/calculate/exact?amount=750", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || w.Header().Get("Cache-Control") != calcCacheControl {
+		t.Fatalf("Expected 200 with ETag and Cache-Control, got %d %v", w.Code, w.Header())
+	}
+
+	// A matching tag, strong, weak or in a list, is answered without running the check
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w = get("/calculate/exact?amount=750", inm)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: expected an empty 304, got %d %q", inm, w.Code, w.Body.String())
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: expected the ETag repeated on the 304, got %q", inm, w.Header().Get("ETag"))
+		}
+	}
+	if calc.calls[750] != 1 {
+		t.Errorf("Expected the check to run once, ran %d times", calc.calls[750])
+	}
+
+	// Another amount or another pack set version is a different result
+	if w = get("/calculate/exact?amount=751", etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("Expected a new result for another amount, got %d with ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+	svc.meta.Version = 6
+	if w = get("/calculate/exact?amount=750", etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("Expected a new result after the pack set changed, got %d with ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	// Sizes that aren't tied to a stored version aren't cacheable
+	svc.meta.Version = 0
+	w = get("/calculate/exact?amount=750", "*")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != "" || w.Header().Get("Cache-Control") != "" {
+		t.Errorf("Expected an uncached 200 without a version, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestCalcETag(t *testing.T) {
+	base := calcETag("exact", 5, 750, []int{250, 500, 1000})
+	if base == "" || base != calcETag("exact", 5, 750, []int{1000, 250, 500}) {
+		t.Errorf("Expected the same ETag regardless of size order, got %q", base)
+	}
+	for _, other := range []string{
+		calcETag("exact", 6, 750, []int{250, 500, 1000}),
+		calcETag("exact", 5, 751, []int{250, 500, 1000}),
+		calcETag("exact", 5, 750, []int{250, 500}),
+		calcETag("calculate", 5, 750, []int{250, 500, 1000}),
+	} {
+		if other == base {
+			t.Errorf("Expected a different ETag, got %q for both", base)
+		}
+	}
+	if got := calcETag("exact", 0, 750, []int{250}); got != "" {
+		t.Errorf("Expected no ETag without a version, got %q", got)
+	}
+}
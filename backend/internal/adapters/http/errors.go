@@ -20,11 +20,18 @@ const (
 	// Client errors (4xx)
 	ErrCodeInvalidInput     ErrorCode = "INVALID_INPUT"
 	ErrCodeValidationFailed ErrorCode = "VALIDATION_FAILED"
+	ErrCodeNotFound         ErrorCode = "NOT_FOUND"
+	ErrCodeLocked           ErrorCode = "LOCKED"
+	ErrCodeConflict         ErrorCode = "CONFLICT"
+	ErrCodeUnauthorized     ErrorCode = "UNAUTHORIZED"
+	ErrCodeForbidden        ErrorCode = "FORBIDDEN"
+	ErrCodeNoSolution       ErrorCode = "NO_SOLUTION"
 
 	// Server errors (5xx)
 	ErrCodeInternalError    ErrorCode = "INTERNAL_ERROR"
 	ErrCodeDatabaseError     ErrorCode = "DATABASE_ERROR"
 	ErrCodeCalculationError  ErrorCode = "CALCULATION_ERROR"
+	ErrCodeUnavailable       ErrorCode = "SERVICE_UNAVAILABLE"
 )
 
 // APIError represents a structured API error response.
@@ -41,19 +48,29 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("[%s] %s", e.Code, e.Message)
 }
 
-// WithDetails adds additional details to the error.
+// WithDetails returns a copy of the error with an additional detail.
+// The receiver is left untouched, so the shared errors below are safe to build on concurrently.
 func (e *APIError) WithDetails(key string, value interface{}) *APIError {
-	if e.Details == nil {
-		e.Details = make(map[string]interface{})
-	}
-	e.Details[key] = value
-	return e
+	c := e.clone()
+	c.Details[key] = value
+	return c
 }
 
-// WithRequestID adds a request ID to the error for tracing.
+// WithRequestID returns a copy of the error carrying a request ID for tracing.
 func (e *APIError) WithRequestID(requestID string) *APIError {
-	e.RequestID = requestID
-	return e
+	c := e.clone()
+	c.RequestID = requestID
+	return c
+}
+
+// clone copies the error and its details map.
+func (e *APIError) clone() *APIError {
+	c := *e
+	c.Details = make(map[string]interface{}, len(e.Details)+1)
+	for k, v := range e.Details {
+		c.Details[k] = v
+	}
+	return &c
 }
 
 // NewAPIError creates a new API error with the given code, message, and status code.
@@ -65,19 +82,45 @@ func NewAPIError(code ErrorCode, message string, statusCode int) *APIError {
 	}
 }
 
+// errorCatalogEntry describes one error code, as listed by GET /errors.
+type errorCatalogEntry struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"` // Default message; responses may carry a more specific one
+	Status  int       `json:"status"`  // HTTP status code
+}
+
+// errorCatalog holds every registered error code in registration order.
+// Entries are copied at registration, so later changes to the shared errors don't leak in.
+var errorCatalog []errorCatalogEntry
+
+// registerError creates an APIError like NewAPIError and adds its code to the catalog.
+// Declare new error codes through it so GET /errors stays complete.
+func registerError(code ErrorCode, message string, statusCode int) *APIError {
+	errorCatalog = append(errorCatalog, errorCatalogEntry{Code: code, Message: message, Status: statusCode})
+	return NewAPIError(code, message, statusCode)
+}
+
 // Common error constructors
 var (
-	ErrInvalidInput     = NewAPIError(ErrCodeInvalidInput, "Invalid input provided", http.StatusBadRequest)
-	ErrValidationFailed = NewAPIError(ErrCodeValidationFailed, "Validation failed", http.StatusBadRequest)
-	ErrInternalError    = NewAPIError(ErrCodeInternalError, "An internal error occurred", http.StatusInternalServerError)
-	ErrDatabaseError    = NewAPIError(ErrCodeDatabaseError, "Database operation failed", http.StatusInternalServerError)
-	ErrCalculationError = NewAPIError(ErrCodeCalculationError, "Calculation failed", http.StatusInternalServerError)
+	ErrInvalidInput     = registerError(ErrCodeInvalidInput, "Invalid input provided", http.StatusBadRequest)
+	ErrValidationFailed = registerError(ErrCodeValidationFailed, "Validation failed", http.StatusBadRequest)
+	ErrNotFound         = registerError(ErrCodeNotFound, "Resource not found", http.StatusNotFound)
+	ErrLocked           = registerError(ErrCodeLocked, "Pack sizes are locked", http.StatusConflict)
+	ErrConflict         = registerError(ErrCodeConflict, "The pack set has changed since the expected version", http.StatusConflict)
+	ErrUnauthorized     = registerError(ErrCodeUnauthorized, "Invalid API key", http.StatusUnauthorized)
+	ErrForbidden        = registerError(ErrCodeForbidden, "Access from this address is not allowed", http.StatusForbidden)
+	ErrNoSolution       = registerError(ErrCodeNoSolution, "No pack combination fulfills the order", http.StatusUnprocessableEntity)
+	ErrInternalError    = registerError(ErrCodeInternalError, "An internal error occurred", http.StatusInternalServerError)
+	ErrDatabaseError    = registerError(ErrCodeDatabaseError, "Database operation failed", http.StatusInternalServerError)
+	ErrCalculationError = registerError(ErrCodeCalculationError, "Calculation failed", http.StatusInternalServerError)
+	ErrUnavailable      = registerError(ErrCodeUnavailable, "Service temporarily unavailable", http.StatusServiceUnavailable)
 )
 
 // ErrorHandler handles errors and writes structured error responses.
 type ErrorHandler struct {
 	logger      *slog.Logger
-	development bool // If true, includes stack traces in errors
+	development bool     // If true, includes stack traces in errors
+	redactor    Redactor // Scrubs client IPs and details before they are logged
 }
 
 // NewErrorHandler creates a new error handler.
@@ -88,9 +131,20 @@ func NewErrorHandler(logger *slog.Logger, development bool) *ErrorHandler {
 	return &ErrorHandler{
 		logger:      logger,
 		development: development,
+		redactor:    passThroughRedactor{},
 	}
 }
 
+// WithRedactor makes the handler pass client IPs and error details through redactor before
+// logging them. Responses are unaffected. A nil redactor logs them unchanged (the default).
+func (h *ErrorHandler) WithRedactor(redactor Redactor) *ErrorHandler {
+	if redactor == nil {
+		redactor = passThroughRedactor{}
+	}
+	h.redactor = redactor
+	return h
+}
+
 // HandleError writes a structured error response to the HTTP response writer.
 func (h *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, err error) {
 	var apiErr *APIError
@@ -100,7 +154,7 @@ func (h *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, err e
 		apiErr = apiError
 	} else {
 		// Convert generic error to APIError
-		apiErr = ErrInternalError
+		apiErr = ErrInternalError.clone()
 		apiErr.Message = err.Error()
 
 		// Log the error with context using slog
@@ -109,7 +163,7 @@ func (h *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, err e
 			"error", err.Error(),
 			"path", r.URL.Path,
 			"method", r.Method,
-			"ip", r.RemoteAddr,
+			"ip", h.redactor.RedactIP(getClientIP(r)),
 		)
 	}
 
@@ -125,7 +179,7 @@ func (h *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, err e
 	}
 
 	// Write error response
-	h.writeErrorResponse(w, apiErr)
+	h.writeErrorResponse(w, r, apiErr)
 }
 
 // HandleAPIError writes an APIError directly to the response.
@@ -143,7 +197,7 @@ func (h *ErrorHandler) HandleAPIError(w http.ResponseWriter, r *http.Request, ap
 		"path", r.URL.Path,
 		"method", r.Method,
 		"status", apiErr.StatusCode,
-		"details", apiErr.Details,
+		"details", h.redactor.RedactDetails(apiErr.Details),
 	)
 
 	// Add stack trace in development mode
@@ -152,44 +206,49 @@ func (h *ErrorHandler) HandleAPIError(w http.ResponseWriter, r *http.Request, ap
 		apiErr = apiErr.WithDetails("stack_trace", strings.Split(stack, "\n"))
 	}
 
-	h.writeErrorResponse(w, apiErr)
+	h.writeErrorResponse(w, r, apiErr)
 }
 
 // writeErrorResponse writes the error response as JSON.
-func (h *ErrorHandler) writeErrorResponse(w http.ResponseWriter, apiErr *APIError) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(apiErr.StatusCode)
-
-	if err := json.NewEncoder(w).Encode(apiErr); err != nil {
+// Clients that ask for application/problem+json get the RFC 7807 shape instead.
+func (h *ErrorHandler) writeErrorResponse(w http.ResponseWriter, r *http.Request, apiErr *APIError) {
+	if err := encodeError(w, r, apiErr); err != nil {
 		h.logger.Error("failed to encode error response", "error", err)
 	}
 }
 
+// encodeError writes apiErr in the format negotiated from the request's Accept header.
+func encodeError(w http.ResponseWriter, r *http.Request, apiErr *APIError) error {
+	var body any = apiErr
+	contentType := "application/json"
+	if wantsProblemJSON(r) {
+		body = newProblemDetails(r, apiErr)
+		contentType = problemContentType
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(apiErr.StatusCode)
+	return json.NewEncoder(w).Encode(body)
+}
+
 // RecoveryMiddleware recovers from panics and returns structured error responses.
+// In development the response carries the panic value and stack trace; in production it stays generic.
 func RecoveryMiddleware(errorHandler *ErrorHandler) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if rec := recover(); rec != nil {
-					var err error
-					switch v := rec.(type) {
-					case error:
-						err = v
-					case string:
-						err = fmt.Errorf("%s", v)
-					default:
-						err = fmt.Errorf("%v", v)
-					}
+					stack := debug.Stack()
 
 					errorHandler.logger.Error(
 						"panic recovered",
-						"error", err.Error(),
+						"error", fmt.Sprintf("%v", rec),
 						"path", r.URL.Path,
 						"method", r.Method,
-						"stack", string(debug.Stack()),
+						"stack", string(stack),
 					)
 
-					errorHandler.HandleError(w, r, err)
+					errorHandler.handlePanic(w, r, rec, stack)
 				}
 			}()
 
@@ -198,9 +257,21 @@ func RecoveryMiddleware(errorHandler *ErrorHandler) func(next http.Handler) http
 	}
 }
 
-// RequestIDMiddleware adds a request ID to the request context and response headers.
-// Uses chi's middleware.RequestID for proper context handling.
-func RequestIDMiddleware(next http.Handler) http.Handler {
-	return middleware.RequestID(next)
-}
+// handlePanic writes the error response for a recovered panic.
+func (h *ErrorHandler) handlePanic(w http.ResponseWriter, r *http.Request, rec any, stack []byte) {
+	apiErr := ErrInternalError
+
+	// Add request ID from context if available
+	if requestID := middleware.GetReqID(r.Context()); requestID != "" {
+		apiErr = apiErr.WithRequestID(requestID)
+	}
 
+	// Only expose panic details in development mode
+	if h.development {
+		apiErr = apiErr.
+			WithDetails("panic", fmt.Sprintf("%v", rec)).
+			WithDetails("stack_trace", strings.Split(string(stack), "\n"))
+	}
+
+	h.writeErrorResponse(w, r, apiErr)
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// panicHandler always panics, simulating a bug in a handler.
+var panicHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+})
+
+func TestRecoveryMiddleware_Development(t *testing.T) {
+	handler := RecoveryMiddleware(NewErrorHandler(slog.Default(), true))(panicHandler)
+
+	req := newTestRequest("GET", "/panic", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Expected JSON error response, got %q", w.Body.String())
+	}
+
+	if errResp.Code != ErrCodeInternalError {
+		t.Errorf("Expected error code INTERNAL_ERROR, got %s", errResp.Code)
+	}
+	if errResp.Details["panic"] != "boom" {
+		t.Errorf("Expected panic value 'boom' in details, got %v", errResp.Details["panic"])
+	}
+	if stack, ok := errResp.Details["stack_trace"].([]interface{}); !ok || len(stack) == 0 {
+		t.Errorf("Expected stack trace in development response, got %v", errResp.Details["stack_trace"])
+	}
+}
+
+func TestRecoveryMiddleware_Production(t *testing.T) {
+	handler := RecoveryMiddleware(NewErrorHandler(slog.Default(), false))(panicHandler)
+
+	req := newTestRequest("GET", "/panic", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Expected JSON error response, got %q", w.Body.String())
+	}
+
+	if errResp.Message != "An internal error occurred" {
+		t.Errorf("Expected generic message, got %q", errResp.Message)
+	}
+	if len(errResp.Details) != 0 {
+		t.Errorf("Expected no details in production response, got %v", errResp.Details)
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	router := newTestRouter(&mockPacksService{}, &mockCalculator{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("GET", "/errors", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp struct {
+		Errors []errorCatalogEntry `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// Every shared error is listed once, with its default message and status
+	shared := []*APIError{ErrInvalidInput, ErrValidationFailed, ErrNotFound, ErrLocked, ErrConflict, ErrUnauthorized,
+		ErrForbidden, ErrNoSolution, ErrInternalError, ErrDatabaseError, ErrCalculationError, ErrUnavailable}
+	if len(resp.Errors) != len(shared) {
+		t.Fatalf("Expected %d error codes, got %d: %+v", len(shared), len(resp.Errors), resp.Errors)
+	}
+	byCode := make(map[ErrorCode]errorCatalogEntry)
+	for _, e := range resp.Errors {
+		byCode[e.Code] = e
+	}
+	for _, e := range shared {
+		entry, ok := byCode[e.Code]
+		if !ok || entry.Status != e.StatusCode || entry.Message == "" {
+			t.Errorf("Expected %s with status %d, got %+v", e.Code, e.StatusCode, entry)
+		}
+	}
+	if byCode[ErrCodeLocked].Message != "Pack sizes are locked" {
+		t.Errorf("Expected the default message, got %q", byCode[ErrCodeLocked].Message)
+	}
+}
+
+func TestRequestIDMiddleware_EchoesHeader(t *testing.T) {
+	router := RequestIDMiddleware(newTestRouter(&mockPacksService{sizes: []int{250, 500}}, &mockCalculator{}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("GET", "/packs", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w.Header().Get("X-Request-ID") == "" {
+		t.Errorf("Expected an X-Request-ID header on a successful response")
+	}
+
+	// A client-supplied ID is kept, and error bodies carry the same ID
+	req := newTestRequest("GET", "/packs/custom/missing", nil)
+	req.Header.Set("X-Request-ID", "trace-123")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got != "trace-123" {
+		t.Errorf("Expected the client's request ID echoed, got %q", got)
+	}
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Expected JSON error response, got %q", w.Body.String())
+	}
+	if errResp.RequestID != "trace-123" {
+		t.Errorf("Expected request_id trace-123 in the error body, got %q", errResp.RequestID)
+	}
+}
@@ -0,0 +1,61 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains the Server-Sent Events stream of pack set changes.
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// getPackEvents streams pack set changes as Server-Sent Events, so dashboards can follow the
+// active set without polling GET /packs. Each change is an event named "packs" whose data is
+// {"version", "sizes", "changedAt"} and whose id is the version. A comment line is sent every
+// EventsHeartbeat to keep proxies from closing an idle connection. The stream ends when the
+// client disconnects, the server shuts down, or the client falls too far behind; clients then
+// reconnect (EventSource does so automatically) and should refetch GET /packs to catch up.
+func (a *packSvcAdapter) getPackEvents(w http.ResponseWriter, r *http.Request) {
+	if a.cfg.PackEvents == nil {
+		a.errorHandler.HandleAPIError(w, r, ErrUnavailable.WithDetails("reason", "pack set change events are not available"))
+		return
+	}
+	
+	// The stream outlives the server's write timeout, so lift it for this response
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	
+	events, unsubscribe := a.cfg.PackEvents.Subscribe()
+	defer unsubscribe()
+	
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return // Streaming isn't supported by this connection
+	}
+	
+	heartbeat := time.NewTicker(a.cfg.EventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %d\nevent: packs\ndata: %s\n\n", event.Version, data)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
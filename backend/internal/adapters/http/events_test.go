@@ -0,0 +1,81 @@
+package http
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/events"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+func TestPackEvents_Stream(t *testing.T) {
+	broker := events.NewBroker()
+	router := NewRouter(&mockPacksService{}, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{
+		PackEvents:      broker,
+		EventsHeartbeat: 20 * time.Millisecond,
+		RequestTimeout:  10 * time.Millisecond, // Streams must not be cut off by the handler deadline
+	})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/packs/events")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+
+	// Outlast the request timeout, then publish a change
+	time.Sleep(50 * time.Millisecond)
+	_ = broker.Publish(t.Context(), domain.PackSetChanged{Version: 3, Sizes: []int{250, 500}, ChangedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)})
+
+	lines := bufio.NewScanner(resp.Body)
+	var heartbeat bool
+	var event []string
+	for lines.Scan() {
+		line := lines.Text()
+		if line == ": ping" {
+			heartbeat = true
+			continue
+		}
+		if line == "" && len(event) > 0 {
+			break
+		}
+		if line != "" {
+			event = append(event, line)
+		}
+	}
+
+	if !heartbeat {
+		t.Errorf("Expected a heartbeat comment before the event")
+	}
+	want := []string{"id: 3", "event: packs", `data: {"version":3,"sizes":[250,500],"changedAt":"2024-05-01T00:00:00Z"}`}
+	if strings.Join(event, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected event %q, got %q", want, event)
+	}
+
+	// Disconnecting ends the subscription
+	resp.Body.Close()
+	deadline := time.Now().Add(time.Second)
+	for broker.Subscribers() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the subscription to end after the client disconnected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPackEvents_Unavailable(t *testing.T) {
+	router := newTestRouter(&mockPacksService{}, &mockCalculator{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("GET", "/packs/events", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without an event source, got %d", w.Code)
+	}
+}
@@ -3,10 +3,20 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/temo/pack-optimizer/backend/internal/domain"
@@ -17,30 +27,164 @@ import (
 type packSvcAdapter struct {
 	svc          domain.PacksService // Service for managing pack sizes
 	calc         domain.Calculator   // Service for calculating optimal pack distributions
+	jobs         domain.JobService   // Service for asynchronous batch calculations
 	errorHandler *ErrorHandler       // Error handler for structured error responses
+	cfg          HandlerConfig       // Business policy settings for request handling
+}
+
+// HandlerConfig holds business policy settings applied by the HTTP handlers.
+// Zero values fall back to the defaults noted on each field.
+type HandlerConfig struct {
+	MinOrderAmount int // Smallest amount accepted by POST /calculate (default 1); used only to build the default Validator
+	MaxBatchSize   int // Largest number of amounts accepted by POST /calculate/batch (default 1000)
+	
+	DefaultPackSizes []int // Sizes restored by POST /packs/reset (default 250,500,1000,2000,5000, the initial seed)
+	
+	MaxBodyBytes      int64 // Body size limit for regular JSON endpoints (default 64 KiB)
+	MaxBatchBodyBytes int64 // Body size limit for batch and job endpoints (default 2 MiB)
+	
+	Validator domain.Validator // Input rules (default domain.NewValidator with MinOrderAmount and default limits)
+	
+	UnitConversions map[string]float64 // Pack units per order unit, keyed by lowercase unit name (e.g. "cases": 12)
+	UnitRounding    string             // Policy for conversions that aren't whole: UnitRoundingError (default) or UnitRoundingUp
+	
+	StrictPackSizes bool // Reject pack sizes above the maximum order amount instead of sending a Warning header
+	
+	MaxPacksWarn int // POST /calculate flags results with more packs than this as fragmented (0 disables)
+	
+	StrictJSON bool // Reject request bodies with fields the endpoint doesn't know instead of ignoring them
+	
+	ElevatedAPIKeys   []string // X-API-Key values that raise the POST /calculate amount limit (none disables)
+	ElevatedMaxAmount int      // Hard maximum amount for every request; elevated keys may go up to it (default: the Validator's maximum, at most domain.MaxElevatedAmount)
+	
+	RequestTimeout   time.Duration // Handler deadline for every route (0 disables)
+	CalculateTimeout time.Duration // Tighter handler deadline for POST /calculate (0 disables)
+	
+	CacheDegraded bool // Reported by /readyz when the service runs without its cache
+	CacheDisabled bool // Caching turned off by configuration; reported by /readyz but not degraded
+	
+	Health func() domain.DependencyHealth // Live dependency health for /readyz (nil reports startup state only)
+	
+	PackEvents      domain.PackEventSubscriber // Pack set changes streamed by GET /packs/events (nil disables it)
+	EventsHeartbeat time.Duration              // Interval between keep-alive comments on event streams (default 15s)
+	
+	Build BuildInfo // Reported by GET /version (StartedAt defaults to router creation)
+	
+	RequestIDs RequestIDGenerator // Generates request IDs for requests without a valid X-Request-ID (nil uses chi's format)
+}
+
+// BuildInfo identifies the running binary for GET /version.
+type BuildInfo struct {
+	Version   string    // Release version, set at build time (default "dev")
+	Commit    string    // VCS revision, set at build time (default "unknown")
+	StartedAt time.Time // Process start, used to report uptime
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults.
+func (c HandlerConfig) withDefaults() HandlerConfig {
+	if c.MinOrderAmount <= 0 {
+		c.MinOrderAmount = 1
+	}
+	if c.MaxBatchSize <= 0 {
+		c.MaxBatchSize = 1000
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = 64 << 10
+	}
+	if c.MaxBatchBodyBytes <= 0 {
+		c.MaxBatchBodyBytes = 2 << 20
+	}
+	if c.Validator == nil {
+		c.Validator = domain.NewValidator(domain.ValidationLimits{MinAmount: c.MinOrderAmount})
+	}
+	if len(c.DefaultPackSizes) == 0 {
+		c.DefaultPackSizes = []int{250, 500, 1000, 2000, 5000}
+	}
+	if c.EventsHeartbeat <= 0 {
+		c.EventsHeartbeat = 15 * time.Second
+	}
+	if c.Build.Version == "" {
+		c.Build.Version = "dev"
+	}
+	if c.Build.Commit == "" {
+		c.Build.Commit = "unknown"
+	}
+	if c.Build.StartedAt.IsZero() {
+		c.Build.StartedAt = time.Now()
+	}
+	if maxAmount := c.Validator.Limits().MaxAmount; c.ElevatedMaxAmount < maxAmount {
+		c.ElevatedMaxAmount = maxAmount
+	}
+	c.ElevatedMaxAmount = min(c.ElevatedMaxAmount, domain.MaxElevatedAmount) // Keeps one calculation's DP table within memory
+	return c
 }
 
 // NewRouter creates and configures a new HTTP router with all API endpoints.
 // It sets up routes for pack management and calculation operations.
-func NewRouter(packsSvc domain.PacksService, calc domain.Calculator, errorHandler *ErrorHandler) chi.Router {
-	r := chi.NewRouter()
-	a := &packSvcAdapter{svc: packsSvc, calc: calc, errorHandler: errorHandler}
+func NewRouter(packsSvc domain.PacksService, calc domain.Calculator, jobs domain.JobService, errorHandler *ErrorHandler, cfg HandlerConfig) chi.Router {
+	root := chi.NewRouter()
+	a := &packSvcAdapter{svc: packsSvc, calc: calc, jobs: jobs, errorHandler: errorHandler, cfg: cfg.withDefaults()}
+	
+	// Event streams stay open indefinitely, so they're registered outside the handler deadline
+	root.Get("/packs/events", a.getPackEvents) // Stream pack set changes (Server-Sent Events)
+	
+	// Handler-execution deadline for everything else; calculations get a tighter one below
+	r := root.With(TimeoutMiddleware(a.cfg.RequestTimeout))
+	
+	// API keys only raise limits, so they're checked only when some are configured
+	if len(a.cfg.ElevatedAPIKeys) > 0 {
+		r.Use(a.authenticate)
+	}
 	
 	// Root endpoint - returns API information
 	r.Get("/", a.getRoot)
 	
 	// Health check endpoint for monitoring and load balancers
 	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	r.Get("/readyz", a.getReady)
+	r.Get("/version", a.getVersion)
 	
 	// Pack size management endpoints
-	r.Get("/packs", a.getPacks)              // Retrieve current pack sizes
-	r.Put("/packs", a.putPacks)              // Replace all pack sizes
-	r.Delete("/packs/{size}", a.deletePack)  // Remove a specific pack size
+	r.Get("/packs", a.getPacks)                 // Retrieve current pack sizes
+	r.Put("/packs", a.putPacks)                 // Replace all pack sizes
+	r.Post("/packs", a.appendPacks)             // Merge new sizes into the active set
+	r.Post("/packs/validate", a.validatePacks)  // Validate pack sizes without persisting
+	r.Post("/packs/reset", a.resetPacks)        // Restore the configured default pack sizes
+	r.Post("/packs/recommend", a.postRecommend) // Recommend a new pack size for an amount distribution
+	r.Delete("/packs", a.deletePacks)           // Remove several pack sizes at once
+	r.Delete("/packs/{size}", a.deletePack)     // Remove a specific pack size
+	
+	// Saved custom pack sets, referenced by ID from POST /calculate
+	r.Post("/packs/custom", a.postCustomSet)    // Save a custom pack set
+	r.Get("/packs/custom/{id}", a.getCustomSet) // Retrieve a saved custom pack set
 	
-	// Calculation endpoint
-	r.Post("/calculate", a.postCalculate)    // Calculate optimal pack distribution
+	// Pack size lock endpoints (freeze changes during maintenance windows)
+	r.Get("/packs/lock", a.getLock)       // Report current lock state
+	r.Post("/packs/lock", a.postLock)     // Lock pack size changes (elevated API key)
+	r.Post("/packs/unlock", a.postUnlock) // Unlock pack size changes (elevated API key)
 	
-	return r
+	// Operational endpoints
+	r.Get("/cache/stats", a.getCacheStats)   // Pack-sizes cache hit ratio
+	r.Post("/cache/flush", a.postCacheFlush) // Drop cached pack lists and calculations (elevated API key)
+	r.Get("/metrics", a.getMetrics)          // Calculator pool saturation and cache counters
+	r.Get("/errors", a.getErrors)            // Catalog of error codes
+	
+	// Calculation endpoints
+	calcTimeout := TimeoutMiddleware(a.cfg.CalculateTimeout)
+	r.With(calcTimeout).Post("/calculate", a.postCalculate) // Calculate optimal pack distribution
+	r.Get("/calculate/exact", a.getExactFit)                // Check whether an amount fits exactly
+	r.Post("/calculate/consolidate", a.postConsolidate)     // Compare consolidated vs per-order optimization
+	r.Post("/calculate/cart", a.postCart)                   // Pack each line item of a cart, with a combined summary
+	r.Post("/calculate/compare", a.postCompare)             // Compare results across pack-size sets
+	r.Post("/calculate/leaderboard", a.postLeaderboard)     // Rank pack-size sets over an amount distribution
+	r.Post("/calculate/batch", a.postBatch)                 // Calculate several amounts in one request
+	r.Post("/calculate/summary", a.postSummary)             // Aggregate statistics over historical amounts
+	
+	// Asynchronous batch calculation endpoints
+	r.Post("/calculate/jobs", a.postJob)    // Submit a large batch for background processing
+	r.Get("/calculate/jobs/{id}", a.getJob) // Poll job progress and results
+	
+	return root
 }
 
 // getRoot returns API information and available endpoints.
@@ -50,21 +194,173 @@ func (a *packSvcAdapter) getRoot(w http.ResponseWriter, r *http.Request) {
 		"version":     "v1",
 		"description": "API for calculating optimal pack distributions",
 		"endpoints": map[string]string{
-			"GET    /healthz":      "Health check",
-			"GET    /packs":        "Get current pack sizes",
-			"PUT    /packs":        "Replace all pack sizes",
-			"DELETE /packs/{size}": "Remove a pack size",
-			"POST   /calculate":    "Calculate optimal pack distribution",
+			"GET    /healthz":               "Health check",
+			"GET    /readyz":                "Readiness, including degraded dependencies",
+			"GET    /version":               "Build version, commit, Go version and uptime",
+			"GET    /packs":                 "Get current pack sizes",
+			"PUT    /packs":                 "Replace all pack sizes",
+			"POST   /packs":                 "Add pack sizes to the active set",
+			"POST   /packs/validate":        "Validate pack sizes without saving",
+			"POST   /packs/reset":           "Restore the default pack sizes",
+			"POST   /packs/recommend":       "Recommend the new pack size that most reduces overage for past amounts",
+			"DELETE /packs":                 "Remove several pack sizes (?sizes=250,500 or JSON body)",
+			"DELETE /packs/{size}":          "Remove a pack size",
+			"POST   /packs/custom":          "Save a custom pack set, returning its ID",
+			"GET    /packs/custom/{id}":     "Get a saved custom pack set",
+			"GET    /packs/events":          "Stream pack set changes as Server-Sent Events",
+			"GET    /packs/lock":            "Get pack size lock state",
+			"POST   /packs/lock":            "Lock pack size changes (needs an elevated X-API-Key)",
+			"POST   /packs/unlock":          "Unlock pack size changes (needs an elevated X-API-Key)",
+			"GET    /cache/stats":           "Pack-sizes cache hits, misses and hit ratio",
+			"POST   /cache/flush":           "Drop cached pack lists and calculations (needs an elevated X-API-Key)",
+			"GET    /metrics":               "Calculator worker pool saturation and cache counters",
+			"GET    /errors":                "Error codes with their default messages and HTTP statuses",
+			"POST   /calculate":             "Calculate optimal pack distribution",
+			"GET    /calculate/exact":       "Check whether ?amount=N fits the active sizes exactly",
+			"POST   /calculate/consolidate": "Compare consolidated vs per-order packing",
+			"POST   /calculate/cart":        "Pack each line item of a cart separately, with combined totals",
+			"POST   /calculate/compare":     "Compare pack-size sets for one amount",
+			"POST   /calculate/leaderboard": "Rank named pack-size sets by overage and packs over an amount distribution",
+			"POST   /calculate/batch":       "Calculate several amounts in one request",
+			"POST   /calculate/summary":     "Aggregate statistics over historical order amounts",
+			"POST   /calculate/jobs":        "Submit a batch calculation job",
+			"GET    /calculate/jobs/{id}":   "Get batch job progress and results",
 		},
 	})
 }
 
+// getCacheStats reports cumulative pack-sizes cache hits, misses and the hit ratio.
+// With ?reset=true the counters are zeroed after being read, so polling with reset gives per-interval numbers.
+func (a *packSvcAdapter) getCacheStats(w http.ResponseWriter, r *http.Request) {
+	reset, _ := strconv.ParseBool(r.URL.Query().Get("reset"))
+	writeJSON(w, http.StatusOK, a.svc.CacheStats(r.Context(), reset))
+}
+
+// postCacheFlush removes every cached pack list and calculation, so operators can rule out stale
+// entries without restarting. It's an admin operation, so it needs an elevated API key.
+func (a *packSvcAdapter) postCacheFlush(w http.ResponseWriter, r *http.Request) {
+	if !a.requireElevated(w, r) {
+		return
+	}
+	
+	removed, err := a.svc.FlushCache(r.Context())
+	if err != nil {
+		a.errorHandler.HandleAPIError(w, r, ErrUnavailable.WithDetails("operation", "flush_cache").WithDetails("removed", removed))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"removed": removed})
+}
+
+// getMetrics reports operational counters: calculator worker pool occupancy and cache effectiveness.
+// Reading them never resets the cache counters; use GET /cache/stats?reset=true for that.
+func (a *packSvcAdapter) getMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"calculatorPool": a.calc.PoolStats(),
+		"cache":          a.svc.CacheStats(r.Context(), false),
+	})
+}
+
+// getErrors lists the error codes the API can return, with their default messages and HTTP statuses.
+// The list comes from the registered error constructors, so it can't fall behind new codes.
+func (a *packSvcAdapter) getErrors(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"errors": errorCatalog})
+}
+
+// getVersion reports which build is running and for how long. It only reads values fixed at
+// startup, so it's as cheap as /healthz and safe to poll during rollouts.
+func (a *packSvcAdapter) getVersion(w http.ResponseWriter, r *http.Request) {
+	uptime := time.Since(a.cfg.Build.StartedAt)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"version":       a.cfg.Build.Version,
+		"commit":        a.cfg.Build.Commit,
+		"goVersion":     runtime.Version(),
+		"startedAt":     a.cfg.Build.StartedAt.UTC().Format(time.RFC3339),
+		"uptime":        uptime.Truncate(time.Second).String(),
+		"uptimeSeconds": int64(uptime.Seconds()),
+	})
+}
+
+// getReady reports whether the service is ready to take traffic.
+// A degraded service (e.g. running without its cache) is still ready, since it can serve every
+// request directly from the repository; the status tells monitoring which dependency is missing.
+// With live health checks, an unreachable database makes the service unavailable (503) and an
+// unreachable cache degrades it; the response then also reports the database and check time.
+func (a *packSvcAdapter) getReady(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]any{"status": "ready", "cache": "ok"}
+	switch {
+	case a.cfg.CacheDegraded:
+		resp["status"], resp["cache"] = "degraded", "unavailable"
+	case a.cfg.CacheDisabled:
+		resp["cache"] = "disabled"
+	}
+	if a.cfg.Health == nil {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	
+	health := a.cfg.Health()
+	resp["database"] = "ok"
+	if !health.CheckedAt.IsZero() {
+		resp["checkedAt"] = health.CheckedAt
+	}
+	if resp["cache"] == "ok" && !health.CacheOK {
+		resp["status"], resp["cache"] = "degraded", "unavailable"
+	}
+	if !health.DatabaseOK {
+		resp["status"], resp["database"] = "unavailable", "unavailable"
+		writeJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
 // getPacks retrieves the current active pack sizes from the service.
-// Returns a JSON response with the list of pack sizes.
+// Returns a JSON response with the list of pack sizes, plus the packs with their SKUs.
+// With ?meta=true the response also includes the pack set version and when it was last changed.
+// With ?includeStatus=true it also includes "configured", which is true once any pack set version
+// exists, even an empty one, so clients can tell "not yet configured" from "configured as empty".
 func (a *packSvcAdapter) getPacks(w http.ResponseWriter, r *http.Request) {
-	sizes, err := a.svc.GetActiveSizes(r.Context())
+	includeStatus, _ := strconv.ParseBool(r.URL.Query().Get("includeStatus"))
+	
+	// The status needs the version, which is read together with the packs
+	var packs []domain.Pack
+	var version int64
+	var err error
+	if includeStatus {
+		packs, version, err = a.svc.GetActivePacksWithVersion(r.Context())
+	} else {
+		packs, err = a.svc.GetActivePacks(r.Context())
+	}
+	if err != nil {
+		a.handleReadError(w, r, err, "get_pack_sizes")
+		return
+	}
+	resp := packsResponse(packs)
+	if includeStatus {
+		resp["configured"] = version > 0
+	}
+	
+	if withMeta, _ := strconv.ParseBool(r.URL.Query().Get("meta")); withMeta {
+		meta, err := a.svc.GetMeta(r.Context())
+		if err != nil {
+			a.errorHandler.HandleError(w, r, ErrDatabaseError.WithDetails("operation", "get_pack_meta"))
+			return
+		}
+		resp["version"] = meta.Version
+		resp["updatedAt"] = meta.UpdatedAt
+	}
+	
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// resetPacks replaces the active set with the configured default pack sizes.
+// It always writes a new version, even if the active set already matches the defaults,
+// so every reset shows up in the version history. SKUs are dropped along with the old set.
+func (a *packSvcAdapter) resetPacks(w http.ResponseWriter, r *http.Request) {
+	// Copy the defaults so the service can't reorder the shared config slice
+	sizes, _, err := a.svc.ReplaceActive(r.Context(), append([]int(nil), a.cfg.DefaultPackSizes...))
 	if err != nil {
-		a.errorHandler.HandleError(w, r, ErrDatabaseError.WithDetails("operation", "get_pack_sizes"))
+		a.handleReplaceError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"sizes": sizes})
@@ -88,161 +384,1751 @@ func (a *packSvcAdapter) deletePack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Get current pack sizes
-	curr, err := a.svc.GetActiveSizes(r.Context())
+	a.removePacks(w, r, []int{val}, false)
+}
+
+// deletePacksReq represents the optional request body for removing several pack sizes.
+type deletePacksReq struct {
+	Sizes  []int `json:"sizes"`  // Pack sizes to remove
+	Strict bool  `json:"strict"` // Fail if any size isn't in the active set
+}
+
+// deletePacks removes several pack sizes in a single update, so a cleanup can't interleave with other changes.
+// Sizes come from the query (?sizes=250,500&strict=true) or, if the query has none, from a JSON body.
+// Sizes not in the active set are ignored unless strict is set, in which case nothing is removed and they are reported.
+func (a *packSvcAdapter) deletePacks(w http.ResponseWriter, r *http.Request) {
+	var req deletePacksReq
+	if raw := r.URL.Query().Get("sizes"); raw != "" {
+		for i, part := range strings.Split(raw, ",") {
+			val, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || val <= 0 {
+				a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "sizes").WithDetails("index", i).WithDetails("value", part).WithDetails("reason", "must be a positive integer"))
+				return
+			}
+			req.Sizes = append(req.Sizes, val)
+		}
+		req.Strict, _ = strconv.ParseBool(r.URL.Query().Get("strict"))
+	} else if apiErr := a.decodeJSON(w, r, a.cfg.MaxBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	
+	if len(req.Sizes) == 0 {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "sizes").WithDetails("reason", "at least one size is required"))
+		return
+	}
+	for i, val := range req.Sizes {
+		if val <= 0 {
+			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "sizes").WithDetails("index", i).WithDetails("value", val).WithDetails("reason", "must be a positive integer"))
+			return
+		}
+	}
+	
+	a.removePacks(w, r, req.Sizes, req.Strict)
+}
+
+// removePacks filters the given sizes out of the active set with one ReplaceActivePacks call, keeping SKUs.
+// If none of the sizes are present the current set is returned unchanged (no new version is created).
+// With strict, any size that isn't present fails the request with 404 before anything is changed.
+func (a *packSvcAdapter) removePacks(w http.ResponseWriter, r *http.Request, sizes []int, strict bool) {
+	// Get current pack sizes (with SKUs, so they survive the update)
+	curr, err := a.svc.GetActivePacks(r.Context())
 	if err != nil {
-		a.errorHandler.HandleError(w, r, ErrDatabaseError.WithDetails("operation", "get_pack_sizes"))
+		a.handleReadError(w, r, err, "get_pack_sizes")
 		return
 	}
 	
-	// Filter out the size to be deleted
-	next := make([]int, 0, len(curr))
-	for _, s := range curr {
-		if s != val {
-			next = append(next, s)
+	remove := make(map[int]bool, len(sizes))
+	for _, s := range sizes {
+		remove[s] = true
+	}
+	
+	// Filter out the sizes to be deleted, noting which ones were found
+	next := make([]domain.Pack, 0, len(curr))
+	for _, p := range curr {
+		if remove[p.Size] {
+			delete(remove, p.Size)
+			continue
+		}
+		next = append(next, p)
+	}
+	
+	if strict && len(remove) > 0 {
+		missing := make([]int, 0, len(remove))
+		for s := range remove {
+			missing = append(missing, s)
 		}
+		sort.Ints(missing)
+		a.errorHandler.HandleAPIError(w, r, ErrNotFound.WithDetails("field", "sizes").WithDetails("missing", missing).WithDetails("reason", "sizes are not in the active set"))
+		return
 	}
 	
-	// If nothing changed (size not found), return current sizes
+	// If nothing changed (no size found), return current sizes
 	if len(next) == len(curr) {
-		writeJSON(w, http.StatusOK, map[string]any{"sizes": curr})
+		writeJSON(w, http.StatusOK, packsResponse(curr))
 		return
 	}
 	
 	// Update pack sizes with the filtered list
-	sizes, err := a.svc.ReplaceActive(r.Context(), next)
+	packs, _, err := a.svc.ReplaceActivePacks(r.Context(), next)
 	if err != nil {
-		a.errorHandler.HandleError(w, r, ErrDatabaseError.WithDetails("operation", "replace_pack_sizes"))
+		a.handleReplaceError(w, r, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"sizes": sizes})
+	writeJSON(w, http.StatusOK, packsResponse(packs))
+}
+
+// handleReplaceError maps errors from replacing the active pack sizes to API errors.
+// A write refused because pack sizes are locked is a 409 Locked; the repository checks the lock
+// as part of the write, so a concurrent POST /packs/lock can't be missed.
+// Policy violations (removing a required size) are validation errors, a set that moved past the
+// If-Match versions is a 409 Conflict, and anything else is a storage failure.
+func (a *packSvcAdapter) handleReplaceError(w http.ResponseWriter, r *http.Request, err error) {
+	var lockedErr *domain.LockedError
+	if errors.As(err, &lockedErr) {
+		a.errorHandler.HandleAPIError(w, r, ErrLocked.WithDetails("reason", "pack sizes are locked; unlock them before making changes"))
+		return
+	}
+	var reqErr *domain.RequiredPackSizesError
+	if errors.As(err, &reqErr) {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+			WithDetails("field", "sizes").
+			WithDetails("missing", reqErr.Missing).
+			WithDetails("reason", "required pack sizes cannot be removed"))
+		return
+	}
+	var conflict *domain.VersionConflictError
+	if errors.As(err, &conflict) {
+		a.errorHandler.HandleAPIError(w, r, ErrConflict.
+			WithDetails("expected", conflict.Expected).
+			WithDetails("current", conflict.Current))
+		return
+	}
+	a.errorHandler.HandleError(w, r, ErrDatabaseError.WithDetails("operation", "replace_pack_sizes"))
+}
+
+// handleReadError maps errors from reading the active pack set to API errors. A dependency known
+// to be down (domain.UnavailableError) is a 503 with Retry-After, so clients can back off until
+// the circuit breaker's next trial; anything else is a storage failure.
+func (a *packSvcAdapter) handleReadError(w http.ResponseWriter, r *http.Request, err error, operation string) {
+	var unavailable *domain.UnavailableError
+	if errors.As(err, &unavailable) {
+		retryAfter := retryAfterSeconds(unavailable.RetryAfter)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		a.errorHandler.HandleAPIError(w, r, ErrUnavailable.
+			WithDetails("operation", operation).
+			WithDetails("dependency", unavailable.Dependency).
+			WithDetails("retryAfterSeconds", retryAfter))
+		return
+	}
+	a.errorHandler.HandleError(w, r, ErrDatabaseError.WithDetails("operation", operation))
+}
+
+// retryAfterSeconds rounds d up to whole seconds for a Retry-After header, which can't say zero
+// without inviting an immediate retry.
+func retryAfterSeconds(d time.Duration) int {
+	return max(int((d+time.Second-1)/time.Second), 1)
+}
+
+// packsResponse builds the JSON body for pack listings.
+// "sizes" keeps the plain integer array for backward compatibility; "packs" adds the SKUs.
+func packsResponse(packs []domain.Pack) map[string]any {
+	sizes := make([]int, len(packs))
+	for i, p := range packs {
+		sizes[i] = p.Size
+	}
+	return map[string]any{"sizes": sizes, "packs": packs}
 }
 
 // putPacksReq represents the request body for updating pack sizes.
+// Sizes may be plain integers ([250, 500]) or objects with SKUs ([{"size":500,"sku":"BOX-500"}]).
 type putPacksReq struct {
-	Sizes []int `json:"sizes"`
+	Sizes []packInput `json:"sizes"`
+}
+
+// sizes returns just the pack sizes from the request, in input order.
+func (req putPacksReq) sizes() []int {
+	out := make([]int, len(req.Sizes))
+	for i, p := range req.Sizes {
+		out[i] = p.Size
+	}
+	return out
+}
+
+// packs returns the request entries as domain packs, and whether any of them carries a SKU.
+func (req putPacksReq) packs() ([]domain.Pack, bool) {
+	out := make([]domain.Pack, len(req.Sizes))
+	labeled := false
+	for i, p := range req.Sizes {
+		out[i] = domain.Pack(p)
+		if p.SKU != "" {
+			labeled = true
+		}
+	}
+	return out, labeled
+}
+
+// packInput is a pack size entry in a request body.
+// It accepts either a bare integer or an object with "size" and optional "sku".
+type packInput struct {
+	Size int
+	SKU  string
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting both supported entry formats.
+func (p *packInput) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &p.Size); err == nil {
+		p.SKU = ""
+		return nil
+	}
+	var obj domain.Pack
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	p.Size, p.SKU = obj.Size, strings.TrimSpace(obj.SKU)
+	return nil
+}
+
+// validationError translates a domain validation failure into a VALIDATION_FAILED APIError,
+// copying the field, details and reason so responses look the same as before the rules moved.
+func validationError(err error) *APIError {
+	var ve *domain.ValidationError
+	if !errors.As(err, &ve) {
+		return ErrValidationFailed.WithDetails("reason", err.Error())
+	}
+	apiErr := ErrValidationFailed.WithDetails("field", ve.Field)
+	for k, v := range ve.Details {
+		apiErr = apiErr.WithDetails(k, v)
+	}
+	return apiErr.WithDetails("reason", ve.Reason)
+}
+
+// calculationError translates a calculator failure into an APIError: a domain.NoSolutionError
+// becomes NO_SOLUTION (422), since the input was valid but can't be packed; anything else is
+// an internal CALCULATION_ERROR.
+func calculationError(err error) *APIError {
+	var ns *domain.NoSolutionError
+	if errors.As(err, &ns) {
+		return ErrNoSolution.WithDetails("amount", ns.Amount).WithDetails("reason", ns.Reason)
+	}
+	return ErrCalculationError
+}
+
+// validateSizes checks pack sizes with the configured validator.
+// Returns a structured validation error pointing at the first offending index, or nil if all sizes are valid.
+// Empty slices are considered valid - validation for zero sizes happens at calculation time.
+func (a *packSvcAdapter) validateSizes(sizes []int) *APIError {
+	if err := a.cfg.Validator.ValidateSizes(sizes); err != nil {
+		return validationError(err)
+	}
+	return nil
+}
+
+// validatePackCount checks that a set about to become the active set doesn't exceed the maximum pack count.
+func (a *packSvcAdapter) validatePackCount(sizes []int) *APIError {
+	if err := a.cfg.Validator.ValidatePackCount(sizes); err != nil {
+		return validationError(err)
+	}
+	return nil
+}
+
+// checkPackSizesWithinAmount flags pack sizes larger than the maximum order amount.
+// In strict mode they fail validation; otherwise the request proceeds with a Warning header.
+func (a *packSvcAdapter) checkPackSizesWithinAmount(w http.ResponseWriter, sizes []int) *APIError {
+	err := a.cfg.Validator.ValidatePackSizesWithinAmount(sizes)
+	if err == nil {
+		return nil
+	}
+	if a.cfg.StrictPackSizes {
+		return validationError(err)
+	}
+	var ve *domain.ValidationError
+	if errors.As(err, &ve) {
+		size, _ := ve.Details["value"].(int)
+		w.Header().Set("Warning", "299 - "+strconv.Quote("pack size "+strconv.Itoa(size)+" exceeds the maximum order amount"))
+	}
+	return nil
+}
+
+// validatePackSKUs checks that SKUs fit within the configured maximum length.
+// Returns a structured validation error pointing at the first offending index, or nil if all SKUs are valid.
+func (a *packSvcAdapter) validatePackSKUs(packs []domain.Pack) *APIError {
+	if err := a.cfg.Validator.ValidateSKUs(packs); err != nil {
+		return validationError(err)
+	}
+	return nil
+}
+
+// invalidPackSizes returns the sizes that would fail validateSizes, in input order.
+func (a *packSvcAdapter) invalidPackSizes(sizes []int) []int {
+	invalid := []int{}
+	for _, s := range sizes {
+		if a.cfg.Validator.ValidateSizes([]int{s}) != nil {
+			invalid = append(invalid, s)
+		}
+	}
+	return invalid
+}
+
+// normalizePackSizes returns a sorted, deduplicated copy of the given sizes.
+// This mirrors the normalization applied by the repository when persisting.
+func normalizePackSizes(sizes []int) []int {
+	uniq := make(map[int]struct{}, len(sizes))
+	out := make([]int, 0, len(sizes))
+	for _, s := range sizes {
+		if _, ok := uniq[s]; ok {
+			continue
+		}
+		uniq[s] = struct{}{}
+		out = append(out, s)
+	}
+	sort.Ints(out)
+	return out
 }
 
 // putPacks replaces all pack sizes with a new set provided in the request body.
 // Validates that all sizes are positive integers and within the maximum limit (10,000).
 // Allows empty arrays - validation for zero sizes happens at calculation time.
+// The response includes the version created, so clients can pin later calculations to it.
+// With an If-Match header naming the version the client last saw, the change is refused with
+// 409 Conflict if the active set has moved on since, instead of overwriting someone else's edit.
 func (a *packSvcAdapter) putPacks(w http.ResponseWriter, r *http.Request) {
 	var req putPacksReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		a.errorHandler.HandleAPIError(w, r, ErrInvalidInput.WithDetails("field", "body").WithDetails("reason", "invalid JSON format"))
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
 		return
 	}
 	
 	// Allow empty arrays - validation happens at calculation time
-	// Validate pack sizes: must be positive and <= 10,000
-	const maxPackSize = 10_000
-	for i, s := range req.Sizes {
-		if s <= 0 {
-			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
-				WithDetails("field", "sizes").
-				WithDetails("index", i).
-				WithDetails("value", s).
-				WithDetails("reason", "pack sizes must be positive"))
-			return
-		}
-		if s > maxPackSize {
-			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
-				WithDetails("field", "sizes").
-				WithDetails("index", i).
-				WithDetails("value", s).
-				WithDetails("reason", "pack sizes cannot exceed 10,000 items"))
+	if apiErr := a.validateSizes(req.sizes()); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	if apiErr := a.validatePackCount(req.sizes()); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	if apiErr := a.checkPackSizesWithinAmount(w, req.sizes()); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	packs, labeled := req.packs()
+	if apiErr := a.validatePackSKUs(packs); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	
+	expected, ok := a.ifMatchVersions(w, r)
+	if !ok {
+		return
+	}
+	
+	// Plain integer arrays keep the original sizes-only path
+	if !labeled && expected == nil {
+		sizes, version, err := a.svc.ReplaceActive(r.Context(), req.sizes())
+		if err != nil {
+			a.handleReplaceError(w, r, err)
 			return
 		}
+		writeJSON(w, http.StatusOK, map[string]any{"sizes": sizes, "version": version})
+		return
 	}
 	
-	// Replace all pack sizes with the new set
-	sizes, err := a.svc.ReplaceActive(r.Context(), req.Sizes)
+	// Replace all pack sizes and their SKUs with the new set, conditionally when If-Match
+	// names versions, so the check and the write happen in one step
+	var out []domain.Pack
+	var version int64
+	var err error
+	if expected != nil {
+		out, version, err = a.svc.ReplaceActivePacksIfVersion(r.Context(), packs, expected)
+	} else {
+		out, version, err = a.svc.ReplaceActivePacks(r.Context(), packs)
+	}
 	if err != nil {
-		a.errorHandler.HandleError(w, r, ErrDatabaseError.WithDetails("operation", "replace_pack_sizes"))
+		a.handleReplaceError(w, r, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"sizes": sizes})
+	if !labeled {
+		sizes := make([]int, len(out))
+		for i, p := range out {
+			sizes[i] = p.Size
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"sizes": sizes, "version": version})
+		return
+	}
+	resp := packsResponse(out)
+	resp["version"] = version
+	writeJSON(w, http.StatusOK, resp)
 }
 
-// calcReq represents the request body for pack calculation.
-type calcReq struct {
-	Amount int   `json:"amount"`           // Number of items to fulfill
-	Sizes  []int `json:"sizes,omitempty"`  // Optional custom pack sizes (uses active if empty)
+// appendPacksReq represents the request body for adding pack sizes to the active set.
+type appendPacksReq struct {
+	Add []int `json:"add"` // Pack sizes to merge into the active set
 }
 
-// postCalculate computes the optimal pack distribution for a given amount.
-// Validates the amount is positive and within limits (1,000,000).
-// If no custom sizes are provided, uses the active pack sizes from the service.
-// Returns a breakdown showing how many packs of each size are needed.
-func (a *packSvcAdapter) postCalculate(w http.ResponseWriter, r *http.Request) {
-	var req calcReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		a.errorHandler.HandleAPIError(w, r, ErrInvalidInput.WithDetails("field", "body").WithDetails("reason", "invalid JSON format"))
+// appendPacks merges sizes into the active set in one update, so clients don't need to read-modify-write.
+// Sizes already present are ignored (keeping their SKUs); if nothing is new the current set is returned
+// unchanged and no new version is created.
+func (a *packSvcAdapter) appendPacks(w http.ResponseWriter, r *http.Request) {
+	var req appendPacksReq
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
 		return
 	}
 	
-	// Validate amount is positive
-	if req.Amount <= 0 {
-		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "amount").WithDetails("value", req.Amount).WithDetails("reason", "amount must be positive"))
+	if len(req.Add) == 0 {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "add").WithDetails("reason", "at least one size is required"))
+		return
+	}
+	if apiErr := a.validateSizes(req.Add); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr.WithDetails("field", "add"))
+		return
+	}
+	if apiErr := a.checkPackSizesWithinAmount(w, req.Add); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr.WithDetails("field", "add"))
 		return
 	}
 	
-	// Validate amount doesn't exceed maximum limit
-	const maxAmount = 1_000_000
-	if req.Amount > maxAmount {
-		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "amount").WithDetails("value", req.Amount).WithDetails("reason", "amount cannot exceed 1,000,000 items"))
+	// Get current pack sizes (with SKUs, so they survive the update)
+	curr, err := a.svc.GetActivePacks(r.Context())
+	if err != nil {
+		a.handleReadError(w, r, err, "get_pack_sizes")
 		return
 	}
 	
-	// Use custom sizes if provided, otherwise fetch active sizes
-	sizes := req.Sizes
-	if len(sizes) == 0 {
-		var err error
-		sizes, err = a.svc.GetActiveSizes(r.Context())
-		if err != nil {
-			a.errorHandler.HandleError(w, r, ErrDatabaseError.WithDetails("operation", "get_pack_sizes"))
-			return
+	// Merge the new sizes, skipping ones already active or repeated in the request
+	seen := make(map[int]bool, len(curr)+len(req.Add))
+	for _, p := range curr {
+		seen[p.Size] = true
+	}
+	next := append([]domain.Pack(nil), curr...)
+	for _, size := range req.Add {
+		if !seen[size] {
+			seen[size] = true
+			next = append(next, domain.Pack{Size: size})
 		}
 	}
 	
-	// Ensure at least one pack size is configured
-	if len(sizes) == 0 {
-		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "sizes").WithDetails("reason", "no pack sizes configured"))
+	// If nothing changed (all sizes already active), return current sizes
+	if len(next) == len(curr) {
+		writeJSON(w, http.StatusOK, packsResponse(curr))
+		return
+	}
+	sizes := make([]int, len(next))
+	for i, p := range next {
+		sizes[i] = p.Size
+	}
+	if apiErr := a.validatePackCount(sizes); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
 		return
 	}
 	
-	// Perform the calculation
-	res, err := a.calc.Compute(r.Context(), req.Amount, sizes)
+	sort.Slice(next, func(i, j int) bool { return next[i].Size < next[j].Size })
+	packs, _, err := a.svc.ReplaceActivePacks(r.Context(), next)
 	if err != nil {
-		a.errorHandler.HandleError(w, r, ErrCalculationError.WithDetails("amount", req.Amount))
+		a.handleReplaceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, packsResponse(packs))
+}
+
+// validatePacks runs the same validation and normalization as putPacks without persisting anything.
+// Returns the normalized (sorted, deduplicated) sizes so clients can preview what would be stored.
+func (a *packSvcAdapter) validatePacks(w http.ResponseWriter, r *http.Request) {
+	var req putPacksReq
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
 		return
 	}
 	
-	// Format breakdown with descending sizes for better readability
-	breakdown := map[string]int{}
-	keys := make([]int, 0, len(res.Breakdown))
-	for s := range res.Breakdown {
-		keys = append(keys, s)
+	if apiErr := a.validateSizes(req.sizes()); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
 	}
-	sort.Sort(sort.Reverse(sort.IntSlice(keys)))
-	for _, s := range keys {
-		breakdown[strconv.Itoa(s)] = res.Breakdown[s]
+	if apiErr := a.validatePackCount(req.sizes()); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	if apiErr := a.checkPackSizesWithinAmount(w, req.sizes()); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	packs, _ := req.packs()
+	if apiErr := a.validatePackSKUs(packs); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
 	}
 	
-	// Return calculation result
 	writeJSON(w, http.StatusOK, map[string]any{
-		"amount":     req.Amount,
-		"totalItems": res.TotalItems,
-		"totalPacks": res.TotalPacks,
-		"breakdown":  breakdown,
-		"overage":    res.Overage,
+		"valid":      true,
+		"normalized": normalizePackSizes(req.sizes()),
 	})
 }
 
-// writeJSON is a helper function to write JSON responses with proper headers.
-// Sets Content-Type header and writes the response with the given status code.
-func writeJSON(w http.ResponseWriter, status int, v any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(v)
+// ifMatchVersions returns the pack set versions named by the request's If-Match header, as entity
+// tags ("7") or bare numbers. expected is nil when the write is unconditional: no header, or "*",
+// which matches any version. The versions are checked by the repository as part of the write, so
+// two clients editing the same version can't both succeed. A malformed header gets a 400 and
+// ok false.
+func (a *packSvcAdapter) ifMatchVersions(w http.ResponseWriter, r *http.Request) (expected []int64, ok bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil, true
+	}
+	expected, wildcard, ok := parseIfMatchVersions(header)
+	if !ok {
+		a.errorHandler.HandleAPIError(w, r, ErrInvalidInput.WithDetails("field", "If-Match").WithDetails("reason", "must list pack set versions, e.g. \"7\""))
+		return nil, false
+	}
+	if wildcard {
+		return nil, true
+	}
+	return expected, true
+}
+
+// parseIfMatchVersions parses an If-Match header into the pack set versions it lists.
+// wildcard is true for "*". Weak tags (W/"7") never match under If-Match's strong comparison
+// (RFC 9110 13.1.1), so they are rejected like any other value that isn't a version.
+func parseIfMatchVersions(header string) (versions []int64, wildcard bool, ok bool) {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return nil, true, true
+		}
+		if len(candidate) >= 2 && candidate[0] == '"' && candidate[len(candidate)-1] == '"' {
+			candidate = candidate[1 : len(candidate)-1]
+		}
+		v, err := strconv.ParseInt(candidate, 10, 64)
+		if err != nil || v < 0 {
+			return nil, false, false
+		}
+		versions = append(versions, v)
+	}
+	return versions, false, len(versions) > 0
+}
+
+// getLock reports whether pack size changes are currently locked.
+func (a *packSvcAdapter) getLock(w http.ResponseWriter, r *http.Request) {
+	locked, err := a.svc.IsLocked(r.Context())
+	if err != nil {
+		a.errorHandler.HandleError(w, r, ErrDatabaseError.WithDetails("operation", "get_pack_lock"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"locked": locked})
+}
+
+// postLock freezes pack size changes. Reads and calculations continue to work.
+func (a *packSvcAdapter) postLock(w http.ResponseWriter, r *http.Request) {
+	a.setLock(w, r, true)
+}
+
+// postUnlock allows pack size changes again.
+func (a *packSvcAdapter) postUnlock(w http.ResponseWriter, r *http.Request) {
+	a.setLock(w, r, false)
+}
+
+// setLock persists the lock state and returns it. Locking is an admin operation, so like
+// POST /cache/flush it needs an elevated API key.
+func (a *packSvcAdapter) setLock(w http.ResponseWriter, r *http.Request, locked bool) {
+	if !a.requireElevated(w, r) {
+		return
+	}
+	if err := a.svc.SetLocked(r.Context(), locked); err != nil {
+		a.errorHandler.HandleError(w, r, ErrDatabaseError.WithDetails("operation", "set_pack_lock"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"locked": locked})
+}
+
+// calcReq represents the request body for pack calculation.
+type calcReq struct {
+	Amount  wholeAmount `json:"amount"`      // Number of items to fulfill; see wholeAmount
+	Sizes   []int `json:"sizes,omitempty"`   // Optional custom pack sizes (uses active if empty)
+	Exclude []int `json:"exclude,omitempty"` // Optional pack sizes to leave out of this calculation
+	
+	// Optional sizes to favor when several solutions have the same items and packs
+	Preferred []int `json:"preferred,omitempty"`
+	
+	// Optional historical pack set version to calculate against instead of the active set
+	Version int64 `json:"version,omitempty"`
+	
+	// Optional tie-break policy ("ItemsFirst" or "PacksFirst") overriding the server default
+	TieBreak string `json:"tieBreak,omitempty"`
+	
+	// Optional lot size: the amount is rounded up to a multiple of it before optimizing
+	RoundTo *int `json:"roundTo,omitempty"`
+	
+	// Optional unit the amount is given in (e.g. "cases"); converted to pack units with the configured factor
+	Unit string `json:"unit,omitempty"`
+	
+	// Optional ID of a saved custom pack set (POST /packs/custom) to calculate with
+	SetID string `json:"setId,omitempty"`
+	
+	// Optional rule requiring at least one pack of every size (promotional bundles)
+	MinOnePerSize bool `json:"minOnePerSize,omitempty"`
+	
+	// Optional pack budget: the solution may use at most this many packs (e.g. fixed pallet slots)
+	FixedPacks *int `json:"fixedPacks,omitempty"`
+	
+	// Optional bias toward fewer packs at the cost of more items, for pathological size sets
+	// whose fewest-items solution is thousands of small packs; the same as tieBreak PacksFirst
+	PreferFewerPacks bool `json:"preferFewerPacks,omitempty"`
+	
+	// Optional minimum fraction (0-1) of the last, partly used pack that must hold ordered items,
+	// to avoid opening a pack for a tiny remainder
+	MinUtilization float64 `json:"minUtilization,omitempty"`
+}
+
+// wholeAmount is an order amount as sent by clients. JavaScript clients often send the amount
+// as a float (263.0) or a string ("263"), so those are accepted when they hold a whole number.
+type wholeAmount int
+
+// UnmarshalJSON implements json.Unmarshaler. A fractional amount (263.5) is a *fractionalNumberError;
+// anything else that isn't a number is a *notNumberError, which decodeJSON reports as a type error.
+func (n *wholeAmount) UnmarshalJSON(data []byte) error {
+	v, ok, err := wholeNumber("amount", data)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &notNumberError{Field: "amount", Value: jsonKind(data)}
+	}
+	*n = wholeAmount(v)
+	return nil
+}
+
+// notNumberError reports a JSON value that isn't a whole number where one is required.
+type notNumberError struct {
+	Field string
+	Value string // Kind of the value found, e.g. "string"
+}
+
+func (e *notNumberError) Error() string {
+	return fmt.Sprintf("%s must be a number, got %s", e.Field, e.Value)
+}
+
+// jsonKind names the kind of a raw JSON value the way json.UnmarshalTypeError does, e.g. "string".
+func jsonKind(raw json.RawMessage) string {
+	switch raw[0] {
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "bool"
+	case '[':
+		return "array"
+	case '{':
+		return "object"
+	}
+	return "number " + string(raw)
+}
+
+// fractionalNumberError reports a number with a fractional part where a whole number is required.
+type fractionalNumberError struct {
+	Field string
+	Value string
+}
+
+func (e *fractionalNumberError) Error() string {
+	return fmt.Sprintf("%s must be a whole number, got %s", e.Field, e.Value)
+}
+
+// wholeNumber decodes raw, a JSON number or a string holding one, as an int. An absent or null
+// value is 0. ok is false if raw isn't a number that fits an int; a number with a fractional
+// part is a *fractionalNumberError for field.
+func wholeNumber(field string, raw json.RawMessage) (n int, ok bool, err error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, true, nil
+	}
+	num := json.Number(raw)
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0, false, nil
+		}
+		// Decoding the content as JSON accepts exactly the JSON number syntax, not "0x10" or "NaN"
+		if err := json.Unmarshal([]byte(s), &num); err != nil || strings.HasPrefix(strings.TrimSpace(s), `"`) {
+			return 0, false, nil
+		}
+	}
+	
+	if i, err := strconv.ParseInt(num.String(), 10, 0); err == nil {
+		return int(i), true, nil
+	}
+	f, err := num.Float64()
+	if err != nil {
+		return 0, false, nil // Not a number, or out of range
+	}
+	if f != math.Trunc(f) {
+		return 0, false, &fractionalNumberError{Field: field, Value: num.String()}
+	}
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false, nil
+	}
+	return int(f), true, nil
+}
+
+// postCalculate computes the optimal pack distribution for a given amount.
+// Validates the amount is positive, at least the configured minimum order amount, and within limits (1,000,000).
+// Requests with an elevated X-API-Key may exceed that limit up to the configured hard maximum.
+// If no custom sizes are provided, uses the active pack sizes from the service.
+// Sizes listed in "exclude" are removed from the chosen set before computing.
+// Sizes listed in "preferred" only break ties between equally optimal solutions.
+// With "version", the sizes of that historical pack set are used (useful for diagnosing pack-set changes).
+// The response carries the "version" of the pack set used, active or historical, so the calculation
+// can be repeated identically later; it is omitted for custom sizes and saved custom sets.
+// "tieBreak" overrides the server's default policy: "ItemsFirst" or "PacksFirst".
+// With "roundTo" the amount is first rounded up to that multiple (billing lots); the response then
+// reports originalAmount and roundedAmount, and amount and overage refer to the rounded amount.
+// Returns a breakdown showing how many packs of each size are needed.
+// With ?detailed=true each breakdown entry becomes {"count": n, "items": size*n}.
+// With ?breakdownFormat=list the breakdown is a list of {"size", "count"} ordered by descending
+// size instead of a map, so clients can iterate it in a stable order.
+// With ?order=asc the ordered outputs (the "packs" list, and the breakdown list) run from the
+// smallest size up instead, e.g. for warehouse pick paths; ?order=desc is the default.
+// With ?summaryOnly=true the breakdown isn't built at all: only amount, totalItems, totalPacks,
+// overage and shortfall are returned (plus version, rounding and unit figures as above).
+// With "minOnePerSize" the result holds at least one pack of every size and the rest of the amount
+// is optimized as usual; one pack of each size must itself stay within the maximum order amount.
+// With "fixedPacks" the solution uses at most that many packs, still minimizing items first;
+// when no combination that small covers the amount the response is NO_SOLUTION (422).
+// With "preferFewerPacks" the fewest packs are chosen first, trading overage for pack count.
+// With "minUtilization" solutions whose last pack, filled largest first, holds less than that
+// fraction of ordered items are skipped; NO_SOLUTION (422) when no solution qualifies.
+// When the server sets a pack warning threshold, results with more packs than that carry
+// "fragmented": true, so clients can flag solutions that are impractical to pick.
+func (a *packSvcAdapter) postCalculate(w http.ResponseWriter, r *http.Request) {
+	var req calcReq
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	
+	// A summary has no breakdown to detail
+	detailed, _ := strconv.ParseBool(r.URL.Query().Get("detailed"))
+	summaryOnly, _ := strconv.ParseBool(r.URL.Query().Get("summaryOnly"))
+	if detailed && summaryOnly {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+			WithDetails("field", "summaryOnly").
+			WithDetails("reason", "summaryOnly cannot be combined with detailed"))
+		return
+	}
+	listed, apiErr := parseBreakdownFormat(r.URL.Query().Get("breakdownFormat"))
+	if apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	if listed && detailed {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+			WithDetails("field", "breakdownFormat").
+			WithDetails("reason", "breakdownFormat=list cannot be combined with detailed"))
+		return
+	}
+	ascending, apiErr := parseSortOrder(r.URL.Query().Get("order"))
+	if apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	
+	// Convert an amount given in another unit (e.g. cases) to pack units
+	ordered := int(req.Amount)
+	if req.Unit != "" {
+		if ordered <= 0 {
+			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "amount").WithDetails("value", ordered).WithDetails("reason", "amount must be positive"))
+			return
+		}
+		var apiErr *APIError
+		if ordered, apiErr = a.toPackUnits(ordered, req.Unit); apiErr != nil {
+			a.errorHandler.HandleAPIError(w, r, apiErr)
+			return
+		}
+	}
+	
+	// Validate the amount: positive, at least the minimum order amount, within the maximum
+	// (or the hard maximum for requests with an elevated API key)
+	if apiErr := a.validateOrderAmount(r, ordered); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	
+	// Reject custom sizes that contain no usable pack size at all,
+	// rather than letting the calculator return an empty result
+	if len(req.Sizes) > 0 {
+		if invalid := a.invalidPackSizes(req.Sizes); len(invalid) == len(req.Sizes) {
+			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+				WithDetails("field", "sizes").
+				WithDetails("values", invalid).
+				WithDetails("maximum", a.cfg.Validator.Limits().MaxPackSize).
+				WithDetails("reason", "pack sizes must be positive and within the maximum pack size"))
+			return
+		}
+	}
+	
+	// Round the amount up to the requested lot size; the rounded amount must still be acceptable
+	amount := ordered
+	if req.RoundTo != nil {
+		if lot := *req.RoundTo; lot <= 0 || lot > a.cfg.Validator.Limits().MaxAmount {
+			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+				WithDetails("field", "roundTo").
+				WithDetails("value", lot).
+				WithDetails("reason", "roundTo must be positive and within the maximum order amount"))
+			return
+		}
+		amount = (ordered + *req.RoundTo - 1) / *req.RoundTo * *req.RoundTo
+		if apiErr := a.validateOrderAmount(r, amount); apiErr != nil {
+			a.errorHandler.HandleAPIError(w, r, apiErr.WithDetails("field", "roundTo").WithDetails("roundedAmount", amount))
+			return
+		}
+	}
+	
+	// Validate the pack budget, if any
+	maxPacks := 0
+	if req.FixedPacks != nil {
+		if maxPacks = *req.FixedPacks; maxPacks <= 0 {
+			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+				WithDetails("field", "fixedPacks").
+				WithDetails("value", maxPacks).
+				WithDetails("reason", "fixedPacks must be positive"))
+			return
+		}
+	}
+	
+	// Validate the utilization minimum, if any
+	if req.MinUtilization < 0 || req.MinUtilization > 1 {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+			WithDetails("field", "minUtilization").
+			WithDetails("value", req.MinUtilization).
+			WithDetails("reason", "minUtilization must be between 0 and 1"))
+		return
+	}
+	
+	// Validate the tie-break policy override, if any
+	var tieBreak domain.TieBreak
+	if req.TieBreak != "" {
+		var ok bool
+		if tieBreak, ok = domain.ParseTieBreak(req.TieBreak); !ok {
+			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+				WithDetails("field", "tieBreak").
+				WithDetails("value", req.TieBreak).
+				WithDetails("reason", "tieBreak must be ItemsFirst or PacksFirst"))
+			return
+		}
+	}
+	if req.PreferFewerPacks {
+		if tieBreak == domain.TieBreakItemsFirst {
+			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+				WithDetails("field", "preferFewerPacks").
+				WithDetails("reason", "preferFewerPacks cannot be combined with tieBreak ItemsFirst"))
+			return
+		}
+		tieBreak = domain.TieBreakPacksFirst
+	}
+	
+	// A historical version replaces the active set, so it can't be combined with custom sizes
+	if req.Version < 0 || (req.Version > 0 && len(req.Sizes) > 0) {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+			WithDetails("field", "version").
+			WithDetails("value", req.Version).
+			WithDetails("reason", "version must be positive and cannot be combined with custom sizes"))
+		return
+	}
+	
+	// Likewise a saved custom set replaces both
+	if req.SetID != "" && (req.Version > 0 || len(req.Sizes) > 0) {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+			WithDetails("field", "setId").
+			WithDetails("reason", "setId cannot be combined with custom sizes or a version"))
+		return
+	}
+	
+	// Use custom sizes if provided, the saved set or historical version requested, otherwise fetch active sizes
+	var sizes []int
+	var skus map[int]string
+	var version int64 // Pack set version used, 0 for custom sizes
+	var err error
+	if req.SetID != "" {
+		set, ok, serr := a.svc.GetCustomSet(r.Context(), req.SetID)
+		if serr != nil {
+			a.errorHandler.HandleError(w, r, ErrInternalError.WithDetails("operation", "get_custom_set"))
+			return
+		}
+		if !ok {
+			a.errorHandler.HandleAPIError(w, r, ErrNotFound.WithDetails("resource", "pack_set").WithDetails("id", req.SetID).WithDetails("reason", "pack set not found or expired"))
+			return
+		}
+		sizes = set.Sizes
+	} else if req.Version > 0 {
+		packs, ok, verr := a.svc.GetPacksAtVersion(r.Context(), req.Version)
+		if verr != nil {
+			a.errorHandler.HandleError(w, r, ErrDatabaseError.WithDetails("operation", "get_pack_version"))
+			return
+		}
+		if !ok {
+			a.errorHandler.HandleAPIError(w, r, ErrNotFound.WithDetails("resource", "pack_set_version").WithDetails("version", req.Version))
+			return
+		}
+		sizes, skus = splitPacks(packs)
+		version = req.Version
+	} else if len(req.Sizes) > 0 {
+		sizes = req.Sizes
+	} else {
+		packs, ver, aerr := a.svc.GetActivePacksWithVersion(r.Context())
+		if aerr != nil {
+			a.handleReadError(w, r, aerr, "get_pack_sizes")
+			return
+		}
+		sizes, skus = splitPacks(packs)
+		version = ver
+	}
+	
+	// Drop any sizes excluded for this calculation only
+	if len(req.Exclude) > 0 {
+		sizes = excludePackSizes(sizes, req.Exclude)
+	}
+	
+	// Ensure at least one pack size is configured
+	if err := a.cfg.Validator.ValidatePackSet(sizes); err != nil {
+		a.errorHandler.HandleAPIError(w, r, validationError(err))
+		return
+	}
+	
+	// One pack of each size can exceed the amount; the bundle is then what gets shipped,
+	// so it must be an acceptable order itself (a bundle within the amount already is)
+	if req.MinOnePerSize {
+		if bundle := bundleItems(sizes); bundle > amount {
+			if apiErr := a.validateOrderAmount(r, bundle); apiErr != nil {
+				a.errorHandler.HandleAPIError(w, r, apiErr.WithDetails("field", "minOnePerSize").WithDetails("bundleItems", bundle))
+				return
+			}
+		}
+	}
+	
+	// Perform the calculation, applying the tie-break override, preferred sizes, summary mode,
+	// the one-of-each rule, the pack budget and the utilization minimum if requested.
+	// The version tells a result cache whether the sizes came from a stored pack set or are custom.
+	opts := domain.CalcOptions{TieBreak: tieBreak, Preferred: req.Preferred, SummaryOnly: summaryOnly, MinOnePerSize: req.MinOnePerSize, MaxPacks: maxPacks, MinUtilization: req.MinUtilization, Version: version}
+	res, err := a.calc.ComputeWithOptions(r.Context(), amount, sizes, opts)
+	if err != nil {
+		a.errorHandler.HandleError(w, r, calculationError(err).WithDetails("amount", amount))
+		return
+	}
+	
+	// Return calculation result, with per-size item contributions if requested
+	resp := calcResponse(amount, res, skus)
+	if summaryOnly {
+		delete(resp, "breakdown")
+		delete(resp, "packs")
+	}
+	if req.RoundTo != nil {
+		resp["originalAmount"] = ordered
+		resp["roundedAmount"] = amount
+	}
+	if req.Unit != "" {
+		resp["converted"] = a.convertResult(int(req.Amount), req.Unit, res)
+	}
+	if version > 0 {
+		resp["version"] = version
+	}
+	if detailed {
+		resp["breakdown"] = detailedBreakdown(res.Breakdown)
+	}
+	if listed && !summaryOnly {
+		resp["breakdown"] = orderedBreakdown(res)
+	}
+	// Only the packs list and a listed breakdown have an order; maps (plain or detailed) don't
+	if ascending {
+		ascendingBreakdown(resp)
+	}
+	if a.cfg.MaxPacksWarn > 0 && res.TotalPacks > a.cfg.MaxPacksWarn {
+		resp["fragmented"] = true
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// getExactFit reports whether ?amount=N can be fulfilled with no overage from the active pack sizes.
+// Only reachability is checked, so it's cheaper than a full calculation when the breakdown isn't needed.
+// The answer is fixed for an amount and pack set version, so responses carry an ETag for that pair
+// and a request with a matching If-None-Match gets a 304 without the check being run.
+func (a *packSvcAdapter) getExactFit(w http.ResponseWriter, r *http.Request) {
+	raw := r.URL.Query().Get("amount")
+	amount, err := strconv.Atoi(raw)
+	if err != nil {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "amount").WithDetails("value", raw).WithDetails("reason", "amount must be an integer"))
+		return
+	}
+	if err := a.cfg.Validator.ValidateAmount(amount); err != nil {
+		a.errorHandler.HandleAPIError(w, r, validationError(err))
+		return
+	}
+	
+	packs, version, err := a.svc.GetActivePacksWithVersion(r.Context())
+	if err != nil {
+		a.handleReadError(w, r, err, "get_pack_sizes")
+		return
+	}
+	sizes, _ := splitPacks(packs)
+	if err := a.cfg.Validator.ValidatePackSet(sizes); err != nil {
+		a.errorHandler.HandleAPIError(w, r, validationError(err))
+		return
+	}
+	
+	if notModified(w, r, calcETag("exact", version, amount, sizes)) {
+		return
+	}
+	exact, err := a.calc.ExactFit(r.Context(), amount, sizes)
+	if err != nil {
+		a.errorHandler.HandleError(w, r, ErrCalculationError.WithDetails("amount", amount))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"amount": amount, "exact": exact})
+}
+
+// consolidateReq represents the request body for consolidated order calculation.
+type consolidateReq struct {
+	Amounts []int  `json:"amounts"`         // Order amounts to consolidate into one shipment
+	Mode    string `json:"mode,omitempty"`  // "sum" (default) or "perOrder"
+	Sizes   []int  `json:"sizes,omitempty"` // Optional custom pack sizes (uses active if empty)
+}
+
+// Consolidation modes supported by postConsolidate.
+const (
+	consolidateModeSum      = "sum"      // Optimize the total of all orders
+	consolidateModePerOrder = "perOrder" // Optimize each order separately
+)
+
+// maxConsolidatedOrders limits how many orders can be merged in one request.
+const maxConsolidatedOrders = 100
+
+// postConsolidate compares optimizing several orders as one shipment against optimizing each order separately.
+// Both strategies are always computed so the savings are visible; the mode selects which breakdown is returned
+// in full ("sum" returns the consolidated breakdown, "perOrder" returns each order's breakdown).
+// The sum of all amounts must stay within the same 1,000,000 limit as a single calculation.
+func (a *packSvcAdapter) postConsolidate(w http.ResponseWriter, r *http.Request) {
+	var req consolidateReq
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	
+	// Default to optimizing the sum
+	if req.Mode == "" {
+		req.Mode = consolidateModeSum
+	}
+	if req.Mode != consolidateModeSum && req.Mode != consolidateModePerOrder {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "mode").WithDetails("value", req.Mode).WithDetails("reason", "mode must be \"sum\" or \"perOrder\""))
+		return
+	}
+	
+	// Validate the list of amounts
+	if len(req.Amounts) == 0 {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "amounts").WithDetails("reason", "at least one amount is required"))
+		return
+	}
+	if len(req.Amounts) > maxConsolidatedOrders {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "amounts").WithDetails("count", len(req.Amounts)).WithDetails("reason", "cannot consolidate more than 100 orders"))
+		return
+	}
+	total := 0
+	for i, amt := range req.Amounts {
+		if amt <= 0 {
+			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "amounts").WithDetails("index", i).WithDetails("value", amt).WithDetails("reason", "amount must be positive"))
+			return
+		}
+		total += amt
+		if total > a.cfg.Validator.Limits().MaxAmount {
+			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+				WithDetails("field", "amounts").
+				WithDetails("maximum", a.cfg.Validator.Limits().MaxAmount).
+				WithDetails("reason", "sum of amounts exceeds the maximum order amount"))
+			return
+		}
+	}
+	
+	// Use custom sizes if provided, otherwise fetch active sizes
+	sizes, skus, err := a.resolveSizes(r, req.Sizes)
+	if err != nil {
+		a.handleReadError(w, r, err, "get_pack_sizes")
+		return
+	}
+	if err := a.cfg.Validator.ValidatePackSet(sizes); err != nil {
+		a.errorHandler.HandleAPIError(w, r, validationError(err))
+		return
+	}
+	
+	// Optimize the consolidated shipment
+	consolidated, err := a.calc.Compute(r.Context(), total, sizes)
+	if err != nil {
+		a.errorHandler.HandleError(w, r, calculationError(err).WithDetails("amount", total))
+		return
+	}
+	
+	// Optimize each order on its own for comparison
+	perOrder := make([]domain.CalculationResult, 0, len(req.Amounts))
+	for _, amt := range req.Amounts {
+		res, err := a.calc.Compute(r.Context(), amt, sizes)
+		if err != nil {
+			a.errorHandler.HandleError(w, r, calculationError(err).WithDetails("amount", amt))
+			return
+		}
+		perOrder = append(perOrder, res)
+	}
+	
+	cmp := compareConsolidation(consolidated, perOrder)
+	resp := map[string]any{
+		"mode":    req.Mode,
+		"amounts": req.Amounts,
+		"consolidated": map[string]any{
+			"amount":     total,
+			"totalItems": consolidated.TotalItems,
+			"totalPacks": consolidated.TotalPacks,
+			"overage":    consolidated.Overage,
+			"shortfall":  consolidated.Shortfall,
+		},
+		"perOrderTotals": map[string]any{
+			"totalItems": cmp.perOrderItems,
+			"totalPacks": cmp.perOrderPacks,
+		},
+		"savings": map[string]any{
+			"items": cmp.itemsSaved,
+			"packs": cmp.packsSaved,
+		},
+	}
+	
+	// Include the full breakdown for the selected strategy
+	if req.Mode == consolidateModeSum {
+		resp["consolidated"] = calcResponse(total, consolidated, skus)
+	} else {
+		orders := make([]map[string]any, 0, len(perOrder))
+		for i, res := range perOrder {
+			orders = append(orders, calcResponse(req.Amounts[i], res, skus))
+		}
+		resp["perOrder"] = orders
+	}
+	
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// compareReq represents the request body for comparing pack-size sets.
+type compareReq struct {
+	Amount int     `json:"amount"` // Number of items to fulfill
+	Sets   [][]int `json:"sets"`   // Candidate pack-size sets to compare
+}
+
+// maxCompareSets limits how many pack-size sets can be compared in one request.
+const maxCompareSets = 10
+
+// postCompare computes the optimal pack distribution for the same amount using each candidate set.
+// Results are returned in request order, along with the index of the set producing the fewest items
+// and the index of the set producing the fewest packs. "best" applies the usual rules:
+// fewest items first, then fewest packs; ties keep the earliest set.
+func (a *packSvcAdapter) postCompare(w http.ResponseWriter, r *http.Request) {
+	var req compareReq
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	
+	// Validate amount
+	if err := a.cfg.Validator.ValidateAmount(req.Amount); err != nil {
+		a.errorHandler.HandleAPIError(w, r, validationError(err))
+		return
+	}
+	
+	// Validate the candidate sets
+	if len(req.Sets) == 0 {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "sets").WithDetails("reason", "at least one pack-size set is required"))
+		return
+	}
+	if len(req.Sets) > maxCompareSets {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "sets").WithDetails("count", len(req.Sets)).WithDetails("reason", "cannot compare more than 10 pack-size sets"))
+		return
+	}
+	for i, set := range req.Sets {
+		if len(set) == 0 {
+			a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "sets").WithDetails("set", i).WithDetails("reason", "no pack sizes configured"))
+			return
+		}
+		if apiErr := a.validateSizes(set); apiErr != nil {
+			a.errorHandler.HandleAPIError(w, r, apiErr.WithDetails("set", i))
+			return
+		}
+	}
+	
+	// Compute each set and track the winners
+	results := make([]map[string]any, 0, len(req.Sets))
+	best, fewestItems, fewestPacks := 0, 0, 0
+	var bestRes domain.CalculationResult
+	var minItems, minPacks int
+	for i, set := range req.Sets {
+		sizes := normalizePackSizes(set)
+		res, err := a.calc.Compute(r.Context(), req.Amount, sizes)
+		if err != nil {
+			a.errorHandler.HandleError(w, r, calculationError(err).WithDetails("amount", req.Amount).WithDetails("set", i))
+			return
+		}
+		
+		entry := calcResponse(req.Amount, res, nil)
+		entry["sizes"] = sizes
+		results = append(results, entry)
+		
+		if i == 0 || res.TotalItems < bestRes.TotalItems ||
+			(res.TotalItems == bestRes.TotalItems && res.TotalPacks < bestRes.TotalPacks) {
+			best, bestRes = i, res
+		}
+		if i == 0 || res.TotalItems < minItems {
+			fewestItems, minItems = i, res.TotalItems
+		}
+		if i == 0 || res.TotalPacks < minPacks {
+			fewestPacks, minPacks = i, res.TotalPacks
+		}
+	}
+	
+	writeJSON(w, http.StatusOK, map[string]any{
+		"amount":      req.Amount,
+		"results":     results,
+		"best":        best,
+		"fewestItems": fewestItems,
+		"fewestPacks": fewestPacks,
+	})
+}
+
+// batchReq represents the request body for calculating several amounts at once.
+type batchReq struct {
+	Amounts []int `json:"amounts"`         // Order amounts to calculate
+	Sizes   []int `json:"sizes,omitempty"` // Optional custom pack sizes (uses active if empty)
+}
+
+// postBatch calculates the optimal pack distribution for several amounts synchronously.
+// Repeated amounts are computed once and the result is copied back to every position
+// they appeared in; all distinct amounts share a single DP table since the sizes match.
+// Batches larger than the configured maximum are rejected; use POST /calculate/jobs instead.
+// An invalid amount or one without a solution fails the whole batch, unless ?partial=true, in
+// which case its result carries an "error" (code and reason) and the rest are still calculated.
+// The response's "aggregate" rolls up the successful results and counts the failed ones.
+func (a *packSvcAdapter) postBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchReq
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBatchBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	partial, _ := strconv.ParseBool(r.URL.Query().Get("partial"))
+	
+	// Validate the batch
+	if len(req.Amounts) == 0 {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "amounts").WithDetails("reason", "at least one amount is required"))
+		return
+	}
+	if len(req.Amounts) > a.cfg.MaxBatchSize {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+			WithDetails("field", "amounts").
+			WithDetails("count", len(req.Amounts)).
+			WithDetails("maximum", a.cfg.MaxBatchSize).
+			WithDetails("reason", "batch contains too many amounts"))
+		return
+	}
+	failed := map[int]map[string]any{} // Amount -> why it failed, in partial mode
+	for i, amt := range req.Amounts {
+		if err := a.cfg.Validator.ValidateAmount(amt); err != nil {
+			if !partial {
+				a.errorHandler.HandleAPIError(w, r, validationError(err).WithDetails("field", "amounts").WithDetails("index", i))
+				return
+			}
+			reason := err.Error()
+			var ve *domain.ValidationError
+			if errors.As(err, &ve) {
+				reason = ve.Reason
+			}
+			failed[amt] = batchItemError(ErrCodeValidationFailed, reason)
+		}
+	}
+	if apiErr := a.validateSizes(req.Sizes); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	
+	// Use custom sizes if provided, otherwise fetch active sizes
+	sizes, skus, err := a.resolveSizes(r, req.Sizes)
+	if err != nil {
+		a.handleReadError(w, r, err, "get_pack_sizes")
+		return
+	}
+	if err := a.cfg.Validator.ValidatePackSet(sizes); err != nil {
+		a.errorHandler.HandleAPIError(w, r, validationError(err))
+		return
+	}
+	
+	// Deduplicate amounts so each distinct valid amount is computed once
+	valid := make([]int, 0, len(req.Amounts))
+	for _, amt := range req.Amounts {
+		if _, ok := failed[amt]; !ok {
+			valid = append(valid, amt)
+		}
+	}
+	distinct, position := dedupeAmounts(valid)
+	
+	computed, err := a.calc.ComputeBatch(r.Context(), distinct, sizes)
+	var ns *domain.NoSolutionError
+	if err != nil && partial && errors.As(err, &ns) {
+		// The batch fails as a whole, so calculate each amount on its own to find which have no solution
+		computed, err = a.computeEach(r, distinct, sizes, failed)
+	}
+	if err != nil {
+		a.errorHandler.HandleError(w, r, calculationError(err).WithDetails("count", len(distinct)))
+		return
+	}
+	
+	// Map results back to the original positions
+	results := make([]map[string]any, len(req.Amounts))
+	okAmounts := make([]int, 0, len(req.Amounts))
+	okResults := make([]domain.CalculationResult, 0, len(req.Amounts))
+	for i, amt := range req.Amounts {
+		if itemErr, ok := failed[amt]; ok {
+			results[i] = map[string]any{"amount": amt, "error": itemErr}
+			continue
+		}
+		res := computed[position[amt]]
+		results[i] = calcResponse(amt, res, skus)
+		okAmounts = append(okAmounts, amt)
+		okResults = append(okResults, res)
+	}
+	
+	writeJSON(w, http.StatusOK, map[string]any{
+		"results":   results,
+		"distinct":  len(distinct),
+		"aggregate": newBatchAggregate(okAmounts, okResults, len(req.Amounts)-len(okAmounts)),
+	})
+}
+
+// computeEach calculates each of amounts separately, for a partial batch in which some have no
+// solution. Those are added to failed and get a zero result; any other failure is returned.
+func (a *packSvcAdapter) computeEach(r *http.Request, amounts []int, sizes []int, failed map[int]map[string]any) ([]domain.CalculationResult, error) {
+	out := make([]domain.CalculationResult, len(amounts))
+	for i, amt := range amounts {
+		res, err := a.calc.Compute(r.Context(), amt, sizes)
+		var ns *domain.NoSolutionError
+		switch {
+		case errors.As(err, &ns):
+			failed[amt] = batchItemError(ErrCodeNoSolution, ns.Reason)
+		case err != nil:
+			return nil, err
+		}
+		out[i] = res
+	}
+	return out, nil
+}
+
+// batchItemError describes why one amount of a partial batch failed.
+func batchItemError(code ErrorCode, reason string) map[string]any {
+	return map[string]any{"code": code, "reason": reason}
+}
+
+// batchAggregate rolls up the successful results of a batch, so callers don't have to.
+type batchAggregate struct {
+	Succeeded             int     `json:"succeeded"`             // Amounts calculated
+	Failed                int     `json:"failed"`                // Amounts left out: invalid or without a solution
+	TotalAmount           int64   `json:"totalAmount"`           // Items ordered by the successful amounts
+	TotalItems            int64   `json:"totalItems"`            // Items shipped
+	TotalPacks            int64   `json:"totalPacks"`            // Packs shipped
+	TotalOverage          int64   `json:"totalOverage"`          // Items shipped beyond what was ordered
+	AverageOveragePercent float64 `json:"averageOveragePercent"` // Mean of per-amount overage percent
+}
+
+// newBatchAggregate aggregates the successful results of a batch (one per amount, repeats
+// included) with the same arithmetic as POST /calculate/summary.
+func newBatchAggregate(amounts []int, results []domain.CalculationResult, failed int) batchAggregate {
+	sum := summarizeResults(amounts, results)
+	return batchAggregate{
+		Succeeded:             sum.Orders,
+		Failed:                failed,
+		TotalAmount:           sum.TotalAmount,
+		TotalItems:            sum.TotalItems,
+		TotalPacks:            sum.TotalPacks,
+		TotalOverage:          sum.TotalOverage,
+		AverageOveragePercent: sum.AverageOveragePercent,
+	}
+}
+
+// dedupeAmounts returns the distinct amounts in order of first appearance,
+// along with each distinct amount's index in that slice.
+func dedupeAmounts(amounts []int) ([]int, map[int]int) {
+	distinct := make([]int, 0, len(amounts))
+	position := make(map[int]int, len(amounts))
+	for _, amt := range amounts {
+		if _, seen := position[amt]; !seen {
+			position[amt] = len(distinct)
+			distinct = append(distinct, amt)
+		}
+	}
+	return distinct, position
+}
+
+// consolidationComparison summarizes how a consolidated shipment compares to separate orders.
+type consolidationComparison struct {
+	perOrderItems int64 // Total items when each order is optimized separately
+	perOrderPacks int64 // Total packs when each order is optimized separately
+	itemsSaved    int64 // Items saved by consolidating (never negative in practice)
+	packsSaved    int64 // Packs saved by consolidating (may be negative if consolidation needs more packs)
+}
+
+// compareConsolidation totals the per-order results and reports what consolidation saves.
+func compareConsolidation(consolidated domain.CalculationResult, perOrder []domain.CalculationResult) consolidationComparison {
+	var cmp consolidationComparison
+	for _, res := range perOrder {
+		cmp.perOrderItems += int64(res.TotalItems)
+		cmp.perOrderPacks += int64(res.TotalPacks)
+	}
+	cmp.itemsSaved = cmp.perOrderItems - int64(consolidated.TotalItems)
+	cmp.packsSaved = cmp.perOrderPacks - int64(consolidated.TotalPacks)
+	return cmp
+}
+
+// resolveSizes returns the custom sizes if any were provided, otherwise the active pack sizes.
+// For active sizes it also returns their SKUs keyed by size; custom sizes carry no SKUs.
+func (a *packSvcAdapter) resolveSizes(r *http.Request, custom []int) ([]int, map[int]string, error) {
+	if len(custom) > 0 {
+		return custom, nil, nil
+	}
+	packs, err := a.svc.GetActivePacks(r.Context())
+	if err != nil {
+		return nil, nil, err
+	}
+	sizes, skus := splitPacks(packs)
+	return sizes, skus, nil
+}
+
+// splitPacks separates packs into their sizes and a size -> SKU map (sizes without SKUs are omitted).
+func splitPacks(packs []domain.Pack) ([]int, map[int]string) {
+	sizes := make([]int, len(packs))
+	skus := make(map[int]string, len(packs))
+	for i, p := range packs {
+		sizes[i] = p.Size
+		if p.SKU != "" {
+			skus[p.Size] = p.SKU
+		}
+	}
+	return sizes, skus
+}
+
+// calcResponse builds the JSON response body for a single calculation result.
+// "breakdown" keeps the size -> quantity map; "packs" lists the same counts with SKUs joined in.
+func calcResponse(amount int, res domain.CalculationResult, skus map[int]string) map[string]any {
+	return map[string]any{
+		"amount":     amount,
+		"totalItems": res.TotalItems,
+		"totalPacks": res.TotalPacks,
+		"breakdown":  formatBreakdown(res.Breakdown),
+		"packs":      labeledBreakdown(res.Breakdown, skus),
+		"overage":    res.Overage,
+		"shortfall":  res.Shortfall,
+	}
+}
+
+// packCount is a breakdown entry with the pack's SKU, e.g. {"size":500,"sku":"BOX-500","count":2}.
+type packCount struct {
+	Size  int    `json:"size"`
+	SKU   string `json:"sku,omitempty"`
+	Count int    `json:"count"`
+}
+
+// labeledBreakdown converts a size -> quantity map into a list ordered by descending size,
+// joining in SKUs where known.
+func labeledBreakdown(counts map[int]int, skus map[int]string) []packCount {
+	out := make([]packCount, 0, len(counts))
+	for s, c := range counts {
+		out = append(out, packCount{Size: s, SKU: skus[s], Count: c})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Size > out[j].Size })
+	return out
+}
+
+// formatBreakdown converts a size -> quantity map into a JSON-friendly map keyed by size string.
+// Sizes are visited in descending order for better readability.
+func formatBreakdown(counts map[int]int) map[string]int {
+	breakdown := map[string]int{}
+	keys := make([]int, 0, len(counts))
+	for s := range counts {
+		keys = append(keys, s)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(keys)))
+	for _, s := range keys {
+		breakdown[strconv.Itoa(s)] = counts[s]
+	}
+	return breakdown
+}
+
+// parseBreakdownFormat parses the ?breakdownFormat= value, reporting whether the ordered list
+// form was asked for. Empty and "map" keep the default size -> quantity map.
+func parseBreakdownFormat(raw string) (bool, *APIError) {
+	switch strings.ToLower(raw) {
+	case "", "map":
+		return false, nil
+	case "list":
+		return true, nil
+	}
+	return false, ErrValidationFailed.WithDetails("field", "breakdownFormat").WithDetails("value", raw).WithDetails("reason", "breakdownFormat must be map or list")
+}
+
+// parseSortOrder parses the ?order= value, reporting whether ascending sizes were asked for.
+// Empty and "desc" keep the default, largest packs first.
+func parseSortOrder(raw string) (bool, *APIError) {
+	switch strings.ToLower(raw) {
+	case "", "desc":
+		return false, nil
+	case "asc":
+		return true, nil
+	}
+	return false, ErrValidationFailed.WithDetails("field", "order").WithDetails("value", raw).WithDetails("reason", "order must be asc or desc")
+}
+
+// ascendingBreakdown reverses the ordered breakdowns of a calculation response, which are built
+// largest size first, so they run from the smallest size up. Maps carry no order, so they're left
+// alone. The breakdown list may be shared with a cached result, so it's reversed as a copy.
+func ascendingBreakdown(resp map[string]any) {
+	if packs, ok := resp["packs"].([]packCount); ok {
+		slices.Reverse(packs)
+	}
+	if list, ok := resp["breakdown"].([]domain.PackCount); ok {
+		list = slices.Clone(list)
+		slices.Reverse(list)
+		resp["breakdown"] = list
+	}
+}
+
+// orderedBreakdown returns res's breakdown as a list ordered by descending size. Results
+// without a BreakdownList, such as ones cached before it was added, get it rebuilt from the map.
+func orderedBreakdown(res domain.CalculationResult) []domain.PackCount {
+	if res.BreakdownList != nil {
+		return res.BreakdownList
+	}
+	if list := domain.NewBreakdownList(res.Breakdown); list != nil {
+		return list
+	}
+	return []domain.PackCount{}
+}
+
+// sizeContribution is a detailed breakdown entry, e.g. {"count":2,"items":1000}.
+type sizeContribution struct {
+	Count int `json:"count"` // Number of packs of this size
+	Items int `json:"items"` // Items contributed by this size (size × count)
+}
+
+// detailedBreakdown converts a size -> quantity map into a map keyed by size string
+// that also carries each size's contribution to the total items.
+func detailedBreakdown(counts map[int]int) map[string]sizeContribution {
+	breakdown := make(map[string]sizeContribution, len(counts))
+	for s, c := range counts {
+		breakdown[strconv.Itoa(s)] = sizeContribution{Count: c, Items: s * c}
+	}
+	return breakdown
+}
+
+// excludePackSizes returns a new slice containing the sizes that are not listed in exclude.
+// The input slice is left untouched since it may be shared with the cache layer.
+func excludePackSizes(sizes, exclude []int) []int {
+	skip := make(map[int]struct{}, len(exclude))
+	for _, s := range exclude {
+		skip[s] = struct{}{}
+	}
+	out := make([]int, 0, len(sizes))
+	for _, s := range sizes {
+		if _, ok := skip[s]; !ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// bundleItems returns the items in one pack of each distinct positive size, as the calculator
+// counts them for CalcOptions.MinOnePerSize.
+func bundleItems(sizes []int) int {
+	seen := make(map[int]struct{}, len(sizes))
+	total := 0
+	for _, s := range sizes {
+		if _, dup := seen[s]; s > 0 && !dup {
+			seen[s] = struct{}{}
+			total += s
+		}
+	}
+	return total
+}
+
+// writeJSON is a helper function to write JSON responses with proper headers.
+// Sets Content-Type header and writes the response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+const (
+	// maxJSONDepth limits object/array nesting in request bodies; no endpoint needs more than a few levels.
+	maxJSONDepth = 32
+	
+	// maxJSONArrayLen limits the length of any JSON array in a request body (the job endpoint's cap).
+	maxJSONArrayLen = maxJobAmounts
+)
+
+// decodeJSON reads a request body of at most limit bytes and decodes it into v.
+// The raw body is checked for excessive nesting and oversized arrays before decoding,
+// so a hostile payload can't make the decoder allocate far more than the body size suggests.
+// Returns an ErrInvalidInput describing the problem, or nil on success.
+// Decode failures carry the byte offset and, for type mismatches, the offending field (see jsonDecodeError).
+// With StrictJSON a field v has no place for is rejected, so a typo like "amounts" is reported
+// instead of the field silently defaulting.
+func (a *packSvcAdapter) decodeJSON(w http.ResponseWriter, r *http.Request, limit int64, v any) *APIError {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return ErrInvalidInput.WithDetails("field", "body").WithDetails("limit_bytes", limit).WithDetails("reason", "request body too large")
+		}
+		return ErrInvalidInput.WithDetails("field", "body").WithDetails("reason", "failed to read request body")
+	}
+	
+	if reason := checkJSONShape(body); reason != "" {
+		return ErrInvalidInput.WithDetails("field", "body").WithDetails("reason", reason)
+	}
+	
+	if err := unmarshalJSON(body, v, a.cfg.StrictJSON); err != nil {
+		// Reported like the decoder's own type errors, pointing just past the value
+		var nanErr *notNumberError
+		if errors.As(err, &nanErr) {
+			err = &json.UnmarshalTypeError{Value: nanErr.Value, Type: reflect.TypeFor[int](), Field: nanErr.Field, Offset: fieldValueEnd(body, nanErr.Field)}
+		}
+		return jsonDecodeError(err)
+	}
+	return nil
+}
+
+// unmarshalJSON decodes data into v like json.Unmarshal. With strict, an object field that v
+// has no place for is an error instead of being ignored.
+func unmarshalJSON(data []byte, v any, strict bool) error {
+	// A decoder stops after the first value, so let json.Unmarshal report malformed input
+	if !strict || !json.Valid(data) {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// fieldValueEnd returns the offset just past the value of the top-level object field in data,
+// where the decoder reports its own type errors, or 0 if there's no such field.
+func fieldValueEnd(data []byte, field string) int64 {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return 0
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return 0
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return 0
+		}
+		if key == field {
+			return dec.InputOffset()
+		}
+	}
+	return 0
+}
+
+// jsonDecodeError converts a json.Unmarshal error into an ErrInvalidInput that points at the problem.
+// Syntax errors report the byte offset; type mismatches also report the field path, the expected type
+// and the JSON value that was found (e.g. "string" where a number was expected). A fractional number
+// where a whole number is required is a VALIDATION_FAILED naming the field and value; an unknown
+// field rejected in strict mode is named too.
+func jsonDecodeError(err error) *APIError {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return ErrInvalidInput.
+			WithDetails("field", "body").
+			WithDetails("offset", syntaxErr.Offset).
+			WithDetails("error", syntaxErr.Error()).
+			WithDetails("reason", "invalid JSON format")
+	}
+	
+	// The decoder has no error type for unknown fields, only this message
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field, uerr := strconv.Unquote(name)
+		if uerr != nil {
+			field = name
+		}
+		return ErrInvalidInput.
+			WithDetails("field", field).
+			WithDetails("reason", fmt.Sprintf("unknown field %q", field))
+	}
+	
+	var fracErr *fractionalNumberError
+	if errors.As(err, &fracErr) {
+		return ErrValidationFailed.
+			WithDetails("field", fracErr.Field).
+			WithDetails("value", fracErr.Value).
+			WithDetails("reason", fracErr.Field+" must be a whole number")
+	}
+	
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		field := typeErr.Field
+		if field == "" {
+			field = "body" // The top-level value itself has the wrong type
+		}
+		return ErrInvalidInput.
+			WithDetails("field", field).
+			WithDetails("offset", typeErr.Offset).
+			WithDetails("expected", typeErr.Type.String()).
+			WithDetails("got", typeErr.Value).
+			WithDetails("reason", "invalid JSON type")
+	}
+	
+	return ErrInvalidInput.WithDetails("field", "body").WithDetails("reason", "invalid JSON format")
+}
+
+// checkJSONShape scans raw JSON for nesting deeper than maxJSONDepth or arrays longer than maxJSONArrayLen.
+// It doesn't validate syntax (json.Unmarshal does that); it returns a reason string, or "" if the shape is fine.
+func checkJSONShape(body []byte) string {
+	// counts[i] holds the number of separators seen in the i-th open container (-1 for objects)
+	counts := make([]int, 0, 8)
+	inString, escaped := false, false
+	for _, c := range body {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		
+		switch c {
+		case '"':
+			inString = true
+		case '[', '{':
+			if len(counts) >= maxJSONDepth {
+				return "JSON nesting exceeds " + strconv.Itoa(maxJSONDepth) + " levels"
+			}
+			if c == '[' {
+				counts = append(counts, 0)
+			} else {
+				counts = append(counts, -1)
+			}
+		case ']', '}':
+			if len(counts) > 0 {
+				counts = counts[:len(counts)-1]
+			}
+		case ',':
+			// n separators means n+1 elements
+			if n := len(counts); n > 0 && counts[n-1] >= 0 {
+				counts[n-1]++
+				if counts[n-1] >= maxJSONArrayLen {
+					return "JSON array exceeds " + strconv.Itoa(maxJSONArrayLen) + " elements"
+				}
+			}
+		}
+	}
+	return ""
 }
@@ -4,12 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
 	"github.com/temo/pack-optimizer/backend/internal/domain"
 )
 
@@ -22,7 +30,7 @@ func newTestErrorHandler() *ErrorHandler {
 
 // newTestRouter creates a router with mocked services for testing.
 func newTestRouter(packsSvc domain.PacksService, calc domain.Calculator) chi.Router {
-	return NewRouter(packsSvc, calc, newTestErrorHandler())
+	return NewRouter(packsSvc, calc, nil, newTestErrorHandler(), HandlerConfig{})
 }
 
 // newTestRequest creates an HTTP test request with JSON body.
@@ -40,8 +48,21 @@ func newTestRequest(method, path string, body interface{}) *http.Request {
 
 // mockPacksService implements domain.PacksService for testing.
 type mockPacksService struct {
-	sizes []int
-	err   error
+	sizes  []int
+	skus   map[int]string
+	locked bool
+	meta   domain.PackSetMeta
+	err    error
+
+	replaceErr error // Returned by ReplaceActive/ReplaceActivePacks only
+
+	versions map[int64][]domain.Pack // Historical pack sets by version
+
+	stats      domain.CacheStats // Returned by CacheStats
+	statsReset bool              // Whether CacheStats was last called with reset
+	flushed    int               // Returned by FlushCache
+
+	customSets map[string]domain.CustomPackSet // Saved custom pack sets by ID
 }
 
 func (m *mockPacksService) GetActiveSizes(ctx context.Context) ([]int, error) {
@@ -51,12 +72,127 @@ func (m *mockPacksService) GetActiveSizes(ctx context.Context) ([]int, error) {
 	return m.sizes, nil
 }
 
-func (m *mockPacksService) ReplaceActive(ctx context.Context, sizes []int) ([]int, error) {
+func (m *mockPacksService) ReplaceActive(ctx context.Context, sizes []int) ([]int, int64, error) {
 	if m.err != nil {
-		return nil, m.err
+		return nil, 0, m.err
+	}
+	if m.locked {
+		return nil, 0, &domain.LockedError{}
+	}
+	if m.replaceErr != nil {
+		return nil, 0, m.replaceErr
 	}
 	m.sizes = sizes
-	return sizes, nil
+	m.meta.Version++
+	return sizes, m.meta.Version, nil
+}
+
+func (m *mockPacksService) GetActivePacks(ctx context.Context) ([]domain.Pack, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	packs := make([]domain.Pack, len(m.sizes))
+	for i, s := range m.sizes {
+		packs[i] = domain.Pack{Size: s, SKU: m.skus[s]}
+	}
+	return packs, nil
+}
+
+func (m *mockPacksService) ReplaceActivePacks(ctx context.Context, packs []domain.Pack) ([]domain.Pack, int64, error) {
+	if m.err != nil {
+		return nil, 0, m.err
+	}
+	if m.locked {
+		return nil, 0, &domain.LockedError{}
+	}
+	if m.replaceErr != nil {
+		return nil, 0, m.replaceErr
+	}
+	m.sizes = make([]int, len(packs))
+	m.skus = map[int]string{}
+	for i, p := range packs {
+		m.sizes[i] = p.Size
+		if p.SKU != "" {
+			m.skus[p.Size] = p.SKU
+		}
+	}
+	m.meta.Version++
+	return packs, m.meta.Version, nil
+}
+
+func (m *mockPacksService) ReplaceActivePacksIfVersion(ctx context.Context, packs []domain.Pack, expected []int64) ([]domain.Pack, int64, error) {
+	if m.err == nil && m.replaceErr == nil && !slices.Contains(expected, m.meta.Version) {
+		return nil, 0, &domain.VersionConflictError{Expected: expected, Current: m.meta.Version}
+	}
+	return m.ReplaceActivePacks(ctx, packs)
+}
+
+func (m *mockPacksService) GetActivePacksWithVersion(ctx context.Context) ([]domain.Pack, int64, error) {
+	packs, err := m.GetActivePacks(ctx)
+	return packs, m.meta.Version, err
+}
+
+func (m *mockPacksService) GetPacksAtVersion(ctx context.Context, version int64) ([]domain.Pack, bool, error) {
+	if m.err != nil {
+		return nil, false, m.err
+	}
+	packs, ok := m.versions[version]
+	return packs, ok, nil
+}
+
+func (m *mockPacksService) GetMeta(ctx context.Context) (domain.PackSetMeta, error) {
+	if m.err != nil {
+		return domain.PackSetMeta{}, m.err
+	}
+	return m.meta, nil
+}
+
+func (m *mockPacksService) IsLocked(ctx context.Context) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	return m.locked, nil
+}
+
+func (m *mockPacksService) SetLocked(ctx context.Context, locked bool) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.locked = locked
+	return nil
+}
+
+func (m *mockPacksService) SaveCustomSet(ctx context.Context, set domain.CustomPackSet) (domain.CustomPackSet, error) {
+	if m.err != nil {
+		return domain.CustomPackSet{}, m.err
+	}
+	if m.customSets == nil {
+		m.customSets = map[string]domain.CustomPackSet{}
+	}
+	set.ID = fmt.Sprintf("set-%d", len(m.customSets)+1)
+	set.CreatedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.customSets[set.ID] = set
+	return set, nil
+}
+
+func (m *mockPacksService) GetCustomSet(ctx context.Context, id string) (domain.CustomPackSet, bool, error) {
+	if m.err != nil {
+		return domain.CustomPackSet{}, false, m.err
+	}
+	set, ok := m.customSets[id]
+	return set, ok, nil
+}
+
+func (m *mockPacksService) CacheStats(ctx context.Context, reset bool) domain.CacheStats {
+	m.statsReset = reset
+	return m.stats
+}
+
+func (m *mockPacksService) FlushCache(ctx context.Context) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	return m.flushed, nil
 }
 
 // mockCalculator implements domain.Calculator for testing.
@@ -72,6 +208,36 @@ func (m *mockCalculator) Compute(ctx context.Context, amount int, sizes []int) (
 	return m.result, nil
 }
 
+func (m *mockCalculator) ComputeWithOptions(ctx context.Context, amount int, sizes []int, opts domain.CalcOptions) (domain.CalculationResult, error) {
+	return m.Compute(ctx, amount, sizes)
+}
+
+func (m *mockCalculator) ExactFit(ctx context.Context, amount int, sizes []int) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	return m.result.Overage == 0 && m.result.TotalItems > 0, nil
+}
+
+func (m *mockCalculator) PoolStats() domain.PoolStats {
+	return domain.PoolStats{Workers: 4, Busy: 4, Saturated: true, Waits: 7}
+}
+
+func (m *mockCalculator) ComputeBatch(ctx context.Context, amounts []int, sizes []int) ([]domain.CalculationResult, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	results := make([]domain.CalculationResult, len(amounts))
+	for i := range amounts {
+		results[i] = m.result
+	}
+	return results, nil
+}
+
+func (m *mockCalculator) ComputeBatchWithOptions(ctx context.Context, amounts []int, sizes []int, opts domain.CalcOptions) ([]domain.CalculationResult, error) {
+	return m.ComputeBatch(ctx, amounts, sizes)
+}
+
 func TestGetPacks(t *testing.T) {
 	svc := &mockPacksService{sizes: []int{250, 500, 1000}}
 	calc := &mockCalculator{}
@@ -152,6 +318,52 @@ func TestPutPacks_InvalidInput(t *testing.T) {
 	}
 }
 
+func TestPutPacks_SizeAboveMaxAmount(t *testing.T) {
+	validator := domain.NewValidator(domain.ValidationLimits{MaxAmount: 1000})
+	body := map[string][]int{"sizes": {250, 500, 2000}}
+
+	// Lenient mode stores the set and warns
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	router := NewRouter(svc, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{Validator: validator})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("PUT", "/packs", body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 in lenient mode, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Warning"); got != `299 - "pack size 2000 exceeds the maximum order amount"` {
+		t.Errorf("Unexpected Warning header: %q", got)
+	}
+	if len(svc.sizes) != 3 {
+		t.Errorf("Expected the set to be stored, got %v", svc.sizes)
+	}
+
+	// Sizes within the maximum amount don't warn
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("PUT", "/packs", map[string][]int{"sizes": {250, 1000}}))
+	if got := w.Header().Get("Warning"); got != "" {
+		t.Errorf("Expected no Warning header, got %q", got)
+	}
+
+	// Strict mode rejects the set without storing it
+	svc = &mockPacksService{sizes: []int{250, 500}}
+	router = NewRouter(svc, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{Validator: validator, StrictPackSizes: true})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("PUT", "/packs", body))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 in strict mode, got %d", w.Code)
+	}
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	if errResp.Details["index"] != float64(2) || errResp.Details["maximum"] != float64(1000) {
+		t.Errorf("Unexpected details: %v", errResp.Details)
+	}
+	if len(svc.sizes) != 2 {
+		t.Errorf("Expected the active set unchanged, got %v", svc.sizes)
+	}
+}
+
 func TestDeletePack(t *testing.T) {
 	svc := &mockPacksService{sizes: []int{250, 500, 1000}}
 	calc := &mockCalculator{}
@@ -200,6 +412,126 @@ func TestDeletePack_LastOne(t *testing.T) {
 	}
 }
 
+func TestAppendPacks(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      any
+		wantCode  int
+		wantSizes []int
+	}{
+		{"merges and sorts", map[string][]int{"add": {1500, 750}}, http.StatusOK, []int{250, 500, 750, 1000, 1500}},
+		{"deduplicates", map[string][]int{"add": {750, 500, 750}}, http.StatusOK, []int{250, 500, 750, 1000}},
+		{"all present", map[string][]int{"add": {250, 1000}}, http.StatusOK, []int{250, 500, 1000}},
+		{"non-positive size", map[string][]int{"add": {750, -5}}, http.StatusBadRequest, []int{250, 500, 1000}},
+		{"size above maximum", map[string][]int{"add": {20000}}, http.StatusBadRequest, []int{250, 500, 1000}},
+		{"empty", map[string][]int{"add": {}}, http.StatusBadRequest, []int{250, 500, 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockPacksService{sizes: []int{250, 500, 1000}, skus: map[int]string{500: "BOX-500"}}
+			router := newTestRouter(svc, &mockCalculator{})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newTestRequest("POST", "/packs", tt.body))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if !reflect.DeepEqual(svc.sizes, tt.wantSizes) {
+				t.Errorf("Expected sizes %v, got %v", tt.wantSizes, svc.sizes)
+			}
+			if svc.skus[500] != "BOX-500" {
+				t.Errorf("Expected existing SKU to be kept, got %v", svc.skus)
+			}
+
+			if tt.wantCode == http.StatusBadRequest {
+				var errResp APIError
+				if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+					t.Fatalf("Failed to decode error response: %v", err)
+				}
+				if errResp.Details["field"] != "add" {
+					t.Errorf("Expected field 'add', got %v", errResp.Details["field"])
+				}
+				return
+			}
+			var resp struct {
+				Sizes []int `json:"sizes"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if !reflect.DeepEqual(resp.Sizes, tt.wantSizes) {
+				t.Errorf("Expected response sizes %v, got %v", tt.wantSizes, resp.Sizes)
+			}
+		})
+	}
+}
+
+func TestDeletePacks_Multiple(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *http.Request
+		wantCode  int
+		wantSizes []int
+	}{
+		{"query", newTestRequest("DELETE", "/packs?sizes=250,1000", nil), http.StatusOK, []int{500, 2000}},
+		{"query ignores unknown size", newTestRequest("DELETE", "/packs?sizes=250,%20750", nil), http.StatusOK, []int{500, 1000, 2000}},
+		{"body", newTestRequest("DELETE", "/packs", map[string]any{"sizes": []int{500, 2000, 750}}), http.StatusOK, []int{250, 1000}},
+		{"query strict with unknown size", newTestRequest("DELETE", "/packs?sizes=250,750&strict=true", nil), http.StatusNotFound, []int{250, 500, 1000, 2000}},
+		{"body strict with unknown size", newTestRequest("DELETE", "/packs", map[string]any{"sizes": []int{250, 750}, "strict": true}), http.StatusNotFound, []int{250, 500, 1000, 2000}},
+		{"strict with known sizes", newTestRequest("DELETE", "/packs?sizes=500,250&strict=true", nil), http.StatusOK, []int{1000, 2000}},
+		{"invalid value", newTestRequest("DELETE", "/packs?sizes=250,abc", nil), http.StatusBadRequest, []int{250, 500, 1000, 2000}},
+		{"non-positive value", newTestRequest("DELETE", "/packs", map[string]any{"sizes": []int{250, 0}}), http.StatusBadRequest, []int{250, 500, 1000, 2000}},
+		{"no sizes", newTestRequest("DELETE", "/packs", map[string]any{"sizes": []int{}}), http.StatusBadRequest, []int{250, 500, 1000, 2000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockPacksService{sizes: []int{250, 500, 1000, 2000}, skus: map[int]string{1000: "BOX-1000"}}
+			router := newTestRouter(svc, &mockCalculator{})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, tt.req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if !reflect.DeepEqual(svc.sizes, tt.wantSizes) {
+				t.Errorf("Expected sizes %v, got %v", tt.wantSizes, svc.sizes)
+			}
+			if tt.wantCode == http.StatusOK {
+				var resp struct {
+					Sizes []int `json:"sizes"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if !reflect.DeepEqual(resp.Sizes, tt.wantSizes) {
+					t.Errorf("Expected response sizes %v, got %v", tt.wantSizes, resp.Sizes)
+				}
+			}
+			if tt.wantCode == http.StatusNotFound {
+				var errResp APIError
+				if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+					t.Fatalf("Failed to decode error response: %v", err)
+				}
+				if missing := fmt.Sprint(errResp.Details["missing"]); missing != "[750]" {
+					t.Errorf("Expected missing [750], got %s", missing)
+				}
+			}
+		})
+	}
+
+	// SKUs of the remaining packs survive the update
+	svc := &mockPacksService{sizes: []int{250, 500, 1000}, skus: map[int]string{1000: "BOX-1000"}}
+	router := newTestRouter(svc, &mockCalculator{})
+	router.ServeHTTP(httptest.NewRecorder(), newTestRequest("DELETE", "/packs?sizes=250,500", nil))
+	if svc.skus[1000] != "BOX-1000" {
+		t.Errorf("Expected SKU of remaining pack to be kept, got %v", svc.skus)
+	}
+}
+
 func TestCalculate_NoPackSizes(t *testing.T) {
 	svc := &mockPacksService{sizes: []int{}}
 	calc := &mockCalculator{}
@@ -230,6 +562,39 @@ func TestCalculate_NoPackSizes(t *testing.T) {
 	}
 }
 
+func TestCalculate_NoSolution(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	calc := &mockCalculator{err: &domain.NoSolutionError{Amount: 100, Reason: "no combination of whole packs fulfills the amount"}}
+	router := newTestRouter(svc, calc)
+
+	for _, req := range []*http.Request{
+		newTestRequest("POST", "/calculate", map[string]int{"amount": 100}),
+		newTestRequest("POST", "/calculate/batch", map[string][]int{"amounts": {100}}),
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: expected status 422, got %d: %s", req.URL.Path, w.Code, w.Body.String())
+		}
+		var errResp APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+			t.Fatalf("Failed to parse error response: %v", err)
+		}
+		if errResp.Code != ErrCodeNoSolution || errResp.Details["amount"] != float64(100) {
+			t.Errorf("%s: expected NO_SOLUTION for amount 100, got %+v", req.URL.Path, errResp)
+		}
+	}
+
+	// Other calculator failures stay internal errors
+	router = newTestRouter(svc, &mockCalculator{err: errors.New("boom")})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]int{"amount": 100}))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 for other errors, got %d", w.Code)
+	}
+}
+
 func TestCalculate(t *testing.T) {
 	svc := &mockPacksService{sizes: []int{250, 500, 1000}}
 	calc := &mockCalculator{
@@ -327,3 +692,2148 @@ func TestCalculate_WithCustomSizes(t *testing.T) {
 	}
 }
 
+func TestValidatePacks(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	calc := &mockCalculator{}
+	router := newTestRouter(svc, calc)
+
+	body := map[string][]int{"sizes": {1000, 250, 1000, 500}}
+	req := newTestRequest("POST", "/packs/validate", body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Valid      bool  `json:"valid"`
+		Normalized []int `json:"normalized"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if !response.Valid {
+		t.Errorf("Expected valid to be true")
+	}
+	if len(response.Normalized) != 3 || response.Normalized[0] != 250 || response.Normalized[2] != 1000 {
+		t.Errorf("Expected normalized [250 500 1000], got %v", response.Normalized)
+	}
+
+	// Validation must not persist anything
+	if len(svc.sizes) != 2 {
+		t.Errorf("Expected active sizes to be unchanged, got %v", svc.sizes)
+	}
+}
+
+func TestValidatePacks_InvalidInput(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	calc := &mockCalculator{}
+	router := newTestRouter(svc, calc)
+
+	body := map[string][]int{"sizes": {250, 15000}}
+	req := newTestRequest("POST", "/packs/validate", body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for size > 10000, got %d", w.Code)
+	}
+
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Expected JSON error response, got %q", w.Body.String())
+	}
+	if errResp.Code != ErrCodeValidationFailed {
+		t.Errorf("Expected error code VALIDATION_FAILED, got %s", errResp.Code)
+	}
+}
+
+func TestCalculate_WithExclude(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500, 5000}}
+	calc := &mockCalculator{
+		result: domain.CalculationResult{
+			Amount:     5000,
+			TotalItems: 5000,
+			TotalPacks: 10,
+			Breakdown:  map[int]int{500: 10},
+		},
+	}
+	router := newTestRouter(svc, calc)
+
+	body := map[string]interface{}{
+		"amount":  5000,
+		"exclude": []int{5000},
+	}
+	req := newTestRequest("POST", "/calculate", body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	// Exclusion applies to this calculation only
+	if len(svc.sizes) != 3 {
+		t.Errorf("Expected active sizes to be unchanged, got %v", svc.sizes)
+	}
+}
+
+func TestCalculate_ExcludeAllSizes(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	calc := &mockCalculator{}
+	router := newTestRouter(svc, calc)
+
+	body := map[string]interface{}{
+		"amount":  100,
+		"exclude": []int{250, 500},
+	}
+	req := newTestRequest("POST", "/calculate", body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 when all sizes are excluded, got %d", w.Code)
+	}
+
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Expected JSON error response, got %q", w.Body.String())
+	}
+	if errResp.Details["reason"] != "no pack sizes configured" {
+		t.Errorf("Expected error reason 'no pack sizes configured', got %v", errResp.Details)
+	}
+}
+
+func TestConsolidate(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500, 1000}}
+	router := newTestRouter(svc, calculator.NewService())
+
+	// Separately: 2 x 250 items; consolidated: 1 x 500 items
+	body := map[string]interface{}{
+		"amounts": []int{200, 200},
+		"mode":    "sum",
+	}
+	req := newTestRequest("POST", "/calculate/consolidate", body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Consolidated   domain.CalculationResult `json:"consolidated"`
+		PerOrderTotals struct {
+			TotalItems int `json:"totalItems"`
+			TotalPacks int `json:"totalPacks"`
+		} `json:"perOrderTotals"`
+		Savings struct {
+			Items int `json:"items"`
+			Packs int `json:"packs"`
+		} `json:"savings"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Consolidated.TotalItems != 500 {
+		t.Errorf("Expected consolidated totalItems 500, got %d", response.Consolidated.TotalItems)
+	}
+	if response.PerOrderTotals.TotalItems != 500 {
+		t.Errorf("Expected per-order totalItems 500, got %d", response.PerOrderTotals.TotalItems)
+	}
+	if response.Savings.Packs != 1 {
+		t.Errorf("Expected 1 pack saved, got %d", response.Savings.Packs)
+	}
+}
+
+func TestConsolidate_InvalidInput(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	router := newTestRouter(svc, calculator.NewService())
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+	}{
+		{name: "no amounts", body: map[string]interface{}{"amounts": []int{}}},
+		{name: "negative amount", body: map[string]interface{}{"amounts": []int{100, -1}}},
+		{name: "sum exceeds limit", body: map[string]interface{}{"amounts": []int{600_000, 600_000}}},
+		{name: "unknown mode", body: map[string]interface{}{"amounts": []int{100}, "mode": "max"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newTestRequest("POST", "/calculate/consolidate", tt.body)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestMaxPackCount(t *testing.T) {
+	validator := domain.NewValidator(domain.ValidationLimits{MaxPackCount: 3})
+
+	tests := []struct {
+		name     string
+		method   string
+		body     any
+		wantCode int
+	}{
+		{"put at limit", "PUT", map[string][]int{"sizes": {250, 500, 1000}}, http.StatusOK},
+		{"put duplicates count once", "PUT", map[string][]int{"sizes": {250, 500, 1000, 500}}, http.StatusOK},
+		{"put above limit", "PUT", map[string][]int{"sizes": {250, 500, 1000, 2000}}, http.StatusBadRequest},
+		{"validate above limit", "POST", map[string][]int{"sizes": {250, 500, 1000, 2000}}, http.StatusBadRequest},
+		{"append to limit", "POST", map[string][]int{"add": {1000}}, http.StatusOK},
+		{"append above limit", "POST", map[string][]int{"add": {1000, 2000}}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockPacksService{sizes: []int{250, 500}}
+			router := NewRouter(svc, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{Validator: validator})
+
+			path := "/packs"
+			if strings.HasPrefix(tt.name, "validate") {
+				path = "/packs/validate"
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newTestRequest(tt.method, path, tt.body))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode != http.StatusBadRequest {
+				return
+			}
+			var errResp APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("Failed to parse error response: %v", err)
+			}
+			if errResp.Code != ErrCodeValidationFailed || errResp.Details["maximum"] != float64(3) {
+				t.Errorf("Expected VALIDATION_FAILED naming the limit, got %s %v", errResp.Code, errResp.Details)
+			}
+			if !reflect.DeepEqual(svc.sizes, []int{250, 500}) {
+				t.Errorf("Expected sizes to be unchanged, got %v", svc.sizes)
+			}
+		})
+	}
+}
+
+func TestConfiguredValidatorLimits(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	validator := domain.NewValidator(domain.ValidationLimits{MaxAmount: 5000, MaxPackSize: 1000})
+	router := NewRouter(svc, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{Validator: validator})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		field  string
+	}{
+		{"amount above configured maximum", "POST", "/calculate", map[string]int{"amount": 5001}, "amount"},
+		{"size above configured maximum", "PUT", "/packs", map[string][]int{"sizes": {250, 2000}}, "sizes"},
+		{"batch amount above configured maximum", "POST", "/calculate/batch", map[string][]int{"amounts": {100, 9000}}, "amounts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newTestRequest(tt.method, tt.path, tt.body))
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", w.Code)
+			}
+			var errResp APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("Failed to parse error response: %v", err)
+			}
+			if errResp.Code != ErrCodeValidationFailed || errResp.Details["field"] != tt.field {
+				t.Errorf("Expected VALIDATION_FAILED on %q, got %s %v", tt.field, errResp.Code, errResp.Details)
+			}
+		})
+	}
+}
+
+func TestValidationErrorDetailsDoNotLeak(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500}}, &mockCalculator{})
+
+	// The first failure carries extra details that must not show up on the next one
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 10, "sizes": []int{-1, -3}}))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]int{"amount": -5}))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	if errResp.Details["field"] != "amount" {
+		t.Errorf("Expected the failure on amount, got %v", errResp.Details)
+	}
+	for _, key := range []string{"values", "maximum"} {
+		if _, ok := errResp.Details[key]; ok {
+			t.Errorf("Expected no %q left over from the previous request, got %v", key, errResp.Details)
+		}
+	}
+	if len(ErrValidationFailed.Details) != 0 {
+		t.Errorf("Expected the shared error to stay untouched, got %v", ErrValidationFailed.Details)
+	}
+}
+
+func TestResetPacks(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{23, 31}}
+	router := NewRouter(svc, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{DefaultPackSizes: []int{100, 50}})
+
+	req := newTestRequest("POST", "/packs/reset", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		Sizes []int `json:"sizes"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Sizes) != 2 || len(svc.sizes) != 2 || svc.sizes[0] != 100 {
+		t.Errorf("Expected the configured defaults to be stored, got %v (stored %v)", response.Sizes, svc.sizes)
+	}
+
+	// Without configured defaults the initial seed is restored
+	svc = &mockPacksService{sizes: []int{23}}
+	router = newTestRouter(svc, &mockCalculator{})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/packs/reset", nil))
+
+	if w.Code != http.StatusOK || len(svc.sizes) != 5 || svc.sizes[4] != 5000 {
+		t.Errorf("Expected the seed sizes, got status %d and %v", w.Code, svc.sizes)
+	}
+}
+
+func TestPackLock(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	calc := &mockCalculator{result: domain.CalculationResult{Amount: 100, TotalItems: 250, TotalPacks: 1}}
+	router := NewRouter(svc, calc, nil, newTestErrorHandler(), HandlerConfig{ElevatedAPIKeys: []string{"admin-key"}})
+
+	// Locking is an admin operation
+	req := newTestRequest("POST", "/packs/lock", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized || svc.locked {
+		t.Fatalf("Expected status 401 without an elevated key, got %d (locked=%v)", w.Code, svc.locked)
+	}
+
+	req = newTestRequest("POST", "/packs/lock", nil)
+	req.Header.Set("X-API-Key", "admin-key")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for lock, got %d", w.Code)
+	}
+	if !svc.locked {
+		t.Fatalf("Expected pack sizes to be locked")
+	}
+
+	// Mutations are rejected while locked
+	mutations := []*http.Request{
+		newTestRequest("PUT", "/packs", map[string][]int{"sizes": {1000}}),
+		newTestRequest("DELETE", "/packs/250", nil),
+		newTestRequest("DELETE", "/packs?sizes=250,500", nil),
+		newTestRequest("POST", "/packs", map[string][]int{"add": {750}}),
+		newTestRequest("POST", "/packs/reset", nil),
+	}
+	for _, req := range mutations {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s %s: expected status 409 while locked, got %d", req.Method, req.URL.Path, w.Code)
+		}
+
+		var errResp APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+			t.Fatalf("Expected JSON error response, got %q", w.Body.String())
+		}
+		if errResp.Code != ErrCodeLocked {
+			t.Errorf("Expected error code LOCKED, got %s", errResp.Code)
+		}
+	}
+	if len(svc.sizes) != 2 {
+		t.Errorf("Expected sizes to be unchanged while locked, got %v", svc.sizes)
+	}
+
+	// Reads and calculations keep working
+	for _, req := range []*http.Request{
+		newTestRequest("GET", "/packs", nil),
+		newTestRequest("POST", "/calculate", map[string]int{"amount": 100}),
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s %s: expected status 200 while locked, got %d", req.Method, req.URL.Path, w.Code)
+		}
+	}
+
+	// Unlocking allows changes again
+	req = newTestRequest("POST", "/packs/unlock", nil)
+	req.Header.Set("X-API-Key", "admin-key")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || svc.locked {
+		t.Fatalf("Expected unlock to succeed, got status %d, locked=%v", w.Code, svc.locked)
+	}
+
+	req = newTestRequest("PUT", "/packs", map[string][]int{"sizes": {1000}})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after unlock, got %d", w.Code)
+	}
+}
+
+func TestCalculate_AllCustomSizesInvalid(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	calc := &mockCalculator{}
+	router := newTestRouter(svc, calc)
+
+	body := map[string]interface{}{
+		"amount": 100,
+		"sizes":  []int{0, -5},
+	}
+	req := newTestRequest("POST", "/calculate", body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 when all custom sizes are invalid, got %d", w.Code)
+	}
+
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Expected JSON error response, got %q", w.Body.String())
+	}
+	if errResp.Code != ErrCodeValidationFailed {
+		t.Errorf("Expected error code VALIDATION_FAILED, got %s", errResp.Code)
+	}
+	values, ok := errResp.Details["values"].([]interface{})
+	if !ok || len(values) != 2 {
+		t.Errorf("Expected offending values [0 -5] in details, got %v", errResp.Details["values"])
+	}
+}
+
+func TestCompare(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	router := newTestRouter(svc, calculator.NewService())
+
+	// Adding a 300 pack lets 300 items ship with no overage
+	body := map[string]interface{}{
+		"amount": 300,
+		"sets":   [][]int{{250, 500}, {250, 300, 500}},
+	}
+	req := newTestRequest("POST", "/calculate/compare", body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Results     []domain.CalculationResult `json:"results"`
+		Best        int                        `json:"best"`
+		FewestItems int                        `json:"fewestItems"`
+		FewestPacks int                        `json:"fewestPacks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(response.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(response.Results))
+	}
+	if response.Results[0].TotalItems != 500 || response.Results[1].TotalItems != 300 {
+		t.Errorf("Expected totalItems 500 and 300, got %d and %d", response.Results[0].TotalItems, response.Results[1].TotalItems)
+	}
+	if response.Best != 1 || response.FewestItems != 1 {
+		t.Errorf("Expected set 1 to win, got best=%d fewestItems=%d", response.Best, response.FewestItems)
+	}
+	if response.FewestPacks != 0 {
+		t.Errorf("Expected tie on packs to keep set 0, got %d", response.FewestPacks)
+	}
+}
+
+func TestCompare_InvalidSet(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	router := newTestRouter(svc, calculator.NewService())
+
+	body := map[string]interface{}{
+		"amount": 300,
+		"sets":   [][]int{{250, 500}, {250, -1}},
+	}
+	req := newTestRequest("POST", "/calculate/compare", body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid set, got %d", w.Code)
+	}
+}
+
+func TestPutPacks_ReturnsVersion(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250}, meta: domain.PackSetMeta{Version: 4}}
+	calc := &mockCalculator{}
+	router := newTestRouter(svc, calc)
+
+	// Each replace reports the version it created, in both request forms
+	for i, body := range []any{
+		map[string][]int{"sizes": {250, 500}},
+		map[string]any{"sizes": []any{map[string]any{"size": 500, "sku": "BOX-500"}}},
+		map[string][]int{"sizes": {1000}},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("PUT", "/packs", body))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var response struct {
+			Version int64 `json:"version"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if want := int64(5 + i); response.Version != want {
+			t.Errorf("Replace %d: expected version %d, got %d", i+1, want, response.Version)
+		}
+	}
+}
+
+func TestPutPacks_IfMatch(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}, meta: domain.PackSetMeta{Version: 7}}
+	router := newTestRouter(svc, &mockCalculator{})
+
+	put := func(ifMatch string, sizes []int) *httptest.ResponseRecorder {
+		req := newTestRequest("PUT", "/packs", map[string][]int{"sizes": sizes})
+		req.Header.Set("If-Match", ifMatch)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A client that last saw version 6 is editing a stale set
+	w := put(`"6"`, []int{1000})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for a stale version, got %d: %s", w.Code, w.Body.String())
+	}
+	var body APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Code != ErrCodeConflict || body.Details["current"] != float64(7) {
+		t.Errorf("Expected a CONFLICT error naming version 7, got %+v", body)
+	}
+	if !reflect.DeepEqual(svc.sizes, []int{250, 500}) || svc.meta.Version != 7 {
+		t.Fatalf("Expected nothing written on conflict, got %v at version %d", svc.sizes, svc.meta.Version)
+	}
+
+	// The current version, in any accepted form, lets the change through (each PUT adds one)
+	for _, ifMatch := range []string{`"7"`, "8", `"3", "9"`, "*"} {
+		if w := put(ifMatch, []int{250}); w.Code != http.StatusOK {
+			t.Errorf("If-Match %s: expected status 200, got %d: %s", ifMatch, w.Code, w.Body.String())
+		}
+	}
+
+	// The version is checked by the write itself: a conflict it reports is a 409 even when the
+	// set still looked current beforehand, as when another writer got in first
+	svc.replaceErr = &domain.VersionConflictError{Expected: []int64{svc.meta.Version}, Current: svc.meta.Version + 1}
+	if w := put(fmt.Sprintf(`"%d"`, svc.meta.Version), []int{500}); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a conflicting write, got %d: %s", w.Code, w.Body.String())
+	}
+	svc.replaceErr = nil
+
+	// Weak or malformed tags can never match a version
+	for _, ifMatch := range []string{`W/"7"`, `"v7"`, `"-1"`} {
+		if w := put(ifMatch, []int{250}); w.Code != http.StatusBadRequest {
+			t.Errorf("If-Match %s: expected status 400, got %d", ifMatch, w.Code)
+		}
+	}
+}
+
+func TestPutPacks_WithSKUs(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250}}
+	calc := &mockCalculator{}
+	router := newTestRouter(svc, calc)
+
+	body := map[string]interface{}{
+		"sizes": []interface{}{
+			250,
+			map[string]interface{}{"size": 500, "sku": "BOX-500"},
+		},
+	}
+	req := newTestRequest("PUT", "/packs", body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Sizes []int         `json:"sizes"`
+		Packs []domain.Pack `json:"packs"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(response.Sizes) != 2 || len(response.Packs) != 2 {
+		t.Fatalf("Expected 2 sizes and packs, got %v / %v", response.Sizes, response.Packs)
+	}
+	if svc.skus[500] != "BOX-500" {
+		t.Errorf("Expected SKU BOX-500 for size 500, got %q", svc.skus[500])
+	}
+}
+
+func TestCalculate_BreakdownIncludesSKUs(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}, skus: map[int]string{500: "BOX-500"}}
+	calc := &mockCalculator{
+		result: domain.CalculationResult{
+			Amount:     1000,
+			TotalItems: 1000,
+			TotalPacks: 2,
+			Breakdown:  map[int]int{500: 2},
+		},
+	}
+	router := newTestRouter(svc, calc)
+
+	req := newTestRequest("POST", "/calculate", map[string]int{"amount": 1000})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Breakdown map[string]int `json:"breakdown"`
+		Packs     []packCount    `json:"packs"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Breakdown["500"] != 2 {
+		t.Errorf("Expected breakdown to keep size map, got %v", response.Breakdown)
+	}
+	if len(response.Packs) != 1 || response.Packs[0] != (packCount{Size: 500, SKU: "BOX-500", Count: 2}) {
+		t.Errorf("Expected packs [{500 BOX-500 2}], got %+v", response.Packs)
+	}
+}
+
+func TestCalculate_MinOrderAmount(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	calc := &mockCalculator{result: domain.CalculationResult{Amount: 100, TotalItems: 250, TotalPacks: 1}}
+	router := NewRouter(svc, calc, nil, newTestErrorHandler(), HandlerConfig{MinOrderAmount: 100})
+
+	tests := []struct {
+		amount       int
+		expectedCode int
+	}{
+		{amount: 99, expectedCode: http.StatusBadRequest},
+		{amount: 100, expectedCode: http.StatusOK},
+		{amount: 101, expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := newTestRequest("POST", "/calculate", map[string]int{"amount": tt.amount})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.expectedCode {
+			t.Errorf("Amount %d: expected status %d, got %d", tt.amount, tt.expectedCode, w.Code)
+		}
+	}
+}
+
+func TestCalculate_MinOrderAmountDefault(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	calc := &mockCalculator{result: domain.CalculationResult{Amount: 1, TotalItems: 250, TotalPacks: 1}}
+	router := newTestRouter(svc, calc)
+
+	// Default minimum of 1 keeps the original behavior
+	req := newTestRequest("POST", "/calculate", map[string]int{"amount": 1})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for amount 1 with default minimum, got %d", w.Code)
+	}
+}
+
+// countingCalculator wraps the real calculator and counts how often each amount is computed.
+type countingCalculator struct {
+	calls map[int]int
+}
+
+func (c *countingCalculator) Compute(ctx context.Context, amount int, sizes []int) (domain.CalculationResult, error) {
+	c.calls[amount]++
+	return calculator.NewService().Compute(ctx, amount, sizes)
+}
+
+func (c *countingCalculator) ComputeWithOptions(ctx context.Context, amount int, sizes []int, opts domain.CalcOptions) (domain.CalculationResult, error) {
+	c.calls[amount]++
+	return calculator.NewService().ComputeWithOptions(ctx, amount, sizes, opts)
+}
+
+func (c *countingCalculator) ExactFit(ctx context.Context, amount int, sizes []int) (bool, error) {
+	c.calls[amount]++
+	return calculator.NewService().ExactFit(ctx, amount, sizes)
+}
+
+func (c *countingCalculator) PoolStats() domain.PoolStats {
+	return domain.PoolStats{}
+}
+
+func (c *countingCalculator) ComputeBatch(ctx context.Context, amounts []int, sizes []int) ([]domain.CalculationResult, error) {
+	for _, amt := range amounts {
+		c.calls[amt]++
+	}
+	return calculator.NewService().ComputeBatch(ctx, amounts, sizes)
+}
+
+func (c *countingCalculator) ComputeBatchWithOptions(ctx context.Context, amounts []int, sizes []int, opts domain.CalcOptions) ([]domain.CalculationResult, error) {
+	for _, amt := range amounts {
+		c.calls[amt]++
+	}
+	return calculator.NewService().ComputeBatchWithOptions(ctx, amounts, sizes, opts)
+}
+
+func TestBatch_DeduplicatesAmounts(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500, 1000}}
+	calc := &countingCalculator{calls: map[int]int{}}
+	router := newTestRouter(svc, calc)
+
+	amounts := []int{251, 1, 251, 501, 1, 251}
+	req := newTestRequest("POST", "/calculate/batch", map[string][]int{"amounts": amounts})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Results []struct {
+			Amount     int `json:"amount"`
+			TotalItems int `json:"totalItems"`
+		} `json:"results"`
+		Distinct int `json:"distinct"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// Each distinct amount is computed exactly once
+	if len(calc.calls) != 3 {
+		t.Errorf("Expected 3 distinct amounts computed, got %v", calc.calls)
+	}
+	for amt, n := range calc.calls {
+		if n != 1 {
+			t.Errorf("Expected amount %d to be computed once, got %d", amt, n)
+		}
+	}
+	if response.Distinct != 3 {
+		t.Errorf("Expected distinct 3, got %d", response.Distinct)
+	}
+
+	// Results are mapped back to the original positions
+	expectedItems := map[int]int{1: 250, 251: 500, 501: 750}
+	if len(response.Results) != len(amounts) {
+		t.Fatalf("Expected %d results, got %d", len(amounts), len(response.Results))
+	}
+	for i, res := range response.Results {
+		if res.Amount != amounts[i] || res.TotalItems != expectedItems[amounts[i]] {
+			t.Errorf("Result %d: expected amount %d / items %d, got %d / %d", i, amounts[i], expectedItems[amounts[i]], res.Amount, res.TotalItems)
+		}
+	}
+}
+
+func TestBatch_TooManyAmounts(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	calc := &mockCalculator{}
+	router := NewRouter(svc, calc, nil, newTestErrorHandler(), HandlerConfig{MaxBatchSize: 3})
+
+	req := newTestRequest("POST", "/calculate/batch", map[string][]int{"amounts": {1, 2, 3, 4}})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for oversized batch, got %d", w.Code)
+	}
+}
+
+func TestGetPacks_IncludeStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		svc        *mockPacksService
+		configured bool
+	}{
+		{"never configured", &mockPacksService{}, false},
+		{"configured as empty", &mockPacksService{meta: domain.PackSetMeta{Version: 3}}, true},
+		{"configured", &mockPacksService{sizes: []int{250, 500}, meta: domain.PackSetMeta{Version: 4}}, true},
+	}
+	for _, tt := range tests {
+		router := newTestRouter(tt.svc, &mockCalculator{})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("GET", "/packs?includeStatus=true", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", tt.name, w.Code)
+		}
+		var response map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", tt.name, err)
+		}
+		if response["configured"] != tt.configured {
+			t.Errorf("%s: expected configured=%v, got %v", tt.name, tt.configured, response["configured"])
+		}
+		if sizes, ok := response["sizes"].([]any); !ok || len(sizes) != len(tt.svc.sizes) {
+			t.Errorf("%s: expected sizes %v, got %v", tt.name, tt.svc.sizes, response["sizes"])
+		}
+
+		// The default shape is unchanged
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("GET", "/packs", nil))
+		if strings.Contains(w.Body.String(), "configured") {
+			t.Errorf("%s: expected no status without ?includeStatus=true, got %s", tt.name, w.Body.String())
+		}
+	}
+}
+
+func TestGetPacks_Meta(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc := &mockPacksService{sizes: []int{250, 500}, meta: domain.PackSetMeta{Version: 7, UpdatedAt: updated}}
+	router := newTestRouter(svc, &mockCalculator{})
+
+	// Default shape has no metadata
+	req := newTestRequest("GET", "/packs", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var plain map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&plain); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if _, ok := plain["version"]; ok {
+		t.Errorf("Expected no version without ?meta=true, got %v", plain)
+	}
+
+	req = newTestRequest("GET", "/packs?meta=true", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		Sizes     []int     `json:"sizes"`
+		Version   int64     `json:"version"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Sizes) != 2 || response.Version != 7 || !response.UpdatedAt.Equal(updated) {
+		t.Errorf("Unexpected meta response: %+v", response)
+	}
+}
+
+func TestPacks_RequiredSizesRejected(t *testing.T) {
+	svc := &mockPacksService{
+		sizes:      []int{250, 500, 1000},
+		replaceErr: &domain.RequiredPackSizesError{Missing: []int{250}},
+	}
+	router := newTestRouter(svc, &mockCalculator{})
+
+	requests := []*http.Request{
+		newTestRequest("PUT", "/packs", map[string][]int{"sizes": {500, 1000}}),
+		newTestRequest("DELETE", "/packs/250", nil),
+	}
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected status 400, got %d", req.Method, req.URL.Path, w.Code)
+			continue
+		}
+		var errResp APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+			t.Fatalf("Failed to parse error response: %v", err)
+		}
+		if errResp.Code != ErrCodeValidationFailed {
+			t.Errorf("%s %s: expected VALIDATION_FAILED, got %s", req.Method, req.URL.Path, errResp.Code)
+		}
+		if missing, ok := errResp.Details["missing"].([]interface{}); !ok || len(missing) != 1 || missing[0] != float64(250) {
+			t.Errorf("%s %s: expected missing [250], got %v", req.Method, req.URL.Path, errResp.Details["missing"])
+		}
+	}
+}
+
+func TestCalculate_SummaryOnly(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500, 1000}}, calculator.NewService())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate?summaryOnly=true", map[string]int{"amount": 1100}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if _, ok := resp["breakdown"]; ok {
+		t.Errorf("Expected no breakdown, got %v", resp["breakdown"])
+	}
+	if _, ok := resp["packs"]; ok {
+		t.Errorf("Expected no packs, got %v", resp["packs"])
+	}
+	if resp["amount"] != float64(1100) || resp["totalItems"] != float64(1250) || resp["totalPacks"] != float64(2) || resp["overage"] != float64(150) || resp["shortfall"] != float64(0) {
+		t.Errorf("Unexpected summary: %v", resp)
+	}
+
+	// There is no breakdown to detail
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate?summaryOnly=true&detailed=true", map[string]int{"amount": 1100}))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 combined with detailed, got %d", w.Code)
+	}
+}
+
+func TestCalculate_BreakdownFormatList(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{23, 31, 53, 250, 500}}, calculator.NewService())
+
+	var first []domain.PackCount
+	for i := range 10 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", "/calculate?breakdownFormat=list", map[string]int{"amount": 12001}))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Breakdown []domain.PackCount `json:"breakdown"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if i == 0 {
+			first = resp.Breakdown
+			if len(first) == 0 {
+				t.Fatalf("Expected a breakdown list, got %s", w.Body.String())
+			}
+			for j := 1; j < len(first); j++ {
+				if first[j].Size >= first[j-1].Size {
+					t.Errorf("Expected sizes in descending order, got %v", first)
+				}
+			}
+			continue
+		}
+		if !reflect.DeepEqual(resp.Breakdown, first) {
+			t.Fatalf("Expected the same list on every call, got %v and %v", first, resp.Breakdown)
+		}
+	}
+
+	// Results without a list, e.g. from an older cache entry, get one from the map
+	calc := &mockCalculator{result: domain.CalculationResult{
+		Amount: 1100, TotalItems: 1250, TotalPacks: 3, Overage: 150,
+		Breakdown: map[int]int{250: 1, 500: 2},
+	}}
+	w := httptest.NewRecorder()
+	newTestRouter(&mockPacksService{sizes: []int{250, 500}}, calc).ServeHTTP(w, newTestRequest("POST", "/calculate?breakdownFormat=list", map[string]int{"amount": 1100}))
+	if !strings.Contains(w.Body.String(), `"breakdown":[{"size":500,"count":2},{"size":250,"count":1}]`) {
+		t.Errorf("Expected the list rebuilt from the map, got %s", w.Body.String())
+	}
+
+	for _, query := range []string{"breakdownFormat=list&detailed=true", "breakdownFormat=tree"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", "/calculate?"+query, map[string]int{"amount": 1100}))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestCalculate_SortOrder(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500, 1000, 2000, 5000}, skus: map[int]string{250: "BOX-250"}}
+	router := newTestRouter(svc, calculator.NewService())
+
+	// 12001 -> 2x5000 + 2000 + 250, listed largest first by default
+	for query, want := range map[string][]int{
+		"":                                 {5000, 2000, 250},
+		"?order=desc":                      {5000, 2000, 250},
+		"?order=asc":                       {250, 2000, 5000},
+		"?order=ASC&breakdownFormat=list":  {250, 2000, 5000},
+		"?order=desc&breakdownFormat=list": {5000, 2000, 250},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", "/calculate"+query, map[string]int{"amount": 12001}))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d: %s", query, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Breakdown json.RawMessage `json:"breakdown"`
+			Packs     []packCount     `json:"packs"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%q: failed to decode response: %v", query, err)
+		}
+
+		lists := map[string][]int{"packs": nil}
+		for _, p := range resp.Packs {
+			lists["packs"] = append(lists["packs"], p.Size)
+		}
+		if strings.Contains(query, "breakdownFormat=list") {
+			var breakdown []domain.PackCount
+			if err := json.Unmarshal(resp.Breakdown, &breakdown); err != nil {
+				t.Fatalf("%q: expected a breakdown list, got %s", query, resp.Breakdown)
+			}
+			for _, p := range breakdown {
+				lists["breakdown"] = append(lists["breakdown"], p.Size)
+			}
+		}
+		for name, got := range lists {
+			if !slices.Equal(got, want) {
+				t.Errorf("%q: expected %s sizes %v, got %v", query, name, want, got)
+			}
+		}
+		if len(resp.Packs) == 3 && resp.Packs[slices.Index(want, 250)].SKU != "BOX-250" {
+			t.Errorf("%q: expected SKUs to stay with their sizes, got %+v", query, resp.Packs)
+		}
+	}
+
+	for _, query := range []string{"order=ascending", "order=size"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", "/calculate?"+query, map[string]int{"amount": 12001}))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"order"`) {
+			t.Errorf("%s: expected a 400 naming the order field, got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCalculate_FragmentedSolutions(t *testing.T) {
+	router := NewRouter(&mockPacksService{sizes: []int{250, 500, 1000}}, calculator.NewService(), nil, newTestErrorHandler(), HandlerConfig{MaxPacksWarn: 1000})
+	calculate := func(body map[string]any) map[string]any {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", "/calculate", body))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %v, got %d: %s", body, w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	// The known pathological case: thousands of packs, dominated by the 53s
+	fewestItems := calculate(map[string]any{"amount": 500000, "sizes": []int{23, 31, 53}})
+	if fewestItems["fragmented"] != true {
+		t.Errorf("Expected %v packs to be flagged as fragmented", fewestItems["totalPacks"])
+	}
+	if fewestItems["overage"] != float64(0) {
+		t.Errorf("Expected the fewest-items solution to be exact, got overage %v", fewestItems["overage"])
+	}
+
+	// Fewer packs first trades overage for pack count, though the sizes still force thousands
+	fewestPacks := calculate(map[string]any{"amount": 500000, "sizes": []int{23, 31, 53}, "preferFewerPacks": true})
+	if fewestPacks["totalPacks"] != float64(9434) {
+		t.Errorf("Expected the minimum of 9434 packs, got %v", fewestPacks["totalPacks"])
+	}
+	if fewestPacks["totalPacks"].(float64) > fewestItems["totalPacks"].(float64) || fewestPacks["totalItems"].(float64) < fewestItems["totalItems"].(float64) {
+		t.Errorf("Expected no more packs and no fewer items than %v, got %v", fewestItems, fewestPacks)
+	}
+	if fewestPacks["fragmented"] != true {
+		t.Errorf("Expected %v packs to be flagged as fragmented", fewestPacks["totalPacks"])
+	}
+
+	if resp := calculate(map[string]any{"amount": 1100}); resp["fragmented"] != nil {
+		t.Errorf("Expected no flag for %v packs, got %v", resp["totalPacks"], resp["fragmented"])
+	}
+
+	// The two policies contradict each other
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 1100, "preferFewerPacks": true, "tieBreak": "ItemsFirst"}))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 combined with tieBreak ItemsFirst, got %d", w.Code)
+	}
+
+	// Without a threshold nothing is flagged
+	w = httptest.NewRecorder()
+	newTestRouter(&mockPacksService{}, calculator.NewService()).ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 500000, "sizes": []int{23, 31, 53}}))
+	if strings.Contains(w.Body.String(), "fragmented") {
+		t.Errorf("Expected no flag with the warning disabled, got %s", w.Body.String())
+	}
+}
+
+func TestCalculate_Shortfall(t *testing.T) {
+	tests := []struct {
+		name      string
+		result    domain.CalculationResult
+		overage   float64
+		shortfall float64
+	}{
+		{"over-fulfilled", domain.CalculationResult{Amount: 1100, TotalItems: 1250, Overage: 150}, 150, 0},
+		{"under-fulfilled", domain.CalculationResult{Amount: 1100, TotalItems: 1000, Shortfall: 100}, 0, 100},
+	}
+	for _, tt := range tests {
+		router := newTestRouter(&mockPacksService{sizes: []int{250, 500}}, &mockCalculator{result: tt.result})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]int{"amount": 1100}))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", tt.name, w.Code)
+		}
+		var resp map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", tt.name, err)
+		}
+		if resp["overage"] != tt.overage || resp["shortfall"] != tt.shortfall {
+			t.Errorf("%s: expected overage %v and shortfall %v, got %v and %v",
+				tt.name, tt.overage, tt.shortfall, resp["overage"], resp["shortfall"])
+		}
+	}
+}
+
+func TestCalculate_MinOnePerSize(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500, 1000}}, calculator.NewService())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 2000, "minOnePerSize": true}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		TotalItems int            `json:"totalItems"`
+		TotalPacks int            `json:"totalPacks"`
+		Overage    int            `json:"overage"`
+		Breakdown  map[string]int `json:"breakdown"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	want := map[string]int{"250": 2, "500": 1, "1000": 1}
+	if resp.TotalItems != 2000 || resp.TotalPacks != 4 || resp.Overage != 0 || !reflect.DeepEqual(resp.Breakdown, want) {
+		t.Errorf("Expected 2000 items in 4 packs as %v, got %+v", want, resp)
+	}
+
+	// One pack of each size is 1750 items, above a maximum order amount of 1000
+	router = NewRouter(&mockPacksService{sizes: []int{250, 500, 1000}}, calculator.NewService(), nil, newTestErrorHandler(),
+		HandlerConfig{Validator: domain.NewValidator(domain.ValidationLimits{MaxAmount: 1000})})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 100, "minOnePerSize": true}))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for an oversized bundle, got %d: %s", w.Code, w.Body.String())
+	}
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Expected JSON error response, got %q", w.Body.String())
+	}
+	if errResp.Details["field"] != "minOnePerSize" || errResp.Details["bundleItems"] != float64(1750) {
+		t.Errorf("Expected minOnePerSize details with 1750 bundle items, got %v", errResp.Details)
+	}
+}
+
+func TestCalculate_FixedPacks(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500, 1000}}, calculator.NewService())
+
+	// Unlimited, 1001 is 250+1000; that already fits in two packs
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 1001, "fixedPacks": 2}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		TotalItems int `json:"totalItems"`
+		TotalPacks int `json:"totalPacks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.TotalItems != 1250 || resp.TotalPacks != 2 {
+		t.Errorf("Expected 1250 items in 2 packs, got %+v", resp)
+	}
+
+	// One pack can't reach 1001
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 1001, "fixedPacks": 1}))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422 when the budget is too small, got %d: %s", w.Code, w.Body.String())
+	}
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Expected JSON error response, got %q", w.Body.String())
+	}
+	if errResp.Code != ErrCodeNoSolution || !strings.Contains(fmt.Sprint(errResp.Details["reason"]), "at most 1 packs") {
+		t.Errorf("Expected NO_SOLUTION naming the budget, got %+v", errResp)
+	}
+
+	for _, budget := range []int{0, -3} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 1001, "fixedPacks": budget}))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("fixedPacks %d: expected status 400, got %d", budget, w.Code)
+		}
+	}
+}
+
+func TestCalculate_DetailedBreakdown(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	calc := &mockCalculator{result: domain.CalculationResult{
+		Amount: 1100, TotalItems: 1250, TotalPacks: 3, Overage: 150,
+		Breakdown: map[int]int{500: 2, 250: 1},
+	}}
+	router := newTestRouter(svc, calc)
+
+	req := newTestRequest("POST", "/calculate?detailed=true", map[string]int{"amount": 1100})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		Breakdown map[string]sizeContribution `json:"breakdown"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Breakdown["500"] != (sizeContribution{Count: 2, Items: 1000}) {
+		t.Errorf("Expected 500 -> {2 1000}, got %+v", response.Breakdown["500"])
+	}
+	if response.Breakdown["250"] != (sizeContribution{Count: 1, Items: 250}) {
+		t.Errorf("Expected 250 -> {1 250}, got %+v", response.Breakdown["250"])
+	}
+
+	// The flat size -> count map stays the default
+	req = newTestRequest("POST", "/calculate", map[string]int{"amount": 1100})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var flat struct {
+		Breakdown map[string]int `json:"breakdown"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&flat); err != nil {
+		t.Fatalf("Expected flat breakdown by default: %v", err)
+	}
+	if flat.Breakdown["500"] != 2 {
+		t.Errorf("Expected flat breakdown 500 -> 2, got %v", flat.Breakdown)
+	}
+}
+
+func TestCalculate_PreferredSizes(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{2, 3, 4}}
+	router := newTestRouter(svc, calculator.NewService())
+
+	req := newTestRequest("POST", "/calculate", map[string]any{"amount": 6, "preferred": []int{3}})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		TotalItems int            `json:"totalItems"`
+		TotalPacks int            `json:"totalPacks"`
+		Breakdown  map[string]int `json:"breakdown"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.TotalItems != 6 || response.TotalPacks != 2 || response.Breakdown["3"] != 2 {
+		t.Errorf("Expected 2x3 for preferred size 3, got %+v", response)
+	}
+}
+
+func TestGetExactFit(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500, 1000}}, calculator.NewService())
+
+	tests := []struct {
+		query    string
+		wantCode int
+		exact    bool
+	}{
+		{"?amount=750", http.StatusOK, true},
+		{"?amount=751", http.StatusOK, false},
+		{"?amount=0", http.StatusBadRequest, false},
+		{"?amount=1000001", http.StatusBadRequest, false},
+		{"?amount=abc", http.StatusBadRequest, false},
+		{"", http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("GET", "/calculate/exact"+tt.query, nil))
+
+		if w.Code != tt.wantCode {
+			t.Fatalf("%q: expected status %d, got %d: %s", tt.query, tt.wantCode, w.Code, w.Body.String())
+		}
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+		var resp struct {
+			Exact bool `json:"exact"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%q: failed to decode response: %v", tt.query, err)
+		}
+		if resp.Exact != tt.exact {
+			t.Errorf("%q: expected exact=%v, got %v", tt.query, tt.exact, resp.Exact)
+		}
+	}
+
+	// No active sizes is a validation error, as for POST /calculate
+	router = newTestRouter(&mockPacksService{sizes: []int{}}, calculator.NewService())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("GET", "/calculate/exact?amount=750", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 with no pack sizes, got %d", w.Code)
+	}
+}
+
+func TestCalculate_RoundTo(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	router := newTestRouter(svc, calculator.NewService())
+
+	tests := []struct {
+		name         string
+		body         map[string]any
+		expectedCode int
+		rounded      int
+		overage      int
+	}{
+		{"rounds up to the lot", map[string]any{"amount": 501, "roundTo": 50}, http.StatusOK, 550, 200},
+		{"exact multiple unchanged", map[string]any{"amount": 500, "roundTo": 50}, http.StatusOK, 500, 0},
+		{"zero lot rejected", map[string]any{"amount": 501, "roundTo": 0}, http.StatusBadRequest, 0, 0},
+		{"negative lot rejected", map[string]any{"amount": 501, "roundTo": -50}, http.StatusBadRequest, 0, 0},
+		{"rounded amount above maximum", map[string]any{"amount": 999_999, "roundTo": 400_000}, http.StatusBadRequest, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newTestRequest("POST", "/calculate", tt.body))
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			var response struct {
+				Amount         int `json:"amount"`
+				OriginalAmount int `json:"originalAmount"`
+				RoundedAmount  int `json:"roundedAmount"`
+				Overage        int `json:"overage"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.OriginalAmount != tt.body["amount"] || response.RoundedAmount != tt.rounded || response.Amount != tt.rounded {
+				t.Errorf("Expected original %v rounded to %d, got %+v", tt.body["amount"], tt.rounded, response)
+			}
+			if response.Overage != tt.overage {
+				t.Errorf("Expected overage %d relative to the rounded amount, got %d", tt.overage, response.Overage)
+			}
+		})
+	}
+
+	// The rounded amount reported above must not show up on a later, unrelated amount failure
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]int{"amount": 2_000_000}))
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	if _, ok := errResp.Details["roundedAmount"]; ok || errResp.Details["field"] != "amount" {
+		t.Errorf("Expected a plain amount failure, got %v", errResp.Details)
+	}
+}
+
+func TestCalculate_TieBreakOverride(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500, 1000}}
+	router := newTestRouter(svc, calculator.NewService())
+
+	tests := []struct {
+		name          string
+		tieBreak      string
+		expectedCode  int
+		expectedItems int
+		expectedPacks int
+	}{
+		{"server default", "", http.StatusOK, 750, 2},
+		{"packs first", "PacksFirst", http.StatusOK, 1000, 1},
+		{"case insensitive", "packsfirst", http.StatusOK, 1000, 1},
+		{"items first", "ItemsFirst", http.StatusOK, 750, 2},
+		{"unknown policy", "FewestBoxes", http.StatusBadRequest, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newTestRequest("POST", "/calculate", map[string]any{"amount": 501, "tieBreak": tt.tieBreak})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			var response struct {
+				TotalItems int `json:"totalItems"`
+				TotalPacks int `json:"totalPacks"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.TotalItems != tt.expectedItems || response.TotalPacks != tt.expectedPacks {
+				t.Errorf("Expected %d items / %d packs, got %+v", tt.expectedItems, tt.expectedPacks, response)
+			}
+		})
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	router := NewRouter(svc, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{MaxBodyBytes: 64, MaxBatchBodyBytes: 1024})
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		reason string
+	}{
+		{"body over route limit", "/calculate", `{"amount": 1, "sizes": [` + strings.Repeat("1,", 40) + `1]}`, "request body too large"},
+		{"deep nesting", "/calculate/batch", strings.Repeat("[", 40) + strings.Repeat("]", 40), "JSON nesting exceeds 32 levels"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", w.Code)
+			}
+			var errResp APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("Failed to parse error response: %v", err)
+			}
+			if errResp.Code != ErrCodeInvalidInput || errResp.Details["reason"] != tt.reason {
+				t.Errorf("Expected INVALID_INPUT %q, got %s %v", tt.reason, errResp.Code, errResp.Details)
+			}
+			// Only an oversized body names the limit; it must not leak into later errors
+			if _, ok := errResp.Details["limit_bytes"]; ok != (tt.reason == "request body too large") {
+				t.Errorf("Unexpected limit_bytes presence in %v", errResp.Details)
+			}
+		})
+	}
+
+	// The batch route accepts a body larger than the regular limit
+	req := newTestRequest("POST", "/calculate/batch", map[string][]int{"amounts": {1000, 2000, 3000, 4000, 5000, 6000, 7000, 8000, 9000, 10000}})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for batch within its limit, got %d", w.Code)
+	}
+}
+
+func TestDecodeErrorDetails(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	router := newTestRouter(svc, &mockCalculator{})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		field    string
+		offset   float64
+		expected string
+	}{
+		{"syntax error", "POST", "/calculate", `{"amount": 12,, "sizes": []}`, "body", 15, ""},
+		{"truncated body", "POST", "/calculate", `{"amount": 12`, "body", 13, ""},
+		{"wrong type", "POST", "/calculate", `{"amount": "twelve"}`, "amount", 19, "int"},
+		{"wrong field type", "PUT", "/packs", `{"sizes": true}`, "sizes", 14, ""},
+		{"wrong top-level type", "PUT", "/packs", `[250, 500]`, "body", 1, "http.putPacksReq"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", w.Code)
+			}
+			var errResp APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("Failed to parse error response: %v", err)
+			}
+			if errResp.Code != ErrCodeInvalidInput {
+				t.Fatalf("Expected INVALID_INPUT, got %s", errResp.Code)
+			}
+			if errResp.Details["field"] != tt.field || errResp.Details["offset"] != tt.offset {
+				t.Errorf("Expected field %q at offset %v, got %v", tt.field, tt.offset, errResp.Details)
+			}
+			if tt.expected != "" && errResp.Details["expected"] != tt.expected {
+				t.Errorf("Expected type %q, got %v", tt.expected, errResp.Details)
+			}
+		})
+	}
+
+	// A type mismatch's details must not carry over to the next decode error
+	for _, body := range []string{`{"sizes": "x"}`, `{"amount":`} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/calculate", strings.NewReader(body)))
+		if body != `{"amount":` {
+			continue
+		}
+		var errResp APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+			t.Fatalf("Failed to parse error response: %v", err)
+		}
+		if _, ok := errResp.Details["expected"]; ok || errResp.Details["got"] != nil {
+			t.Errorf("Expected no type mismatch details on a syntax error, got %v", errResp.Details)
+		}
+	}
+}
+
+func TestCheckJSONShape(t *testing.T) {
+	longArray := "[" + strings.Repeat("1,", maxJSONArrayLen) + "1]"
+	tests := []struct {
+		body string
+		ok   bool
+	}{
+		{`{"amounts": [1, 2, 3], "sizes": [250, 500]}`, true},
+		{`{"note": "[[[[ commas, in, strings ]]]]"}`, true},
+		{`{"escaped": "quote \" [ inside"}`, true},
+		{longArray, false},
+		{strings.Repeat(`{"a":`, 33) + "1" + strings.Repeat("}", 33), false},
+	}
+
+	for i, tt := range tests {
+		if reason := checkJSONShape([]byte(tt.body)); (reason == "") != tt.ok {
+			t.Errorf("Case %d: expected ok=%v, got reason %q", i, tt.ok, reason)
+		}
+	}
+}
+
+func TestCalculate_HistoricalVersion(t *testing.T) {
+	svc := &mockPacksService{
+		sizes:    []int{250, 500},
+		versions: map[int64][]domain.Pack{3: {{Size: 100}, {Size: 300, SKU: "BOX-300"}}},
+	}
+	router := newTestRouter(svc, calculator.NewService())
+
+	req := newTestRequest("POST", "/calculate", map[string]any{"amount": 250, "version": 3})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		TotalItems int         `json:"totalItems"`
+		Packs      []packCount `json:"packs"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.TotalItems != 300 || len(response.Packs) != 1 || response.Packs[0].SKU != "BOX-300" {
+		t.Errorf("Expected 1x300 (BOX-300) from version 3, got %+v", response)
+	}
+
+	// Unknown versions are not found
+	req = newTestRequest("POST", "/calculate", map[string]any{"amount": 250, "version": 99})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown version, got %d", w.Code)
+	}
+
+	// Versions can't be combined with custom sizes
+	req = newTestRequest("POST", "/calculate", map[string]any{"amount": 250, "version": 3, "sizes": []int{250}})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for version with custom sizes, got %d", w.Code)
+	}
+}
+
+func TestCalculate_VersionStamp(t *testing.T) {
+	svc := &mockPacksService{
+		sizes:    []int{250, 500},
+		meta:     domain.PackSetMeta{Version: 7},
+		versions: map[int64][]domain.Pack{3: {{Size: 100}, {Size: 300}}},
+	}
+	router := newTestRouter(svc, calculator.NewService())
+
+	tests := []struct {
+		name        string
+		body        map[string]any
+		wantVersion int64 // 0 means the field is absent
+		wantItems   int
+	}{
+		{"active set", map[string]any{"amount": 251}, 7, 500},
+		{"historical version", map[string]any{"amount": 251, "version": 3}, 3, 300},
+		{"custom sizes", map[string]any{"amount": 251, "sizes": []int{100}}, 0, 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newTestRequest("POST", "/calculate", tt.body))
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var response struct {
+				TotalItems int    `json:"totalItems"`
+				Version    *int64 `json:"version"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.TotalItems != tt.wantItems {
+				t.Errorf("Expected %d items, got %d", tt.wantItems, response.TotalItems)
+			}
+			switch {
+			case tt.wantVersion == 0 && response.Version != nil:
+				t.Errorf("Expected no version for custom sizes, got %d", *response.Version)
+			case tt.wantVersion != 0 && (response.Version == nil || *response.Version != tt.wantVersion):
+				t.Errorf("Expected version %d, got %v", tt.wantVersion, response.Version)
+			}
+		})
+	}
+
+	// Recomputing with the stamped version gives the same result after the active set changes
+	svc.versions[7] = []domain.Pack{{Size: 250}, {Size: 500}}
+	svc.sizes, svc.meta.Version = []int{1000}, 8
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 251, "version": 7}))
+	if !strings.Contains(w.Body.String(), `"totalItems":500`) {
+		t.Errorf("Expected version 7 to reproduce 500 items, got %s", w.Body.String())
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		degraded bool
+		disabled bool
+		status   string
+		cache    string
+	}{
+		{status: "ready", cache: "ok"},
+		{degraded: true, status: "degraded", cache: "unavailable"},
+		{disabled: true, status: "ready", cache: "disabled"},
+	}
+
+	for _, tt := range tests {
+		router := NewRouter(&mockPacksService{}, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{CacheDegraded: tt.degraded, CacheDisabled: tt.disabled})
+
+		req := newTestRequest("GET", "/readyz", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", w.Code)
+		}
+		var response map[string]string
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response["status"] != tt.status || response["cache"] != tt.cache {
+			t.Errorf("Degraded=%v disabled=%v: expected %q/%q, got %v", tt.degraded, tt.disabled, tt.status, tt.cache, response)
+		}
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	started := time.Now().Add(-90 * time.Second)
+	router := NewRouter(&mockPacksService{}, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{
+		Build:           BuildInfo{Version: "1.2.0", Commit: "abc1234", StartedAt: started},
+		ElevatedAPIKeys: []string{"secret"},
+	})
+
+	// No API key is needed
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("GET", "/version", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp struct {
+		Version       string `json:"version"`
+		Commit        string `json:"commit"`
+		GoVersion     string `json:"goVersion"`
+		StartedAt     string `json:"startedAt"`
+		Uptime        string `json:"uptime"`
+		UptimeSeconds int64  `json:"uptimeSeconds"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Version != "1.2.0" || resp.Commit != "abc1234" {
+		t.Errorf("Expected version 1.2.0 and commit abc1234, got %q and %q", resp.Version, resp.Commit)
+	}
+	if resp.GoVersion != runtime.Version() {
+		t.Errorf("Expected Go version %s, got %q", runtime.Version(), resp.GoVersion)
+	}
+	if resp.StartedAt != started.UTC().Format(time.RFC3339) {
+		t.Errorf("Expected startedAt %s, got %q", started.UTC().Format(time.RFC3339), resp.StartedAt)
+	}
+	if resp.UptimeSeconds < 90 || resp.Uptime == "" {
+		t.Errorf("Expected at least 90s of uptime, got %q (%d)", resp.Uptime, resp.UptimeSeconds)
+	}
+
+	// Unset build information falls back to placeholders
+	w = httptest.NewRecorder()
+	newTestRouter(&mockPacksService{}, &mockCalculator{}).ServeHTTP(w, newTestRequest("GET", "/version", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Version != "dev" || resp.Commit != "unknown" {
+		t.Errorf("Expected dev/unknown placeholders, got %q and %q", resp.Version, resp.Commit)
+	}
+}
+
+func TestGetReady_LiveHealth(t *testing.T) {
+	checked := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		health   domain.DependencyHealth
+		disabled bool
+		code     int
+		status   string
+		database string
+		cache    string
+	}{
+		{domain.DependencyHealth{DatabaseOK: true, CacheOK: true, CheckedAt: checked}, false, http.StatusOK, "ready", "ok", "ok"},
+		{domain.DependencyHealth{DatabaseOK: true, CheckedAt: checked}, false, http.StatusOK, "degraded", "ok", "unavailable"},
+		{domain.DependencyHealth{DatabaseOK: true, CheckedAt: checked}, true, http.StatusOK, "ready", "ok", "disabled"},
+		{domain.DependencyHealth{CacheOK: true, CheckedAt: checked}, false, http.StatusServiceUnavailable, "unavailable", "unavailable", "ok"},
+	}
+
+	for _, tt := range tests {
+		health := tt.health
+		router := NewRouter(&mockPacksService{}, &mockCalculator{}, nil, newTestErrorHandler(), HandlerConfig{
+			CacheDisabled: tt.disabled,
+			Health:        func() domain.DependencyHealth { return health },
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("GET", "/readyz", nil))
+
+		if w.Code != tt.code {
+			t.Errorf("%+v: expected status %d, got %d", tt.health, tt.code, w.Code)
+		}
+		var response map[string]string
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response["status"] != tt.status || response["database"] != tt.database || response["cache"] != tt.cache {
+			t.Errorf("%+v: expected %q/%q/%q, got %v", tt.health, tt.status, tt.database, tt.cache, response)
+		}
+		if response["checkedAt"] != "2024-05-01T12:00:00Z" {
+			t.Errorf("Expected the check time, got %q", response["checkedAt"])
+		}
+	}
+}
+
+func TestGetCacheStats(t *testing.T) {
+	svc := &mockPacksService{stats: domain.CacheStats{Hits: 3, Misses: 1, HitRatio: 0.75}}
+	router := newTestRouter(svc, &mockCalculator{})
+
+	for _, tc := range []struct {
+		query string
+		reset bool
+	}{
+		{"", false},
+		{"?reset=true", true},
+		{"?reset=false", false},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest(http.MethodGet, "/cache/stats"+tc.query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d", tc.query, w.Code)
+		}
+
+		var stats domain.CacheStats
+		if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+			t.Fatalf("%q: failed to decode response: %v", tc.query, err)
+		}
+		if stats != svc.stats {
+			t.Errorf("%q: expected %+v, got %+v", tc.query, svc.stats, stats)
+		}
+		if svc.statsReset != tc.reset {
+			t.Errorf("%q: expected reset=%v, got %v", tc.query, tc.reset, svc.statsReset)
+		}
+	}
+}
+
+func TestGetMetrics(t *testing.T) {
+	svc := &mockPacksService{stats: domain.CacheStats{Hits: 3, Misses: 1, HitRatio: 0.75}, statsReset: true}
+	calc := &mockCalculator{}
+	router := newTestRouter(svc, calc)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp struct {
+		CalculatorPool domain.PoolStats  `json:"calculatorPool"`
+		Cache          domain.CacheStats `json:"cache"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.CalculatorPool != calc.PoolStats() {
+		t.Errorf("Expected pool stats %+v, got %+v", calc.PoolStats(), resp.CalculatorPool)
+	}
+	if resp.Cache != svc.stats {
+		t.Errorf("Expected cache stats %+v, got %+v", svc.stats, resp.Cache)
+	}
+	if svc.statsReset {
+		t.Errorf("Expected metrics not to reset cache counters")
+	}
+}
+
+func TestPackReads_DatabaseUnavailable(t *testing.T) {
+	svc := &mockPacksService{err: &domain.UnavailableError{Dependency: "database", RetryAfter: 2500 * time.Millisecond}}
+	router := newTestRouter(svc, &mockCalculator{})
+
+	for _, req := range []*http.Request{
+		newTestRequest("GET", "/packs", nil),
+		newTestRequest("POST", "/calculate", map[string]int{"amount": 500}),
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("%s %s: expected status 503, got %d", req.Method, req.URL.Path, w.Code)
+		}
+		// Rounded up, so clients don't retry before the breaker's trial
+		if got := w.Header().Get("Retry-After"); got != "3" {
+			t.Errorf("%s %s: expected Retry-After 3, got %q", req.Method, req.URL.Path, got)
+		}
+		var body APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if body.Code != ErrCodeUnavailable || body.Details["dependency"] != "database" || body.Details["retryAfterSeconds"] != float64(3) {
+			t.Errorf("%s %s: expected SERVICE_UNAVAILABLE for the database, got %+v", req.Method, req.URL.Path, body)
+		}
+	}
+
+	// Other failures stay internal errors
+	svc.err = errors.New("connection reset")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("GET", "/packs", nil))
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Retry-After") != "" {
+		t.Errorf("Expected a 500 without Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestCalculate_MinUtilization(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500, 1000, 2000, 5000}}, calculator.NewService())
+
+	// By default 5001 opens a 250 pack for one item; half full packs means three 2000s instead
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 5001, "minUtilization": 0.5}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		TotalItems int            `json:"totalItems"`
+		Breakdown  map[string]int `json:"breakdown"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.TotalItems != 6000 || resp.Breakdown["2000"] != 3 {
+		t.Errorf("Expected three 2000 packs, got %+v", resp)
+	}
+
+	// Fully used packs only: no multiple of 250 is 5001
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 5001, "minUtilization": 1}))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422 when no solution is utilized enough, got %d: %s", w.Code, w.Body.String())
+	}
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Expected JSON error response, got %q", w.Body.String())
+	}
+	if errResp.Code != ErrCodeNoSolution || !strings.Contains(fmt.Sprint(errResp.Details["reason"]), "100% utilized") {
+		t.Errorf("Expected NO_SOLUTION naming the minimum, got %+v", errResp)
+	}
+
+	for _, minUtil := range []float64{-0.1, 1.5} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 5001, "minUtilization": minUtil}))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("minUtilization %g: expected status 400, got %d", minUtil, w.Code)
+		}
+	}
+}
+
+func TestCalculate_FlexibleAmount(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500, 1000}}, calculator.NewService())
+
+	for _, body := range []string{
+		`{"amount": 263}`,
+		`{"amount": 263.0}`,
+		`{"amount": 2.63e2}`,
+		`{"amount": "263"}`,
+		`{"amount": "263.0", "sizes": [250, 500, 1000]}`,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/calculate", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d: %s", body, w.Code, w.Body.String())
+			continue
+		}
+		var resp struct {
+			Amount     int `json:"amount"`
+			TotalItems int `json:"totalItems"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.Amount != 263 || resp.TotalItems != 500 {
+			t.Errorf("%s: expected 263 packed as 500 items, got %+v", body, resp)
+		}
+	}
+
+	tests := []struct {
+		body  string
+		code  ErrorCode
+		field string
+	}{
+		{`{"amount": 263.5}`, ErrCodeValidationFailed, "amount"},
+		{`{"amount": "263.5"}`, ErrCodeValidationFailed, "amount"},
+		{`{"amount": "two hundred"}`, ErrCodeInvalidInput, "amount"},
+		{`{"amount": "0x107"}`, ErrCodeInvalidInput, "amount"},
+		{`{"amount": ""}`, ErrCodeInvalidInput, "amount"},
+		{`{"amount": true}`, ErrCodeInvalidInput, "amount"},
+		{`{"amount": 1e400}`, ErrCodeInvalidInput, "amount"},
+		{`{"amount": 263, "sizes": "250"}`, ErrCodeInvalidInput, "sizes"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/calculate", strings.NewReader(tt.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d: %s", tt.body, w.Code, w.Body.String())
+			continue
+		}
+		var errResp APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+			t.Fatalf("Failed to parse error response: %v", err)
+		}
+		if errResp.Code != tt.code || errResp.Details["field"] != tt.field {
+			t.Errorf("%s: expected %s for %s, got %+v", tt.body, tt.code, tt.field, errResp)
+		}
+	}
+}
+
+func TestStrictJSON_UnknownFields(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500, 1000}}
+	strict := NewRouter(svc, calculator.NewService(), nil, newTestErrorHandler(), HandlerConfig{StrictJSON: true})
+	lenient := newTestRouter(svc, calculator.NewService())
+
+	tests := []struct {
+		method, path, body, field string
+	}{
+		{"POST", "/calculate", `{"amounts": 263}`, "amounts"},
+		{"POST", "/calculate", `{"amount": 263, "tiebreak": "PacksFirst", "sizez": [250]}`, "sizez"},
+		{"POST", "/calculate", `{"amount": "263", "unknown": true}`, "unknown"},
+		{"PUT", "/packs", `{"size": [250, 500]}`, "size"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		strict.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected status 400, got %d: %s", tt.method, tt.body, w.Code, w.Body.String())
+			continue
+		}
+		var errResp APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+			t.Fatalf("Failed to parse error response: %v", err)
+		}
+		if errResp.Code != ErrCodeInvalidInput || errResp.Details["field"] != tt.field ||
+			errResp.Details["reason"] != fmt.Sprintf("unknown field %q", tt.field) {
+			t.Errorf("%s %s: expected INVALID_INPUT naming %q, got %+v", tt.method, tt.body, tt.field, errResp)
+		}
+	}
+
+	// Without strict mode the typo is ignored, so the missing amount is what gets reported
+	w := httptest.NewRecorder()
+	lenient.ServeHTTP(w, httptest.NewRequest("POST", "/calculate", strings.NewReader(`{"amounts": 263}`)))
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || errResp.Details["field"] != "amount" {
+		t.Errorf("Expected the lenient router to report the amount, got %d: %+v", w.Code, errResp)
+	}
+
+	// Known fields still decode, with the usual errors, in strict mode
+	for body, code := range map[string]int{
+		`{"amount": 263, "tieBreak": "PacksFirst"}`: http.StatusOK,
+		`{"amount": 263.0, "sizes": [250, 500]}`:    http.StatusOK,
+		`{"amount": 263,, "sizes": []}`:             http.StatusBadRequest,
+		`{"amount": 263} {"amount": 1}`:             http.StatusBadRequest,
+		`{"amount": "twelve"}`:                      http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		strict.ServeHTTP(w, httptest.NewRequest("POST", "/calculate", strings.NewReader(body)))
+		if w.Code != code {
+			t.Errorf("%s: expected status %d, got %d: %s", body, code, w.Code, w.Body.String())
+		}
+	}
+}
+
+// unsolvableCalculator is the real calculator, except that the amounts in unsolvable have no
+// solution, failing a batch containing them as the real one does.
+type unsolvableCalculator struct {
+	*calculator.Service
+	unsolvable map[int]bool
+}
+
+func (c *unsolvableCalculator) Compute(ctx context.Context, amount int, sizes []int) (domain.CalculationResult, error) {
+	if c.unsolvable[amount] {
+		return domain.CalculationResult{}, &domain.NoSolutionError{Amount: amount, Reason: "no combination of whole packs fulfills the amount"}
+	}
+	return c.Service.Compute(ctx, amount, sizes)
+}
+
+func (c *unsolvableCalculator) ComputeBatch(ctx context.Context, amounts []int, sizes []int) ([]domain.CalculationResult, error) {
+	for _, amt := range amounts {
+		if c.unsolvable[amt] {
+			return nil, &domain.NoSolutionError{Amount: amt, Reason: "no combination of whole packs fulfills the amount"}
+		}
+	}
+	return c.Service.ComputeBatch(ctx, amounts, sizes)
+}
+
+func TestBatch_Aggregate(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500, 1000}}
+	calc := &unsolvableCalculator{Service: calculator.NewService(), unsolvable: map[int]bool{777: true}}
+	validator := domain.NewValidator(domain.ValidationLimits{MaxAmount: 5000})
+	router := NewRouter(svc, calc, nil, newTestErrorHandler(), HandlerConfig{Validator: validator})
+
+	type aggregate struct {
+		Succeeded             int     `json:"succeeded"`
+		Failed                int     `json:"failed"`
+		TotalAmount           int     `json:"totalAmount"`
+		TotalItems            int     `json:"totalItems"`
+		TotalPacks            int     `json:"totalPacks"`
+		TotalOverage          int     `json:"totalOverage"`
+		AverageOveragePercent float64 `json:"averageOveragePercent"`
+	}
+	type response struct {
+		Results []struct {
+			Amount     int `json:"amount"`
+			TotalItems int `json:"totalItems"`
+			Error      *struct {
+				Code   ErrorCode `json:"code"`
+				Reason string    `json:"reason"`
+			} `json:"error"`
+		} `json:"results"`
+		Aggregate aggregate `json:"aggregate"`
+	}
+	batch := func(path string, amounts []int) (int, response) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", path, map[string][]int{"amounts": amounts}))
+		var resp response
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return w.Code, resp
+	}
+
+	// All valid: 251 -> 500 (1 pack), 501 -> 750 (2 packs), each with overage 249
+	code, resp := batch("/calculate/batch", []int{251, 501, 251})
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	avg := (249.0/251*100*2 + 249.0/501*100) / 3
+	want := aggregate{Succeeded: 3, TotalAmount: 1003, TotalItems: 1750, TotalPacks: 4, TotalOverage: 747, AverageOveragePercent: avg}
+	if resp.Aggregate != want {
+		t.Errorf("Expected aggregate %+v, got %+v", want, resp.Aggregate)
+	}
+
+	// A mix of valid amounts, invalid ones (0, above the maximum) and one without a solution
+	mixed := []int{251, 0, 501, 9000, 251, 777}
+	if code, _ := batch("/calculate/batch", mixed); code != http.StatusBadRequest {
+		t.Errorf("Expected the whole batch to fail without partial, got %d", code)
+	}
+	code, resp = batch("/calculate/batch?partial=true", mixed)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200 with partial, got %d", code)
+	}
+	want.Failed = 3
+	if resp.Aggregate != want {
+		t.Errorf("Expected failed items to be left out of %+v, got %+v", want, resp.Aggregate)
+	}
+	if len(resp.Results) != len(mixed) {
+		t.Fatalf("Expected %d results, got %d", len(mixed), len(resp.Results))
+	}
+	for i, wantCode := range []ErrorCode{"", ErrCodeValidationFailed, "", ErrCodeValidationFailed, "", ErrCodeNoSolution} {
+		res := resp.Results[i]
+		if res.Amount != mixed[i] {
+			t.Errorf("Result %d: expected amount %d, got %d", i, mixed[i], res.Amount)
+		}
+		switch {
+		case wantCode == "" && (res.Error != nil || res.TotalItems == 0):
+			t.Errorf("Result %d: expected a solution, got %+v", i, res)
+		case wantCode != "" && (res.Error == nil || res.Error.Code != wantCode || res.Error.Reason == ""):
+			t.Errorf("Result %d: expected a %s error with a reason, got %+v", i, wantCode, res.Error)
+		}
+	}
+
+	// Nothing succeeds: the aggregate is all zero but for the failures
+	code, resp = batch("/calculate/batch?partial=true", []int{0, 777})
+	if code != http.StatusOK || resp.Aggregate != (aggregate{Failed: 2}) {
+		t.Errorf("Expected an empty aggregate with 2 failures, got %d: %+v", code, resp.Aggregate)
+	}
+}
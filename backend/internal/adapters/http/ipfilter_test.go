@@ -0,0 +1,167 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mustIPFilter builds an IPFilter, failing the test on a parse error.
+func mustIPFilter(t *testing.T, allow, deny []string) *IPFilter {
+	t.Helper()
+	f, err := NewIPFilter(allow, deny)
+	if err != nil {
+		t.Fatalf("NewIPFilter: %v", err)
+	}
+	return f
+}
+
+// requestFrom builds a request whose direct peer is remoteAddr.
+func requestFrom(remoteAddr string) *http.Request {
+	req := httptest.NewRequest("GET", "/api/v1/packs", nil)
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
+func TestIPFilter_Allowlist(t *testing.T) {
+	router := newSecuredRouterWith(SecurityConfig{IPFilter: mustIPFilter(t, []string{"10.0.0.0/8", "192.0.2.7"}, nil)})
+
+	for addr, want := range map[string]int{
+		"10.1.2.3:4000":       http.StatusOK,
+		"192.0.2.7:4000":      http.StatusOK,
+		"192.0.2.8:4000":      http.StatusForbidden,
+		"[::1]:4000":          http.StatusForbidden,
+		"[::ffff:10.9.9.9]:1": http.StatusOK, // IPv4-mapped addresses match IPv4 ranges
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, requestFrom(addr))
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", addr, want, w.Code)
+		}
+	}
+}
+
+func TestIPFilter_Denylist(t *testing.T) {
+	router := newSecuredRouterWith(SecurityConfig{IPFilter: mustIPFilter(t, nil, []string{"203.0.113.0/24", "2001:db8::/32"})})
+
+	for addr, want := range map[string]int{
+		"203.0.113.50:4000":  http.StatusForbidden,
+		"[2001:db8::1]:4000": http.StatusForbidden,
+		"198.51.100.1:4000":  http.StatusOK,
+		"[2001:db9::1]:4000": http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, requestFrom(addr))
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", addr, want, w.Code)
+		}
+	}
+}
+
+func TestIPFilter_DenylistTakesPrecedence(t *testing.T) {
+	router := newSecuredRouterWith(SecurityConfig{IPFilter: mustIPFilter(t, []string{"10.0.0.0/8"}, []string{"10.6.0.0/16"})})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, requestFrom("10.6.1.1:4000"))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected the denylist to win inside an allowed range, got %d", w.Code)
+	}
+
+	var body APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Code != ErrCodeForbidden || body.Details["ip"] != "10.6.1.1" {
+		t.Errorf("Expected a FORBIDDEN error naming the IP, got %+v", body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, requestFrom("10.7.1.1:4000"))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the rest of the allowed range to pass, got %d", w.Code)
+	}
+}
+
+func TestIPFilter_EvaluatesForwardedClient(t *testing.T) {
+	router := newSecuredRouterWith(SecurityConfig{
+		IPFilter:  mustIPFilter(t, []string{"198.51.100.0/24"}, nil),
+		ClientIPs: mustClientIPResolver(t, []string{"172.16.0.0/12"}),
+	})
+
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		header     string
+		value      string
+		want       int
+	}{
+		// The trusted proxy itself is outside the allowlist; the original caller is judged
+		{"allowed client via trusted proxy", "172.16.0.1:4000", "X-Forwarded-For", "198.51.100.9, 172.16.0.2", http.StatusOK},
+		{"refused client via trusted proxy", "172.16.0.1:4000", "X-Forwarded-For", "203.0.113.1", http.StatusForbidden},
+		// Hops left of the first untrusted one are whatever the client sent
+		{"spoofed hop before the client", "172.16.0.1:4000", "X-Forwarded-For", "198.51.100.9, 203.0.113.1", http.StatusForbidden},
+		// Anyone else's forwarding headers are ignored, so they can't claim an allowed address
+		{"spoofed header from untrusted peer", "203.0.113.5:4000", "X-Forwarded-For", "198.51.100.9", http.StatusForbidden},
+		{"spoofed X-Real-IP from untrusted peer", "203.0.113.5:4000", "X-Real-IP", "198.51.100.9", http.StatusForbidden},
+		{"header ignored for allowed peer", "198.51.100.9:4000", "X-Forwarded-For", "203.0.113.1", http.StatusOK},
+		// A client IP that doesn't parse can't be cleared, so it's refused
+		{"unparseable client IP", "172.16.0.1:4000", "X-Real-IP", "not-an-ip", http.StatusForbidden},
+	} {
+		req := requestFrom(tc.remoteAddr)
+		req.Header.Set(tc.header, tc.value)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+}
+
+func TestIPFilter_RedactsRefusedIP(t *testing.T) {
+	redactor, err := NewPrivacyRedactor(IPLogTruncate, nil, nil)
+	if err != nil {
+		t.Fatalf("NewPrivacyRedactor: %v", err)
+	}
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	router := newSecuredRouterWith(SecurityConfig{IPFilter: mustIPFilter(t, nil, []string{"203.0.113.0/24"}), Redactor: redactor})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, requestFrom("203.0.113.50:4000"))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, got %d", w.Code)
+	}
+	var body APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Details["ip"] != "203.0.113.0/24" {
+		t.Errorf("Expected the truncated IP in the response, got %v", body.Details["ip"])
+	}
+	if strings.Contains(logs.String(), "203.0.113.50") || !strings.Contains(logs.String(), "ip=203.0.113.0/24") {
+		t.Errorf("Expected only the truncated IP in the log, got %q", logs.String())
+	}
+}
+
+func TestNewIPFilter(t *testing.T) {
+	if f, err := NewIPFilter(nil, nil); err != nil || f != nil {
+		t.Errorf("Expected no filter for empty lists, got %v, %v", f, err)
+	}
+	for _, tc := range []struct{ allow, deny []string }{
+		{[]string{"10.0.0.0/33"}, nil},
+		{nil, []string{"example.com"}},
+	} {
+		if _, err := NewIPFilter(tc.allow, tc.deny); err == nil {
+			t.Errorf("Expected an error for allow=%v deny=%v", tc.allow, tc.deny)
+		}
+	}
+	// Host bits in a CIDR are ignored rather than rejected
+	if f := mustIPFilter(t, []string{"10.1.2.3/8"}, nil); !f.Allowed("10.200.0.1") {
+		t.Errorf("Expected 10.1.2.3/8 to cover 10.0.0.0/8")
+	}
+}
@@ -0,0 +1,91 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains handlers for asynchronous batch calculation jobs.
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxJobAmounts limits how many amounts a single asynchronous job may contain.
+const maxJobAmounts = 100_000
+
+// jobReq represents the request body for submitting a batch calculation job.
+type jobReq struct {
+	Amounts []int `json:"amounts"`         // Order amounts to calculate
+	Sizes   []int `json:"sizes,omitempty"` // Optional custom pack sizes (uses active if empty)
+}
+
+// postJob validates a large batch of amounts and queues it for background processing.
+// Pack sizes are resolved at submission time, so later changes to the active set don't affect the job.
+// Returns 202 Accepted with the job ID; clients poll GET /calculate/jobs/{id} for progress.
+func (a *packSvcAdapter) postJob(w http.ResponseWriter, r *http.Request) {
+	// Job state lives in the cache, so jobs can't be tracked while running without it
+	if a.cfg.CacheDegraded || a.cfg.CacheDisabled {
+		a.errorHandler.HandleAPIError(w, r, ErrUnavailable.WithDetails("reason", "async jobs require the cache, which is unavailable"))
+		return
+	}
+	
+	var req jobReq
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBatchBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+
+	// Validate the batch
+	if len(req.Amounts) == 0 {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "amounts").WithDetails("reason", "at least one amount is required"))
+		return
+	}
+	if len(req.Amounts) > maxJobAmounts {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "amounts").WithDetails("count", len(req.Amounts)).WithDetails("reason", "a job cannot contain more than 100,000 amounts"))
+		return
+	}
+	for i, amt := range req.Amounts {
+		if err := a.cfg.Validator.ValidateAmount(amt); err != nil {
+			a.errorHandler.HandleAPIError(w, r, validationError(err).WithDetails("field", "amounts").WithDetails("index", i))
+			return
+		}
+	}
+	if apiErr := a.validateSizes(req.Sizes); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+
+	// Use custom sizes if provided, otherwise fetch active sizes
+	sizes, _, err := a.resolveSizes(r, req.Sizes)
+	if err != nil {
+		a.handleReadError(w, r, err, "get_pack_sizes")
+		return
+	}
+	if err := a.cfg.Validator.ValidatePackSet(sizes); err != nil {
+		a.errorHandler.HandleAPIError(w, r, validationError(err))
+		return
+	}
+
+	job, err := a.jobs.Submit(r.Context(), req.Amounts, sizes)
+	if err != nil {
+		a.errorHandler.HandleError(w, r, ErrInternalError.WithDetails("operation", "submit_job"))
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// getJob returns the progress of a batch calculation job, including results once completed.
+func (a *packSvcAdapter) getJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	job, ok, err := a.jobs.Get(r.Context(), id)
+	if err != nil {
+		a.errorHandler.HandleError(w, r, ErrInternalError.WithDetails("operation", "get_job"))
+		return
+	}
+	if !ok {
+		a.errorHandler.HandleAPIError(w, r, ErrNotFound.WithDetails("resource", "job").WithDetails("id", id).WithDetails("reason", "job not found or expired"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// mockJobService implements domain.JobService for testing.
+type mockJobService struct {
+	jobs      map[string]domain.Job
+	submitted []int
+	sizes     []int
+}
+
+func (m *mockJobService) Submit(ctx context.Context, amounts []int, sizes []int) (domain.Job, error) {
+	job := domain.Job{ID: "job-1", Status: domain.JobQueued, Total: len(amounts)}
+	m.submitted, m.sizes = amounts, sizes
+	m.jobs[job.ID] = job
+	return job, nil
+}
+
+func (m *mockJobService) Get(ctx context.Context, id string) (domain.Job, bool, error) {
+	job, ok := m.jobs[id]
+	return job, ok, nil
+}
+
+func TestPostJob(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	jobs := &mockJobService{jobs: map[string]domain.Job{}}
+	router := NewRouter(svc, &mockCalculator{}, jobs, newTestErrorHandler(), HandlerConfig{})
+
+	body := map[string][]int{"amounts": {1, 251, 501}}
+	req := newTestRequest("POST", "/calculate/jobs", body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var job domain.Job
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if job.ID != "job-1" || job.Status != domain.JobQueued || job.Total != 3 {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+	if len(jobs.sizes) != 2 {
+		t.Errorf("Expected active sizes to be resolved at submission, got %v", jobs.sizes)
+	}
+}
+
+func TestPostJob_InvalidInput(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	jobs := &mockJobService{jobs: map[string]domain.Job{}}
+	router := NewRouter(svc, &mockCalculator{}, jobs, newTestErrorHandler(), HandlerConfig{})
+
+	for name, body := range map[string]map[string][]int{
+		"no amounts":     {"amounts": {}},
+		"zero amount":    {"amounts": {100, 0}},
+		"amount too big": {"amounts": {1_000_001}},
+		"invalid size":   {"amounts": {100}, "sizes": {-1}},
+	} {
+		req := newTestRequest("POST", "/calculate/jobs", body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", name, w.Code)
+		}
+	}
+	if jobs.submitted != nil {
+		t.Errorf("Expected no job to be submitted, got %v", jobs.submitted)
+	}
+}
+
+func TestMinOrderAmount_AppliesToAmountLists(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	jobs := &mockJobService{jobs: map[string]domain.Job{}}
+	router := NewRouter(svc, &mockCalculator{}, jobs, newTestErrorHandler(), HandlerConfig{MinOrderAmount: 100})
+
+	for path, body := range map[string]map[string]any{
+		"/calculate/jobs":        {"amounts": []int{100, 50}},
+		"/calculate/summary":     {"amounts": []int{100, 50}},
+		"/calculate/leaderboard": {"amounts": []int{100, 50}, "sets": []map[string]any{{"name": "current", "sizes": []int{250}}}},
+		"/packs/recommend":       {"amounts": []int{100, 50}, "minSize": 100, "maxSize": 400, "step": 50},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", path, body))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400 for an amount below the minimum, got %d", path, w.Code)
+		}
+	}
+	if jobs.submitted != nil {
+		t.Errorf("Expected no job to be submitted, got %v", jobs.submitted)
+	}
+}
+
+func TestGetJob(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	jobs := &mockJobService{jobs: map[string]domain.Job{
+		"done": {ID: "done", Status: domain.JobCompleted, Total: 1, Processed: 1},
+	}}
+	router := NewRouter(svc, &mockCalculator{}, jobs, newTestErrorHandler(), HandlerConfig{})
+
+	req := newTestRequest("GET", "/calculate/jobs/done", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var job domain.Job
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if job.Status != domain.JobCompleted {
+		t.Errorf("Expected completed job, got %s", job.Status)
+	}
+
+	// Unknown jobs are reported as not found
+	req = newTestRequest("GET", "/calculate/jobs/missing", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown job, got %d", w.Code)
+	}
+
+	var errResp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	if errResp.Code != ErrCodeNotFound {
+		t.Errorf("Expected error code NOT_FOUND, got %s", errResp.Code)
+	}
+}
+
+func TestPostJob_UnavailableWithoutCache(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	router := NewRouter(svc, &mockCalculator{}, &mockJobService{}, newTestErrorHandler(), HandlerConfig{CacheDegraded: true})
+
+	req := newTestRequest("POST", "/calculate/jobs", map[string][]int{"amounts": {1, 2}})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 in degraded mode, got %d", w.Code)
+	}
+}
@@ -0,0 +1,151 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains the handler that ranks candidate pack catalogs over an amount distribution.
+package http
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// Leaderboard limits: every set is computed against every distinct amount, so the work grows
+// with their product and both are capped well below the summary's distribution limit.
+const (
+	maxLeaderboardSets    = 10     // Candidate pack catalogs per request
+	maxLeaderboardAmounts = 10_000 // Amounts in the distribution, repeats included
+	maxLeaderboardName    = 64     // Characters in a set name
+)
+
+// namedSizeSet is a candidate pack catalog.
+type namedSizeSet struct {
+	Name  string `json:"name"`  // Label identifying the set in the leaderboard
+	Sizes []int  `json:"sizes"` // Pack sizes of the catalog
+}
+
+// leaderboardReq represents the request body for ranking pack catalogs.
+type leaderboardReq struct {
+	Sets    []namedSizeSet `json:"sets"`    // Candidate catalogs to rank
+	Amounts []int          `json:"amounts"` // Distribution of order amounts, e.g. an order history
+}
+
+// leaderboardEntry is one catalog's performance over the distribution.
+type leaderboardEntry struct {
+	Rank                  int     `json:"rank"`                  // 1 for the best catalog
+	Name                  string  `json:"name"`                  // Set name from the request
+	Sizes                 []int   `json:"sizes"`                 // Normalized pack sizes
+	AverageOveragePercent float64 `json:"averageOveragePercent"` // Mean of per-order overage percent
+	AveragePacks          float64 `json:"averagePacks"`          // Mean packs per order
+	TotalItems            int64   `json:"totalItems"`            // Items shipped over the distribution
+	TotalPacks            int64   `json:"totalPacks"`            // Packs shipped over the distribution
+	TotalOverage          int64   `json:"totalOverage"`          // Items shipped beyond what was ordered
+}
+
+// validateLeaderboardSets checks that there are 1 to maxLeaderboardSets sets, each with a unique
+// non-empty name and a valid, non-empty list of pack sizes.
+func (a *packSvcAdapter) validateLeaderboardSets(sets []namedSizeSet) *APIError {
+	if len(sets) == 0 {
+		return ErrValidationFailed.WithDetails("field", "sets").WithDetails("reason", "at least one pack-size set is required")
+	}
+	if len(sets) > maxLeaderboardSets {
+		return ErrValidationFailed.WithDetails("field", "sets").WithDetails("count", len(sets)).WithDetails("maximum", maxLeaderboardSets).WithDetails("reason", "too many pack-size sets")
+	}
+	seen := make(map[string]bool, len(sets))
+	for i, set := range sets {
+		name := strings.TrimSpace(set.Name)
+		switch {
+		case name == "":
+			return ErrValidationFailed.WithDetails("field", "sets").WithDetails("set", i).WithDetails("reason", "every set needs a name")
+		case len(name) > maxLeaderboardName:
+			return ErrValidationFailed.WithDetails("field", "sets").WithDetails("set", i).WithDetails("reason", "set names cannot exceed 64 characters")
+		case seen[name]:
+			return ErrValidationFailed.WithDetails("field", "sets").WithDetails("set", i).WithDetails("name", name).WithDetails("reason", "set names must be unique")
+		}
+		seen[name] = true
+
+		if len(set.Sizes) == 0 {
+			return ErrValidationFailed.WithDetails("field", "sets").WithDetails("set", i).WithDetails("reason", "no pack sizes configured")
+		}
+		if apiErr := a.validateSizes(set.Sizes); apiErr != nil {
+			return apiErr.WithDetails("set", i)
+		}
+		if err := a.cfg.Validator.ValidatePackSet(set.Sizes); err != nil {
+			return validationError(err).WithDetails("set", i)
+		}
+	}
+	return nil
+}
+
+// postLeaderboard ranks candidate pack catalogs by how they would have served a distribution of
+// order amounts: by average overage percent, then by average packs per order, with ties keeping
+// request order. Where POST /calculate/compare looks at one amount, this weighs every amount by
+// how often it occurs. Each distinct amount is computed once per set.
+func (a *packSvcAdapter) postLeaderboard(w http.ResponseWriter, r *http.Request) {
+	var req leaderboardReq
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBatchBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+
+	// Validate the distribution and the candidate sets
+	if len(req.Amounts) > maxLeaderboardAmounts {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+			WithDetails("field", "amounts").
+			WithDetails("count", len(req.Amounts)).
+			WithDetails("maximum", maxLeaderboardAmounts).
+			WithDetails("reason", "too many amounts for a leaderboard"))
+		return
+	}
+	if apiErr := a.validateDistribution(req.Amounts); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	if apiErr := a.validateLeaderboardSets(req.Sets); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+
+	// Compute each distinct amount once per set, then weigh the results by the distribution
+	distinct, position := dedupeAmounts(req.Amounts)
+	entries := make([]leaderboardEntry, 0, len(req.Sets))
+	results := make([]domain.CalculationResult, len(req.Amounts))
+	for i, set := range req.Sets {
+		sizes := normalizePackSizes(set.Sizes)
+		computed, err := a.calc.ComputeBatch(r.Context(), distinct, sizes)
+		if err != nil {
+			a.errorHandler.HandleError(w, r, calculationError(err).WithDetails("set", i).WithDetails("count", len(distinct)))
+			return
+		}
+		for j, amt := range req.Amounts {
+			results[j] = computed[position[amt]]
+		}
+
+		sum := summarizeResults(req.Amounts, results)
+		entries = append(entries, leaderboardEntry{
+			Name:                  strings.TrimSpace(set.Name),
+			Sizes:                 sizes,
+			AverageOveragePercent: sum.AverageOveragePercent,
+			AveragePacks:          float64(sum.TotalPacks) / float64(sum.Orders),
+			TotalItems:            sum.TotalItems,
+			TotalPacks:            sum.TotalPacks,
+			TotalOverage:          sum.TotalOverage,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].AverageOveragePercent != entries[j].AverageOveragePercent {
+			return entries[i].AverageOveragePercent < entries[j].AverageOveragePercent
+		}
+		return entries[i].AveragePacks < entries[j].AveragePacks
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"orders":          len(req.Amounts),
+		"distinctAmounts": len(distinct),
+		"leaderboard":     entries,
+	})
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+)
+
+func TestPostLeaderboard(t *testing.T) {
+	calc := &countingCalculator{calls: map[int]int{}}
+	router := newTestRouter(&mockPacksService{sizes: []int{250}}, calc)
+
+	body := map[string]any{
+		"sets": []map[string]any{
+			{"name": "current", "sizes": []int{250, 500, 1000}},
+			{"name": "fine", "sizes": []int{100, 50, 100}},
+			{"name": "coarse", "sizes": []int{1000}},
+		},
+		"amounts": []int{100, 250, 100, 600},
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate/leaderboard", body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Orders          int                `json:"orders"`
+		DistinctAmounts int                `json:"distinctAmounts"`
+		Leaderboard     []leaderboardEntry `json:"leaderboard"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if resp.Orders != 4 || resp.DistinctAmounts != 3 {
+		t.Errorf("Expected 4 orders / 3 distinct, got %d / %d", resp.Orders, resp.DistinctAmounts)
+	}
+
+	// fine: exact every time; current: 150% + 0% + 150% + 25%; coarse: 900% + 300% + 900% + 66.7%
+	var names []string
+	for i, e := range resp.Leaderboard {
+		names = append(names, e.Name)
+		if e.Rank != i+1 {
+			t.Errorf("Expected %s ranked %d, got %d", e.Name, i+1, e.Rank)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"fine", "current", "coarse"}) {
+		t.Fatalf("Expected ranking [fine current coarse], got %v", names)
+	}
+	fine := resp.Leaderboard[0]
+	if fine.AverageOveragePercent != 0 || fine.AveragePacks != 2.75 || fine.TotalPacks != 11 || !reflect.DeepEqual(fine.Sizes, []int{50, 100}) {
+		t.Errorf("Unexpected entry for fine: %+v", fine)
+	}
+	if current := resp.Leaderboard[1]; current.AverageOveragePercent != 81.25 || current.TotalOverage != 450 {
+		t.Errorf("Unexpected entry for current: %+v", current)
+	}
+
+	// Each distinct amount is computed once per set
+	if calc.calls[100] != 3 {
+		t.Errorf("Expected amount 100 computed once per set, got %d calls", calc.calls[100])
+	}
+}
+
+func TestPostLeaderboard_TiesRankByPacks(t *testing.T) {
+	router := newTestRouter(&mockPacksService{}, calculator.NewService())
+
+	// Both sets fit 500 exactly; the one needing fewer packs wins despite coming second
+	body := map[string]any{
+		"sets":    []map[string]any{{"name": "small", "sizes": []int{250}}, {"name": "large", "sizes": []int{500}}},
+		"amounts": []int{500},
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate/leaderboard", body))
+
+	var resp struct {
+		Leaderboard []leaderboardEntry `json:"leaderboard"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(resp.Leaderboard) != 2 || resp.Leaderboard[0].Name != "large" {
+		t.Errorf("Expected large first, got %+v", resp.Leaderboard)
+	}
+}
+
+func TestPostLeaderboard_Validation(t *testing.T) {
+	router := newTestRouter(&mockPacksService{}, calculator.NewService())
+	set := func(name string, sizes ...int) map[string]any { return map[string]any{"name": name, "sizes": sizes} }
+
+	tooManySets := make([]map[string]any, maxLeaderboardSets+1)
+	for i := range tooManySets {
+		tooManySets[i] = set(string(rune('a'+i)), 250)
+	}
+
+	for name, body := range map[string]map[string]any{
+		"no sets":             {"sets": []map[string]any{}, "amounts": []int{100}},
+		"too many sets":       {"sets": tooManySets, "amounts": []int{100}},
+		"unnamed set":         {"sets": []map[string]any{set(" ", 250)}, "amounts": []int{100}},
+		"duplicate names":     {"sets": []map[string]any{set("a", 250), set("a", 500)}, "amounts": []int{100}},
+		"empty set":           {"sets": []map[string]any{set("a")}, "amounts": []int{100}},
+		"invalid size":        {"sets": []map[string]any{set("a", -5)}, "amounts": []int{100}},
+		"no amounts":          {"sets": []map[string]any{set("a", 250)}, "amounts": []int{}},
+		"too many amounts":    {"sets": []map[string]any{set("a", 250)}, "amounts": make([]int, maxLeaderboardAmounts+1)},
+		"non-positive amount": {"sets": []map[string]any{set("a", 250)}, "amounts": []int{0}},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newTestRequest("POST", "/calculate/leaderboard", body))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", name, w.Code)
+		}
+	}
+}
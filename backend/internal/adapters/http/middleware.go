@@ -4,8 +4,10 @@ package http
 
 import (
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"log/slog"
@@ -19,16 +21,44 @@ type RateLimitConfig struct {
 	RequestsPerMinute int  // Maximum requests per minute per IP
 	BurstSize         int  // Burst size for token bucket
 	Enabled           bool // Whether rate limiting is enabled
+
+	Redactor Redactor // Scrubs logged client IPs; nil logs them unchanged
 }
 
 // DDoSProtectionConfig holds configuration for DDoS protection.
 type DDoSProtectionConfig struct {
-	MaxRequestSize    int64 // Maximum request body size in bytes (10MB default)
-	MaxHeaderSize     int   // Maximum header size in bytes
-	MaxConcurrentReqs int   // Maximum concurrent requests per IP
-	Enabled           bool  // Whether DDoS protection is enabled
+	MaxRequestSize    int64    // Maximum request body size in bytes (10MB default)
+	MaxHeaderSize     int      // Maximum header size in bytes
+	MaxConcurrentReqs int      // Maximum in-flight requests per IP (0 disables the limit)
+	Enabled           bool     // Whether DDoS protection is enabled
+
+	// Paths whose requests don't count against MaxConcurrentReqs: monitoring endpoints, which
+	// must stay reachable, and long-lived streams, which would hold a slot for their lifetime
+	UnlimitedPaths []string
+
+	Redactor Redactor // Scrubs logged client IPs; nil logs them unchanged
+}
+
+// SuspiciousRequestConfig holds configuration for heuristic blocking of suspicious requests
+// (SQL injection patterns, scanner user agents). It is independent of the DDoS size limits.
+type SuspiciousRequestConfig struct {
+	Enabled   bool     // Whether suspicious requests are rejected
+	SkipPaths []string // Paths exempt from blocking
+	Redactor  Redactor // Scrubs logged client IPs; nil logs them unchanged
+}
+
+// InternalPaths are monitoring endpoints that must stay reachable by health checkers and
+// metrics scrapers. Suspicious-request blocking (and any auth middleware) must skip them,
+// since monitoring agents often identify themselves with user agents like "...bot".
+var InternalPaths = []string{
+	"/healthz", "/readyz", "/metrics", "/version",
+	"/api/v1/healthz", "/api/v1/readyz", "/api/v1/metrics", "/api/v1/version",
 }
 
+// StreamingPaths are long-lived responses (Server-Sent Events) that stay open for as long as the
+// client listens, so they're exempt from the per-IP in-flight request limit.
+var StreamingPaths = []string{"/packs/events", "/api/v1/packs/events"}
+
 // SecurityConfig holds all security-related configuration.
 type SecurityConfig struct {
 	RateLimitEnabled      bool
@@ -37,22 +67,50 @@ type SecurityConfig struct {
 	DDoSProtectionEnabled bool
 	MaxRequestSize        string
 	MaxHeaderSize         string
+	MaxConcurrentReqs     string
+
+	SuspiciousRequestBlockingEnabled bool
+
+	// IPFilter restricts which client IPs are served; nil serves everyone
+	IPFilter *IPFilter
+
+	// ClientIPs resolves client IPs for filtering, limits and logs; nil uses the connection's peer
+	ClientIPs *ClientIPResolver
+
+	// Redactor scrubs client IPs in security logs and 403 responses, as in error logs; nil leaves them unchanged
+	Redactor Redactor
 }
 
 // SetupSecurityMiddleware configures and applies all security middleware to the router.
 // This centralizes security middleware setup in the HTTP transport layer.
 func SetupSecurityMiddleware(r *chi.Mux, cfg SecurityConfig) {
+	// 0. Client IP - resolve the caller once, trusting forwarding headers only from trusted proxies
+	r.Use(resolveClientIP(cfg.ClientIPs))
+
 	// 1. Security headers - add security headers to all responses
 	r.Use(securityHeaders)
 
-	// 2. DDoS protection - protect against DDoS attacks
-	ddosConfig := parseDDoSProtectionConfig(cfg.MaxRequestSize, cfg.MaxHeaderSize)
+	// 2. IP filtering - refuse clients outside the allowlist or on the denylist
+	r.Use(ipFiltering(cfg.IPFilter, cfg.Redactor))
+
+	// 3. DDoS protection - protect against DDoS attacks
+	ddosConfig := parseDDoSProtectionConfig(cfg.MaxRequestSize, cfg.MaxHeaderSize, cfg.MaxConcurrentReqs)
 	ddosConfig.Enabled = cfg.DDoSProtectionEnabled
+	ddosConfig.UnlimitedPaths = append(slices.Clone(InternalPaths), StreamingPaths...)
+	ddosConfig.Redactor = cfg.Redactor
 	r.Use(ddosProtection(ddosConfig))
 
-	// 3. Rate limiting - limit requests per IP
+	// 4. Suspicious request blocking - reject requests matching attack heuristics
+	r.Use(blockSuspiciousRequests(SuspiciousRequestConfig{
+		Enabled:   cfg.SuspiciousRequestBlockingEnabled,
+		SkipPaths: InternalPaths,
+		Redactor:  cfg.Redactor,
+	}))
+
+	// 5. Rate limiting - limit requests per IP
 	rateLimitConfig := parseRateLimitConfig(cfg.RateLimitRPM, cfg.RateLimitBurst)
 	rateLimitConfig.Enabled = cfg.RateLimitEnabled
+	rateLimitConfig.Redactor = cfg.Redactor
 	r.Use(rateLimit(rateLimitConfig))
 }
 
@@ -81,16 +139,16 @@ func rateLimit(config RateLimitConfig) func(next http.Handler) http.Handler {
 		}
 	}
 
-	// Create rate limiter that limits by IP address
+	// Create rate limiter that limits by client IP address
 	// httprate uses a token bucket algorithm
 	limiter := httprate.Limit(
 		requestsPerMinute,
 		time.Minute,
-		httprate.WithKeyFuncs(httprate.KeyByIP, httprate.KeyByEndpoint),
+		httprate.WithKeyFuncs(keyByClientIP, httprate.KeyByEndpoint),
 		httprate.WithLimitHandler(func(w http.ResponseWriter, r *http.Request) {
 			slog.Warn(
 				"rate limit exceeded",
-				"ip", getClientIP(r),
+				"ip", redactIP(config.Redactor, getClientIP(r)),
 				"path", r.URL.Path,
 			)
 
@@ -104,8 +162,17 @@ func rateLimit(config RateLimitConfig) func(next http.Handler) http.Handler {
 	return limiter
 }
 
+// keyByClientIP keys rate limits by the resolved client IP. httprate.KeyByIP would read
+// forwarding headers from any peer, letting a client pick a fresh key per request.
+func keyByClientIP(r *http.Request) (string, error) {
+	return getClientIP(r), nil
+}
+
 // ddosProtection creates middleware to protect against DDoS attacks.
-// Includes request size limits, header size limits, and basic connection throttling.
+// Includes request size limits, header size limits, and a limit on in-flight requests per client IP,
+// which keeps one client from tying up every worker with slow requests (429 when exceeded).
+// Requests to config.UnlimitedPaths are never counted against the in-flight limit.
+// Pattern-based blocking of suspicious requests is a separate middleware (blockSuspiciousRequests).
 func ddosProtection(config DDoSProtectionConfig) func(next http.Handler) http.Handler {
 	if !config.Enabled {
 		return func(next http.Handler) http.Handler {
@@ -113,6 +180,11 @@ func ddosProtection(config DDoSProtectionConfig) func(next http.Handler) http.Ha
 		}
 	}
 
+	var inFlight *concurrencyLimiter
+	if config.MaxConcurrentReqs > 0 {
+		inFlight = newConcurrencyLimiter(config.MaxConcurrentReqs)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Limit request body size
@@ -132,7 +204,7 @@ func ddosProtection(config DDoSProtectionConfig) func(next http.Handler) http.Ha
 				if headerSize > config.MaxHeaderSize {
 					slog.Warn(
 						"request header too large",
-						"ip", getClientIP(r),
+						"ip", redactIP(config.Redactor, getClientIP(r)),
 						"header_size", headerSize,
 					)
 
@@ -143,11 +215,82 @@ func ddosProtection(config DDoSProtectionConfig) func(next http.Handler) http.Ha
 				}
 			}
 
-			// Check for suspicious patterns
-			if isSuspiciousRequest(r) {
+			// Limit in-flight requests per IP; the slot is released even if the handler panics
+			if inFlight != nil && !isInternalPath(r.URL.Path, config.UnlimitedPaths) {
+				ip := getClientIP(r)
+				if !inFlight.acquire(ip) {
+					slog.Warn(
+						"too many concurrent requests",
+						"ip", redactIP(config.Redactor, ip),
+						"limit", config.MaxConcurrentReqs,
+					)
+
+					w.Header().Set("Content-Type", "application/json")
+					w.Header().Set("Retry-After", "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+					w.Write([]byte(`{"error":"too many concurrent requests","message":"wait for earlier requests to finish"}`))
+					return
+				}
+				defer inFlight.release(ip)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// concurrencyLimiter counts in-flight requests per client IP.
+// IPs without requests in flight are removed, so the map only holds active clients.
+type concurrencyLimiter struct {
+	max int
+
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// newConcurrencyLimiter creates a limiter allowing max in-flight requests per IP.
+func newConcurrencyLimiter(max int) *concurrencyLimiter {
+	return &concurrencyLimiter{max: max, inFlight: make(map[string]int)}
+}
+
+// acquire takes a slot for ip, reporting false if ip already has max requests in flight.
+func (l *concurrencyLimiter) acquire(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[ip] >= l.max {
+		return false
+	}
+	l.inFlight[ip]++
+	return true
+}
+
+// release returns a slot taken by acquire.
+func (l *concurrencyLimiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n := l.inFlight[ip] - 1; n > 0 {
+		l.inFlight[ip] = n
+	} else {
+		delete(l.inFlight, ip)
+	}
+}
+
+// blockSuspiciousRequests creates middleware that rejects requests matching attack heuristics
+// with 403 Forbidden. Paths in config.SkipPaths (internal monitoring endpoints) are exempt.
+func blockSuspiciousRequests(config SuspiciousRequestConfig) func(next http.Handler) http.Handler {
+	if !config.Enabled {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Check for suspicious patterns (internal monitoring paths are exempt)
+			if !isInternalPath(r.URL.Path, config.SkipPaths) && isSuspiciousRequest(r) {
 				slog.Warn(
 					"suspicious request detected",
-					"ip", getClientIP(r),
+					"ip", redactIP(config.Redactor, getClientIP(r)),
 					"path", r.URL.Path,
 					"user_agent", r.UserAgent(),
 				)
@@ -186,30 +329,14 @@ func securityHeaders(next http.Handler) http.Handler {
 	})
 }
 
-// getClientIP extracts the real client IP address from the request.
-// Checks X-Forwarded-For, X-Real-IP headers for proxied requests.
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (first IP in chain)
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		ips := strings.Split(forwarded, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
+// isInternalPath reports whether path is one of the skipped internal paths.
+func isInternalPath(path string, skip []string) bool {
+	for _, p := range skip {
+		if path == p {
+			return true
 		}
 	}
-
-	// Check X-Real-IP header
-	realIP := r.Header.Get("X-Real-IP")
-	if realIP != "" {
-		return realIP
-	}
-
-	// Fall back to RemoteAddr
-	ip := r.RemoteAddr
-	if idx := strings.LastIndex(ip, ":"); idx != -1 {
-		ip = ip[:idx]
-	}
-	return ip
+	return false
 }
 
 // isSuspiciousRequest checks for common DDoS attack patterns.
@@ -290,11 +417,14 @@ func parseRateLimitConfig(requestsPerMinute, burstSize string) RateLimitConfig {
 }
 
 // parseDDoSProtectionConfig parses DDoS protection configuration from environment variables.
-func parseDDoSProtectionConfig(maxRequestSize, maxHeaderSize string) DDoSProtectionConfig {
+// An empty or invalid maxConcurrent keeps the default of 50; "0" disables the concurrency limit.
+func parseDDoSProtectionConfig(maxRequestSize, maxHeaderSize, maxConcurrent string) DDoSProtectionConfig {
 	config := DDoSProtectionConfig{
 		Enabled:        true,
 		MaxRequestSize: 10 * 1024 * 1024, // 10MB default
 		MaxHeaderSize:  8192,              // 8KB default
+
+		MaxConcurrentReqs: 50,
 	}
 
 	if maxRequestSize != "" {
@@ -309,6 +439,12 @@ func parseDDoSProtectionConfig(maxRequestSize, maxHeaderSize string) DDoSProtect
 		}
 	}
 
+	if maxConcurrent != "" {
+		if val, err := strconv.Atoi(maxConcurrent); err == nil && val >= 0 {
+			config.MaxConcurrentReqs = val
+		}
+	}
+
 	return config
 }
 
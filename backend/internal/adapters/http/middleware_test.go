@@ -0,0 +1,329 @@
+package http
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// newSecuredRouter builds a router with the security middleware and a few stub endpoints.
+func newSecuredRouter() *chi.Mux {
+	return newSecuredRouterWith(SecurityConfig{
+		RateLimitEnabled:                 false,
+		DDoSProtectionEnabled:            true,
+		SuspiciousRequestBlockingEnabled: true,
+	})
+}
+
+// newSecuredRouterWith builds the stub router with the given security configuration.
+func newSecuredRouterWith(cfg SecurityConfig) *chi.Mux {
+	r := chi.NewRouter()
+	SetupSecurityMiddleware(r, cfg)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r.Get("/api/v1/healthz", ok)
+	r.Get("/readyz", ok)
+	r.Get("/api/v1/packs", ok)
+	return r
+}
+
+func TestDDoSProtection_MonitoringAgentReachesHealthEndpoints(t *testing.T) {
+	router := newSecuredRouter()
+
+	for _, path := range []string{"/api/v1/healthz", "/readyz"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("User-Agent", "Datadog Agent/7.50 monitoring-bot")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200 for monitoring agent, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestDDoSProtection_SuspiciousAgentBlockedElsewhere(t *testing.T) {
+	router := newSecuredRouter()
+
+	req := httptest.NewRequest("GET", "/api/v1/packs", nil)
+	req.Header.Set("User-Agent", "Datadog Agent/7.50 monitoring-bot")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 outside the skip list, got %d", w.Code)
+	}
+}
+
+func TestSuspiciousRequestBlocking_Toggle(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      SecurityConfig
+		wantCode int
+	}{
+		{"blocking without size limits", SecurityConfig{SuspiciousRequestBlockingEnabled: true}, http.StatusForbidden},
+		{"size limits without blocking", SecurityConfig{DDoSProtectionEnabled: true}, http.StatusOK},
+		{"both disabled", SecurityConfig{}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newSecuredRouterWith(tt.cfg)
+
+			req := httptest.NewRequest("GET", "/api/v1/packs?q=1%27%20OR%201=1", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, w.Code)
+			}
+		})
+	}
+
+	// Size limits keep working with blocking off
+	router := newSecuredRouterWith(SecurityConfig{DDoSProtectionEnabled: true, MaxHeaderSize: "64"})
+	req := httptest.NewRequest("GET", "/api/v1/packs", nil)
+	req.Header.Set("X-Padding", strings.Repeat("a", 100))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 for oversized headers, got %d", w.Code)
+	}
+}
+
+func TestDDoSProtection_MaxConcurrentRequestsPerIP(t *testing.T) {
+	const limit = 3
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/panic":
+			panic("boom")
+		case "/fast":
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := ddosProtection(parseDDoSProtectionConfig("", "", strconv.Itoa(limit)))(slow)
+
+	request := func(path, ip string) *http.Request {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":4000"
+		return req
+	}
+
+	// Fill every slot for one IP
+	var wg sync.WaitGroup
+	codes := make([]int, limit)
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request("/api/v1/packs", "203.0.113.7"))
+			codes[i] = w.Code
+		}(i)
+		<-entered
+	}
+
+	// One more from the same IP is rejected; another IP is unaffected
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request("/api/v1/packs", "203.0.113.7"))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After for request %d, got %d", limit+1, w.Code)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request("/fast", "198.51.100.1"))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected another IP to get through, got %d", w.Code)
+	}
+
+	for i := 0; i < limit; i++ {
+		release <- struct{}{}
+	}
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Request %d: expected 200, got %d", i, code)
+		}
+	}
+
+	// Finished and panicking requests give their slot back
+	for i := 0; i < limit; i++ {
+		func() {
+			defer func() { _ = recover() }()
+			handler.ServeHTTP(httptest.NewRecorder(), request("/panic", "203.0.113.7"))
+		}()
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request("/fast", "203.0.113.7"))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the slots to be released, got %d", w.Code)
+	}
+}
+
+func TestDDoSProtection_UnlimitedPathsSkipConcurrencyLimit(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	held := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/healthz" {
+			entered <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	config := parseDDoSProtectionConfig("", "", "1")
+	config.UnlimitedPaths = append(slices.Clone(InternalPaths), StreamingPaths...)
+	handler := ddosProtection(config)(held)
+
+	request := func(path string) *http.Request {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "203.0.113.7:4000"
+		return req
+	}
+
+	// Two open event streams from one client, beyond its limit of 1, leave its slot free
+	var wg sync.WaitGroup
+	codes := make([]int, 3)
+	for i, path := range []string{"/api/v1/packs/events", "/api/v1/packs/events", "/api/v1/packs"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request(path))
+			codes[i] = w.Code
+		}()
+		<-entered
+	}
+
+	// With the slot taken, health checks still get through but other requests don't
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request("/api/v1/healthz"))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the health check to get through, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request("/api/v1/packs"))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 once the slot is taken, got %d", w.Code)
+	}
+
+	for range codes {
+		release <- struct{}{}
+	}
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Request %d: expected 200, got %d", i, code)
+		}
+	}
+}
+
+func TestDDoSProtection_ConcurrencyLimitKeysOnTrustedClientIP(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	r := chi.NewRouter()
+	SetupSecurityMiddleware(r, SecurityConfig{
+		DDoSProtectionEnabled: true,
+		MaxConcurrentReqs:     "1",
+		ClientIPs:             mustClientIPResolver(t, []string{"10.0.0.0/8"}),
+	})
+	r.Get("/api/v1/packs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("hold") != "" {
+			entered <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := func(path, remoteAddr, forwarded string) *http.Request {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		return req
+	}
+
+	// One client behind the proxy holds its only slot
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.ServeHTTP(httptest.NewRecorder(), request("/api/v1/packs?hold=1", "10.0.0.1:4000", "198.51.100.1"))
+	}()
+	<-entered
+
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       int
+	}{
+		{"same client via the proxy", "10.0.0.1:4000", "198.51.100.1", http.StatusTooManyRequests},
+		{"another client via the proxy", "10.0.0.1:4000", "198.51.100.2", http.StatusOK},
+		// Its own address counts, not the client it names
+		{"untrusted peer naming the busy client", "192.0.2.9:4000", "198.51.100.1", http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, request("/api/v1/packs", tc.remoteAddr, tc.forwarded))
+		if w.Code != tc.want {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+
+	release <- struct{}{}
+	<-done
+}
+
+func TestSecurityMiddleware_RedactsLoggedIPs(t *testing.T) {
+	redactor, err := NewPrivacyRedactor(IPLogHash, []byte("key"), nil)
+	if err != nil {
+		t.Fatalf("NewPrivacyRedactor: %v", err)
+	}
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	router := newSecuredRouterWith(SecurityConfig{
+		DDoSProtectionEnabled:            true,
+		MaxHeaderSize:                    "64",
+		SuspiciousRequestBlockingEnabled: true,
+		Redactor:                         redactor,
+	})
+
+	oversized := httptest.NewRequest("GET", "/api/v1/packs", nil)
+	oversized.Header.Set("X-Padding", strings.Repeat("a", 100))
+	suspicious := httptest.NewRequest("GET", "/api/v1/packs", nil)
+	suspicious.Header.Set("User-Agent", "sqlmap/1.7")
+	for _, req := range []*http.Request{oversized, suspicious} {
+		req.RemoteAddr = "198.51.100.23:4000"
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	want := "ip=" + redactor.RedactIP("198.51.100.23")
+	if strings.Contains(logs.String(), "198.51.100.23") || strings.Count(logs.String(), want) != 2 {
+		t.Errorf("Expected both warnings to log the hashed IP %s, got %q", want, logs.String())
+	}
+}
+
+func TestParseDDoSProtectionConfig_MaxConcurrentReqs(t *testing.T) {
+	for _, tt := range []struct {
+		value string
+		want  int
+	}{
+		{"", 50}, {"10", 10}, {"0", 0}, {"-1", 50}, {"abc", 50},
+	} {
+		if got := parseDDoSProtectionConfig("", "", tt.value).MaxConcurrentReqs; got != tt.want {
+			t.Errorf("%q: expected %d, got %d", tt.value, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains handlers for saved custom pack sets, which calculations reference by ID.
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// maxCustomSetName limits the length of a custom pack set's label.
+const maxCustomSetName = 100
+
+// customSetReq represents the request body for saving a custom pack set.
+type customSetReq struct {
+	Name  string `json:"name,omitempty"` // Optional label
+	Sizes []int  `json:"sizes"`          // Pack sizes in the set
+}
+
+// postCustomSet validates and saves a custom pack set, returning it with its ID.
+// Sizes are normalized (sorted, deduplicated) before saving, like PUT /packs.
+// Sets live in the cache with a time-to-live, so saving needs the cache to be available.
+func (a *packSvcAdapter) postCustomSet(w http.ResponseWriter, r *http.Request) {
+	if a.cfg.CacheDegraded || a.cfg.CacheDisabled {
+		a.errorHandler.HandleAPIError(w, r, ErrUnavailable.WithDetails("reason", "custom pack sets require the cache, which is unavailable"))
+		return
+	}
+
+	var req customSetReq
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+
+	if len(req.Name) > maxCustomSetName {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.WithDetails("field", "name").WithDetails("reason", "name cannot exceed 100 characters"))
+		return
+	}
+	if apiErr := a.validateSizes(req.Sizes); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	sizes := normalizePackSizes(req.Sizes)
+	if err := a.cfg.Validator.ValidatePackSet(sizes); err != nil {
+		a.errorHandler.HandleAPIError(w, r, validationError(err))
+		return
+	}
+	if apiErr := a.validatePackCount(sizes); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+
+	set, err := a.svc.SaveCustomSet(r.Context(), domain.CustomPackSet{Name: req.Name, Sizes: sizes})
+	if err != nil {
+		a.errorHandler.HandleError(w, r, ErrInternalError.WithDetails("operation", "save_custom_set"))
+		return
+	}
+	writeJSON(w, http.StatusCreated, set)
+}
+
+// getCustomSet returns a saved custom pack set.
+func (a *packSvcAdapter) getCustomSet(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	set, ok, err := a.svc.GetCustomSet(r.Context(), id)
+	if err != nil {
+		a.errorHandler.HandleError(w, r, ErrInternalError.WithDetails("operation", "get_custom_set"))
+		return
+	}
+	if !ok {
+		a.errorHandler.HandleAPIError(w, r, ErrNotFound.WithDetails("resource", "pack_set").WithDetails("id", id).WithDetails("reason", "pack set not found or expired"))
+		return
+	}
+	writeJSON(w, http.StatusOK, set)
+}
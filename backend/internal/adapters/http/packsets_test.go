@@ -0,0 +1,92 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+func TestCustomSets_SaveAndCalculate(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500, 1000}}
+	router := newTestRouter(svc, calculator.NewService())
+
+	// Save a set; sizes are normalized
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/packs/custom", map[string]any{"name": "odd", "sizes": []int{53, 23, 31, 23}}))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var saved domain.CustomPackSet
+	if err := json.Unmarshal(w.Body.Bytes(), &saved); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if saved.ID == "" || saved.Name != "odd" || !reflect.DeepEqual(saved.Sizes, []int{23, 31, 53}) {
+		t.Fatalf("Unexpected saved set: %+v", saved)
+	}
+
+	// Retrieve it
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("GET", "/packs/custom/"+saved.ID, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	// Calculate with it instead of the active sizes
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 263, "setId": saved.ID}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var res struct {
+		TotalItems int `json:"totalItems"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if res.TotalItems != 263 {
+		t.Errorf("Expected an exact fit of 263 with the saved set, got %d", res.TotalItems)
+	}
+}
+
+func TestCustomSets_Errors(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250}, customSets: map[string]domain.CustomPackSet{"s1": {ID: "s1", Sizes: []int{23}}}}
+	router := newTestRouter(svc, calculator.NewService())
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     any
+		wantCode int
+	}{
+		{"empty set", "POST", "/packs/custom", map[string][]int{"sizes": {}}, http.StatusBadRequest},
+		{"invalid size", "POST", "/packs/custom", map[string][]int{"sizes": {23, 0}}, http.StatusBadRequest},
+		{"unknown set", "GET", "/packs/custom/nope", nil, http.StatusNotFound},
+		{"calculate with unknown set", "POST", "/calculate", map[string]any{"amount": 10, "setId": "nope"}, http.StatusNotFound},
+		{"set with sizes", "POST", "/calculate", map[string]any{"amount": 10, "setId": "s1", "sizes": []int{5}}, http.StatusBadRequest},
+		{"set with version", "POST", "/calculate", map[string]any{"amount": 10, "setId": "s1", "version": 2}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newTestRequest(tt.method, tt.path, tt.body))
+			if w.Code != tt.wantCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Saving needs the cache
+	router = NewRouter(svc, calculator.NewService(), nil, newTestErrorHandler(), HandlerConfig{CacheDisabled: true})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/packs/custom", map[string][]int{"sizes": {23}}))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without the cache, got %d", w.Code)
+	}
+}
@@ -0,0 +1,72 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains the RFC 7807 (application/problem+json) error format.
+package http
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// problemContentType is the RFC 7807 media type; clients opt in through the Accept header.
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix turns an ErrorCode into the problem type URI, e.g. "urn:pack-optimizer:error:NOT_FOUND".
+const problemTypePrefix = "urn:pack-optimizer:error:"
+
+// problemDetails is the RFC 7807 representation of an APIError.
+// Code, Details and RequestID are carried over as extension members so nothing is lost.
+type problemDetails struct {
+	Type      string         `json:"type"`               // Problem type URI built from the error code
+	Title     string         `json:"title"`              // APIError.Message
+	Status    int            `json:"status"`             // HTTP status code
+	Detail    string         `json:"detail,omitempty"`   // Details["reason"], when there is one
+	Instance  string         `json:"instance,omitempty"` // Request path the error occurred on
+	Code      ErrorCode      `json:"code"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+// newProblemDetails maps apiErr to the RFC 7807 shape for the request r.
+func newProblemDetails(r *http.Request, apiErr *APIError) problemDetails {
+	p := problemDetails{
+		Type:      problemTypePrefix + string(apiErr.Code),
+		Title:     apiErr.Message,
+		Status:    apiErr.StatusCode,
+		Instance:  r.URL.Path,
+		Code:      apiErr.Code,
+		Details:   apiErr.Details,
+		RequestID: apiErr.RequestID,
+	}
+	if reason, ok := apiErr.Details["reason"].(string); ok {
+		p.Detail = reason
+	}
+	return p
+}
+
+// wantsProblemJSON reports whether the Accept header prefers application/problem+json.
+// It must be listed with a non-zero quality at least as high as plain application/json;
+// wildcards alone keep the default format.
+func wantsProblemJSON(r *http.Request) bool {
+	problemQ, jsonQ := 0.0, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case problemContentType:
+			problemQ = max(problemQ, q)
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorResponse_Formats(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500}}, &mockCalculator{})
+
+	tests := []struct {
+		name        string
+		accept      string
+		wantProblem bool
+	}{
+		{"no accept header", "", false},
+		{"plain json", "application/json", false},
+		{"wildcard", "*/*", false},
+		{"problem json", "application/problem+json", true},
+		{"problem json preferred", "application/json;q=0.5, application/problem+json", true},
+		{"plain json preferred", "application/problem+json;q=0.4, application/json", false},
+		{"problem json refused", "application/problem+json;q=0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newTestRequest("POST", "/calculate", map[string]any{"amount": -1})
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+
+			if !tt.wantProblem {
+				if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Expected application/json, got %q", ct)
+				}
+				var errResp APIError
+				if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+					t.Fatalf("Failed to parse error response: %v", err)
+				}
+				if errResp.Code != ErrCodeValidationFailed || errResp.Message == "" {
+					t.Errorf("Unexpected error response: %s", w.Body.String())
+				}
+				return
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+				t.Errorf("Expected %s, got %q", problemContentType, ct)
+			}
+			var p problemDetails
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatalf("Failed to parse problem response: %v", err)
+			}
+			if p.Type != problemTypePrefix+string(ErrCodeValidationFailed) || p.Title != "Validation failed" ||
+				p.Status != http.StatusBadRequest || p.Instance != "/calculate" || p.Detail == "" {
+				t.Errorf("Unexpected problem response: %s", w.Body.String())
+			}
+			if p.Details["field"] != "amount" {
+				t.Errorf("Expected details to be kept as an extension, got %v", p.Details)
+			}
+		})
+	}
+}
+
+func TestRecoveryMiddleware_ProblemJSON(t *testing.T) {
+	handler := RecoveryMiddleware(newTestErrorHandler())(panicHandler)
+
+	req := newTestRequest("GET", "/panic", nil)
+	req.Header.Set("Accept", problemContentType)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var p problemDetails
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("Failed to parse problem response: %v", err)
+	}
+	if p.Status != http.StatusInternalServerError || p.Code != ErrCodeInternalError {
+		t.Errorf("Unexpected problem response: %s", w.Body.String())
+	}
+}
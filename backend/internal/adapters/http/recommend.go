@@ -0,0 +1,169 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains the handler that recommends a new pack size for a distribution of order amounts.
+package http
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// Recommendation limits. Every candidate needs a DP table over the largest amount, so the work
+// grows with candidates × (largest amount + largest size); these bounds keep a request to seconds.
+const (
+	maxRecommendCandidates = 200
+	maxRecommendCells      = 100_000_000 // Candidates × table length
+)
+
+// recommendReq represents the request body for recommending a new pack size.
+type recommendReq struct {
+	Amounts []int `json:"amounts"`         // Historical order amounts
+	Sizes   []int `json:"sizes,omitempty"` // Optional base pack sizes (uses active if empty)
+	MinSize int   `json:"minSize"`         // Smallest candidate size
+	MaxSize int   `json:"maxSize"`         // Largest candidate size
+	Step    int   `json:"step,omitempty"`  // Distance between candidates (default 1)
+}
+
+// sizeScore is the outcome of a pack set over the whole distribution.
+type sizeScore struct {
+	TotalOverage int64 `json:"totalOverage"` // Items shipped beyond what was ordered
+	TotalPacks   int64 `json:"totalPacks"`   // Packs shipped
+}
+
+// recommendation is the best candidate size and how much it improves on the base set.
+type recommendation struct {
+	Size             int     `json:"size"`             // Pack size to add
+	TotalOverage     int64   `json:"totalOverage"`     // Overage with Size added
+	TotalPacks       int64   `json:"totalPacks"`       // Packs with Size added
+	OverageReduction int64   `json:"overageReduction"` // Items of overage saved across the distribution
+	OverageReducedBy float64 `json:"overageReducedBy"` // Saved overage as a percent of the baseline overage
+}
+
+// postRecommend evaluates adding each candidate size in [minSize, maxSize] (every step items) to the
+// current (or given) pack sizes, and recommends the one that most reduces total overage over the
+// amounts. Ties go to fewer packs, then the smaller size. Sizes already in the set are skipped.
+// "recommendation" is null when no candidate reduces overage.
+// Candidate sets are one-offs, so they're scored on uncached tables rather than crowding the
+// calculator's table cache, and the handler stops once the request's context ends.
+func (a *packSvcAdapter) postRecommend(w http.ResponseWriter, r *http.Request) {
+	var req recommendReq
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBatchBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+
+	if apiErr := a.validateDistribution(req.Amounts); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	if apiErr := a.validateSizes(req.Sizes); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+
+	// Validate the candidate range
+	if req.Step == 0 {
+		req.Step = 1
+	}
+	if maxSize := a.cfg.Validator.Limits().MaxPackSize; req.MinSize <= 0 || req.MaxSize < req.MinSize || req.MaxSize > maxSize || req.Step < 0 {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+			WithDetails("field", "minSize").
+			WithDetails("minSize", req.MinSize).
+			WithDetails("maxSize", req.MaxSize).
+			WithDetails("step", req.Step).
+			WithDetails("maximum", maxSize).
+			WithDetails("reason", "candidate range must satisfy 0 < minSize <= maxSize <= maximum pack size, with a positive step"))
+		return
+	}
+	if count := (req.MaxSize-req.MinSize)/req.Step + 1; count > maxRecommendCandidates {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+			WithDetails("field", "step").
+			WithDetails("candidates", count).
+			WithDetails("maximum", maxRecommendCandidates).
+			WithDetails("reason", "too many candidate sizes; narrow the range or increase the step"))
+		return
+	}
+
+	// Use custom sizes if provided, otherwise fetch active sizes
+	sizes, _, err := a.resolveSizes(r, req.Sizes)
+	if err != nil {
+		a.handleReadError(w, r, err, "get_pack_sizes")
+		return
+	}
+	if err := a.cfg.Validator.ValidatePackSet(sizes); err != nil {
+		a.errorHandler.HandleAPIError(w, r, validationError(err))
+		return
+	}
+
+	// Candidates not already in the set; bound the total DP work they need
+	var candidates []int
+	for c := req.MinSize; c <= req.MaxSize; c += req.Step {
+		if !slices.Contains(sizes, c) {
+			candidates = append(candidates, c)
+		}
+	}
+	distinct, position := dedupeAmounts(req.Amounts)
+	tableLen := slices.Max(distinct) + max(slices.Max(sizes), req.MaxSize)
+	if cells := len(candidates) * tableLen; cells > maxRecommendCells {
+		a.errorHandler.HandleAPIError(w, r, ErrValidationFailed.
+			WithDetails("field", "amounts").
+			WithDetails("candidates", len(candidates)).
+			WithDetails("largestAmount", slices.Max(distinct)).
+			WithDetails("reason", "evaluating this many candidates against these amounts is too expensive; narrow the candidate range"))
+		return
+	}
+
+	// score totals the overage and packs of a pack set across the distribution
+	score := func(set []int, opts domain.CalcOptions) (sizeScore, error) {
+		computed, err := a.calc.ComputeBatchWithOptions(r.Context(), distinct, set, opts)
+		if err != nil {
+			return sizeScore{}, err
+		}
+		var s sizeScore
+		for _, amt := range req.Amounts {
+			res := computed[position[amt]]
+			s.TotalOverage += int64(res.Overage)
+			s.TotalPacks += int64(res.TotalPacks)
+		}
+		return s, nil
+	}
+
+	baseline, err := score(sizes, domain.CalcOptions{})
+	if err != nil {
+		a.errorHandler.HandleError(w, r, calculationError(err).WithDetails("count", len(distinct)))
+		return
+	}
+
+	// Evaluate each candidate added to the base set; candidates ascend, so ties keep the smaller size
+	var best *recommendation
+	for _, c := range candidates {
+		if r.Context().Err() != nil {
+			return // Timed out or the client went away; the timeout middleware answers if anyone is left
+		}
+		s, err := score(append(slices.Clone(sizes), c), domain.CalcOptions{Uncached: true})
+		if err != nil {
+			a.errorHandler.HandleError(w, r, calculationError(err).WithDetails("candidate", c))
+			return
+		}
+		if s.TotalOverage >= baseline.TotalOverage {
+			continue
+		}
+		if best == nil || s.TotalOverage < best.TotalOverage ||
+			(s.TotalOverage == best.TotalOverage && s.TotalPacks < best.TotalPacks) {
+			best = &recommendation{Size: c, TotalOverage: s.TotalOverage, TotalPacks: s.TotalPacks}
+		}
+	}
+	if best != nil {
+		best.OverageReduction = baseline.TotalOverage - best.TotalOverage
+		best.OverageReducedBy = float64(best.OverageReduction) * 100 / float64(baseline.TotalOverage)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sizes":          sizes,
+		"orders":         len(req.Amounts),
+		"evaluated":      len(candidates),
+		"baseline":       baseline,
+		"recommendation": best,
+	})
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+func TestRecommend(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500}}, calculator.NewService())
+
+	req := newTestRequest("POST", "/packs/recommend", map[string]any{
+		"amounts": []int{300, 300, 600},
+		"minSize": 100,
+		"maxSize": 400,
+		"step":    50,
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Evaluated      int             `json:"evaluated"`
+		Baseline       sizeScore       `json:"baseline"`
+		Recommendation *recommendation `json:"recommendation"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// 250 is already active, leaving 100, 150, 200, 300, 350 and 400
+	if resp.Evaluated != 6 {
+		t.Errorf("Expected 6 candidates evaluated, got %d", resp.Evaluated)
+	}
+	// Baseline: 300 -> 500 and 600 -> 750
+	if resp.Baseline != (sizeScore{TotalOverage: 550, TotalPacks: 4}) {
+		t.Errorf("Unexpected baseline: %+v", resp.Baseline)
+	}
+	// 100, 150 and 300 all remove the overage; 300 needs the fewest packs
+	want := recommendation{Size: 300, TotalOverage: 0, TotalPacks: 4, OverageReduction: 550, OverageReducedBy: 100}
+	if resp.Recommendation == nil || *resp.Recommendation != want {
+		t.Errorf("Expected %+v, got %+v", want, resp.Recommendation)
+	}
+}
+
+func TestRecommend_NoImprovement(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500}}, calculator.NewService())
+
+	req := newTestRequest("POST", "/packs/recommend", map[string]any{"amounts": []int{500, 750}, "minSize": 100, "maxSize": 200})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp["recommendation"] != nil {
+		t.Errorf("Expected no recommendation without overage to reduce, got %v", resp["recommendation"])
+	}
+}
+
+// optionsRecorder records the options of every batch and calls onBatch after each one.
+type optionsRecorder struct {
+	*calculator.Service
+	opts    []domain.CalcOptions
+	onBatch func()
+}
+
+func (c *optionsRecorder) ComputeBatchWithOptions(ctx context.Context, amounts []int, sizes []int, opts domain.CalcOptions) ([]domain.CalculationResult, error) {
+	c.opts = append(c.opts, opts)
+	if c.onBatch != nil {
+		defer c.onBatch()
+	}
+	return c.Service.ComputeBatchWithOptions(ctx, amounts, sizes, opts)
+}
+
+func TestRecommend_ScoresCandidatesUncached(t *testing.T) {
+	calc := &optionsRecorder{Service: calculator.NewService()}
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500}}, calc)
+
+	req := newTestRequest("POST", "/packs/recommend", map[string]any{"amounts": []int{300, 600}, "minSize": 100, "maxSize": 300, "step": 100})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The baseline is the active set; every candidate set is a one-off
+	if len(calc.opts) != 4 || calc.opts[0].Uncached {
+		t.Fatalf("Expected a cached baseline and 3 candidates, got %+v", calc.opts)
+	}
+	for i, opts := range calc.opts[1:] {
+		if !opts.Uncached {
+			t.Errorf("Candidate %d: expected an uncached calculation", i)
+		}
+	}
+}
+
+func TestRecommend_StopsWhenContextEnds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calc := &optionsRecorder{Service: calculator.NewService()}
+	calc.onBatch = func() {
+		if len(calc.opts) == 2 { // The baseline and the first candidate
+			cancel()
+		}
+	}
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500}}, calc)
+
+	req := newTestRequest("POST", "/packs/recommend", map[string]any{"amounts": []int{300, 600}, "minSize": 100, "maxSize": 400, "step": 100}).WithContext(ctx)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(calc.opts) != 2 {
+		t.Errorf("Expected scoring to stop after the first candidate, got %d calculations", len(calc.opts))
+	}
+}
+
+func TestRecommend_Validation(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250, 500}}, calculator.NewService())
+
+	tests := []struct {
+		name  string
+		body  map[string]any
+		field string
+	}{
+		{"no amounts", map[string]any{"amounts": []int{}, "minSize": 1, "maxSize": 10}, "amounts"},
+		{"missing range", map[string]any{"amounts": []int{300}}, "minSize"},
+		{"inverted range", map[string]any{"amounts": []int{300}, "minSize": 10, "maxSize": 5}, "minSize"},
+		{"negative step", map[string]any{"amounts": []int{300}, "minSize": 1, "maxSize": 10, "step": -1}, "minSize"},
+		{"too many candidates", map[string]any{"amounts": []int{300}, "minSize": 1, "maxSize": 1000}, "step"},
+		{"too much work", map[string]any{"amounts": []int{1_000_000}, "minSize": 50, "maxSize": 10_000, "step": 50}, "amounts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newTestRequest("POST", "/packs/recommend", tt.body))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+			var errResp APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("Failed to parse error response: %v", err)
+			}
+			if errResp.Details["field"] != tt.field {
+				t.Errorf("Expected field %q, got %v", tt.field, errResp.Details["field"])
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Redactor scrubs request data before ErrorHandler or the security middleware log it, for
+// deployments that mustn't keep client IPs or other personal data in their logs. Implementations
+// must not modify their arguments: details maps often belong to the shared API errors.
+type Redactor interface {
+	RedactIP(ip string) string
+	RedactDetails(details map[string]interface{}) map[string]interface{}
+}
+
+// passThroughRedactor logs everything unchanged; it is the ErrorHandler default.
+type passThroughRedactor struct{}
+
+func (passThroughRedactor) RedactIP(ip string) string { return ip }
+
+func (passThroughRedactor) RedactDetails(details map[string]interface{}) map[string]interface{} {
+	return details
+}
+
+// redactIP returns ip as redactor renders it; a nil redactor leaves it unchanged.
+func redactIP(redactor Redactor, ip string) string {
+	if redactor == nil {
+		return ip
+	}
+	return redactor.RedactIP(ip)
+}
+
+// How PrivacyRedactor logs client IPs.
+const (
+	IPLogPlain    = "plain"    // Unchanged
+	IPLogHash     = "hash"     // Keyed hash: one client keeps one token, which can't be reversed without the key
+	IPLogTruncate = "truncate" // Network prefix only: IPv4 /24, IPv6 /48
+)
+
+// redactedValue replaces the values of sensitive detail keys.
+const redactedValue = "[REDACTED]"
+
+// PrivacyRedactor hashes or truncates client IPs and masks the values of sensitive detail keys.
+type PrivacyRedactor struct {
+	ipMode    string
+	hashKey   []byte
+	sensitive map[string]bool // Lowercased detail keys whose values are masked
+}
+
+// NewPrivacyRedactor creates a redactor logging IPs per ipMode ("plain", "hash" or "truncate")
+// and masking the detail keys in sensitiveKeys, matched case-insensitively. For "hash" an empty
+// hashKey is replaced by a random one, so tokens only stay stable until the process restarts.
+// Returns an error for an unknown ipMode.
+func NewPrivacyRedactor(ipMode string, hashKey []byte, sensitiveKeys []string) (*PrivacyRedactor, error) {
+	mode := strings.ToLower(ipMode)
+	switch mode {
+	case "":
+		mode = IPLogPlain
+	case IPLogPlain, IPLogTruncate:
+	case IPLogHash:
+		if len(hashKey) == 0 {
+			hashKey = make([]byte, 32)
+			if _, err := rand.Read(hashKey); err != nil {
+				return nil, fmt.Errorf("generating IP hash key: %w", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown IP log mode %q (want plain, hash or truncate)", ipMode)
+	}
+
+	sensitive := make(map[string]bool, len(sensitiveKeys))
+	for _, k := range sensitiveKeys {
+		sensitive[strings.ToLower(k)] = true
+	}
+	return &PrivacyRedactor{ipMode: mode, hashKey: hashKey, sensitive: sensitive}, nil
+}
+
+// RedactIP returns ip as configured. A port (as in a request's RemoteAddr) is dropped when
+// hashing or truncating, so a client's connections all log the same value; an address that
+// doesn't parse is logged as "[REDACTED]" rather than risk leaking it.
+func (p *PrivacyRedactor) RedactIP(ip string) string {
+	if p.ipMode == IPLogPlain || ip == "" {
+		return ip
+	}
+	addr, ok := parseLoggedIP(ip)
+	if !ok {
+		return redactedValue
+	}
+
+	if p.ipMode == IPLogHash {
+		mac := hmac.New(sha256.New, p.hashKey)
+		mac.Write(addr.AsSlice())
+		return "ip-" + hex.EncodeToString(mac.Sum(nil)[:8])
+	}
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.String()
+}
+
+// parseLoggedIP parses an address with or without a port.
+func parseLoggedIP(ip string) (netip.Addr, bool) {
+	if ap, err := netip.ParseAddrPort(ip); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.Trim(ip, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+// RedactDetails returns a copy of details with sensitive values masked; details itself is
+// returned when nothing needs masking.
+func (p *PrivacyRedactor) RedactDetails(details map[string]interface{}) map[string]interface{} {
+	var out map[string]interface{}
+	for k := range details {
+		if !p.sensitive[strings.ToLower(k)] {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(details))
+			for k, v := range details {
+				out[k] = v
+			}
+		}
+		out[k] = redactedValue
+	}
+	if out == nil {
+		return details
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrivacyRedactor_RedactIP(t *testing.T) {
+	truncate, err := NewPrivacyRedactor("truncate", nil, nil)
+	if err != nil {
+		t.Fatalf("NewPrivacyRedactor: %v", err)
+	}
+	for ip, want := range map[string]string{
+		"192.0.2.77:51234":        "192.0.2.0/24",
+		"192.0.2.77":              "192.0.2.0/24",
+		"[2001:db8:1:2::9]:443":   "2001:db8:1::/48",
+		"[::ffff:198.51.100.7]:1": "198.51.100.0/24",
+		"not-an-ip":               redactedValue,
+	} {
+		if got := truncate.RedactIP(ip); got != want {
+			t.Errorf("truncate %q: expected %q, got %q", ip, want, got)
+		}
+	}
+
+	hash, err := NewPrivacyRedactor("hash", []byte("secret"), nil)
+	if err != nil {
+		t.Fatalf("NewPrivacyRedactor: %v", err)
+	}
+	a, b := hash.RedactIP("192.0.2.77:51234"), hash.RedactIP("192.0.2.77:60000")
+	if a != b || !strings.HasPrefix(a, "ip-") || strings.Contains(a, "192.0.2") {
+		t.Errorf("Expected one opaque token per client regardless of port, got %q and %q", a, b)
+	}
+	if c := hash.RedactIP("192.0.2.78:51234"); c == a {
+		t.Errorf("Expected different clients to get different tokens, got %q for both", c)
+	}
+	other, _ := NewPrivacyRedactor("hash", []byte("another"), nil)
+	if other.RedactIP("192.0.2.77") == a {
+		t.Errorf("Expected tokens to depend on the key")
+	}
+
+	plain, _ := NewPrivacyRedactor("", nil, nil)
+	if got := plain.RedactIP("192.0.2.77:51234"); got != "192.0.2.77:51234" {
+		t.Errorf("Expected plain mode to log the address unchanged, got %q", got)
+	}
+	if _, err := NewPrivacyRedactor("anonymize", nil, nil); err == nil {
+		t.Errorf("Expected an error for an unknown mode")
+	}
+}
+
+func TestPrivacyRedactor_RedactDetails(t *testing.T) {
+	r, _ := NewPrivacyRedactor("plain", nil, []string{"Email", "token"})
+	details := map[string]interface{}{"email": "a@example.com", "TOKEN": "t-1", "operation": "get_pack_sizes"}
+
+	got := r.RedactDetails(details)
+	if got["email"] != redactedValue || got["TOKEN"] != redactedValue || got["operation"] != "get_pack_sizes" {
+		t.Errorf("Expected sensitive values masked and the rest kept, got %v", got)
+	}
+	if details["email"] != "a@example.com" {
+		t.Errorf("Expected the original details untouched, got %v", details)
+	}
+	if r.RedactDetails(nil) != nil {
+		t.Errorf("Expected nil details to stay nil")
+	}
+}
+
+func TestErrorHandler_WithRedactor(t *testing.T) {
+	var logs bytes.Buffer
+	redactor, _ := NewPrivacyRedactor("hash", []byte("secret"), []string{"email"})
+	h := NewErrorHandler(slog.New(slog.NewTextHandler(&logs, nil)), false).WithRedactor(redactor)
+
+	req := newTestRequest("GET", "/packs", nil)
+	req.RemoteAddr = "192.0.2.77:51234"
+	h.HandleError(httptest.NewRecorder(), req, errors.New("boom"))
+	h.HandleAPIError(httptest.NewRecorder(), req, NewAPIError(ErrCodeValidationFailed, "Validation failed", 400).
+		WithDetails("email", "a@example.com"))
+
+	out := logs.String()
+	if strings.Contains(out, "192.0.2.77") || !strings.Contains(out, redactor.RedactIP(req.RemoteAddr)) {
+		t.Errorf("Expected the client IP logged as its hash, got %q", out)
+	}
+	if strings.Contains(out, "a@example.com") || !strings.Contains(out, redactedValue) {
+		t.Errorf("Expected the email detail masked, got %q", out)
+	}
+
+	// Without a redactor everything is logged as before
+	logs.Reset()
+	h = NewErrorHandler(slog.New(slog.NewTextHandler(&logs, nil)), false).WithRedactor(nil)
+	h.HandleError(httptest.NewRecorder(), req, errors.New("boom"))
+	if !strings.Contains(logs.String(), "ip=192.0.2.77") {
+		t.Errorf("Expected the client IP logged unchanged, got %q", logs.String())
+	}
+}
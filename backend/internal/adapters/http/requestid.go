@@ -0,0 +1,100 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains the request ID middleware and its ID generators.
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDGenerator returns a new request ID for a request that doesn't bring a usable one.
+type RequestIDGenerator func() string
+
+// maxRequestIDLength bounds client-supplied request IDs; W3C trace IDs and UUIDs fit easily.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware adds a request ID to the request context and response headers,
+// generating IDs in chi's format. See NewRequestIDMiddleware.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return NewRequestIDMiddleware(nil)(next)
+}
+
+// NewRequestIDMiddleware returns middleware that adds a request ID to the request context
+// (read it with middleware.GetReqID) and to the X-Request-ID response header.
+// An ID sent by the client in X-Request-ID is kept for upstream correlation, provided it's at
+// most 128 characters from a conservative set (letters, digits and -_.:/+=@); anything else is
+// untrusted input headed for logs and headers, so it's replaced by a new ID. New IDs come from
+// generate, or from chi's middleware.RequestID when generate is nil.
+// The response header is set before the handler runs, so every response carries it,
+// successful or not.
+func NewRequestIDMiddleware(generate RequestIDGenerator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+			next.ServeHTTP(w, r)
+		})
+		chiRequestID := middleware.RequestID(echo)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(middleware.RequestIDHeader)
+			if id != "" && !validRequestID(id) {
+				// chi would keep the header, so drop it from a copy of the request
+				r = r.Clone(r.Context())
+				r.Header.Del(middleware.RequestIDHeader)
+				id = ""
+			}
+			if id != "" || generate == nil {
+				chiRequestID.ServeHTTP(w, r)
+				return
+			}
+			ctx := context.WithValue(r.Context(), middleware.RequestIDKey, generate())
+			echo.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to keep.
+func validRequestID(id string) bool {
+	if len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '/', c == '+', c == '=', c == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewUUIDv7 returns a random UUID version 7 (RFC 9562) in its canonical text form.
+// Its first 48 bits are the Unix time in milliseconds, so IDs sort by creation time in logs.
+func NewUUIDv7() string {
+	var u [16]byte
+	_, _ = rand.Read(u[6:])
+	ms := uint64(time.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		u[i] = byte(ms >> (40 - 8*i))
+	}
+	u[6] = u[6]&0x0f | 0x70 // Version 7
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
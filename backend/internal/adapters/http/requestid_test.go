@@ -0,0 +1,95 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// requestIDProbe records the request ID handlers see in the context.
+func requestIDProbe(seen *string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*seen = middleware.GetReqID(r.Context())
+	})
+}
+
+func TestNewRequestIDMiddleware_Generator(t *testing.T) {
+	var seen string
+	handler := NewRequestIDMiddleware(func() string { return "generated-1" })(requestIDProbe(&seen))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/packs", nil))
+	if seen != "generated-1" || w.Header().Get("X-Request-ID") != "generated-1" {
+		t.Errorf("Expected the generated ID in context and header, got %q and %q", seen, w.Header().Get("X-Request-ID"))
+	}
+
+	// Without a generator, chi's format is used
+	handler = NewRequestIDMiddleware(nil)(requestIDProbe(&seen))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/packs", nil))
+	if seen == "" || !strings.Contains(seen, "/") || w.Header().Get("X-Request-ID") != seen {
+		t.Errorf("Expected a chi-format ID echoed, got %q and %q", seen, w.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestNewRequestIDMiddleware_IncomingID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		kept     bool
+	}{
+		{"uuid", "0190b8f2-5c3e-7a1b-9f00-1c2d3e4f5a6b", true},
+		{"trace header", "Root=1-5759e988-bd862e3fe1be46a994272793", true},
+		{"chi format", "api-7f9c/Xk2lPq9aZs-000042", true},
+		{"newline", "abc\r\nSet-Cookie: x=1", false},
+		{"spaces", "hello world", false},
+		{"quotes", `"><script>`, false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+	for _, generate := range []RequestIDGenerator{nil, func() string { return "generated-1" }} {
+		for _, tt := range tests {
+			var seen string
+			handler := NewRequestIDMiddleware(generate)(requestIDProbe(&seen))
+
+			req := httptest.NewRequest("GET", "/packs", nil)
+			req.Header.Set("X-Request-ID", tt.incoming)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if kept := seen == tt.incoming; kept != tt.kept {
+				t.Errorf("%s (generator %v): expected kept=%v, got %q", tt.name, generate != nil, tt.kept, seen)
+			}
+			if seen == "" || w.Header().Get("X-Request-ID") != seen {
+				t.Errorf("%s: expected the ID %q echoed, got %q", tt.name, seen, w.Header().Get("X-Request-ID"))
+			}
+			if req.Header.Get("X-Request-ID") != tt.incoming {
+				t.Errorf("%s: expected the caller's request left untouched", tt.name)
+			}
+		}
+	}
+}
+
+func TestNewUUIDv7(t *testing.T) {
+	format := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	first := NewUUIDv7()
+	time.Sleep(2 * time.Millisecond)
+	second := NewUUIDv7()
+	for _, id := range []string{first, second} {
+		if !format.MatchString(id) {
+			t.Errorf("Expected a version 7 UUID, got %q", id)
+		}
+		if !validRequestID(id) {
+			t.Errorf("Expected %q to pass request ID validation", id)
+		}
+	}
+	// The millisecond timestamp leads, so later IDs sort after earlier ones
+	if first >= second {
+		t.Errorf("Expected %q to sort before %q", first, second)
+	}
+}
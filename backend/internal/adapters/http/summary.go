@@ -0,0 +1,145 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains the handler that summarizes pack usage over historical order amounts.
+package http
+
+import (
+	"net/http"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// summaryReq represents the request body for summarizing historical order amounts.
+type summaryReq struct {
+	Amounts []int `json:"amounts"`         // Past order amounts
+	Sizes   []int `json:"sizes,omitempty"` // Optional custom pack sizes (uses active if empty)
+}
+
+// overageBucket is one histogram bucket of orders grouped by overage percent.
+type overageBucket struct {
+	Label string  `json:"label"` // Human-readable range, e.g. "5-10%"
+	Max   float64 `json:"-"`     // Inclusive upper bound in percent
+	Count int     `json:"count"` // Orders whose overage percent falls in this bucket
+}
+
+// overageBucketBounds defines the histogram buckets; the last bucket is open-ended.
+var overageBucketBounds = []struct {
+	label string
+	max   float64
+}{
+	{"0%", 0},
+	{"0-5%", 5},
+	{"5-10%", 10},
+	{"10-25%", 25},
+	{"25-50%", 50},
+	{"50%+", -1},
+}
+
+// orderSummary holds aggregate statistics for a set of orders. The totals are int64: a
+// distribution of up to 100,000 amounts near domain.MaxLimit sums far beyond 32 bits.
+type orderSummary struct {
+	Orders                int             `json:"orders"`                // Number of orders summarized
+	DistinctAmounts       int             `json:"distinctAmounts"`       // Number of distinct amounts computed
+	TotalAmount           int64           `json:"totalAmount"`           // Items ordered
+	TotalItems            int64           `json:"totalItems"`            // Items shipped
+	TotalPacks            int64           `json:"totalPacks"`            // Packs shipped
+	TotalOverage          int64           `json:"totalOverage"`          // Items shipped beyond what was ordered
+	AverageOveragePercent float64         `json:"averageOveragePercent"` // Mean of per-order overage percent
+	Histogram             []overageBucket `json:"histogram"`             // Orders grouped by overage percent
+}
+
+// summarizeResults aggregates per-order results (one per amount, in any order).
+func summarizeResults(amounts []int, results []domain.CalculationResult) orderSummary {
+	sum := orderSummary{Orders: len(amounts), Histogram: make([]overageBucket, len(overageBucketBounds))}
+	for i, b := range overageBucketBounds {
+		sum.Histogram[i] = overageBucket{Label: b.label, Max: b.max}
+	}
+
+	totalPercent := 0.0
+	for i, amt := range amounts {
+		res := results[i]
+		sum.TotalAmount += int64(amt)
+		sum.TotalItems += int64(res.TotalItems)
+		sum.TotalPacks += int64(res.TotalPacks)
+		sum.TotalOverage += int64(res.Overage)
+
+		percent := float64(res.Overage) * 100 / float64(amt)
+		totalPercent += percent
+
+		// Find the first bucket whose upper bound covers this percent
+		for j := range sum.Histogram {
+			if sum.Histogram[j].Max < 0 || percent <= sum.Histogram[j].Max {
+				sum.Histogram[j].Count++
+				break
+			}
+		}
+	}
+	if len(amounts) > 0 {
+		sum.AverageOveragePercent = totalPercent / float64(len(amounts))
+	}
+	return sum
+}
+
+// validateDistribution checks a distribution of historical order amounts: at least one and at most
+// 100,000 amounts, each one the validator accepts as an order amount (as POST /calculate does).
+func (a *packSvcAdapter) validateDistribution(amounts []int) *APIError {
+	if len(amounts) == 0 {
+		return ErrValidationFailed.WithDetails("field", "amounts").WithDetails("reason", "at least one amount is required")
+	}
+	if len(amounts) > maxJobAmounts {
+		return ErrValidationFailed.WithDetails("field", "amounts").WithDetails("count", len(amounts)).WithDetails("reason", "a distribution cannot contain more than 100,000 amounts")
+	}
+	for i, amt := range amounts {
+		if err := a.cfg.Validator.ValidateAmount(amt); err != nil {
+			return validationError(err).WithDetails("field", "amounts").WithDetails("index", i)
+		}
+	}
+	return nil
+}
+
+// postSummary reports aggregate statistics for past order amounts under the current (or custom) pack sizes:
+// total items, packs and overage, the average overage percent, and a histogram of overage buckets.
+// Repeated amounts are computed once, which matters for real order histories.
+func (a *packSvcAdapter) postSummary(w http.ResponseWriter, r *http.Request) {
+	var req summaryReq
+	if apiErr := a.decodeJSON(w, r, a.cfg.MaxBatchBodyBytes, &req); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+
+	// Validate the amounts
+	if apiErr := a.validateDistribution(req.Amounts); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+	if apiErr := a.validateSizes(req.Sizes); apiErr != nil {
+		a.errorHandler.HandleAPIError(w, r, apiErr)
+		return
+	}
+
+	// Use custom sizes if provided, otherwise fetch active sizes
+	sizes, _, err := a.resolveSizes(r, req.Sizes)
+	if err != nil {
+		a.handleReadError(w, r, err, "get_pack_sizes")
+		return
+	}
+	if err := a.cfg.Validator.ValidatePackSet(sizes); err != nil {
+		a.errorHandler.HandleAPIError(w, r, validationError(err))
+		return
+	}
+
+	// Compute each distinct amount once, then expand back to one result per order
+	distinct, position := dedupeAmounts(req.Amounts)
+	computed, err := a.calc.ComputeBatch(r.Context(), distinct, sizes)
+	if err != nil {
+		a.errorHandler.HandleError(w, r, calculationError(err).WithDetails("count", len(distinct)))
+		return
+	}
+	results := make([]domain.CalculationResult, len(req.Amounts))
+	for i, amt := range req.Amounts {
+		results[i] = computed[position[amt]]
+	}
+
+	summary := summarizeResults(req.Amounts, results)
+	summary.DistinctAmounts = len(distinct)
+	writeJSON(w, http.StatusOK, summary)
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+func TestPostSummary(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	calc := &countingCalculator{calls: map[int]int{}}
+	router := newTestRouter(svc, calc)
+
+	req := newTestRequest("POST", "/calculate/summary", map[string][]int{"amounts": {250, 1, 251, 1}})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var sum orderSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &sum); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if sum.Orders != 4 || sum.DistinctAmounts != 3 {
+		t.Errorf("Expected 4 orders / 3 distinct, got %d / %d", sum.Orders, sum.DistinctAmounts)
+	}
+	if sum.TotalAmount != 503 || sum.TotalItems != 1250 || sum.TotalPacks != 4 || sum.TotalOverage != 747 {
+		t.Errorf("Unexpected totals: %+v", sum)
+	}
+	if calc.calls[1] != 1 {
+		t.Errorf("Expected repeated amount to be computed once, got %d", calc.calls[1])
+	}
+	if sum.Histogram[0].Label != "0%" || sum.Histogram[0].Count != 1 {
+		t.Errorf("Expected one order with no overage, got %+v", sum.Histogram[0])
+	}
+	if last := sum.Histogram[len(sum.Histogram)-1]; last.Count != 3 {
+		t.Errorf("Expected three orders in the 50%%+ bucket, got %+v", last)
+	}
+}
+
+func TestSummarizeResults_BucketBoundaries(t *testing.T) {
+	amounts := []int{100, 100, 100, 100}
+	results := []domain.CalculationResult{
+		{TotalItems: 105, TotalPacks: 1, Overage: 5},  // exactly 5% -> 0-5%
+		{TotalItems: 106, TotalPacks: 1, Overage: 6},  // 5-10%
+		{TotalItems: 125, TotalPacks: 1, Overage: 25}, // exactly 25% -> 10-25%
+		{TotalItems: 150, TotalPacks: 2, Overage: 50}, // exactly 50% -> 25-50%
+	}
+
+	sum := summarizeResults(amounts, results)
+
+	expected := map[string]int{"0%": 0, "0-5%": 1, "5-10%": 1, "10-25%": 1, "25-50%": 1, "50%+": 0}
+	for _, b := range sum.Histogram {
+		if b.Count != expected[b.Label] {
+			t.Errorf("Bucket %s: expected %d, got %d", b.Label, expected[b.Label], b.Count)
+		}
+	}
+	if sum.AverageOveragePercent != 21.5 {
+		t.Errorf("Expected average overage 21.5%%, got %v", sum.AverageOveragePercent)
+	}
+}
+
+func TestSummarizeResults_ExtremeBounds(t *testing.T) {
+	// The largest distribution accepted, every amount at the hard limit with one-item packs
+	amounts := make([]int, maxJobAmounts)
+	results := make([]domain.CalculationResult, maxJobAmounts)
+	for i := range amounts {
+		amounts[i] = domain.MaxLimit
+		results[i] = domain.CalculationResult{Amount: domain.MaxLimit, TotalItems: domain.MaxLimit + 1, TotalPacks: domain.MaxLimit, Overage: 1}
+	}
+	n := int64(maxJobAmounts)
+	limit := int64(domain.MaxLimit)
+
+	sum := summarizeResults(amounts, results)
+	if sum.TotalAmount != n*limit || sum.TotalItems != n*(limit+1) || sum.TotalPacks != n*limit || sum.TotalOverage != n {
+		t.Errorf("Expected exact totals far beyond 32 bits, got %+v", sum)
+	}
+	if sum.TotalAmount <= math.MaxInt32 {
+		t.Fatalf("Expected the test to exceed 32 bits, got a total of %d", sum.TotalAmount)
+	}
+
+	agg := newBatchAggregate(amounts, results, 0)
+	if agg.TotalAmount != sum.TotalAmount || agg.TotalItems != sum.TotalItems || agg.TotalPacks != sum.TotalPacks || agg.TotalOverage != sum.TotalOverage {
+		t.Errorf("Expected the batch aggregate to match the summary, got %+v", agg)
+	}
+}
+
+func TestPostSummary_Validation(t *testing.T) {
+	router := newTestRouter(&mockPacksService{sizes: []int{250}}, calculator.NewService())
+
+	for _, body := range []map[string][]int{{"amounts": {}}, {"amounts": {0}}} {
+		req := newTestRequest("POST", "/calculate/summary", body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Body %v: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
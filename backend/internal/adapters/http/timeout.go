@@ -0,0 +1,113 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains the handler-execution deadline middleware.
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// TimeoutMiddleware gives each request a context deadline of d. When the handler hasn't finished
+// by then, the client gets a 503 with a structured error and whatever the handler writes later is
+// discarded. Handlers keep running until they return, so long work should watch r.Context().
+// A non-positive d disables the deadline.
+func TimeoutMiddleware(d time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if rec := recover(); rec != nil {
+						panicked <- rec
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case rec := <-panicked:
+				// Re-raise on the request goroutine so RecoveryMiddleware handles it
+				panic(rec)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				dst := w.Header()
+				for k, v := range tw.header {
+					dst[k] = v
+				}
+				if tw.code == 0 {
+					tw.code = http.StatusOK
+				}
+				w.WriteHeader(tw.code)
+				_, _ = w.Write(tw.buf.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return // Client went away; nobody is left to answer
+				}
+				_ = encodeError(w, r, timeoutError(r, d))
+			}
+		})
+	}
+}
+
+// timeoutError builds the response for a request that exceeded its deadline.
+func timeoutError(r *http.Request, d time.Duration) *APIError {
+	apiErr := NewAPIError(ErrCodeUnavailable, "Request timed out", http.StatusServiceUnavailable).
+		WithDetails("timeout", d.String()).
+		WithDetails("reason", "request did not complete within its deadline")
+	if requestID := middleware.GetReqID(r.Context()); requestID != "" {
+		apiErr = apiErr.WithRequestID(requestID)
+	}
+	return apiErr
+}
+
+// timeoutWriter buffers a handler's response so it can be dropped if the deadline passes first.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.header }
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
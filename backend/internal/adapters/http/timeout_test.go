@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// blockingCalculator holds every calculation until the request context ends.
+type blockingCalculator struct {
+	mockCalculator
+}
+
+func (b *blockingCalculator) Compute(ctx context.Context, amount int, sizes []int) (domain.CalculationResult, error) {
+	<-ctx.Done()
+	return domain.CalculationResult{}, ctx.Err()
+}
+
+func (b *blockingCalculator) ComputeWithOptions(ctx context.Context, amount int, sizes []int, opts domain.CalcOptions) (domain.CalculationResult, error) {
+	return b.Compute(ctx, amount, sizes)
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	t.Run("fast handler passes through", func(t *testing.T) {
+		h := TimeoutMiddleware(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Deadline(); !ok {
+				t.Errorf("Expected request context to carry a deadline")
+			}
+			w.Header().Set("X-Test", "yes")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("done"))
+		}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusCreated || w.Body.String() != "done" || w.Header().Get("X-Test") != "yes" {
+			t.Errorf("Expected handler response unchanged, got %d %q %v", w.Code, w.Body.String(), w.Header())
+		}
+	})
+
+	t.Run("slow handler gets 503", func(t *testing.T) {
+		h := TimeoutMiddleware(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			_, _ = w.Write([]byte("too late"))
+		}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected status 503, got %d: %s", w.Code, w.Body.String())
+		}
+		var errResp APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+			t.Fatalf("Failed to parse error response: %v", err)
+		}
+		if errResp.Code != ErrCodeUnavailable || errResp.Details["timeout"] != "10ms" {
+			t.Errorf("Unexpected error response: %+v", errResp)
+		}
+	})
+
+	t.Run("zero disables", func(t *testing.T) {
+		h := TimeoutMiddleware(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Deadline(); ok {
+				t.Errorf("Expected no deadline")
+			}
+		}))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+
+	t.Run("panic reaches recovery", func(t *testing.T) {
+		h := RecoveryMiddleware(newTestErrorHandler())(TimeoutMiddleware(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status 500, got %d", w.Code)
+		}
+	})
+}
+
+func TestCalculate_Timeout(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	cfg := HandlerConfig{RequestTimeout: time.Second, CalculateTimeout: 20 * time.Millisecond}
+	router := NewRouter(svc, &blockingCalculator{}, nil, newTestErrorHandler(), cfg)
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 100}))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %d: %s", w.Code, w.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the calculate deadline to apply, took %v", elapsed)
+	}
+}
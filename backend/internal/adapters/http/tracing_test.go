@@ -0,0 +1,70 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider recording every span, with the W3C propagator,
+// until the test ends.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return rec
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	rec := recordSpans(t)
+	r := chi.NewRouter()
+	r.Use(TracingMiddleware)
+	r.Get("/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	r.Get("/packs", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest("GET", "/jobs/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/packs", nil))
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	failed := spans[0]
+	if failed.Name() != "HTTP GET /jobs/{id}" {
+		t.Errorf("Expected the span named after the route, got %q", failed.Name())
+	}
+	if failed.Status().Code != codes.Error {
+		t.Errorf("Expected a 5xx to mark the span failed, got %+v", failed.Status())
+	}
+	if got := failed.Parent().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the caller's trace to continue, got trace %s", got)
+	}
+
+	ok := spans[1]
+	if ok.Status().Code == codes.Error || ok.Parent().IsValid() {
+		t.Errorf("Expected a successful root span, got %+v with parent %v", ok.Status(), ok.Parent())
+	}
+	for _, kv := range ok.Attributes() {
+		if kv.Key == "http.response.status_code" && kv.Value.AsInt64() != http.StatusOK {
+			t.Errorf("Expected status 200 for a handler that writes nothing, got %d", kv.Value.AsInt64())
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Package http provides HTTP handlers for the pack optimizer API.
+// This file contains the conversion of order amounts given in other units (e.g. cases) to pack units.
+package http
+
+import (
+	"math"
+	"strings"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// Unit rounding policies for conversions that don't produce a whole number of units.
+const (
+	UnitRoundingError = "error" // Reject the request (default)
+	UnitRoundingUp    = "up"    // Round up to the next whole unit, so the order is always covered
+)
+
+// conversionEpsilon absorbs float error when deciding whether a converted amount is whole.
+const conversionEpsilon = 1e-9
+
+// convertedResult reports a calculation in the unit the order was given in.
+// Quantities are fractional when a pack doesn't hold a whole number of that unit.
+type convertedResult struct {
+	Unit       string          `json:"unit"`                // Unit of the requested amount, e.g. "cases"
+	Factor     float64         `json:"factor"`              // Pack units per one of Unit
+	Amount     int             `json:"amount"`              // Requested amount, in Unit
+	TotalItems float64         `json:"totalItems"`          // Items shipped, in Unit
+	Overage    float64         `json:"overage"`             // Items shipped beyond the order, in Unit
+	Shortfall  float64         `json:"shortfall"`           // Items of the order left unfulfilled, in Unit
+	Breakdown  map[int]float64 `json:"breakdown,omitempty"` // Pack size -> contents of those packs, in Unit (omitted for summaries)
+}
+
+// unitFactor looks up the configured pack units per one of unit, ignoring case.
+func (a *packSvcAdapter) unitFactor(unit string) (float64, bool) {
+	factor, ok := a.cfg.UnitConversions[strings.ToLower(unit)]
+	return factor, ok && factor > 0
+}
+
+// toPackUnits converts an amount given in unit to pack units with the configured rounding policy.
+// Returns a validation error if the unit is unknown, the result isn't whole under the "error" policy,
+// or the result exceeds the hard maximum order amount. The caller still checks the converted amount
+// with validateOrderAmount, which applies the lower limit to requests without an elevated API key.
+func (a *packSvcAdapter) toPackUnits(amount int, unit string) (int, *APIError) {
+	factor, ok := a.unitFactor(unit)
+	if !ok {
+		return 0, ErrValidationFailed.
+			WithDetails("field", "unit").
+			WithDetails("value", unit).
+			WithDetails("reason", "unit is not configured")
+	}
+
+	units := float64(amount) * factor
+	if maxAmount := a.cfg.ElevatedMaxAmount; units > float64(maxAmount) {
+		return 0, ErrValidationFailed.
+			WithDetails("field", "amount").
+			WithDetails("value", amount).
+			WithDetails("unit", unit).
+			WithDetails("maximum", maxAmount).
+			WithDetails("reason", "amount exceeds the maximum order amount once converted to pack units")
+	}
+
+	whole := math.Round(units)
+	if math.Abs(units-whole) < conversionEpsilon {
+		return int(whole), nil
+	}
+	if a.cfg.UnitRounding == UnitRoundingUp {
+		return int(math.Ceil(units)), nil
+	}
+	return 0, ErrValidationFailed.
+		WithDetails("field", "amount").
+		WithDetails("value", amount).
+		WithDetails("unit", unit).
+		WithDetails("converted", units).
+		WithDetails("reason", "amount is not a whole number of pack units")
+}
+
+// convertResult expresses a calculation result in unit, next to the pack-unit figures.
+func (a *packSvcAdapter) convertResult(amount int, unit string, res domain.CalculationResult) convertedResult {
+	factor, _ := a.unitFactor(unit)
+	out := convertedResult{
+		Unit:       strings.ToLower(unit),
+		Factor:     factor,
+		Amount:     amount,
+		TotalItems: float64(res.TotalItems) / factor,
+		Overage:    float64(res.Overage) / factor,
+		Shortfall:  float64(res.Shortfall) / factor,
+	}
+	if res.Breakdown == nil {
+		return out
+	}
+	out.Breakdown = make(map[int]float64, len(res.Breakdown))
+	for size, count := range res.Breakdown {
+		out.Breakdown[size] = float64(size*count) / factor
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+)
+
+func TestCalculate_Unit(t *testing.T) {
+	conversions := map[string]float64{"cases": 12, "kg": 2.5}
+
+	tests := []struct {
+		name     string
+		rounding string
+		body     map[string]any
+		wantCode int
+		wantAmt  int // Pack units calculated for
+		field    string
+	}{
+		{"cases converted", "", map[string]any{"amount": 50, "unit": "cases"}, http.StatusOK, 600, ""},
+		{"unit name ignores case", "", map[string]any{"amount": 50, "unit": "CASES"}, http.StatusOK, 600, ""},
+		{"whole fractional factor", "", map[string]any{"amount": 4, "unit": "kg"}, http.StatusOK, 10, ""},
+		{"not whole rejected", "", map[string]any{"amount": 3, "unit": "kg"}, http.StatusBadRequest, 0, "amount"},
+		{"not whole rounded up", UnitRoundingUp, map[string]any{"amount": 3, "unit": "kg"}, http.StatusOK, 8, ""},
+		{"unknown unit", "", map[string]any{"amount": 3, "unit": "pallets"}, http.StatusBadRequest, 0, "unit"},
+		{"non-positive amount", "", map[string]any{"amount": 0, "unit": "cases"}, http.StatusBadRequest, 0, "amount"},
+		{"above maximum once converted", "", map[string]any{"amount": 100_000, "unit": "cases"}, http.StatusBadRequest, 0, "amount"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockPacksService{sizes: []int{250, 500}}
+			cfg := HandlerConfig{UnitConversions: conversions, UnitRounding: tt.rounding}
+			router := NewRouter(svc, calculator.NewService(), nil, newTestErrorHandler(), cfg)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newTestRequest("POST", "/calculate", tt.body))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				var errResp APIError
+				if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+					t.Fatalf("Failed to parse error response: %v", err)
+				}
+				if errResp.Details["field"] != tt.field {
+					t.Errorf("Expected field %q, got %v", tt.field, errResp.Details["field"])
+				}
+				return
+			}
+
+			var resp struct {
+				Amount int `json:"amount"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if resp.Amount != tt.wantAmt {
+				t.Errorf("Expected calculation for %d units, got %d", tt.wantAmt, resp.Amount)
+			}
+		})
+	}
+}
+
+func TestCalculate_UnitBreakdown(t *testing.T) {
+	svc := &mockPacksService{sizes: []int{250, 500}}
+	cfg := HandlerConfig{UnitConversions: map[string]float64{"cases": 12}}
+	router := NewRouter(svc, calculator.NewService(), nil, newTestErrorHandler(), cfg)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newTestRequest("POST", "/calculate", map[string]any{"amount": 50, "unit": "cases"}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		TotalItems int             `json:"totalItems"`
+		Converted  convertedResult `json:"converted"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// 50 cases = 600 units, packed as 500 + 250 = 750 units = 62.5 cases
+	c := resp.Converted
+	if resp.TotalItems != 750 {
+		t.Fatalf("Expected 750 units shipped, got %d", resp.TotalItems)
+	}
+	if c.Unit != "cases" || c.Factor != 12 || c.Amount != 50 || c.TotalItems != 62.5 || c.Overage != 12.5 {
+		t.Errorf("Unexpected converted totals: %+v", c)
+	}
+	if math.Abs(c.Breakdown[500]-500.0/12) > 1e-9 || math.Abs(c.Breakdown[250]-250.0/12) > 1e-9 {
+		t.Errorf("Unexpected converted breakdown: %v", c.Breakdown)
+	}
+}
@@ -6,23 +6,41 @@ package postgres
 import (
 	"context"
 	"errors"
-	"slices"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
 )
 
 // Repository implements the pack size persistence layer using PostgreSQL.
 // It uses an append-only versioning strategy where each update creates a new row.
+//
+// Pack set reads (sizes, packs, version, timestamp) may be served by a read replica,
+// while writes and the lock state always use the primary.
 type Repository struct {
-	db *pgxpool.Pool // PostgreSQL connection pool
+	db   *pgxpool.Pool // PostgreSQL connection pool (primary)
+	read *pgxpool.Pool // Pool for pack set reads (replica, or the primary if none)
 }
 
 // New creates a new PostgreSQL repository instance.
 func New(db *pgxpool.Pool) *Repository {
-	return &Repository{db: db}
+	return &Repository{db: db, read: db}
+}
+
+// NewWithReplica creates a repository that routes pack set reads to a read replica.
+// Falls back to the primary for reads when replica is nil.
+//
+// CurrentVersion is read from the replica too, so a lagging replica reports the version
+// matching the data it returns. Cache entries are keyed by that version, which keeps
+// stale data from being cached under a newer version's key.
+func NewWithReplica(primary, replica *pgxpool.Pool) *Repository {
+	if replica == nil {
+		replica = primary
+	}
+	return &Repository{db: primary, read: replica}
 }
 
 // GetAllActive retrieves the latest version of pack sizes from the database.
@@ -31,7 +49,7 @@ func New(db *pgxpool.Pool) *Repository {
 func (r *Repository) GetAllActive() ([]int, error) {
 	const q = `SELECT sizes FROM pack_sets ORDER BY version DESC LIMIT 1`
 	var arr []int32
-	err := r.db.QueryRow(context.Background(), q).Scan(&arr)
+	err := r.read.QueryRow(context.Background(), q).Scan(&arr)
 	if err != nil {
 		// Handle case where no rows exist (fresh database)
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -59,38 +77,223 @@ func (r *Repository) GetAllActive() ([]int, error) {
 // - Sorting the result
 //
 // Note: Empty arrays are allowed - validation happens at the API layer.
-func (r *Repository) ReplaceActive(sizes []int) ([]int, error) {
+// Sizes stored this way carry no SKUs. Returns the new row's version along with the sizes.
+func (r *Repository) ReplaceActive(sizes []int) ([]int, int64, error) {
+	packs := make([]domain.Pack, len(sizes))
+	for i, s := range sizes {
+		packs[i] = domain.Pack{Size: s}
+	}
+	
+	out, version, err := r.ReplaceActivePacks(packs)
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	// Return just the normalized sizes
+	result := make([]int, len(out))
+	for i, p := range out {
+		result[i] = p.Size
+	}
+	return result, version, nil
+}
+
+// GetActivePacks retrieves the latest version of pack sizes together with their SKUs.
+// Sizes without a stored SKU are returned with an empty SKU.
+// If no rows exist, returns an empty array instead of an error.
+func (r *Repository) GetActivePacks() ([]domain.Pack, error) {
+	const q = `SELECT sizes, skus FROM pack_sets ORDER BY version DESC LIMIT 1`
+	var arr []int32
+	var skus map[string]string
+	err := r.read.QueryRow(context.Background(), q).Scan(&arr, &skus)
+	if err != nil {
+		// Handle case where no rows exist (fresh database)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []domain.Pack{}, nil
+		}
+		return nil, err
+	}
+	
+	return toPacks(arr, skus), nil
+}
+
+// GetActivePacksWithVersion retrieves the latest pack set together with its version number.
+// Both come from the same row, so the version always describes exactly the returned packs.
+// If no rows exist, returns an empty array and version 0.
+func (r *Repository) GetActivePacksWithVersion() ([]domain.Pack, int64, error) {
+	const q = `SELECT version, sizes, skus FROM pack_sets ORDER BY version DESC LIMIT 1`
+	var version int64
+	var arr []int32
+	var skus map[string]string
+	err := r.read.QueryRow(context.Background(), q).Scan(&version, &arr, &skus)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []domain.Pack{}, 0, nil
+		}
+		return nil, 0, err
+	}
+	
+	return toPacks(arr, skus), version, nil
+}
+
+// GetPacksByVersion retrieves the pack sizes and SKUs stored in a specific version.
+// Returns false if the version doesn't exist. Historical rows never change, so this is
+// safe to read from a replica once the version has replicated.
+func (r *Repository) GetPacksByVersion(version int64) ([]domain.Pack, bool, error) {
+	const q = `SELECT sizes, skus FROM pack_sets WHERE version = $1`
+	var arr []int32
+	var skus map[string]string
+	err := r.read.QueryRow(context.Background(), q, version).Scan(&arr, &skus)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	
+	return toPacks(arr, skus), true, nil
+}
+
+// toPacks joins SKUs (keyed by size string) back onto the stored sizes, sorted by size.
+// Sizes without a stored SKU get an empty SKU.
+func toPacks(arr []int32, skus map[string]string) []domain.Pack {
+	packs := make([]domain.Pack, len(arr))
+	for i, v := range arr {
+		packs[i] = domain.Pack{Size: int(v), SKU: skus[strconv.Itoa(int(v))]}
+	}
+	
+	// Sort for consistency
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Size < packs[j].Size })
+	return packs
+}
+
+// ReplaceActivePacks creates a new version of pack sizes and SKUs by inserting a new row.
+// Normalization matches ReplaceActive; when a size appears more than once,
+// the last non-empty SKU wins. The version comes back from the INSERT itself, so it is
+// exactly the row written even when other replicas write concurrently.
+// The lock is checked in the same transaction as the insert (see checkUnlocked).
+func (r *Repository) ReplaceActivePacks(packs []domain.Pack) ([]domain.Pack, int64, error) {
+	arr, skus, out := normalizePacks(packs)
+	
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	
+	if err := checkUnlocked(ctx, tx); err != nil {
+		return nil, 0, err
+	}
+	
+	// Insert new version with current timestamp
+	const q = `INSERT INTO pack_sets (sizes, skus, created_at) VALUES ($1, $2, $3) RETURNING version`
+	var version int64
+	if err := tx.QueryRow(ctx, q, arr, skus, time.Now().UTC()).Scan(&version); err != nil {
+		return nil, 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, 0, err
+	}
+	
+	return out, version, nil
+}
+
+// checkUnlocked returns a *domain.LockedError if pack size changes are locked. It reads the lock
+// row FOR SHARE, so a concurrent SetLocked waits for tx to finish and a lock taken just before
+// is seen once it commits: the lock and the write can't interleave.
+func checkUnlocked(ctx context.Context, tx pgx.Tx) error {
+	var locked bool
+	err := tx.QueryRow(ctx, `SELECT locked FROM pack_lock WHERE id FOR SHARE`).Scan(&locked)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if locked {
+		return &domain.LockedError{}
+	}
+	return nil
+}
+
+// ReplaceActivePacksIfVersion inserts a new version like ReplaceActivePacks, but only while the
+// latest version is one of expected. The check and the insert run in one transaction holding a
+// SHARE ROW EXCLUSIVE lock on pack_sets, which conflicts with itself and with plain inserts, so
+// no other write can land between them; reads are not blocked. When the check fails nothing is
+// written and a *domain.VersionConflictError carries the latest version. The pack size lock is
+// checked in the same transaction, as in ReplaceActivePacks.
+func (r *Repository) ReplaceActivePacksIfVersion(packs []domain.Pack, expected []int64) ([]domain.Pack, int64, error) {
+	arr, skus, out := normalizePacks(packs)
+	
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	
+	if _, err := tx.Exec(ctx, `LOCK TABLE pack_sets IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return nil, 0, err
+	}
+	if err := checkUnlocked(ctx, tx); err != nil {
+		return nil, 0, err
+	}
+	
+	const q = `INSERT INTO pack_sets (sizes, skus, created_at)
+		SELECT $1, $2, $3 WHERE (SELECT COALESCE(MAX(version),0) FROM pack_sets) = ANY($4)
+		RETURNING version`
+	var version int64
+	err = tx.QueryRow(ctx, q, arr, skus, time.Now().UTC(), expected).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// No row written: report the version that is actually active
+		var current int64
+		if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(version),0) FROM pack_sets`).Scan(&current); err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, &domain.VersionConflictError{Expected: expected, Current: current}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, 0, err
+	}
+	
+	return out, version, nil
+}
+
+// normalizePacks prepares packs for storage: non-positive sizes are dropped, duplicates merged
+// (the last non-empty SKU wins) and the result sorted by size. Returns the sizes as a PostgreSQL
+// int32 array, the SKUs keyed by size, and the normalized packs.
+func normalizePacks(packs []domain.Pack) ([]int32, map[string]string, []domain.Pack) {
 	// Allow empty arrays - validation happens at API layer
 	// Normalize: remove duplicates and invalid values
-	uniq := make(map[int]struct{})
-	for _, s := range sizes {
-		if s > 0 {
-			uniq[s] = struct{}{}
+	uniq := make(map[int]string)
+	for _, p := range packs {
+		if p.Size <= 0 {
+			continue
+		}
+		if p.SKU != "" || uniq[p.Size] == "" {
+			uniq[p.Size] = p.SKU
 		}
 	}
 	
 	// Rebuild sorted slice from unique values
-	sizes = sizes[:0]
+	sizes := make([]int, 0, len(uniq))
 	for s := range uniq {
 		sizes = append(sizes, s)
 	}
 	sort.Ints(sizes)
 	
-	// Convert to PostgreSQL int32 array format
+	// Convert to PostgreSQL int32 array format, collecting SKUs keyed by size
 	arr := make([]int32, len(sizes))
+	skus := make(map[string]string)
+	out := make([]domain.Pack, len(sizes))
 	for i, v := range sizes {
 		arr[i] = int32(v)
+		out[i] = domain.Pack{Size: v, SKU: uniq[v]}
+		if uniq[v] != "" {
+			skus[strconv.Itoa(v)] = uniq[v]
+		}
 	}
-	
-	// Insert new version with current timestamp
-	const q = `INSERT INTO pack_sets (sizes, created_at) VALUES ($1, $2)`
-	_, err := r.db.Exec(context.Background(), q, arr, time.Now().UTC())
-	if err != nil {
-		return nil, err
-	}
-	
-	// Return a copy of the normalized sizes
-	return slices.Clone(sizes), nil
+	return arr, skus, out
 }
 
 // CurrentVersion returns the highest version number from the pack_sets table.
@@ -98,6 +301,45 @@ func (r *Repository) ReplaceActive(sizes []int) ([]int, error) {
 func (r *Repository) CurrentVersion() (int64, error) {
 	const q = `SELECT COALESCE(MAX(version),0) FROM pack_sets`
 	var v int64
-	err := r.db.QueryRow(context.Background(), q).Scan(&v)
+	err := r.read.QueryRow(context.Background(), q).Scan(&v)
 	return v, err
 }
+
+// LatestCreatedAt returns the created_at timestamp of the latest pack_sets row.
+// Returns the zero time if no versions exist.
+func (r *Repository) LatestCreatedAt() (time.Time, error) {
+	const q = `SELECT created_at FROM pack_sets ORDER BY version DESC LIMIT 1`
+	var t time.Time
+	err := r.read.QueryRow(context.Background(), q).Scan(&t)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// IsLocked reports whether pack size changes are currently locked.
+// Returns false if the lock row doesn't exist yet (fresh database).
+func (r *Repository) IsLocked() (bool, error) {
+	const q = `SELECT locked FROM pack_lock WHERE id`
+	var locked bool
+	err := r.db.QueryRow(context.Background(), q).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return locked, nil
+}
+
+// SetLocked persists the pack size lock state.
+// The lock lives in a single-row table so it survives process restarts.
+func (r *Repository) SetLocked(locked bool) error {
+	const q = `INSERT INTO pack_lock (id, locked, updated_at) VALUES (TRUE, $1, $2)
+ON CONFLICT (id) DO UPDATE SET locked = EXCLUDED.locked, updated_at = EXCLUDED.updated_at`
+	_, err := r.db.Exec(context.Background(), q, locked, time.Now().UTC())
+	return err
+}
@@ -3,21 +3,40 @@
 package redisad
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"io"
 	"strings"
 	"time"
 
 	gredis "github.com/redis/go-redis/v9"
 )
 
+// compressedMagic prefixes gzip-compressed values so Get can tell them from raw ones.
+// Cached values are JSON, which never starts with a NUL byte.
+var compressedMagic = []byte("\x00gz")
+
 // Cache implements the domain.Cache interface using Redis.
 type Cache struct {
-	rdb *gredis.Client // Redis client connection
+	rdb         *gredis.Client // Redis client connection
+	compressMin int            // Values at least this many bytes are gzip-compressed (0 disables compression)
 }
 
 // New creates a new Redis cache adapter.
 func New(rdb *gredis.Client) *Cache { return &Cache{rdb: rdb} }
 
+// WithCompression gzip-compresses values of at least minBytes before storing them.
+// Smaller values are stored as is, since compression wouldn't pay for its overhead.
+// Compressed values are always decoded by Get, so turning compression off later is safe.
+func (c *Cache) WithCompression(minBytes int) *Cache {
+	if minBytes < 1 {
+		minBytes = 1
+	}
+	c.compressMin = minBytes
+	return c
+}
+
 // Get retrieves a value from Redis by key.
 // Returns nil if the key doesn't exist (Redis.Nil error is converted to nil).
 // Returns the error for other failures (connection issues, etc.).
@@ -27,35 +46,137 @@ func (c *Cache) Get(key string) ([]byte, error) {
 		// Key doesn't exist - return nil instead of error
 		return nil, nil
 	}
-	return s, err
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(s)
 }
 
 // Set stores a value in Redis with a time-to-live (TTL).
 // The TTL is specified in seconds and determines how long the value will be cached.
+// With compression enabled, large values are stored gzip-compressed.
 func (c *Cache) Set(key string, value []byte, ttlSeconds int) error {
+	value, err := encodeValue(value, c.compressMin)
+	if err != nil {
+		return err
+	}
 	return c.rdb.Set(context.Background(), key, value, time.Duration(ttlSeconds)*time.Second).Err()
 }
 
+// GetMany retrieves the values of keys with a single MGET, so a batch costs one round trip.
+// The result has one entry per key, in the order of keys; a missing key's entry is nil.
+func (c *Cache) GetMany(keys []string) ([][]byte, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	vals, err := c.rdb.MGet(context.Background(), keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeValues(vals)
+}
+
+// SetMany stores entries with a pipeline of SETs sharing one TTL, sent in a single round trip.
+// Values are compressed as by Set. Returns the first error of any SET in the pipeline.
+func (c *Cache) SetMany(entries map[string][]byte, ttlSeconds int) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	encoded := make(map[string][]byte, len(entries))
+	for key, value := range entries {
+		value, err := encodeValue(value, c.compressMin)
+		if err != nil {
+			return err
+		}
+		encoded[key] = value
+	}
+	ttl := time.Duration(ttlSeconds) * time.Second
+	_, err := c.rdb.Pipelined(context.Background(), func(pipe gredis.Pipeliner) error {
+		for key, value := range encoded {
+			pipe.Set(context.Background(), key, value, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// decodeValues converts an MGET reply to one value per key, decoding each as Get does.
+// MGET answers a missing key (or one holding a non-string type) with nil, which stays a nil entry.
+func decodeValues(vals []interface{}) ([][]byte, error) {
+	out := make([][]byte, len(vals))
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		b, err := decodeValue([]byte(s))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = b
+	}
+	return out, nil
+}
+
+// encodeValue gzip-compresses value behind compressedMagic when it's at least minBytes long.
+// A minBytes of 0 leaves every value raw.
+func encodeValue(value []byte, minBytes int) ([]byte, error) {
+	if minBytes <= 0 || len(value) < minBytes {
+		return value, nil
+	}
+	var buf bytes.Buffer
+	buf.Write(compressedMagic)
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(value); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeValue reverses encodeValue; values without compressedMagic are returned as stored.
+func decodeValue(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, compressedMagic) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data[len(compressedMagic):]))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// Delete removes a single key with DEL.
+func (c *Cache) Delete(key string) error {
+	return c.rdb.Del(context.Background(), key).Err()
+}
+
 // DeleteByPrefix removes all keys matching the given prefix.
 // Uses Redis SCAN to iterate through keys matching the pattern, then deletes them.
 // This is used for cache invalidation when data changes (e.g., pack sizes updated).
-// Returns the first error encountered, if any.
-func (c *Cache) DeleteByPrefix(prefix string) error {
+// Returns the number of keys deleted and the first error encountered, if any.
+func (c *Cache) DeleteByPrefix(prefix string) (int, error) {
 	// Use SCAN to find all keys matching the prefix pattern
 	iter := c.rdb.Scan(context.Background(), 0, prefix+"*", 0).Iterator()
 	var firstErr error
+	removed := 0
 	
-	// Delete each matching key
+	// Delete each matching key (a key that expired meanwhile isn't counted)
 	for iter.Next(context.Background()) {
-		if err := c.rdb.Del(context.Background(), iter.Val()).Err(); err != nil && firstErr == nil {
+		n, err := c.rdb.Del(context.Background(), iter.Val()).Result()
+		if err != nil && firstErr == nil {
 			firstErr = err
 		}
+		removed += int(n)
 	}
 	
 	// Check for iterator errors (ignore EOF which indicates end of scan)
 	if err := iter.Err(); err != nil && !strings.Contains(err.Error(), "EOF") {
-		return err
+		return removed, err
 	}
 	
-	return firstErr
+	return removed, firstErr
 }
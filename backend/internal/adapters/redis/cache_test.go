@@ -0,0 +1,73 @@
+package redisad
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncodeValue_RoundTrip(t *testing.T) {
+	large := []byte(`{"results":[` + strings.Repeat(`{"amount":12001,"breakdown":{"250":1,"2000":1,"5000":2}},`, 2000) + `{}]}`)
+
+	stored, err := encodeValue(large, 1024)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if !bytes.HasPrefix(stored, compressedMagic) {
+		t.Fatalf("Expected a large value to be compressed")
+	}
+	if len(stored) >= len(large)/10 {
+		t.Errorf("Expected repetitive JSON to shrink well, got %d of %d bytes", len(stored), len(large))
+	}
+
+	got, err := decodeValue(stored)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if !bytes.Equal(got, large) {
+		t.Errorf("Round trip changed the value")
+	}
+}
+
+func TestEncodeValue_BelowThreshold(t *testing.T) {
+	small := []byte(`[250,500,1000]`)
+
+	for _, minBytes := range []int{0, 1024} {
+		stored, err := encodeValue(small, minBytes)
+		if err != nil {
+			t.Fatalf("encode failed: %v", err)
+		}
+		if !bytes.Equal(stored, small) {
+			t.Errorf("minBytes %d: expected the value stored raw, got %q", minBytes, stored)
+		}
+	}
+
+	// Raw values, e.g. written before compression was enabled, are returned unchanged
+	got, err := decodeValue(small)
+	if err != nil || !bytes.Equal(got, small) {
+		t.Errorf("Expected a raw value back unchanged, got %q (%v)", got, err)
+	}
+}
+
+func TestDecodeValues_KeepsKeyOrder(t *testing.T) {
+	large := strings.Repeat(`{"amount":12001}`, 200)
+	compressed, err := encodeValue([]byte(large), 1024)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	// MGET replies with a string per stored key and nil per missing one, in the order asked
+	got, err := decodeValues([]interface{}{nil, `[250,500]`, string(compressed), nil})
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	want := [][]byte{nil, []byte(`[250,500]`), []byte(large), nil}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) || (want[i] == nil) != (got[i] == nil) {
+			t.Errorf("Entry %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
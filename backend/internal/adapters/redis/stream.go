@@ -0,0 +1,127 @@
+package redisad
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	gredis "github.com/redis/go-redis/v9"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// StreamPublisher implements the domain.Publisher interface by appending events to a Redis stream.
+// Consumers read the stream with XREAD or consumer groups and can replay it from any entry ID.
+type StreamPublisher struct {
+	rdb    *gredis.Client // Redis client connection
+	stream string         // Stream key, e.g. "packs:events"
+	maxLen int64          // Approximate stream length cap (0 keeps every event)
+}
+
+// NewStreamPublisher creates a publisher appending to the given stream.
+// The stream is trimmed to roughly maxLen entries so it doesn't grow without bound.
+func NewStreamPublisher(rdb *gredis.Client, stream string, maxLen int64) *StreamPublisher {
+	return &StreamPublisher{rdb: rdb, stream: stream, maxLen: maxLen}
+}
+
+// Publish appends a pack set change to the stream.
+// Each entry has the fields "version", "sizes" (a JSON array) and "changedAt" (RFC 3339).
+func (p *StreamPublisher) Publish(ctx context.Context, event domain.PackSetChanged) error {
+	sizes, err := json.Marshal(event.Sizes)
+	if err != nil {
+		return err
+	}
+	return p.rdb.XAdd(ctx, &gredis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: map[string]any{
+			"version":   strconv.FormatInt(event.Version, 10),
+			"sizes":     string(sizes),
+			"changedAt": event.ChangedAt.Format(time.RFC3339Nano),
+		},
+	}).Err()
+}
+
+// streamReadBlock is how long one XREAD waits for new entries before it's reissued.
+const streamReadBlock = 5 * time.Second
+
+// streamRetryDelay is the pause after a failed XREAD before trying again.
+const streamRetryDelay = time.Second
+
+// StreamSubscriber follows a Redis stream written by StreamPublisher, so every replica sees
+// pack set changes made through any of them.
+type StreamSubscriber struct {
+	rdb    *gredis.Client // Redis client connection
+	stream string         // Stream key, e.g. "packs:events"
+}
+
+// NewStreamSubscriber creates a subscriber following the given stream.
+func NewStreamSubscriber(rdb *gredis.Client, stream string) *StreamSubscriber {
+	return &StreamSubscriber{rdb: rdb, stream: stream}
+}
+
+// Run passes each event appended to the stream after Run starts to handle, until ctx is done.
+// Failed reads are retried after a short pause and malformed entries are skipped; onError,
+// if not nil, is told about both.
+func (s *StreamSubscriber) Run(ctx context.Context, handle func(domain.PackSetChanged), onError func(error)) {
+	lastID := "$" // Only entries added from now on
+	for ctx.Err() == nil {
+		streams, err := s.rdb.XRead(ctx, &gredis.XReadArgs{
+			Streams: []string{s.stream, lastID},
+			Block:   streamReadBlock,
+		}).Result()
+		if err == gredis.Nil {
+			continue // Nothing new within the block time
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if onError != nil {
+				onError(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(streamRetryDelay):
+			}
+			continue
+		}
+
+		for _, st := range streams {
+			for _, msg := range st.Messages {
+				lastID = msg.ID
+				event, err := parseStreamEvent(msg.Values)
+				if err != nil {
+					if onError != nil {
+						onError(fmt.Errorf("stream entry %s: %w", msg.ID, err))
+					}
+					continue
+				}
+				handle(event)
+			}
+		}
+	}
+}
+
+// parseStreamEvent decodes the fields written by StreamPublisher.Publish.
+func parseStreamEvent(values map[string]any) (domain.PackSetChanged, error) {
+	var event domain.PackSetChanged
+	version, _ := values["version"].(string)
+	sizes, _ := values["sizes"].(string)
+	changedAt, _ := values["changedAt"].(string)
+
+	var err error
+	if event.Version, err = strconv.ParseInt(version, 10, 64); err != nil {
+		return event, fmt.Errorf("invalid version %q", version)
+	}
+	if err := json.Unmarshal([]byte(sizes), &event.Sizes); err != nil {
+		return event, fmt.Errorf("invalid sizes %q", sizes)
+	}
+	if event.ChangedAt, err = time.Parse(time.RFC3339Nano, changedAt); err != nil {
+		return event, fmt.Errorf("invalid changedAt %q", changedAt)
+	}
+	return event, nil
+}
@@ -0,0 +1,133 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// errFlightAborted is returned to callers waiting on a calculation that panicked.
+var errFlightAborted = errors.New("calculator: shared calculation aborted")
+
+// flightGroup deduplicates identical calculations in flight: while one caller (the leader)
+// computes a key, callers asking for the same key wait for its result instead of computing it
+// again. This covers the window before a result cache is populated, e.g. a burst of requests
+// for the same flash-sale amount. Nothing is kept once the leader finishes.
+// It is safe for concurrent use.
+type flightGroup struct {
+	mu     sync.Mutex
+	calls  map[string]*flightCall // Key -> calculation in flight
+	shared atomic.Uint64          // Callers served by another caller's calculation
+}
+
+// flightCall is one calculation in flight. res and err are written before done is closed.
+type flightCall struct {
+	done    chan struct{}
+	waiters int // Callers waiting on this calculation besides the leader
+	res     domain.CalculationResult
+	err     error
+}
+
+// newFlightGroup creates an empty flight group.
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// do returns the result of fn for key, calling fn only if no identical calculation is in
+// flight. A waiting caller gives up when its own context ends. If the leader's context ended
+// first, its error says nothing about the calculation, so waiters that are still live run it
+// themselves. When a result is shared, each caller gets its own copy of the breakdown, so callers
+// can't see each other's changes to it.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (domain.CalculationResult, error)) (domain.CalculationResult, error) {
+	for {
+		g.mu.Lock()
+		c, ok := g.calls[key]
+		if !ok {
+			c = &flightCall{done: make(chan struct{})}
+			g.calls[key] = c
+			g.mu.Unlock()
+
+			if g.lead(key, c, fn) {
+				return cloneResult(c.res), c.err
+			}
+			return c.res, c.err
+		}
+		c.waiters++
+		g.mu.Unlock()
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return domain.CalculationResult{}, ctx.Err()
+		}
+		if isContextErr(c.err) && ctx.Err() == nil {
+			continue // The leader gave up; try again, possibly as the leader
+		}
+		g.shared.Add(1)
+		return cloneResult(c.res), c.err
+	}
+}
+
+// lead runs fn for the calculation c under key and publishes its result, reporting whether
+// any caller waited for it. If fn panics, waiters are released with errFlightAborted.
+func (g *flightGroup) lead(key string, c *flightCall, fn func() (domain.CalculationResult, error)) (waited bool) {
+	c.err = errFlightAborted
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key) // No caller can join from here on
+		waited = c.waiters > 0
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.res, c.err = fn()
+	return
+}
+
+// inFlight returns how many callers wait on the calculation for key besides its leader,
+// or -1 if none is in flight.
+func (g *flightGroup) inFlight(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c, ok := g.calls[key]; ok {
+		return c.waiters
+	}
+	return -1
+}
+
+// isContextErr reports whether err comes from a canceled or expired context.
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// cloneResult copies the breakdown of res, which is otherwise shared between callers.
+func cloneResult(res domain.CalculationResult) domain.CalculationResult {
+	res.Breakdown = maps.Clone(res.Breakdown)
+	res.BreakdownList = slices.Clone(res.BreakdownList)
+	return res
+}
+
+// flightKey identifies a calculation of amount with sizes under opts. It covers every option
+// that affects the result (tie-break policy, preferred sizes, summary form, at least one of each
+// size, pack limit and utilization minimum), so only calculations with identical results share
+// a key; Uncached and Version change where tables or results are kept, not the result, so they
+// are left out. Sizes are keyed in canonical form, so any order or duplication of the same set
+// shares a key; the caller's slice is left as it is.
+func flightKey(amount int, sizes []int, opts domain.CalcOptions) string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(amount))
+	b.WriteString("|" + SizesKey(sizes))
+	b.WriteString("|" + string(opts.TieBreak))
+	b.WriteString("|" + SizesKey(opts.Preferred))
+	b.WriteString("|" + strconv.FormatBool(opts.SummaryOnly))
+	b.WriteString("|" + strconv.FormatBool(opts.MinOnePerSize))
+	b.WriteString("|" + strconv.Itoa(opts.MaxPacks))
+	b.WriteString("|" + strconv.FormatFloat(opts.MinUtilization, 'g', -1, 64))
+	return b.String()
+}
@@ -0,0 +1,280 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// waitForWaiters waits until n callers wait on the calculation for key in g.
+func waitForWaiters(t *testing.T, g *flightGroup, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for g.inFlight(key) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d waiting callers, got %d", n, g.inFlight(key))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestService_ComputeDeduplicatesInFlight(t *testing.T) {
+	const callers = 50
+	s := NewService().WithWorkers(1)
+	sizes := []int{250, 500, 1000, 2000, 5000}
+
+	// Hold the only worker, so the first caller blocks inside its calculation while the rest arrive
+	if err := s.pool.acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	results := make([]domain.CalculationResult, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// The same set in any order is the same calculation
+			order := sizes
+			if i%2 == 1 {
+				order = []int{5000, 250, 2000, 500, 1000, 250}
+			}
+			results[i], errs[i] = s.Compute(context.Background(), 12001, order)
+		}()
+	}
+	waitForWaiters(t, s.flights, flightKey(12001, sizes, domain.CalcOptions{TieBreak: s.tieBreak}), callers-1)
+	s.pool.release()
+	wg.Wait()
+
+	// Only the first caller ever took (and so waited for) a worker
+	if stats := s.pool.stats(); stats.Waits != 1 {
+		t.Errorf("Expected one calculation, got %d waits for a worker", stats.Waits)
+	}
+	if shared := s.flights.shared.Load(); shared != callers-1 {
+		t.Errorf("Expected %d callers to share the result, got %d", callers-1, shared)
+	}
+	for i := range callers {
+		if errs[i] != nil {
+			t.Fatalf("Caller %d: unexpected error %v", i, errs[i])
+		}
+		if results[i].TotalItems != 12250 || results[i].Breakdown[5000] != 2 || results[i].Breakdown[2000] != 1 {
+			t.Errorf("Caller %d: expected 2x5000 + 2000 + 250, got %+v", i, results[i])
+		}
+	}
+
+	// Each caller has its own breakdown
+	results[0].Breakdown[5000] = 99
+	if results[1].Breakdown[5000] != 2 {
+		t.Errorf("Expected callers not to share a breakdown map")
+	}
+
+	// Nothing is kept once the calculation finishes
+	if n := s.flights.inFlight(flightKey(12001, sizes, domain.CalcOptions{TieBreak: s.tieBreak})); n != -1 {
+		t.Errorf("Expected no calculation in flight, got %d waiters", n)
+	}
+}
+
+func TestFlightGroup_DistinctKeysRunSeparately(t *testing.T) {
+	g := newFlightGroup()
+	var mu sync.Mutex
+	calls := map[string]int{}
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "a", "c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.do(context.Background(), key, func() (domain.CalculationResult, error) {
+				mu.Lock()
+				calls[key]++
+				mu.Unlock()
+				return domain.CalculationResult{}, nil
+			})
+		}()
+	}
+	wg.Wait()
+	if calls["b"] != 1 || calls["c"] != 1 || calls["a"] < 1 {
+		t.Errorf("Expected every key to be calculated, got %v", calls)
+	}
+}
+
+func TestFlightGroup_WaiterContext(t *testing.T) {
+	g := newFlightGroup()
+	release := make(chan struct{})
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, err := g.do(context.Background(), "k", func() (domain.CalculationResult, error) {
+			<-release
+			return domain.CalculationResult{TotalItems: 250}, nil
+		})
+		leaderDone <- err
+	}()
+	waitForWaiters(t, g, "k", 0)
+
+	// A waiter whose context ends stops waiting without affecting the leader
+	ctx, cancel := context.WithCancel(context.Background())
+	waiterDone := make(chan error, 1)
+	go func() {
+		_, err := g.do(ctx, "k", func() (domain.CalculationResult, error) {
+			t.Error("Expected the waiter not to calculate")
+			return domain.CalculationResult{}, nil
+		})
+		waiterDone <- err
+	}()
+	waitForWaiters(t, g, "k", 1)
+	cancel()
+	if err := <-waiterDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the waiter to be canceled, got %v", err)
+	}
+
+	close(release)
+	if err := <-leaderDone; err != nil {
+		t.Errorf("Expected the leader to finish, got %v", err)
+	}
+}
+
+func TestFlightGroup_LeaderCanceled(t *testing.T) {
+	g := newFlightGroup()
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	go g.do(ctx, "k", func() (domain.CalculationResult, error) {
+		<-release
+		return domain.CalculationResult{}, ctx.Err()
+	})
+	waitForWaiters(t, g, "k", 0)
+
+	// The leader's cancellation isn't the waiter's, so the waiter calculates for itself
+	done := make(chan domain.CalculationResult, 1)
+	go func() {
+		res, err := g.do(context.Background(), "k", func() (domain.CalculationResult, error) {
+			return domain.CalculationResult{TotalItems: 500}, nil
+		})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		done <- res
+	}()
+	waitForWaiters(t, g, "k", 1)
+	cancel()
+	close(release)
+	if res := <-done; res.TotalItems != 500 {
+		t.Errorf("Expected the waiter's own result, got %+v", res)
+	}
+}
+
+func TestFlightGroup_LeaderPanics(t *testing.T) {
+	g := newFlightGroup()
+	release := make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		g.do(context.Background(), "k", func() (domain.CalculationResult, error) {
+			<-release
+			panic("boom")
+		})
+	}()
+	waitForWaiters(t, g, "k", 0)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.do(context.Background(), "k", nil)
+		done <- err
+	}()
+	waitForWaiters(t, g, "k", 1)
+	close(release)
+	if err := <-done; !errors.Is(err, errFlightAborted) {
+		t.Errorf("Expected errFlightAborted, got %v", err)
+	}
+}
+
+func TestFlightKey(t *testing.T) {
+	sizes := []int{500, 250, 250, 1000}
+	opts := domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst}
+	key := flightKey(251, sizes, opts)
+	if want := flightKey(251, []int{250, 500, 1000}, opts); key != want {
+		t.Errorf("Expected the canonical key %q, got %q", want, key)
+	}
+	if sizes[0] != 500 || len(sizes) != 4 {
+		t.Errorf("Expected the caller's sizes to be left as they were, got %v", sizes)
+	}
+
+	// Options that only change where tables or results are kept don't change the result
+	for _, same := range []domain.CalcOptions{
+		{TieBreak: domain.TieBreakItemsFirst, Uncached: true},
+		{TieBreak: domain.TieBreakItemsFirst, Version: 7},
+	} {
+		if other := flightKey(251, sizes, same); other != key {
+			t.Errorf("Expected options %+v to share the key %q, got %q", same, key, other)
+		}
+	}
+
+	for _, other := range []string{
+		flightKey(252, sizes, opts),
+		flightKey(251, []int{250, 500}, opts),
+		flightKey(251, sizes, domain.CalcOptions{TieBreak: domain.TieBreakPacksFirst}),
+		flightKey(251, sizes, domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst, Preferred: []int{500}}),
+		flightKey(251, sizes, domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst, SummaryOnly: true}),
+		flightKey(251, sizes, domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst, MinOnePerSize: true}),
+		flightKey(251, sizes, domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst, MaxPacks: 3}),
+		flightKey(251, sizes, domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst, MinUtilization: 0.5}),
+	} {
+		if other == key {
+			t.Errorf("Expected a different key than %q", key)
+		}
+	}
+}
+
+func TestService_ComputeWithOptionsDeduplicatesInFlight(t *testing.T) {
+	const callers = 20
+	s := NewService().WithWorkers(1)
+	sizes := []int{250, 500, 1000, 2000, 5000}
+	// Options as POST /calculate passes them: a tie-break override, a pack limit and a summary
+	opts := domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst, MaxPacks: 10, SummaryOnly: true}
+	detailed := opts
+	detailed.SummaryOnly = false
+
+	// Hold the only worker, so the first caller blocks inside its calculation while the rest arrive
+	if err := s.pool.acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	results := make([]domain.CalculationResult, callers+1)
+	errs := make([]error, callers+1)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.ComputeWithOptions(context.Background(), 12001, sizes, opts)
+		}()
+	}
+	waitForWaiters(t, s.flights, flightKey(12001, sizes, opts), callers-1)
+
+	// The same amount with a breakdown is a different result, so it doesn't join
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[callers], errs[callers] = s.ComputeWithOptions(context.Background(), 12001, sizes, detailed)
+	}()
+	waitForWaiters(t, s.flights, flightKey(12001, sizes, detailed), 0)
+	s.pool.release()
+	wg.Wait()
+
+	if shared := s.flights.shared.Load(); shared != callers-1 {
+		t.Errorf("Expected %d callers to share the result, got %d", callers-1, shared)
+	}
+	for i := range callers {
+		if errs[i] != nil {
+			t.Fatalf("Caller %d: unexpected error %v", i, errs[i])
+		}
+		if results[i].TotalItems != 12250 || len(results[i].Breakdown) != 0 {
+			t.Errorf("Caller %d: expected a 12250-item summary, got %+v", i, results[i])
+		}
+	}
+	if errs[callers] != nil || results[callers].Breakdown[5000] != 2 {
+		t.Errorf("Expected the detailed caller to get its own breakdown, got %+v (err %v)", results[callers], errs[callers])
+	}
+}
@@ -0,0 +1,62 @@
+package calculator
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// workerPool bounds how many calculations run at once. Each calculation may build a DP table
+// with millions of entries, so unbounded concurrency would trade throughput for memory.
+// When every worker is busy, callers wait for a free one until their context ends (backpressure).
+type workerPool struct {
+	slots   chan struct{} // One token per running calculation
+	waiting atomic.Int64  // Callers currently queued for a worker
+	waits   atomic.Uint64 // Acquisitions that found every worker busy
+}
+
+// newWorkerPool creates a pool of n workers; n <= 0 means GOMAXPROCS.
+func newWorkerPool(n int) *workerPool {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return &workerPool{slots: make(chan struct{}, n)}
+}
+
+// acquire takes a worker, waiting while the pool is saturated.
+// Returns the context's error if it ends first; the caller must not release in that case.
+func (p *workerPool) acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	p.waits.Add(1)
+	p.waiting.Add(1)
+	defer p.waiting.Add(-1)
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release returns a worker taken by acquire.
+func (p *workerPool) release() { <-p.slots }
+
+// stats reports the current occupancy of the pool.
+func (p *workerPool) stats() domain.PoolStats {
+	busy := len(p.slots)
+	return domain.PoolStats{
+		Workers:   cap(p.slots),
+		Busy:      busy,
+		Waiting:   int(p.waiting.Load()),
+		Saturated: busy == cap(p.slots),
+		Waits:     p.waits.Load(),
+	}
+}
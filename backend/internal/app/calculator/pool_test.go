@@ -0,0 +1,88 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool_Backpressure(t *testing.T) {
+	p := newWorkerPool(2)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := p.acquire(ctx); err != nil {
+			t.Fatalf("Acquire %d: %v", i, err)
+		}
+	}
+	if s := p.stats(); s.Workers != 2 || s.Busy != 2 || !s.Saturated || s.Waits != 0 {
+		t.Fatalf("Expected a saturated pool with no waits, got %+v", s)
+	}
+
+	// A third caller waits until a worker is released
+	acquired := make(chan error, 1)
+	go func() { acquired <- p.acquire(ctx) }()
+
+	deadline := time.Now().Add(time.Second)
+	for p.stats().Waiting != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected one waiting caller, got %+v", p.stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-acquired:
+		t.Fatalf("Expected caller to wait while the pool is saturated")
+	default:
+	}
+
+	p.release()
+	if err := <-acquired; err != nil {
+		t.Fatalf("Expected queued caller to get a worker, got %v", err)
+	}
+	if s := p.stats(); s.Busy != 2 || s.Waiting != 0 || s.Waits != 1 {
+		t.Errorf("Unexpected stats after hand-over: %+v", s)
+	}
+}
+
+func TestWorkerPool_ContextEndsWait(t *testing.T) {
+	p := newWorkerPool(1)
+	if err := p.acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if s := p.stats(); s.Busy != 1 || s.Waiting != 0 {
+		t.Errorf("Expected abandoned wait to leave the pool unchanged, got %+v", s)
+	}
+}
+
+func TestService_WithWorkers(t *testing.T) {
+	if got := NewService().PoolStats().Workers; got != runtime.GOMAXPROCS(0) {
+		t.Errorf("Expected GOMAXPROCS workers by default, got %d", got)
+	}
+
+	svc := NewService().WithWorkers(1)
+	ctx := context.Background()
+	if _, err := svc.ComputeBatch(ctx, []int{1, 251}, []int{250, 500}); err != nil {
+		t.Fatalf("ComputeBatch: %v", err)
+	}
+	if s := svc.PoolStats(); s.Workers != 1 || s.Busy != 0 {
+		t.Errorf("Expected the worker to be released, got %+v", s)
+	}
+
+	// A saturated service fails calculations whose context ends while waiting
+	_ = svc.pool.acquire(ctx)
+	defer svc.pool.release()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := svc.Compute(canceled, 251, []int{250, 500}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context canceled while waiting for a worker, got %v", err)
+	}
+}
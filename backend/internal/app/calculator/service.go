@@ -5,16 +5,21 @@ package calculator
 
 import (
 	"context"
+	"fmt"
 	"sort"
 
 	"github.com/temo/pack-optimizer/backend/internal/domain"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Result represents the output of a pack calculation.
+// Feasible is false when no combination of packs fulfills the amount; the other fields are then zero.
+// A non-positive amount is feasible with zero packs, since there is nothing to fulfill.
 type Result struct {
 	TotalItems int         // Total number of items in the solution
 	TotalPacks int         // Total number of packs needed
 	Counts     map[int]int // Map of pack size -> quantity needed
+	Feasible   bool        // Whether a solution exists
 }
 
 // Compute uses dynamic programming to find the minimal total items >= amount,
@@ -27,21 +32,209 @@ type Result struct {
 // 1. Sanitize and sort pack sizes
 // 2. Build DP table where dp[i] = minimum packs needed for i items
 // 3. For each target amount, try all pack sizes and choose optimal combination
-// 4. Find the best target >= amount with minimum items, then minimum packs
+// 4. Take the smallest reachable target >= amount (minimum items); dp[target] is then
+//    the minimum packs for exactly those items, so Rule 3 needs no separate search
 // 5. Reconstruct solution by backtracking through choices
 //
 // Time Complexity: O(amount × pack_sizes)
 // Space Complexity: O(amount)
 func Compute(amount int, sizes []int) Result {
-	// Handle edge cases
-	if amount <= 0 || len(sizes) == 0 {
-		return Result{TotalItems: 0, TotalPacks: 0, Counts: map[int]int{}}
+	return ComputeMany([]int{amount}, sizes)[0]
+}
+
+// ComputeMany solves several amounts against the same pack sizes, sharing one DP table.
+// The table is built once up to the largest amount, so a batch costs about as much as
+// its biggest member. Results are returned in the same order as amounts and are identical
+// to calling Compute for each amount individually.
+func ComputeMany(amounts []int, sizes []int) []Result {
+	// A background context never ends, so there is no error to report
+	results, _ := computeMany(context.Background(), amounts, sizes, domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst}, freshTable(nil))
+	return results
+}
+
+// ComputeWithOptions works like Compute but applies a tie-break policy and preferred sizes.
+// With PacksFirst the number of packs is minimized before total items; an empty policy means ItemsFirst.
+// Among solutions with the same total items and the same number of packs, the one using
+// the most packs of preferred sizes wins. The preference never changes items or pack count.
+// Preferred sizes that aren't in sizes are ignored.
+// With SummaryOnly the solution isn't reconstructed: Counts is nil, the totals are unchanged.
+// With MinOnePerSize the solution holds at least one pack of every size.
+// With MaxPacks the solution uses at most that many packs, and is infeasible if none does.
+// With MinUtilization solutions whose partly used pack holds less than that fraction of ordered
+// items are skipped, and the result is infeasible if none qualifies.
+func ComputeWithOptions(amount int, sizes []int, opts domain.CalcOptions) Result {
+	results, _ := computeMany(context.Background(), []int{amount}, sizes, opts, freshTable(preferredSet(opts.Preferred)))
+	return results[0]
+}
+
+// ExactFit reports whether amount can be made up exactly from whole packs of the given sizes.
+// It only tracks reachability (one bool per item count), which is cheaper than Compute's table
+// of pack counts and choices. Non-positive amounts and sizes never fit, nor do amounts above
+// domain.MaxLimit, which no table is built for.
+func ExactFit(amount int, sizes []int) bool {
+	fits, _ := exactFit(context.Background(), amount, sizes)
+	return fits
+}
+
+// exactFit implements ExactFit, giving up with the context's error once ctx ends.
+func exactFit(ctx context.Context, amount int, sizes []int) (bool, error) {
+	if amount <= 0 || amount > domain.MaxLimit {
+		return false, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	reach := make([]bool, amount+1)
+	reach[0] = true
+	for i := 1; i <= amount; i++ {
+		if i%cancelCheckCells == 0 && ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		for _, s := range sizes {
+			if s > 0 && s <= i && reach[i-s] {
+				reach[i] = true
+				break
+			}
+		}
+	}
+	return reach[amount], nil
+}
+
+// preferredSet converts a list of preferred sizes to a lookup set (nil if empty).
+func preferredSet(preferred []int) map[int]bool {
+	if len(preferred) == 0 {
+		return nil
+	}
+	pref := make(map[int]bool, len(preferred))
+	for _, s := range preferred {
+		pref[s] = true
+	}
+	return pref
+}
+
+// tableSource returns a filled DP table able to answer amounts up to maxAmount for sanitized sizes.
+// It fails with the context's error if ctx ends while the table is being filled.
+type tableSource func(ctx context.Context, maxAmount int, sizes []int) (*table, error)
+
+// freshTable returns a tableSource that builds a new table on every call.
+func freshTable(preferred map[int]bool) tableSource {
+	return func(ctx context.Context, maxAmount int, sizes []int) (*table, error) {
+		return buildTable(ctx, maxAmount, sizes, preferred)
+	}
+}
+
+// computeMany is the shared implementation behind ComputeMany, ComputeWithOptions and the Service.
+// opts.Preferred is ignored here; preferences are part of the table that tables returns.
+//
+// With opts.MinOnePerSize one pack of every size (the bundle) is taken up front and only the
+// rest of each amount is optimized. Any solution with at least one of each size is the bundle
+// plus a solution for the rest, and both objectives are additive, so the sum is optimal too.
+// With opts.MaxPacks the bundle's packs count against the limit, leaving the rest for solve.
+//
+// Table fills and solution searches check ctx every cancelCheckCells cells, so a calculation
+// whose caller has gone away stops early and returns the context's error.
+func computeMany(ctx context.Context, amounts []int, sizes []int, opts domain.CalcOptions, tables tableSource) ([]Result, error) {
+	results := make([]Result, len(amounts))
+	
+	sizes = sanitizeSizes(sizes)
+	bundle := 0 // Items in the forced packs
+	if opts.MinOnePerSize {
+		for _, s := range sizes {
+			bundle += s
+			if bundle > domain.MaxLimit {
+				break // Too large to total, see below
+			}
+		}
+	}
+	
+	// Handle edge cases. Amounts above domain.MaxLimit, or with a bundle above it, have no
+	// solution: their totals could overflow, and the table would be far too large anyway.
+	outOfRange := func(a int) bool { return a > domain.MaxLimit || bundle > domain.MaxLimit }
+	maxAmount := 0
+	for _, a := range amounts {
+		if a-bundle > maxAmount && !outOfRange(a) {
+			maxAmount = a - bundle
+		}
+	}
+	if len(sizes) == 0 || (maxAmount <= 0 && bundle == 0) {
+		for i, a := range amounts {
+			// Nothing to fulfill is trivially solved; a positive amount without sizes is not
+			results[i] = emptyResult()
+			results[i].Feasible = a <= 0
+		}
+		return results, nil
+	}
+	
+	// Packs left for solve after the bundle; negative when the bundle alone exceeds the limit
+	maxPacks := inf
+	if opts.MaxPacks > 0 {
+		maxPacks = opts.MaxPacks
+		if bundle > 0 {
+			maxPacks -= len(sizes)
+		}
+	}
+	
+	// The bundle may cover every amount, leaving nothing for a table to answer
+	t := &table{}
+	if maxAmount > 0 {
+		var err error
+		if t, err = tables(ctx, maxAmount, sizes); err != nil {
+			return nil, err
+		}
+	}
+	for i, a := range amounts {
+		if outOfRange(a) {
+			results[i] = emptyResult()
+			continue
+		}
+		var err error
+		if opts.MinUtilization > 0 && bundle == 0 {
+			if results[i], err = t.solveUtilized(ctx, a, maxAmount, maxPacks, opts, tables); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if results[i], err = t.solve(ctx, a-bundle, maxPacks, opts); err != nil {
+			return nil, err
+		}
+		if bundle > 0 && results[i].Feasible {
+			addBundle(&results[i], sizes, opts.SummaryOnly)
+			// The bundle alone covers the amount, so solve had no candidates to filter
+			if over := bundle - a; opts.MinUtilization > 0 && !meetsUtilization(sizes[0], over, opts.MinUtilization) {
+				results[i] = emptyResult()
+			}
+		}
+	}
+	return results, nil
+}
+
+// addBundle adds one pack of each size to a solution for the rest of an amount.
+func addBundle(res *Result, sizes []int, summaryOnly bool) {
+	res.TotalPacks += len(sizes)
+	if summaryOnly {
+		res.Counts = nil
+	}
+	for _, s := range sizes {
+		res.TotalItems += s
+		if !summaryOnly {
+			res.Counts[s]++
+		}
+	}
+}
+
+// sanitizeSizes removes duplicates, filters invalid values, and sorts the sizes in place.
+// Sizes that are already clean, such as the active sizes the repository returns, are passed
+// through without allocating or sorting. Cleanliness is checked rather than trusted, since a
+// zero or negative size would corrupt the DP table, and one above domain.MaxLimit could
+// overflow its bounds.
+func sanitizeSizes(sizes []int) []int {
+	if isSanitized(sizes) {
+		return sizes
 	}
 	
-	// Sanitize sizes: remove duplicates, filter invalid values, and sort
 	unique := make(map[int]struct{})
 	for _, s := range sizes {
-		if s > 0 {
+		if s > 0 && s <= domain.MaxLimit {
 			unique[s] = struct{}{}
 		}
 	}
@@ -50,67 +243,191 @@ func Compute(amount int, sizes []int) Result {
 		sizes = append(sizes, s)
 	}
 	sort.Ints(sizes)
+	return sizes
+}
+
+// isSanitized reports whether sizes are positive, at most domain.MaxLimit and strictly ascending,
+// i.e. already in the form sanitizeSizes produces. It costs one pass and no allocations.
+func isSanitized(sizes []int) bool {
+	for i, s := range sizes {
+		if s <= 0 || s > domain.MaxLimit || (i > 0 && s <= sizes[i-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// emptyResult is the zero-pack result; callers set Feasible for nothing to compute (true)
+// or no solution (false).
+func emptyResult() Result {
+	return Result{TotalItems: 0, TotalPacks: 0, Counts: map[int]int{}}
+}
+
+// inf represents an impossible DP state.
+const inf = int(^uint(0)>>1) / 2
+
+// cancelCheckCells is how many DP cells are filled or searched between checks of the context,
+// frequent enough to stop within milliseconds and rare enough to cost nothing measurable.
+const cancelCheckCells = 1 << 16
+
+// table is a filled DP table for a set of sanitized pack sizes.
+// A table is never modified once filled, so it can be shared between goroutines;
+// extend returns a new, larger table instead.
+type table struct {
+	sizes     []int  // Sanitized pack sizes (positive, unique, sorted ascending)
+	isPref    []bool // isPref[i] reports whether sizes[i] is preferred
+	dp        []int  // dp[i] = minimum packs needed for i items
+	prev      []int  // prev[i] = pack size used to reach i items
+	prefs     []int  // prefs[i] = preferred packs used by the chosen solution for i items
+	maxS      int    // Largest pack size
+	maxAmount int    // Largest amount the table can answer
+}
+
+// buildTable fills the DP table for every item count needed to answer amounts up to maxAmount.
+// sizes must already be sanitized (positive, unique, sorted ascending).
+// When preferred is non-empty, ties in pack count are broken by the number of preferred packs;
+// both objectives are additive, so the lexicographic DP stays optimal.
+// If ctx ends during the fill, the partial table is dropped and the context's error returned.
+func buildTable(ctx context.Context, maxAmount int, sizes []int, preferred map[int]bool) (*table, error) {
+	// Look up preferences once per size rather than inside the hot loop
+	isPref := make([]bool, len(sizes))
+	for i, s := range sizes {
+		isPref[i] = preferred[s]
+	}
+	
+	tb := &table{
+		sizes:  append([]int(nil), sizes...), // Callers may reuse their slice
+		isPref: isPref,
+		dp:     []int{0}, // Base case: 0 items requires 0 packs
+		prev:   []int{0},
+		prefs:  []int{0},
+		maxS:   sizes[len(sizes)-1],
+	}
+	return tb.extend(ctx, maxAmount)
+}
+
+// extend returns a table that answers amounts up to maxAmount, reusing the values already computed.
+// The receiver is left untouched; if it is already large enough it is returned as is.
+// If ctx ends during the fill, the new table is dropped and the context's error returned.
+func (tb *table) extend(ctx context.Context, maxAmount int) (*table, error) {
+	if maxAmount <= tb.maxAmount {
+		return tb, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err // Don't allocate a table nobody is waiting for
+	}
 	
 	// Calculate upper bound for DP table
 	// We need to search up to amount + maxSize - 1 to find optimal solution
-	maxS := sizes[len(sizes)-1]
-	targetUpper := amount + maxS - 1
+	targetUpper := maxAmount + tb.maxS - 1
+	from := len(tb.dp)
 	
-	// Initialize DP table with infinity (representing impossible states)
-	const inf = int(^uint(0) >> 1) / 2
+	// Copy the computed prefix; the rest starts out impossible
 	dp := make([]int, targetUpper+1)      // dp[i] = minimum packs needed for i items
 	prev := make([]int, targetUpper+1)    // prev[i] = pack size used to reach i items
+	prefs := make([]int, targetUpper+1)   // prefs[i] = preferred packs used by the chosen solution for i items
+	copy(dp, tb.dp)
+	copy(prev, tb.prev)
+	copy(prefs, tb.prefs)
 	
-	// Initialize all states as impossible
-	for i := 1; i <= targetUpper; i++ {
-		dp[i] = inf
-		prev[i] = -1
-	}
-	
-	// Base case: 0 items requires 0 packs
-	dp[0] = 0
-	
-	// Bottom-up DP: fill the table for all possible item counts
-	for t := 1; t <= targetUpper; t++ {
+	// Bottom-up DP: fill the table for the remaining item counts
+	sizes, isPref := tb.sizes, tb.isPref
+	for t := from; t <= targetUpper; t++ {
+		if (t-from+1)%cancelCheckCells == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		best := inf      // Best (minimum) number of packs found so far
 		bestS := -1     // Pack size that gives the best result
+		bestPref := -1  // Preferred packs used by the best result
 		
 		// Try each pack size to see if we can improve the solution
-		for _, s := range sizes {
+		for i, s := range sizes {
 			// Check if we can use this pack size (target >= size)
 			// and if we have a valid solution for (target - size)
 			if t >= s && dp[t-s] != inf {
-				// If using this pack size gives fewer total packs, update best
-				if dp[t-s]+1 < best {
+				p := prefs[t-s]
+				if isPref[i] {
+					p++
+				}
+				
+				// If using this pack size gives fewer total packs, update best;
+				// on a tie, prefer the solution using more preferred packs
+				if dp[t-s]+1 < best || (dp[t-s]+1 == best && p > bestPref) {
 					best = dp[t-s] + 1
 					bestS = s
+					bestPref = p
 				}
 			}
 		}
 		
 		dp[t] = best
 		prev[t] = bestS
+		prefs[t] = bestPref
+	}
+	
+	return &table{sizes: sizes, isPref: isPref, dp: dp, prev: prev, prefs: prefs, maxS: tb.maxS, maxAmount: maxAmount}, nil
+}
+
+// solve finds the optimal solution for a single amount using the filled table, using at most
+// maxPacks packs (inf for no limit). With opts.SummaryOnly the backtracking is skipped and
+// Counts is left nil. The search gives up with the context's error once ctx ends.
+func (tb *table) solve(ctx context.Context, amount, maxPacks int, opts domain.CalcOptions) (Result, error) {
+	if amount <= 0 {
+		res := emptyResult()
+		res.Feasible = maxPacks >= 0
+		return res, nil
 	}
 	
-	// Find the best target >= amount with minimum items (Rule 2)
-	// If multiple targets have same items, choose one with minimum packs (Rule 3)
+	// Find the best target >= amount with minimum items (Rule 2).
+	// Each target is one item total, and dp[target] already holds its minimum packs (Rule 3).
+	// The limit doesn't widen the search: dropping any pack from a total beyond this window
+	// leaves a total that still covers the amount with fewer packs.
+	targetUpper := amount + tb.maxS - 1
 	bestT := -1
 	for t := amount; t <= targetUpper; t++ {
-		if dp[t] != inf {
+		if (t-amount)%cancelCheckCells == 0 && ctx.Err() != nil {
+			return Result{}, ctx.Err()
+		}
+		if tb.dp[t] == inf || tb.dp[t] > maxPacks {
+			continue // Unreachable, or needs more packs than allowed
+		}
+		// Every pack holds at least the smallest size; with a bundle, that size is always used
+		if opts.MinUtilization > 0 && !meetsUtilization(tb.sizes[0], t-amount, opts.MinUtilization) {
+			continue // Its last pack would be too empty
+		}
+		if opts.TieBreak != domain.TieBreakPacksFirst {
 			bestT = t
 			break // First valid solution has minimum items (since we search in order)
 		}
+		// PacksFirst: keep the target with the fewest packs; ties keep the smaller target.
+		// ceil(amount/maxS) packs of the largest size land inside this window, so the
+		// minimum pack count is always found without searching further.
+		if bestT == -1 || tb.dp[t] < tb.dp[bestT] {
+			bestT = t
+		}
 	}
 	
-	// If no solution found, return empty result
+	// If no solution found, return an infeasible result
 	if bestT == -1 {
-		return Result{TotalItems: 0, TotalPacks: 0, Counts: map[int]int{}}
+		return emptyResult(), nil
 	}
 	
-	// Reconstruct the solution by backtracking through prev array
+	return tb.result(bestT, opts.SummaryOnly), nil
+}
+
+// result returns the solution the table holds for total items. With summaryOnly the
+// backtracking is skipped and Counts is left nil.
+func (tb *table) result(total int, summaryOnly bool) Result {
+	// dp[total] is the pack count the reconstruction below would add up to
+	if summaryOnly {
+		return Result{TotalItems: total, TotalPacks: tb.dp[total], Feasible: true}
+	}
+	
+	// Reconstruct the solution by backtracking through prev array.
+	// prev[t] always leads to a total with dp[t]-1 packs, so this yields exactly dp[total] packs.
 	counts := map[int]int{}
-	for t := bestT; t > 0; {
-		s := prev[t]
+	for t := total; t > 0; {
+		s := tb.prev[t]
 		if s <= 0 {
 			break
 		}
@@ -125,30 +442,281 @@ func Compute(amount int, sizes []int) Result {
 	}
 	
 	return Result{
-		TotalItems: bestT,
+		TotalItems: total,
 		TotalPacks: totalPacks,
 		Counts:     counts,
+		Feasible:   true,
 	}
 }
 
+// solveUtilized works like solve, but only accepts solutions meeting opts.MinUtilization.
+// Whether a total qualifies depends on its smallest pack, not just on the total, and the
+// receiver keeps only one solution per total, so each total t is instead looked up in the
+// table for the sizes big enough to be the partly used pack at its overage: the sizes from
+// the first one meeting the minimum up, all of which do. Those tables come from tables as
+// the search first needs them, the receiver serving for all sizes.
+// Larger overages need larger packs, so the search stops once even the largest size fails.
+func (tb *table) solveUtilized(ctx context.Context, amount, maxAmount, maxPacks int, opts domain.CalcOptions, tables tableSource) (Result, error) {
+	if amount <= 0 {
+		return tb.solve(ctx, amount, maxPacks, opts)
+	}
+	
+	bySuffix := map[int]*table{0: tb} // Tables keyed by the index of their smallest size
+	var best *table
+	bestT := -1
+	for t := amount; t <= amount+tb.maxS-1; t++ {
+		overage := t - amount
+		if overage%cancelCheckCells == 0 && ctx.Err() != nil {
+			return Result{}, ctx.Err()
+		}
+		k := sort.Search(len(tb.sizes), func(i int) bool {
+			return meetsUtilization(tb.sizes[i], overage, opts.MinUtilization)
+		})
+		if k == len(tb.sizes) {
+			break // Not even the largest pack can hold this much overage
+		}
+		st, ok := bySuffix[k]
+		if !ok {
+			var err error
+			if st, err = tables(ctx, maxAmount, tb.sizes[k:]); err != nil {
+				return Result{}, err
+			}
+			bySuffix[k] = st
+		}
+		if st.dp[t] == inf || st.dp[t] > maxPacks {
+			continue
+		}
+		if opts.TieBreak != domain.TieBreakPacksFirst {
+			best, bestT = st, t
+			break // Totals are tried in order, so the first one has the fewest items
+		}
+		if bestT == -1 || st.dp[t] < best.dp[bestT] {
+			best, bestT = st, t
+		}
+	}
+	if bestT == -1 {
+		return emptyResult(), nil
+	}
+	return best.result(bestT, opts.SummaryOnly), nil
+}
+
+// meetsUtilization reports whether a solution whose smallest pack is smallest, holding overage
+// unordered items, uses at least minUtil of every pack. Packs are filled largest first, so the
+// smallest pack is the partly used one and holds smallest-overage ordered items (none at all
+// once the overage reaches its size).
+func meetsUtilization(smallest, overage int, minUtil float64) bool {
+	return overage <= 0 || float64(smallest-overage) >= minUtil*float64(smallest)
+}
+
 // Service implements the domain.Calculator port.
 // This is the application service that wraps the Compute function
 // and converts it to the domain interface format.
-type Service struct{}
+// DP tables are cached per pack-size set (see tableCache), so repeated calculations with
+// the same sizes only pay for the part of the table they haven't needed before.
+// Every calculation runs on a worker from a shared pool (see workerPool), bounding memory use.
+// Identical Compute calls in flight at the same time share one calculation (see flightGroup).
+type Service struct {
+	tieBreak domain.TieBreak // Policy used when a request doesn't choose one
+	tables   *tableCache     // Filled DP tables keyed by pack-size set
+	pool     *workerPool     // Bounds concurrent calculations
+	flights  *flightGroup    // Compute calls in flight, keyed by amount and sizes
+}
+
+// NewService creates a new calculator service instance using the ItemsFirst policy.
+func NewService() *Service { return NewServiceWithTieBreak(domain.TieBreakItemsFirst) }
+
+// NewServiceWithTieBreak creates a calculator service whose default policy is tieBreak.
+func NewServiceWithTieBreak(tieBreak domain.TieBreak) *Service {
+	return &Service{tieBreak: tieBreak, tables: newTableCache(defaultTableCacheCells), pool: newWorkerPool(0), flights: newFlightGroup()}
+}
 
-// NewService creates a new calculator service instance.
-func NewService() *Service { return &Service{} }
+// WithWorkers sets how many calculations may run at once; n <= 0 means GOMAXPROCS (the default).
+// Calculations beyond that wait for a free worker until their context ends.
+func (s *Service) WithWorkers(n int) *Service {
+	s.pool = newWorkerPool(n)
+	return s
+}
+
+// PoolStats implements the domain.Calculator interface.
+func (s *Service) PoolStats() domain.PoolStats {
+	return s.pool.stats()
+}
 
 // Compute implements the domain.Calculator interface.
 // It calls the core Compute function and converts the result to domain format,
 // including calculating the overage (difference between total items and requested amount).
+// A call identical to one already in flight waits for that result instead of taking a worker.
 func (s *Service) Compute(ctx context.Context, amount int, sizes []int) (domain.CalculationResult, error) {
-	res := Compute(amount, sizes)
+	ctx, span := startSpan(ctx, "calculator.Compute", sizes, attribute.Int("calc.amount", amount))
+	out, err := s.flights.do(ctx, flightKey(amount, sizes, domain.CalcOptions{TieBreak: s.tieBreak}), func() (domain.CalculationResult, error) {
+		if err := s.pool.acquire(ctx); err != nil {
+			return domain.CalculationResult{}, err
+		}
+		defer s.pool.release()
+		
+		results, err := computeMany(ctx, []int{amount}, sizes, domain.CalcOptions{TieBreak: s.tieBreak}, s.tables.get)
+		if err != nil {
+			return domain.CalculationResult{}, err
+		}
+		return toCalculationResult(amount, results[0])
+	})
+	endSpan(span, out, err)
+	return out, err
+}
+
+// ComputeWithOptions implements the domain.Calculator interface.
+// An empty tie-break policy in opts falls back to the service default.
+// Preferred sizes change the table itself, so those calculations bypass the table cache, as do
+// Uncached ones.
+// With SummaryOnly the result has no Breakdown. When MaxPacks is too small to reach the amount
+// the *domain.NoSolutionError says so.
+// Like Compute, a call identical to one already in flight, options included, waits for that
+// result instead of taking a worker.
+func (s *Service) ComputeWithOptions(ctx context.Context, amount int, sizes []int, opts domain.CalcOptions) (domain.CalculationResult, error) {
+	if opts.TieBreak == "" {
+		opts.TieBreak = s.tieBreak
+	}
+	ctx, span := startSpan(ctx, "calculator.ComputeWithOptions", sizes,
+		attribute.Int("calc.amount", amount), attribute.String("calc.tie_break", string(opts.TieBreak)))
+	out, err := s.flights.do(ctx, flightKey(amount, sizes, opts), func() (domain.CalculationResult, error) {
+		if err := s.pool.acquire(ctx); err != nil {
+			return domain.CalculationResult{}, err
+		}
+		defer s.pool.release()
+		
+		results, err := computeMany(ctx, []int{amount}, sizes, opts, s.tablesFor(opts))
+		if err != nil {
+			return domain.CalculationResult{}, err
+		}
+		return toOptionsResult(amount, sizes, opts, results[0])
+	})
+	endSpan(span, out, err)
+	return out, err
+}
+
+// tablesFor returns where calculations with opts get their DP tables: the table cache, or
+// one-off tables when preferred sizes change the table or the caller asks to stay uncached.
+func (s *Service) tablesFor(opts domain.CalcOptions) tableSource {
+	if len(opts.Preferred) > 0 || opts.Uncached {
+		return freshTable(preferredSet(opts.Preferred))
+	}
+	return s.tables.get
+}
+
+// toOptionsResult works like toCalculationResult, but explains an infeasible result in terms
+// of the options constraining the solution when there are any.
+func toOptionsResult(amount int, sizes []int, opts domain.CalcOptions, res Result) (domain.CalculationResult, error) {
+	if !res.Feasible && len(sizes) > 0 && amount <= domain.MaxLimit {
+		if reason := constraintReason(opts); reason != "" {
+			return domain.CalculationResult{}, &domain.NoSolutionError{Amount: amount, Reason: reason}
+		}
+	}
+	return toCalculationResult(amount, res)
+}
+
+// constraintReason explains an infeasible result in terms of the options that constrain the
+// solution, or returns "" when none do.
+func constraintReason(opts domain.CalcOptions) string {
+	switch {
+	case opts.MaxPacks > 0 && opts.MinUtilization > 0:
+		return fmt.Sprintf("no combination of at most %d packs fulfills the amount with every pack at least %g%% utilized", opts.MaxPacks, opts.MinUtilization*100)
+	case opts.MaxPacks > 0:
+		return fmt.Sprintf("no combination of at most %d packs fulfills the amount", opts.MaxPacks)
+	case opts.MinUtilization > 0:
+		return fmt.Sprintf("no combination of packs fulfills the amount with every pack at least %g%% utilized", opts.MinUtilization*100)
+	}
+	return ""
+}
+
+// ExactFit implements the domain.Calculator interface.
+func (s *Service) ExactFit(ctx context.Context, amount int, sizes []int) (bool, error) {
+	if err := s.pool.acquire(ctx); err != nil {
+		return false, err
+	}
+	defer s.pool.release()
+	
+	return exactFit(ctx, amount, sizes)
+}
+
+// ComputeBatch implements the domain.Calculator interface.
+// All amounts share a single DP table since they use the same pack sizes, so a batch takes one worker.
+// If any amount has no solution, the batch fails with that amount's *domain.NoSolutionError.
+func (s *Service) ComputeBatch(ctx context.Context, amounts []int, sizes []int) ([]domain.CalculationResult, error) {
+	ctx, span := startSpan(ctx, "calculator.ComputeBatch", sizes, attribute.Int("calc.amounts", len(amounts)))
+	if err := s.pool.acquire(ctx); err != nil {
+		endSpanErr(span, err)
+		return nil, err
+	}
+	defer s.pool.release()
+	
+	results, err := computeMany(ctx, amounts, sizes, domain.CalcOptions{TieBreak: s.tieBreak}, s.tables.get)
+	if err != nil {
+		endSpanErr(span, err)
+		return nil, err
+	}
+	out := make([]domain.CalculationResult, len(results))
+	for i, res := range results {
+		var err error
+		if out[i], err = toCalculationResult(amounts[i], res); err != nil {
+			endSpanErr(span, err)
+			return nil, err
+		}
+	}
+	endSpanErr(span, nil)
+	return out, nil
+}
+
+// ComputeBatchWithOptions implements the domain.Calculator interface.
+// Options apply as in ComputeWithOptions; the amounts still share one DP table and one worker.
+func (s *Service) ComputeBatchWithOptions(ctx context.Context, amounts []int, sizes []int, opts domain.CalcOptions) ([]domain.CalculationResult, error) {
+	if opts.TieBreak == "" {
+		opts.TieBreak = s.tieBreak
+	}
+	ctx, span := startSpan(ctx, "calculator.ComputeBatchWithOptions", sizes,
+		attribute.Int("calc.amounts", len(amounts)), attribute.String("calc.tie_break", string(opts.TieBreak)))
+	if err := s.pool.acquire(ctx); err != nil {
+		endSpanErr(span, err)
+		return nil, err
+	}
+	defer s.pool.release()
+
+	results, err := computeMany(ctx, amounts, sizes, opts, s.tablesFor(opts))
+	if err != nil {
+		endSpanErr(span, err)
+		return nil, err
+	}
+	out := make([]domain.CalculationResult, len(results))
+	for i, res := range results {
+		var err error
+		if out[i], err = toOptionsResult(amounts[i], sizes, opts, res); err != nil {
+			endSpanErr(span, err)
+			return nil, err
+		}
+	}
+	endSpanErr(span, nil)
+	return out, nil
+}
+
+// toCalculationResult converts a Result for amount to domain format, splitting the difference
+// between total items and the requested amount into overage and shortfall, both non-negative.
+// A non-positive amount asks for nothing, so it has neither. An infeasible result becomes a
+// *domain.NoSolutionError, so callers can tell it apart from the zero result of empty input.
+func toCalculationResult(amount int, res Result) (domain.CalculationResult, error) {
+	if !res.Feasible {
+		reason := "no combination of whole packs fulfills the amount"
+		if amount > domain.MaxLimit {
+			reason = "amount exceeds the largest amount the calculator supports"
+		}
+		return domain.CalculationResult{}, &domain.NoSolutionError{Amount: amount, Reason: reason}
+	}
+	need := max(amount, 0)
 	return domain.CalculationResult{
-		Amount:     amount,
-		TotalItems: res.TotalItems,
-		Overage:    res.TotalItems - amount,
-		TotalPacks: res.TotalPacks,
-		Breakdown:  res.Counts,
+		Amount:        amount,
+		TotalItems:    res.TotalItems,
+		Overage:       max(res.TotalItems-need, 0),
+		Shortfall:     max(need-res.TotalItems, 0),
+		TotalPacks:    res.TotalPacks,
+		Breakdown:     res.Counts,
+		BreakdownList: domain.NewBreakdownList(res.Counts),
 	}, nil
 }
@@ -1,7 +1,14 @@
 package calculator
 
 import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
 )
 
 func TestCompute_StandardPackSizes(t *testing.T) {
@@ -270,15 +277,15 @@ func TestCompute_OptimizationRules(t *testing.T) {
 func TestCompute_EmptyAndInvalidInputs(t *testing.T) {
 	t.Run("Empty sizes", func(t *testing.T) {
 		res := Compute(100, []int{})
-		if res.TotalItems != 0 || res.TotalPacks != 0 {
-			t.Errorf("Expected empty result, got %+v", res)
+		if res.TotalItems != 0 || res.TotalPacks != 0 || res.Feasible {
+			t.Errorf("Expected an infeasible empty result, got %+v", res)
 		}
 	})
 
 	t.Run("Zero amount", func(t *testing.T) {
 		res := Compute(0, []int{250, 500})
-		if res.TotalItems != 0 || res.TotalPacks != 0 {
-			t.Errorf("Expected empty result for zero amount, got %+v", res)
+		if res.TotalItems != 0 || res.TotalPacks != 0 || !res.Feasible {
+			t.Errorf("Expected a feasible empty result for zero amount, got %+v", res)
 		}
 	})
 
@@ -331,3 +338,913 @@ func TestCompute_SpecialScenarios(t *testing.T) {
 		}
 	})
 }
+
+func BenchmarkCompute(b *testing.B) {
+	manySizes := make([]int, 0, 50)
+	for s := 97; len(manySizes) < cap(manySizes); s += 89 {
+		manySizes = append(manySizes, s)
+	}
+
+	benchmarks := []struct {
+		name   string
+		amount int
+		sizes  []int
+	}{
+		{name: "standard sizes, medium amount", amount: 12001, sizes: []int{250, 500, 1000, 2000, 5000}},
+		{name: "coprime small sizes, one million", amount: 1_000_000, sizes: []int{23, 31, 53}},
+		{name: "many sizes, medium amount", amount: 100_000, sizes: manySizes},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			sizes := make([]int, len(bm.sizes))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				copy(sizes, bm.sizes)
+				Compute(bm.amount, sizes)
+			}
+		})
+	}
+}
+
+func TestSanitizeSizes(t *testing.T) {
+	tests := []struct {
+		sizes []int
+		want  []int
+	}{
+		{[]int{250, 500, 1000}, []int{250, 500, 1000}},
+		{[]int{1000, 250, 500, 250}, []int{250, 500, 1000}},
+		{[]int{250, 0, -5, 500}, []int{250, 500}},
+		{[]int{500, 500}, []int{500}},
+		{nil, []int{}},
+	}
+	for _, tt := range tests {
+		got := sanitizeSizes(append([]int(nil), tt.sizes...))
+		if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("sanitizeSizes(%v) = %v, want %v", tt.sizes, got, tt.want)
+		}
+	}
+	
+	// Already clean sizes skip the map and sort entirely
+	sorted := []int{250, 500, 1000, 2000, 5000}
+	if allocs := testing.AllocsPerRun(100, func() { sanitizeSizes(sorted) }); allocs != 0 {
+		t.Errorf("Expected no allocations for sorted sizes, got %v", allocs)
+	}
+}
+
+// BenchmarkSanitizeSizes shows what the already-sorted fast path saves on every calculation
+// with the active sizes, compared with sizes that need deduplicating and sorting.
+func BenchmarkSanitizeSizes(b *testing.B) {
+	sorted := []int{250, 500, 1000, 2000, 5000}
+	unsorted := []int{5000, 250, 2000, 500, 1000, 250}
+	
+	b.Run("sorted", func(b *testing.B) {
+		buf := make([]int, len(sorted))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			copy(buf, sorted)
+			sanitizeSizes(buf)
+		}
+	})
+	b.Run("unsorted", func(b *testing.B) {
+		buf := make([]int, len(unsorted))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			copy(buf, unsorted)
+			sanitizeSizes(buf)
+		}
+	})
+}
+
+func TestComputeMany_MatchesCompute(t *testing.T) {
+	sizes := []int{23, 31, 53}
+	amounts := []int{500000, 1, 263, 0, 12001, 263}
+	
+	results := ComputeMany(amounts, append([]int(nil), sizes...))
+	if len(results) != len(amounts) {
+		t.Fatalf("Expected %d results, got %d", len(amounts), len(results))
+	}
+	
+	for i, amount := range amounts {
+		expected := Compute(amount, append([]int(nil), sizes...))
+		if results[i].TotalItems != expected.TotalItems || results[i].TotalPacks != expected.TotalPacks {
+			t.Errorf("Amount %d: expected %d items / %d packs, got %d / %d",
+				amount, expected.TotalItems, expected.TotalPacks, results[i].TotalItems, results[i].TotalPacks)
+		}
+	}
+}
+
+func TestComputeWithOptions_SummaryOnly(t *testing.T) {
+	sizes := []int{23, 31, 53}
+	
+	for _, policy := range []domain.TieBreak{domain.TieBreakItemsFirst, domain.TieBreakPacksFirst} {
+		for _, amount := range []int{1, 263, 12001, 500000} {
+			full := ComputeWithOptions(amount, append([]int(nil), sizes...), domain.CalcOptions{TieBreak: policy})
+			summary := ComputeWithOptions(amount, append([]int(nil), sizes...), domain.CalcOptions{TieBreak: policy, SummaryOnly: true})
+			if summary.Counts != nil {
+				t.Errorf("%s, amount %d: expected no breakdown, got %v", policy, amount, summary.Counts)
+			}
+			if summary.TotalItems != full.TotalItems || summary.TotalPacks != full.TotalPacks || !summary.Feasible {
+				t.Errorf("%s, amount %d: expected %d items / %d packs, got %+v",
+					policy, amount, full.TotalItems, full.TotalPacks, summary)
+			}
+		}
+	}
+}
+
+func TestComputeWithOptions_MinOnePerSize(t *testing.T) {
+	sizes := []int{250, 500, 1000}
+	tests := []struct {
+		amount int
+		items  int
+		counts map[int]int
+	}{
+		{1, 1750, map[int]int{250: 1, 500: 1, 1000: 1}},    // The bundle alone covers the amount
+		{1750, 1750, map[int]int{250: 1, 500: 1, 1000: 1}}, // Exactly the bundle
+		{2000, 2000, map[int]int{250: 2, 500: 1, 1000: 1}}, // 250 left over
+		{2600, 2750, map[int]int{250: 1, 500: 1, 1000: 2}}, // 850 left over: one 1000 beats 250+250+500
+	}
+	for _, tt := range tests {
+		res := ComputeWithOptions(tt.amount, append([]int(nil), sizes...), domain.CalcOptions{MinOnePerSize: true})
+		if !res.Feasible || res.TotalItems != tt.items || !reflect.DeepEqual(res.Counts, tt.counts) {
+			t.Errorf("Amount %d: expected %d items as %v, got %+v", tt.amount, tt.items, tt.counts, res)
+		}
+	}
+
+	// Every size appears, and the rest is filled exactly as an unconstrained calculation would fill it
+	odd := []int{23, 31, 53}
+	for _, policy := range []domain.TieBreak{domain.TieBreakItemsFirst, domain.TieBreakPacksFirst} {
+		for _, amount := range []int{50, 107, 108, 263, 12001} {
+			res := ComputeWithOptions(amount, append([]int(nil), odd...), domain.CalcOptions{TieBreak: policy, MinOnePerSize: true})
+			rest := ComputeWithOptions(amount-107, append([]int(nil), odd...), domain.CalcOptions{TieBreak: policy})
+			packs := 0
+			for _, s := range odd {
+				if res.Counts[s] < 1 {
+					t.Errorf("%s, amount %d: expected at least one pack of %d, got %v", policy, amount, s, res.Counts)
+				}
+				packs += res.Counts[s]
+			}
+			if res.TotalItems != rest.TotalItems+107 || res.TotalPacks != rest.TotalPacks+3 || res.TotalPacks != packs {
+				t.Errorf("%s, amount %d: expected %d items / %d packs, got %+v",
+					policy, amount, rest.TotalItems+107, rest.TotalPacks+3, res)
+			}
+
+			summary := ComputeWithOptions(amount, append([]int(nil), odd...), domain.CalcOptions{TieBreak: policy, MinOnePerSize: true, SummaryOnly: true})
+			if summary.Counts != nil || summary.TotalItems != res.TotalItems || summary.TotalPacks != res.TotalPacks {
+				t.Errorf("%s, amount %d: expected summary %d items / %d packs, got %+v", policy, amount, res.TotalItems, res.TotalPacks, summary)
+			}
+		}
+	}
+}
+
+func TestCompute_PerformanceRegressionGuard(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping performance guard in short mode")
+	}
+
+	// Generous bound: the O(amount × sizes) DP finishes in well under a second on CI.
+	// An accidental O(n²) regression would blow far past this.
+	const limit = 5 * time.Second
+
+	start := time.Now()
+	res := Compute(1_000_000, []int{23, 31, 53})
+	elapsed := time.Since(start)
+
+	if res.TotalItems != 1_000_000 {
+		t.Errorf("Expected exact fit of 1000000 items, got %d", res.TotalItems)
+	}
+	if elapsed > limit {
+		t.Errorf("Compute(1000000, [23 31 53]) took %v, expected under %v", elapsed, limit)
+	}
+}
+
+func TestComputeWithOptions_PreferredBreaksTiesOnly(t *testing.T) {
+	tests := []struct {
+		name          string
+		amount        int
+		sizes         []int
+		preferred     []int
+		expectedItems int
+		expectedPacks int
+		expectedCount map[int]int
+	}{
+		{"no preference keeps default tie-break", 6, []int{2, 3, 4}, nil, 6, 2, map[int]int{2: 1, 4: 1}},
+		{"preference picks tied alternative", 6, []int{2, 3, 4}, []int{3}, 6, 2, map[int]int{3: 2}},
+		{"preference never adds packs", 5, []int{1, 5}, []int{1}, 5, 1, map[int]int{5: 1}},
+		{"preference never adds items", 5, []int{3, 5}, []int{3}, 5, 1, map[int]int{5: 1}},
+		{"unknown preferred size ignored", 6, []int{2, 3, 4}, []int{7}, 6, 2, map[int]int{2: 1, 4: 1}},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := ComputeWithOptions(tt.amount, tt.sizes, domain.CalcOptions{Preferred: tt.preferred})
+			baseline := Compute(tt.amount, append([]int(nil), tt.sizes...))
+			
+			if res.TotalItems != tt.expectedItems || res.TotalPacks != tt.expectedPacks {
+				t.Errorf("Expected %d items / %d packs, got %d / %d", tt.expectedItems, tt.expectedPacks, res.TotalItems, res.TotalPacks)
+			}
+			if res.TotalItems != baseline.TotalItems || res.TotalPacks != baseline.TotalPacks {
+				t.Errorf("Preference changed the primary objective: %+v vs baseline %+v", res, baseline)
+			}
+			if len(res.Counts) != len(tt.expectedCount) {
+				t.Fatalf("Expected counts %v, got %v", tt.expectedCount, res.Counts)
+			}
+			for s, c := range tt.expectedCount {
+				if res.Counts[s] != c {
+					t.Errorf("Expected counts %v, got %v", tt.expectedCount, res.Counts)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeWithOptions_TieBreak(t *testing.T) {
+	tests := []struct {
+		name          string
+		amount        int
+		sizes         []int
+		tieBreak      domain.TieBreak
+		expectedItems int
+		expectedPacks int
+	}{
+		// The policies diverge: 500+250 has less overage, a single 1000 has fewer packs
+		{"items first takes less overage", 501, []int{250, 500, 1000}, domain.TieBreakItemsFirst, 750, 2},
+		{"packs first takes fewer packs", 501, []int{250, 500, 1000}, domain.TieBreakPacksFirst, 1000, 1},
+		{"empty policy means items first", 501, []int{250, 500, 1000}, "", 750, 2},
+		// Among equal pack counts, packs first still minimizes items
+		{"packs first then items", 1001, []int{500, 1000}, domain.TieBreakPacksFirst, 1500, 2},
+		// Both policies agree when an exact single pack exists
+		{"policies agree on exact fit", 1000, []int{500, 1000}, domain.TieBreakPacksFirst, 1000, 1},
+		{"packs first with no largest-size fit", 12, []int{5, 7}, domain.TieBreakPacksFirst, 12, 2},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := ComputeWithOptions(tt.amount, tt.sizes, domain.CalcOptions{TieBreak: tt.tieBreak})
+			if res.TotalItems != tt.expectedItems || res.TotalPacks != tt.expectedPacks {
+				t.Errorf("Expected %d items / %d packs, got %d / %d (%v)", tt.expectedItems, tt.expectedPacks, res.TotalItems, res.TotalPacks, res.Counts)
+			}
+		})
+	}
+}
+
+func TestCompute_MinimalPacksForSelectedTotal(t *testing.T) {
+	tests := []struct {
+		name          string
+		amount        int
+		sizes         []int
+		tieBreak      domain.TieBreak
+		expectedItems int
+		expectedPacks int
+		expectedCount map[int]int
+	}{
+		// Largest-first would take 4+1+1; the minimum for exactly 6 items is 3+3
+		{"not largest first", 6, []int{1, 3, 4}, domain.TieBreakItemsFirst, 6, 2, map[int]int{3: 2}},
+		// Rounding up to the minimal total still uses its fewest packs
+		{"overage with fewest packs", 11, []int{4, 6}, domain.TieBreakItemsFirst, 12, 2, map[int]int{6: 2}},
+		// PacksFirst may pick a larger total if it needs fewer packs, but never more items than needed for that count
+		{"packs first larger total", 9, []int{1, 10}, domain.TieBreakPacksFirst, 10, 1, map[int]int{10: 1}},
+		{"items first same sizes", 9, []int{1, 10}, domain.TieBreakItemsFirst, 9, 9, map[int]int{1: 9}},
+		{"packs first smallest total among fewest packs", 7, []int{3, 4, 5}, domain.TieBreakPacksFirst, 7, 2, map[int]int{3: 1, 4: 1}},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := ComputeWithOptions(tt.amount, tt.sizes, domain.CalcOptions{TieBreak: tt.tieBreak})
+			if res.TotalItems != tt.expectedItems || res.TotalPacks != tt.expectedPacks {
+				t.Fatalf("Expected %d items / %d packs, got %d / %d (%v)", tt.expectedItems, tt.expectedPacks, res.TotalItems, res.TotalPacks, res.Counts)
+			}
+			if !reflect.DeepEqual(res.Counts, tt.expectedCount) {
+				t.Errorf("Expected breakdown %v, got %v", tt.expectedCount, res.Counts)
+			}
+		})
+	}
+}
+
+func TestService_NoSolution(t *testing.T) {
+	svc := NewService()
+	ctx := context.Background()
+
+	// Nothing to fulfill is not an error
+	if res, err := svc.Compute(ctx, 0, []int{250}); err != nil || res.TotalPacks != 0 {
+		t.Errorf("Expected an empty result for zero amount, got %+v, %v", res, err)
+	}
+
+	var ns *domain.NoSolutionError
+	if _, err := svc.Compute(ctx, 100, []int{0, -5}); !errors.As(err, &ns) || ns.Amount != 100 {
+		t.Errorf("Expected a NoSolutionError for amount 100 without usable sizes, got %v", err)
+	}
+	if _, err := svc.ComputeWithOptions(ctx, 100, nil, domain.CalcOptions{}); !errors.As(err, &ns) {
+		t.Errorf("Expected a NoSolutionError from ComputeWithOptions, got %v", err)
+	}
+	if _, err := svc.ComputeBatch(ctx, []int{0, 7}, nil); !errors.As(err, &ns) || ns.Amount != 7 {
+		t.Errorf("Expected the batch to fail on amount 7, got %v", err)
+	}
+}
+
+func TestService_CancelledContextStopsCalculation(t *testing.T) {
+	svc := NewService().WithWorkers(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Each of these would fill a table of about a billion cells if the context were ignored
+	amount := domain.MaxLimit
+	calls := map[string]func() error{
+		"Compute": func() error {
+			_, err := svc.Compute(ctx, amount, []int{3, 7})
+			return err
+		},
+		"ComputeWithOptions": func() error {
+			_, err := svc.ComputeWithOptions(ctx, amount, []int{3, 7}, domain.CalcOptions{Uncached: true, MinUtilization: 0.5})
+			return err
+		},
+		"ComputeBatch": func() error {
+			_, err := svc.ComputeBatch(ctx, []int{10, amount}, []int{3, 7})
+			return err
+		},
+		"ExactFit": func() error {
+			_, err := svc.ExactFit(ctx, amount, []int{3, 7})
+			return err
+		},
+	}
+	for name, call := range calls {
+		start := time.Now()
+		if err := call(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v", name, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: took %s after its context was cancelled", name, elapsed)
+		}
+		// The worker is released, so the next calculation isn't stuck behind this one
+		if busy := svc.PoolStats().Busy; busy != 0 {
+			t.Errorf("%s: %d workers still busy", name, busy)
+		}
+	}
+}
+
+// countdownCtx is a context that reports itself cancelled after its first n Err calls,
+// so a calculation can be cancelled deterministically part way through.
+type countdownCtx struct {
+	context.Context
+	n int
+}
+
+func (c *countdownCtx) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestBuildTable_StopsMidFill(t *testing.T) {
+	ctx := &countdownCtx{Context: context.Background(), n: 3}
+	if _, err := buildTable(ctx, 100*cancelCheckCells, []int{3, 7}, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the fill to stop with context.Canceled, got %v", err)
+	}
+	if ctx.n != 0 {
+		t.Errorf("Expected the fill to check its context until cancelled, %d checks left", ctx.n)
+	}
+
+	// A solution search over a wide window stops too
+	tb, err := buildTable(context.Background(), 10, []int{3 * cancelCheckCells}, nil)
+	if err != nil {
+		t.Fatalf("buildTable failed: %v", err)
+	}
+	if _, err := tb.solve(&countdownCtx{Context: context.Background(), n: 1}, 10, inf, domain.CalcOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the search to stop with context.Canceled, got %v", err)
+	}
+}
+
+func TestCompute_ExtremeBounds(t *testing.T) {
+	// At the default limits every breakdown adds up to its total, which stays within one pack of the amount
+	sizes := []int{domain.DefaultMaxPackSize, domain.DefaultMaxPackSize - 1, 4999, 23}
+	for _, amount := range []int{domain.DefaultMaxAmount, domain.DefaultMaxAmount - 1, domain.DefaultMaxPackSize + 1} {
+		res := Compute(amount, sizes)
+		if !res.Feasible {
+			t.Fatalf("Amount %d: expected a solution", amount)
+		}
+		items, packs := 0, 0
+		for s, c := range res.Counts {
+			items += s * c
+			packs += c
+		}
+		if items != res.TotalItems || packs != res.TotalPacks {
+			t.Errorf("Amount %d: breakdown %v adds up to %d items in %d packs, result says %d in %d", amount, res.Counts, items, packs, res.TotalItems, res.TotalPacks)
+		}
+		if res.TotalItems < amount || res.TotalItems >= amount+domain.DefaultMaxPackSize {
+			t.Errorf("Amount %d: total %d is outside [amount, amount + largest size)", amount, res.TotalItems)
+		}
+	}
+
+	// Amounts and sizes beyond domain.MaxLimit would overflow the table bounds; they have no solution
+	// instead, without building a table
+	for _, amount := range []int{domain.MaxLimit + 1, math.MaxInt} {
+		if res := Compute(amount, []int{250, 500}); res.Feasible {
+			t.Errorf("Amount %d: expected no solution, got %+v", amount, res)
+		}
+		if ExactFit(amount, []int{1}) {
+			t.Errorf("Amount %d: expected no exact fit", amount)
+		}
+	}
+	if res := Compute(251, []int{math.MaxInt, domain.MaxLimit + 1, 250}); res.TotalItems != 500 || len(res.Counts) != 1 {
+		t.Errorf("Expected sizes above MaxLimit to be dropped, got %+v", res)
+	}
+	// One pack of each size would total more than MaxLimit
+	huge := []int{domain.MaxLimit, domain.MaxLimit - 1, 250}
+	if res := ComputeWithOptions(251, huge, domain.CalcOptions{MinOnePerSize: true}); res.Feasible {
+		t.Errorf("Expected no solution for a bundle above MaxLimit, got %+v", res)
+	}
+
+	// Amounts in a batch are judged on their own
+	results := ComputeMany([]int{251, math.MaxInt, 12001}, []int{250, 500, 1000, 2000, 5000})
+	if !results[0].Feasible || results[1].Feasible || !results[2].Feasible || results[2].TotalItems != 12250 {
+		t.Errorf("Expected only the out-of-range amount to fail, got %+v", results)
+	}
+
+	var ns *domain.NoSolutionError
+	_, err := NewService().ComputeWithOptions(context.Background(), math.MaxInt, []int{250}, domain.CalcOptions{MaxPacks: 3})
+	if !errors.As(err, &ns) || ns.Reason != "amount exceeds the largest amount the calculator supports" {
+		t.Errorf("Expected a NoSolutionError naming the supported range, got %v", err)
+	}
+}
+
+func TestToCalculationResult_OverageAndShortfall(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    int
+		items     int
+		overage   int
+		shortfall int
+	}{
+		{"rounded up", 251, 500, 249, 0},
+		{"exact", 500, 500, 0, 0},
+		{"under-fulfilled", 600, 500, 0, 100},
+		{"zero amount", 0, 0, 0, 0},
+		{"negative amount", -5, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		res, err := toCalculationResult(tt.amount, Result{TotalItems: tt.items, Feasible: true})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		if res.Overage != tt.overage || res.Shortfall != tt.shortfall {
+			t.Errorf("%s: expected overage %d and shortfall %d, got %d and %d",
+				tt.name, tt.overage, tt.shortfall, res.Overage, res.Shortfall)
+		}
+	}
+
+	// Round-up calculations never fall short
+	svc := NewService()
+	for _, amount := range []int{1, 250, 251, 12001} {
+		res, err := svc.Compute(context.Background(), amount, []int{250, 500, 1000})
+		if err != nil || res.Shortfall != 0 || res.Overage != res.TotalItems-amount {
+			t.Errorf("Amount %d: expected overage %d and no shortfall, got %+v, %v", amount, res.TotalItems-amount, res, err)
+		}
+	}
+}
+
+func TestService_DefaultTieBreak(t *testing.T) {
+	ctx := context.Background()
+	svc := NewServiceWithTieBreak(domain.TieBreakPacksFirst)
+	
+	res, _ := svc.Compute(ctx, 501, []int{250, 500, 1000})
+	if res.TotalPacks != 1 || res.TotalItems != 1000 {
+		t.Errorf("Expected service default PacksFirst to ship 1 pack of 1000, got %+v", res)
+	}
+	
+	// A per-request policy overrides the service default
+	res, _ = svc.ComputeWithOptions(ctx, 501, []int{250, 500, 1000}, domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst})
+	if res.TotalItems != 750 {
+		t.Errorf("Expected ItemsFirst override to ship 750 items, got %+v", res)
+	}
+	
+	batch, _ := svc.ComputeBatch(ctx, []int{501}, []int{250, 500, 1000})
+	if batch[0].TotalPacks != 1 {
+		t.Errorf("Expected batch to use the service default, got %+v", batch[0])
+	}
+}
+
+func TestExactFit(t *testing.T) {
+	tests := []struct {
+		amount int
+		sizes  []int
+		want   bool
+	}{
+		{750, []int{250, 500, 1000}, true},
+		{751, []int{250, 500, 1000}, false},
+		{263, []int{23, 31, 53}, true}, // 23*2 + 31*7
+		{7, []int{4, 6}, false},
+		{10, []int{4, 6}, true},
+		{5, []int{0, -5, 5}, true},
+		{0, []int{250}, false},
+		{100, nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := ExactFit(tt.amount, tt.sizes); got != tt.want {
+			t.Errorf("ExactFit(%d, %v) = %v, want %v", tt.amount, tt.sizes, got, tt.want)
+		}
+		// Agrees with the full calculation whenever there is something to calculate
+		if tt.amount > 0 && len(tt.sizes) > 0 {
+			if res := Compute(tt.amount, append([]int(nil), tt.sizes...)); (res.TotalItems == tt.amount) != tt.want {
+				t.Errorf("ExactFit(%d, %v) disagrees with Compute total %d", tt.amount, tt.sizes, res.TotalItems)
+			}
+		}
+	}
+}
+
+// FuzzCompute checks the invariants every Compute result must satisfy, against an independent
+// reachability DP: the order is covered with the fewest items possible, the breakdown adds up,
+// and no solution with the same items uses fewer packs. Empty results are only allowed when
+// there is nothing to compute.
+//
+// sizesRaw is read as little-endian uint16 pack sizes (zeros and duplicates included);
+// big, if non-zero, adds one size of up to 1,000,000 items.
+func FuzzCompute(f *testing.F) {
+	f.Add(12001, []byte{250, 0, 244, 1, 232, 3, 208, 7, 136, 19}, 0) // 250, 500, 1000, 2000, 5000
+	f.Add(501, []byte{250, 0, 244, 1}, 0)
+	f.Add(100, []byte{}, 0)
+	f.Add(0, []byte{23, 0}, 0)
+	f.Add(-5, []byte{23, 0}, 0)
+	f.Add(263, []byte{0, 0, 23, 0, 23, 0, 31, 0, 53, 0}, 0) // Zero and duplicate sizes
+	f.Add(7, []byte{4, 0, 6, 0}, 0)                         // No exact solution
+	f.Add(1, []byte{}, 1_000_000)                           // Very large single size
+	f.Add(999_999, []byte{1, 0}, 1_000_000)
+
+	f.Fuzz(func(t *testing.T, amount int, sizesRaw []byte, big int) {
+		amount %= 1_000_001
+		var sizes []int
+		for i := 0; i+1 < len(sizesRaw) && len(sizes) < 8; i += 2 {
+			sizes = append(sizes, int(sizesRaw[i])|int(sizesRaw[i+1])<<8)
+		}
+		if big %= 1_000_001; big != 0 {
+			sizes = append(sizes, big)
+		}
+
+		res := Compute(amount, append([]int(nil), sizes...))
+
+		valid := map[int]bool{}
+		maxS := 0
+		for _, s := range sizes {
+			if s > 0 {
+				valid[s] = true
+				maxS = max(maxS, s)
+			}
+		}
+		if amount <= 0 || len(valid) == 0 {
+			if res.TotalItems != 0 || res.TotalPacks != 0 || len(res.Counts) != 0 {
+				t.Fatalf("Compute(%d, %v): expected empty result, got %+v", amount, sizes, res)
+			}
+			return
+		}
+
+		// The breakdown uses only given sizes, with positive counts adding up to the totals
+		items, packs := 0, 0
+		for s, c := range res.Counts {
+			if !valid[s] || c <= 0 {
+				t.Fatalf("Compute(%d, %v): invalid breakdown entry %d x %d", amount, sizes, s, c)
+			}
+			items += s * c
+			packs += c
+		}
+		if items != res.TotalItems || packs != res.TotalPacks {
+			t.Fatalf("Compute(%d, %v): breakdown %v doesn't add up to %d items in %d packs", amount, sizes, res.Counts, res.TotalItems, res.TotalPacks)
+		}
+
+		// Whole packs of the largest size always cover the order within one pack of overage
+		if res.TotalItems < amount || res.TotalItems >= amount+maxS {
+			t.Fatalf("Compute(%d, %v): total %d outside [%d, %d)", amount, sizes, res.TotalItems, amount, amount+maxS)
+		}
+
+		// Reference DP: fewest packs for every total up to the result
+		minPacks := make([]int, res.TotalItems+1)
+		for i := 1; i <= res.TotalItems; i++ {
+			minPacks[i] = -1
+			for s := range valid {
+				if s <= i && minPacks[i-s] >= 0 && (minPacks[i] < 0 || minPacks[i-s]+1 < minPacks[i]) {
+					minPacks[i] = minPacks[i-s] + 1
+				}
+			}
+		}
+		for i := amount; i < res.TotalItems; i++ {
+			if minPacks[i] >= 0 {
+				t.Fatalf("Compute(%d, %v): %d items are reachable, fewer than %d", amount, sizes, i, res.TotalItems)
+			}
+		}
+		if minPacks[res.TotalItems] != res.TotalPacks {
+			t.Fatalf("Compute(%d, %v): %d items need only %d packs, got %d", amount, sizes, res.TotalItems, minPacks[res.TotalItems], res.TotalPacks)
+		}
+	})
+}
+
+// bruteForce is a test oracle: it enumerates every combination of pack counts that can be optimal
+// and returns the best one under tieBreak, without any DP. Items and packs decide; the breakdown
+// itself is not unique, so only the totals are returned.
+//
+// No optimal solution reaches amount+maxS items: dropping any pack from such a combination would
+// still cover the order with fewer items and fewer packs. That bounds the search for both policies.
+func bruteForce(amount int, sizes []int, tieBreak domain.TieBreak) (items, packs int) {
+	maxS := 0
+	for _, s := range sizes {
+		maxS = max(maxS, s)
+	}
+	limit := amount + maxS - 1
+
+	better := func(i, p int) bool {
+		if items == 0 {
+			return true
+		}
+		if tieBreak == domain.TieBreakPacksFirst {
+			return p < packs || (p == packs && i < items)
+		}
+		return i < items || (i == items && p < packs)
+	}
+
+	var walk func(idx, total, count int)
+	walk = func(idx, total, count int) {
+		if idx == len(sizes) {
+			if total >= amount && better(total, count) {
+				items, packs = total, count
+			}
+			return
+		}
+		for n := 0; total+n*sizes[idx] <= limit; n++ {
+			walk(idx+1, total+n*sizes[idx], count+n)
+		}
+	}
+	walk(0, 0, 0)
+	return items, packs
+}
+
+func TestCompute_MatchesBruteForce(t *testing.T) {
+	sizeSets := [][]int{
+		{1},
+		{3},
+		{2, 3},
+		{3, 5},
+		{4, 6},
+		{6, 9, 20},
+		{5, 7, 11},
+		{2, 3, 4},
+		{1, 5, 6, 9},
+		{7, 13, 17, 19},
+		{10, 25, 40},
+		{23, 31, 53},
+	}
+	policies := []domain.TieBreak{domain.TieBreakItemsFirst, domain.TieBreakPacksFirst}
+
+	for _, sizes := range sizeSets {
+		for amount := 1; amount <= 120; amount++ {
+			for _, policy := range policies {
+				wantItems, wantPacks := bruteForce(amount, sizes, policy)
+				res := ComputeWithOptions(amount, append([]int(nil), sizes...), domain.CalcOptions{TieBreak: policy})
+				if res.TotalItems != wantItems || res.TotalPacks != wantPacks {
+					t.Errorf("%s: Compute(%d, %v) = %d items / %d packs, brute force finds %d / %d",
+						policy, amount, sizes, res.TotalItems, res.TotalPacks, wantItems, wantPacks)
+				}
+			}
+		}
+	}
+}
+
+func TestComputeWithOptions_MaxPacks(t *testing.T) {
+	tests := []struct {
+		amount   int
+		sizes    []int
+		maxPacks int
+		feasible bool
+		items    int
+		counts   map[int]int
+	}{
+		{9, []int{3, 5}, 0, true, 9, map[int]int{3: 3}},  // No limit
+		{9, []int{3, 5}, 3, true, 9, map[int]int{3: 3}},  // The limit isn't binding
+		{9, []int{3, 5}, 2, true, 10, map[int]int{5: 2}}, // Two packs cost an extra item
+		{11, []int{3, 5}, 2, false, 0, nil},              // Two packs reach at most 10
+		{1001, []int{250, 500, 1000}, 1, false, 0, nil},  // No single pack covers it
+		{1001, []int{250, 500, 1000}, 2, true, 1250, map[int]int{250: 1, 1000: 1}},
+		{0, []int{3, 5}, 1, true, 0, map[int]int{}}, // Nothing to fulfill
+	}
+	for _, tt := range tests {
+		res := ComputeWithOptions(tt.amount, tt.sizes, domain.CalcOptions{MaxPacks: tt.maxPacks})
+		if res.Feasible != tt.feasible {
+			t.Errorf("Amount %d, max %d packs: expected feasible=%v, got %+v", tt.amount, tt.maxPacks, tt.feasible, res)
+			continue
+		}
+		if tt.feasible && (res.TotalItems != tt.items || !reflect.DeepEqual(res.Counts, tt.counts)) {
+			t.Errorf("Amount %d, max %d packs: expected %d items as %v, got %+v", tt.amount, tt.maxPacks, tt.items, tt.counts, res)
+		}
+	}
+
+	// Against brute force: the fewest items reachable with at most maxPacks packs (fewest packs on ties)
+	sizes := []int{4, 7, 11}
+	for maxPacks := 1; maxPacks <= 4; maxPacks++ {
+		for amount := 1; amount <= 50; amount++ {
+			bestItems, bestPacks := -1, 0
+			for a := 0; a <= maxPacks; a++ {
+				for b := 0; a+b <= maxPacks; b++ {
+					for c := 0; a+b+c <= maxPacks; c++ {
+						items := 4*a + 7*b + 11*c
+						if items >= amount && (bestItems == -1 || items < bestItems || items == bestItems && a+b+c < bestPacks) {
+							bestItems, bestPacks = items, a+b+c
+						}
+					}
+				}
+			}
+			res := ComputeWithOptions(amount, sizes, domain.CalcOptions{MaxPacks: maxPacks})
+			if res.Feasible != (bestItems != -1) || res.Feasible && (res.TotalItems != bestItems || res.TotalPacks != bestPacks) {
+				t.Errorf("Amount %d, max %d packs: expected %d items / %d packs, got %+v", amount, maxPacks, bestItems, bestPacks, res)
+			}
+		}
+	}
+
+	// PacksFirst already uses the fewest packs, so a limit only decides feasibility
+	if res := ComputeWithOptions(9, []int{3, 5}, domain.CalcOptions{TieBreak: domain.TieBreakPacksFirst, MaxPacks: 2}); !res.Feasible || res.TotalPacks != 2 {
+		t.Errorf("Expected PacksFirst to fit in 2 packs, got %+v", res)
+	}
+	if res := ComputeWithOptions(16, []int{3, 5}, domain.CalcOptions{TieBreak: domain.TieBreakPacksFirst, MaxPacks: 3}); res.Feasible {
+		t.Errorf("Expected no PacksFirst solution for 16 in 3 packs, got %+v", res)
+	}
+}
+
+func TestComputeWithOptions_MaxPacksWithMinOnePerSize(t *testing.T) {
+	sizes := []int{3, 5}
+	// The bundle (3+5) uses two of the packs
+	if res := ComputeWithOptions(9, sizes, domain.CalcOptions{MinOnePerSize: true, MaxPacks: 2}); res.Feasible {
+		t.Errorf("Expected 9 not to fit in the bundle alone, got %+v", res)
+	}
+	if res := ComputeWithOptions(9, sizes, domain.CalcOptions{MinOnePerSize: true, MaxPacks: 3}); !res.Feasible || res.TotalItems != 11 || res.TotalPacks != 3 {
+		t.Errorf("Expected the bundle plus a 3, got %+v", res)
+	}
+	if res := ComputeWithOptions(8, sizes, domain.CalcOptions{MinOnePerSize: true, MaxPacks: 2}); !res.Feasible || res.TotalItems != 8 {
+		t.Errorf("Expected the bundle alone, got %+v", res)
+	}
+	// Fewer packs than sizes can't hold the bundle, even for a tiny amount
+	if res := ComputeWithOptions(1, sizes, domain.CalcOptions{MinOnePerSize: true, MaxPacks: 1}); res.Feasible {
+		t.Errorf("Expected no solution with 1 pack for 2 sizes, got %+v", res)
+	}
+}
+
+func TestService_ComputeWithOptions_MaxPacksNoSolution(t *testing.T) {
+	svc := NewService()
+	_, err := svc.ComputeWithOptions(context.Background(), 11, []int{3, 5}, domain.CalcOptions{MaxPacks: 2})
+	var ns *domain.NoSolutionError
+	if !errors.As(err, &ns) || ns.Amount != 11 || ns.Reason != "no combination of at most 2 packs fulfills the amount" {
+		t.Errorf("Expected a NoSolutionError naming the pack limit, got %v", err)
+	}
+}
+
+func TestService_ComputeBatchWithOptions_Uncached(t *testing.T) {
+	s := NewService()
+	ctx := context.Background()
+	amounts := []int{1, 251, 501, 12001}
+	sizes := []int{250, 500, 1000, 2000, 5000}
+
+	uncached, err := s.ComputeBatchWithOptions(ctx, amounts, sizes, domain.CalcOptions{Uncached: true})
+	if err != nil {
+		t.Fatalf("ComputeBatchWithOptions failed: %v", err)
+	}
+	if len(s.tables.entries) != 0 {
+		t.Errorf("Expected uncached calculations to leave the table cache empty, got %d entries", len(s.tables.entries))
+	}
+
+	cached, err := s.ComputeBatch(ctx, amounts, sizes)
+	if err != nil {
+		t.Fatalf("ComputeBatch failed: %v", err)
+	}
+	if len(s.tables.entries) != 1 {
+		t.Errorf("Expected ComputeBatch to cache its table, got %d entries", len(s.tables.entries))
+	}
+	for i := range amounts {
+		if uncached[i].TotalItems != cached[i].TotalItems || uncached[i].TotalPacks != cached[i].TotalPacks {
+			t.Errorf("Amount %d: uncached %+v differs from cached %+v", amounts[i], uncached[i], cached[i])
+		}
+	}
+}
+
+func TestService_BreakdownListOrdered(t *testing.T) {
+	svc := NewService()
+	sizes := []int{23, 250, 53, 1000, 31, 500}
+
+	first, err := svc.Compute(context.Background(), 12001, sizes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(first.BreakdownList) != len(first.Breakdown) {
+		t.Fatalf("Expected one list entry per breakdown size, got %v for %v", first.BreakdownList, first.Breakdown)
+	}
+	for i, pc := range first.BreakdownList {
+		if i > 0 && pc.Size >= first.BreakdownList[i-1].Size {
+			t.Errorf("Expected sizes in descending order, got %v", first.BreakdownList)
+		}
+		if first.Breakdown[pc.Size] != pc.Count {
+			t.Errorf("Expected %d packs of %d as in the breakdown, got %d", first.Breakdown[pc.Size], pc.Size, pc.Count)
+		}
+	}
+
+	// Map iteration order varies between runs; the list must not
+	for range 20 {
+		again, err := svc.Compute(context.Background(), 12001, sizes)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(again.BreakdownList, first.BreakdownList) {
+			t.Fatalf("Expected the same list on every call, got %v and %v", first.BreakdownList, again.BreakdownList)
+		}
+	}
+
+	summary, err := svc.ComputeWithOptions(context.Background(), 12001, sizes, domain.CalcOptions{SummaryOnly: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if summary.BreakdownList != nil {
+		t.Errorf("Expected no list for a summary, got %v", summary.BreakdownList)
+	}
+}
+
+func TestComputeWithOptions_MinUtilization(t *testing.T) {
+	standard := []int{250, 500, 1000, 2000, 5000}
+
+	// Without a minimum, 5001 opens a 250 pack for the 1 item left after the 5000
+	if res := ComputeWithOptions(5001, standard, domain.CalcOptions{}); !reflect.DeepEqual(res.Counts, map[int]int{5000: 1, 250: 1}) {
+		t.Fatalf("Expected 5000 + 250, got %+v", res)
+	}
+
+	tests := []struct {
+		name     string
+		amount   int
+		sizes    []int
+		minUtil  float64
+		tieBreak domain.TieBreak
+		counts   map[int]int // nil for no solution
+	}{
+		// Every pack at least half used: three 2000s, the last holding 1001 of the 5001
+		{"half full", 5001, standard, 0.5, domain.TieBreakItemsFirst, map[int]int{2000: 3}},
+		{"half full, packs first", 5001, standard, 0.5, domain.TieBreakPacksFirst, map[int]int{2000: 3}},
+		// An exact fit uses every pack fully, whatever the minimum
+		{"exact fit", 5250, standard, 1, domain.TieBreakItemsFirst, map[int]int{5000: 1, 250: 1}},
+		// A minimum the default solution already meets changes nothing: the 5000 holds 4950
+		{"already met", 4950, standard, 0.99, domain.TieBreakItemsFirst, map[int]int{5000: 1}},
+		// One size only: the second 5000 pack would hold a single item
+		{"single size", 5001, []int{5000}, 0.1, domain.TieBreakItemsFirst, nil},
+		{"single size, low minimum", 5001, []int{5000}, 0.0001, domain.TieBreakItemsFirst, map[int]int{5000: 2}},
+	}
+	for _, tt := range tests {
+		res := ComputeWithOptions(tt.amount, tt.sizes, domain.CalcOptions{TieBreak: tt.tieBreak, MinUtilization: tt.minUtil})
+		if tt.counts == nil {
+			if res.Feasible {
+				t.Errorf("%s: expected no solution, got %+v", tt.name, res)
+			}
+			continue
+		}
+		if !res.Feasible || !reflect.DeepEqual(res.Counts, tt.counts) {
+			t.Errorf("%s: expected %v, got %+v", tt.name, tt.counts, res)
+		}
+	}
+
+	// With one of each size the smallest size is always the partly used pack. The bundle 3+5 is
+	// filled 5 first: 7 leaves 2 of the 3 ordered (67%), 6 only 1 (33%) and 4 none at all.
+	for amount, feasible := range map[int]bool{7: true, 6: false, 4: false} {
+		res := ComputeWithOptions(amount, []int{3, 5}, domain.CalcOptions{MinOnePerSize: true, MinUtilization: 0.5})
+		if res.Feasible != feasible || feasible && res.TotalItems != 8 {
+			t.Errorf("Amount %d with one of each size: expected feasible=%v, got %+v", amount, feasible, res)
+		}
+	}
+}
+
+func TestComputeWithOptions_MinUtilizationMatchesBruteForce(t *testing.T) {
+	// The fewest items (fewest packs on ties) among solutions whose smallest pack, filled
+	// last, holds at least minUtil of ordered items
+	bruteForceUtilized := func(amount int, sizes []int, minUtil float64) (items, packs int) {
+		limit := amount + sizes[len(sizes)-1] - 1
+		var walk func(idx, total, count, smallest int)
+		walk = func(idx, total, count, smallest int) {
+			if idx == len(sizes) {
+				over := total - amount
+				if count > 0 && over >= 0 && (over == 0 || float64(smallest-over) >= minUtil*float64(smallest)) &&
+					(items == 0 || total < items || total == items && count < packs) {
+					items, packs = total, count
+				}
+				return
+			}
+			walk(idx+1, total, count, smallest)
+			for n := 1; total+n*sizes[idx] <= limit; n++ {
+				walk(idx+1, total+n*sizes[idx], count+n, min(smallest, sizes[idx]))
+			}
+		}
+		walk(0, 0, 0, sizes[len(sizes)-1])
+		return items, packs
+	}
+
+	for _, sizes := range [][]int{{3, 5}, {4, 6, 9}, {5, 7, 11}, {6, 9, 20}, {7, 13, 17, 19}, {10, 25, 40}} {
+		for _, minUtil := range []float64{0.25, 0.5, 0.8} {
+			for amount := 1; amount <= 100; amount++ {
+				wantItems, wantPacks := bruteForceUtilized(amount, sizes, minUtil)
+				res := ComputeWithOptions(amount, append([]int(nil), sizes...), domain.CalcOptions{MinUtilization: minUtil})
+				if res.Feasible != (wantItems > 0) || res.Feasible && (res.TotalItems != wantItems || res.TotalPacks != wantPacks) {
+					t.Errorf("%v, %g, amount %d: expected %d items / %d packs, got %+v", sizes, minUtil, amount, wantItems, wantPacks, res)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package calculator
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultTableCacheCells bounds the table cache to about 2 million DP cells (roughly 48 MB),
+// enough for one table covering the 1,000,000-item API limit plus many smaller ones.
+const defaultTableCacheCells = 2_000_000
+
+// tableCache keeps filled DP tables keyed by pack-size set. The DP values for a set of sizes
+// don't depend on the requested amount, so a later request only pays for the cells beyond
+// what earlier requests already computed. Least recently used sets are evicted once the total
+// number of cached cells exceeds maxCells; a single table larger than that is never cached.
+// It is safe for concurrent use.
+type tableCache struct {
+	mu       sync.Mutex
+	maxCells int                      // Upper bound on the summed length of cached tables
+	cells    int                      // Current summed length of cached tables
+	order    *list.List               // Entries by recency, most recent first
+	entries  map[string]*list.Element // Sizes key -> element holding a *tableEntry
+}
+
+// tableEntry is one cached table and its key.
+type tableEntry struct {
+	key string
+	tb  *table
+}
+
+// newTableCache creates an empty cache holding at most maxCells DP cells.
+func newTableCache(maxCells int) *tableCache {
+	return &tableCache{maxCells: maxCells, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns a table for sizes (already sanitized) that answers amounts up to maxAmount.
+// A cached table that is too short is extended rather than rebuilt. Building happens outside
+// the lock, so concurrent callers may occasionally duplicate work, but never block each other
+// for the duration of a DP fill; published tables are immutable, so readers are unaffected.
+// A fill cut short by ctx caches nothing and returns the context's error.
+func (c *tableCache) get(ctx context.Context, maxAmount int, sizes []int) (*table, error) {
+	key := SizesKey(sizes)
+
+	c.mu.Lock()
+	var tb *table
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		tb = el.Value.(*tableEntry).tb
+	}
+	c.mu.Unlock()
+
+	if tb != nil && tb.maxAmount >= maxAmount {
+		return tb, nil
+	}
+	var err error
+	if tb == nil {
+		tb, err = buildTable(ctx, maxAmount, sizes, nil)
+	} else {
+		tb, err = tb.extend(ctx, maxAmount)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, tb)
+	return tb, nil
+}
+
+// put stores tb under key unless an equal or larger table is already cached,
+// then evicts least recently used tables until the cache is within its bound.
+func (c *tableCache) put(key string, tb *table) {
+	n := len(tb.dp)
+	if n > c.maxCells {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*tableEntry)
+		if len(e.tb.dp) >= n {
+			return // A concurrent caller already stored a table at least this large
+		}
+		c.cells += n - len(e.tb.dp)
+		e.tb = tb
+		c.order.MoveToFront(el)
+	} else {
+		c.entries[key] = c.order.PushFront(&tableEntry{key: key, tb: tb})
+		c.cells += n
+	}
+
+	// The new entry is at the front and fits on its own, so eviction never reaches it
+	for c.cells > c.maxCells {
+		el := c.order.Back()
+		e := el.Value.(*tableEntry)
+		c.order.Remove(el)
+		delete(c.entries, e.key)
+		c.cells -= len(e.tb.dp)
+	}
+}
+
+// len returns the number of cached pack-size sets.
+func (c *tableCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// SizesKey returns the canonical key for a set of pack sizes: the sizes the calculator uses,
+// sanitized as every calculation sanitizes them, joined by commas, e.g. "250,500,1000". Order,
+// repeats and invalid sizes don't change the key, and sizes itself is left untouched. It is the
+// one canonical form for sizes in cache and in-flight keys, inside this package and out.
+func SizesKey(sizes []int) string {
+	if !isSanitized(sizes) {
+		sizes = sanitizeSizes(slices.Clone(sizes))
+	}
+	var b strings.Builder
+	for i, s := range sizes {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(s))
+	}
+	return b.String()
+}
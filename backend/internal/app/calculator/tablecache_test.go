@@ -0,0 +1,203 @@
+package calculator
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestTableCache_MatchesCompute(t *testing.T) {
+	svc := NewService()
+	ctx := context.Background()
+	sizes := []int{23, 31, 53}
+	rng := rand.New(rand.NewSource(1))
+
+	// Mixed growing and shrinking amounts exercise both reuse and extension
+	for i := 0; i < 200; i++ {
+		amount := 1 + rng.Intn(20_000)
+		got, _ := svc.Compute(ctx, amount, append([]int(nil), sizes...))
+		want := Compute(amount, append([]int(nil), sizes...))
+		if got.TotalItems != want.TotalItems || got.TotalPacks != want.TotalPacks {
+			t.Fatalf("amount %d: cached %d items / %d packs, uncached %d / %d", amount, got.TotalItems, got.TotalPacks, want.TotalItems, want.TotalPacks)
+		}
+	}
+	if n := svc.tables.len(); n != 1 {
+		t.Errorf("Expected one cached size set, got %d", n)
+	}
+}
+
+// mustGet returns c.get's table for a context that never ends, failing the test on error.
+func mustGet(t *testing.T, c *tableCache, maxAmount int, sizes []int) *table {
+	t.Helper()
+	tb, err := c.get(context.Background(), maxAmount, sizes)
+	if err != nil {
+		t.Fatalf("get(%d, %v) failed: %v", maxAmount, sizes, err)
+	}
+	return tb
+}
+
+func TestTableCache_ExtendsInsteadOfRebuilding(t *testing.T) {
+	c := newTableCache(1_000_000)
+	sizes := []int{250, 500, 1000}
+
+	small := mustGet(t, c, 1000, sizes)
+	if again := mustGet(t, c, 900, sizes); again != small {
+		t.Errorf("Expected a smaller amount to reuse the cached table")
+	}
+
+	large := mustGet(t, c, 5000, sizes)
+	if large == small || large.maxAmount != 5000 {
+		t.Fatalf("Expected an extended table up to 5000, got maxAmount %d", large.maxAmount)
+	}
+	// The original table is left intact for any goroutine still reading it
+	if small.maxAmount != 1000 || len(small.dp) != 1000+1000 {
+		t.Errorf("Extending modified the original table (maxAmount %d, len %d)", small.maxAmount, len(small.dp))
+	}
+	for i := range small.dp {
+		if small.dp[i] != large.dp[i] || small.prev[i] != large.prev[i] {
+			t.Fatalf("Extended table differs from the original at %d", i)
+		}
+	}
+}
+
+func TestTableCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newTableCache(3000)
+
+	mustGet(t, c, 900, []int{100}) // 999 cells
+	mustGet(t, c, 900, []int{200}) // 1099 cells
+	mustGet(t, c, 900, []int{100}) // Touch the first set so the second is least recently used
+	mustGet(t, c, 900, []int{300}) // 1199 cells: exceeds the bound, evicting {200}
+
+	if _, ok := c.entries["200"]; ok {
+		t.Errorf("Expected the least recently used set to be evicted")
+	}
+	if _, ok := c.entries["100"]; !ok {
+		t.Errorf("Expected the recently used set to stay cached")
+	}
+	if c.cells > c.maxCells {
+		t.Errorf("Cache holds %d cells, bound is %d", c.cells, c.maxCells)
+	}
+
+	// Tables larger than the whole bound are computed but not cached
+	if tb := mustGet(t, c, 10_000, []int{7}); tb.maxAmount != 10_000 {
+		t.Errorf("Expected an uncached table up to 10000, got %d", tb.maxAmount)
+	}
+	if _, ok := c.entries["7"]; ok {
+		t.Errorf("Expected an oversized table not to be cached")
+	}
+}
+
+func TestTableCache_CancelledFillIsNotCached(t *testing.T) {
+	c := newTableCache(1_000_000)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.get(ctx, 500_000, []int{250, 500}); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if n := c.len(); n != 0 {
+		t.Errorf("Expected nothing cached after a cancelled fill, got %d sets", n)
+	}
+}
+
+func TestTableCache_ConcurrentUse(t *testing.T) {
+	svc := NewService()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for i := 0; i < 50; i++ {
+				amount := 1 + rng.Intn(50_000)
+				got, _ := svc.Compute(ctx, amount, []int{250, 500, 1000, 2000, 5000})
+				want := Compute(amount, []int{250, 500, 1000, 2000, 5000})
+				if got.TotalItems != want.TotalItems || got.TotalPacks != want.TotalPacks {
+					t.Errorf("amount %d: cached %+v, uncached %+v", amount, got, want)
+					return
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+}
+
+// BenchmarkRepeatedSizes compares a fresh DP table per request with the service's table cache
+// for a stream of different amounts against the same pack sizes.
+func BenchmarkRepeatedSizes(b *testing.B) {
+	sizes := []int{23, 31, 53}
+	amounts := make([]int, 256)
+	rng := rand.New(rand.NewSource(1))
+	for i := range amounts {
+		amounts[i] = 1 + rng.Intn(100_000)
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		buf := make([]int, len(sizes))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			copy(buf, sizes)
+			Compute(amounts[i%len(amounts)], buf)
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		svc := NewService()
+		ctx := context.Background()
+		buf := make([]int, len(sizes))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			copy(buf, sizes)
+			svc.Compute(ctx, amounts[i%len(amounts)], buf)
+		}
+	})
+}
+
+func TestSizesKey_PermutationsShareKey(t *testing.T) {
+	want := SizesKey([]int{250, 500, 1000})
+	for _, sizes := range [][]int{
+		{1000, 500, 250},
+		{500, 250, 1000},
+		{250, 250, 500, 1000, 1000},
+		{0, -5, 1000, 250, 500},
+	} {
+		if got := SizesKey(sizes); got != want {
+			t.Errorf("Expected %v to share the key of [250 500 1000], got %q and %q", sizes, got, want)
+		}
+	}
+
+	sizes := []int{1000, 250, 500, 250}
+	SizesKey(sizes)
+	if sizes[0] != 1000 || sizes[3] != 250 {
+		t.Errorf("Expected the caller's sizes to be left untouched, got %v", sizes)
+	}
+}
+
+func TestSizesKey_DistinctSetsDiffer(t *testing.T) {
+	// Every subset of 1-9, plus sets whose digits run together when concatenated
+	seen := make(map[string][]int)
+	check := func(sizes []int) {
+		t.Helper()
+		key := SizesKey(sizes)
+		if prev, ok := seen[key]; ok {
+			t.Fatalf("Expected distinct keys, got %q for both %v and %v", key, prev, sizes)
+		}
+		seen[key] = sizes
+	}
+	for mask := 0; mask < 1<<9; mask++ {
+		var sizes []int
+		for i := 0; i < 9; i++ {
+			if mask&(1<<i) != 0 {
+				sizes = append(sizes, i+1)
+			}
+		}
+		check(sizes)
+	}
+	check([]int{123})
+	check([]int{1, 23})
+	check([]int{12, 3})
+	check([]int{1, 2, 3, 100})
+}
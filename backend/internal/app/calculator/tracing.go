@@ -0,0 +1,45 @@
+package calculator
+
+import (
+	"context"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans started by this package.
+const tracerName = "github.com/temo/pack-optimizer/backend/internal/app/calculator"
+
+// startSpan starts a calculation span recording the input size. With tracing disabled the
+// global provider is a no-op, so the span costs next to nothing.
+func startSpan(ctx context.Context, name string, sizes []int, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.Int("calc.sizes", len(sizes)))
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records the outcome of a single calculation on span and ends it.
+func endSpan(span trace.Span, res domain.CalculationResult, err error) {
+	if err == nil {
+		span.SetAttributes(
+			attribute.Bool("calc.feasible", true),
+			attribute.Int("calc.total_items", res.TotalItems),
+			attribute.Int("calc.total_packs", res.TotalPacks),
+		)
+	}
+	endSpanErr(span, err)
+}
+
+// endSpanErr records err, if any, on span and ends it. An amount without a solution is an
+// answer rather than a failure, so it only marks the span infeasible.
+func endSpanErr(span trace.Span, err error) {
+	if _, ok := err.(*domain.NoSolutionError); ok {
+		span.SetAttributes(attribute.Bool("calc.feasible", false))
+	} else if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
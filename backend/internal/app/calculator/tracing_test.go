@@ -0,0 +1,74 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider recording every span until the test ends.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+// spanAttrs collects the attributes of span by key.
+func spanAttrs(span sdktrace.ReadOnlySpan) map[string]attribute.Value {
+	out := make(map[string]attribute.Value)
+	for _, kv := range span.Attributes() {
+		out[string(kv.Key)] = kv.Value
+	}
+	return out
+}
+
+func TestService_Spans(t *testing.T) {
+	rec := recordSpans(t)
+	svc := NewService()
+
+	if _, err := svc.Compute(context.Background(), 501, []int{250, 500, 1000}); err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	var noSolution *domain.NoSolutionError
+	if _, err := svc.ComputeWithOptions(context.Background(), 1000, []int{250}, domain.CalcOptions{MaxPacks: 2}); !errors.As(err, &noSolution) {
+		t.Fatalf("Expected NoSolutionError, got %v", err)
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	attrs := spanAttrs(spans[0])
+	if spans[0].Name() != "calculator.Compute" {
+		t.Errorf("Unexpected span name %q", spans[0].Name())
+	}
+	if attrs["calc.amount"].AsInt64() != 501 || attrs["calc.sizes"].AsInt64() != 3 {
+		t.Errorf("Expected amount 501 and 3 sizes, got %v and %v", attrs["calc.amount"].Emit(), attrs["calc.sizes"].Emit())
+	}
+	if !attrs["calc.feasible"].AsBool() || attrs["calc.total_items"].AsInt64() != 750 || attrs["calc.total_packs"].AsInt64() != 2 {
+		t.Errorf("Expected a feasible 750-item, 2-pack result, got %v", spans[0].Attributes())
+	}
+
+	// An amount without a solution is an answer, not an error
+	attrs = spanAttrs(spans[1])
+	if v, ok := attrs["calc.feasible"]; !ok || v.AsBool() {
+		t.Errorf("Expected the span marked infeasible, got %v", spans[1].Attributes())
+	}
+	if spans[1].Status().Code == codes.Error {
+		t.Errorf("Expected no error status for an infeasible amount")
+	}
+	if attrs["calc.tie_break"].AsString() != string(domain.TieBreakItemsFirst) {
+		t.Errorf("Expected the default tie-break recorded, got %q", attrs["calc.tie_break"].AsString())
+	}
+}
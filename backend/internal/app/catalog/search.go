@@ -0,0 +1,201 @@
+// Package catalog evaluates pack-size catalogs offline.
+// Given a universe of candidate sizes and a representative set of order amounts, it finds the
+// subset of K sizes that minimizes the average overage, using the regular calculator for scoring.
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+)
+
+// Search limits. The search is exhaustive (with pruning), so its cost grows with the number of
+// K-subsets of the universe and the DP work of scoring each; these bounds keep a single run within minutes.
+const (
+	MaxUniverse = 24      // Largest number of distinct candidate sizes
+	MaxK        = 8       // Largest subset size
+	MaxSubsets  = 200_000 // Largest number of K-subsets the search may have to consider
+	MaxAmounts  = 100_000 // Largest number of amounts in the distribution
+	MaxAmount   = 1_000_000
+	MaxCells    = 100_000_000_000 // Most DP table cells (item counts × sizes tried) the search may fill
+)
+
+// ErrSearchTooLarge is returned when the requested search exceeds the limits above.
+var ErrSearchTooLarge = errors.New("search space too large")
+
+// Result describes the best subset found.
+type Result struct {
+	Sizes          []int   // Best subset, ascending
+	AverageOverage float64 // Mean overage in items per order
+	AveragePacks   float64 // Mean packs per order (breaks ties in overage)
+	Subsets        int     // Number of K-subsets in the search space
+	Evaluated      int     // Subsets scored in full
+	Pruned         int     // Partial subsets discarded by the lower bound
+}
+
+// score is the total overage and packs of a subset across all amounts.
+type score struct {
+	overage int
+	packs   int
+}
+
+// less reports whether s is better than o: less overage, then fewer packs.
+func (s score) less(o score) bool {
+	return s.overage < o.overage || (s.overage == o.overage && s.packs < o.packs)
+}
+
+// BestSubset returns the k-size subset of candidates with the lowest average overage over amounts,
+// breaking ties by fewer packs and then by the lexicographically smallest sizes.
+//
+// Adding sizes never increases the overage for any amount, so a partial subset can be bounded by
+// scoring it together with every candidate still available to it; branches whose bound is already
+// worse than the best complete subset are skipped.
+func BestSubset(candidates []int, k int, amounts []int) (Result, error) {
+	universe := uniqueSorted(candidates)
+	if len(universe) == 0 {
+		return Result{}, errors.New("at least one positive candidate size is required")
+	}
+	if len(universe) > MaxUniverse {
+		return Result{}, fmt.Errorf("%w: %d candidate sizes (limit %d)", ErrSearchTooLarge, len(universe), MaxUniverse)
+	}
+	if k <= 0 || k > len(universe) {
+		return Result{}, fmt.Errorf("k must be between 1 and the number of candidate sizes (%d)", len(universe))
+	}
+	if k > MaxK {
+		return Result{}, fmt.Errorf("%w: k=%d (limit %d)", ErrSearchTooLarge, k, MaxK)
+	}
+	subsets := binomial(len(universe), k)
+	if subsets > MaxSubsets {
+		return Result{}, fmt.Errorf("%w: %d subsets of %d sizes from %d candidates (limit %d)", ErrSearchTooLarge, subsets, k, len(universe), MaxSubsets)
+	}
+	if len(amounts) == 0 {
+		return Result{}, errors.New("at least one amount is required")
+	}
+	if len(amounts) > MaxAmounts {
+		return Result{}, fmt.Errorf("%w: %d amounts (limit %d)", ErrSearchTooLarge, len(amounts), MaxAmounts)
+	}
+
+	// Score each distinct amount once, weighted by how often it occurs
+	weights := make(map[int]int, len(amounts))
+	for _, a := range amounts {
+		if a <= 0 || a > MaxAmount {
+			return Result{}, fmt.Errorf("amount %d must be between 1 and %d", a, MaxAmount)
+		}
+		weights[a]++
+	}
+	distinct := make([]int, 0, len(weights))
+	for a := range weights {
+		distinct = append(distinct, a)
+	}
+	sort.Ints(distinct)
+
+	// Every subset within the limits above can still be too much DP work when the amounts are large
+	if cells := searchCells(len(universe), k, subsets, distinct[len(distinct)-1]+universe[len(universe)-1]); cells > MaxCells {
+		return Result{}, fmt.Errorf("%w: up to %.3g DP cells for %d subsets and amounts up to %d (limit %.3g)",
+			ErrSearchTooLarge, cells, subsets, distinct[len(distinct)-1], float64(MaxCells))
+	}
+
+	s := &searcher{universe: universe, k: k, amounts: distinct, weights: weights}
+	s.search(0, make([]int, 0, k))
+
+	n := float64(len(amounts))
+	return Result{
+		Sizes:          s.bestSizes,
+		AverageOverage: float64(s.best.overage) / n,
+		AveragePacks:   float64(s.best.packs) / n,
+		Subsets:        subsets,
+		Evaluated:      s.evaluated,
+		Pruned:         s.pruned,
+	}, nil
+}
+
+// searcher holds the state of one depth-first subset search.
+type searcher struct {
+	universe []int       // Candidate sizes, ascending
+	k        int         // Subset size
+	amounts  []int       // Distinct amounts
+	weights  map[int]int // Occurrences of each amount
+
+	best      score
+	bestSizes []int
+	evaluated int
+	pruned    int
+}
+
+// search extends chosen with candidates from universe[from:] until it holds k sizes.
+func (s *searcher) search(from int, chosen []int) {
+	if len(chosen) == s.k {
+		s.evaluated++
+		sc := s.score(chosen)
+		if s.bestSizes == nil || sc.less(s.best) {
+			s.best = sc
+			s.bestSizes = append([]int(nil), chosen...)
+		}
+		return
+	}
+
+	// Bound: no completion can beat chosen plus every remaining candidate. Only worth checking
+	// when at least two more sizes are needed, since otherwise the children are leaves anyway.
+	if s.bestSizes != nil && s.k-len(chosen) >= 2 {
+		all := append(append([]int(nil), chosen...), s.universe[from:]...)
+		if s.score(all).overage > s.best.overage {
+			s.pruned++
+			return
+		}
+	}
+
+	for i := from; i <= len(s.universe)-(s.k-len(chosen)); i++ {
+		s.search(i+1, append(chosen, s.universe[i]))
+	}
+}
+
+// score computes the weighted overage and packs of sizes over the distinct amounts.
+func (s *searcher) score(sizes []int) score {
+	results := calculator.ComputeMany(s.amounts, append([]int(nil), sizes...))
+	var sc score
+	for i, res := range results {
+		w := s.weights[s.amounts[i]]
+		sc.overage += (res.TotalItems - s.amounts[i]) * w
+		sc.packs += res.TotalPacks * w
+	}
+	return sc
+}
+
+// uniqueSorted returns the distinct positive values of sizes in ascending order.
+func uniqueSorted(sizes []int) []int {
+	seen := make(map[int]bool, len(sizes))
+	out := make([]int, 0, len(sizes))
+	for _, s := range sizes {
+		if s > 0 && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// searchCells bounds the DP cells a search fills. Each scored subset has at most k-1 partial
+// ancestors scored for the bound, so there are at most k*subsets score calls; each fills a table
+// of up to tableSize item counts (the largest amount plus the largest size), trying at most n
+// sizes per count. Computed in floating point, since the product can exceed an int.
+func searchCells(n, k, subsets, tableSize int) float64 {
+	return float64(k) * float64(subsets) * float64(tableSize) * float64(n)
+}
+
+// binomial returns n choose k, capped just above MaxSubsets to avoid overflow.
+func binomial(n, k int) int {
+	if k > n-k {
+		k = n - k
+	}
+	c := 1
+	for i := 1; i <= k; i++ {
+		c = c * (n - k + i) / i
+		if c > MaxSubsets {
+			return MaxSubsets + 1
+		}
+	}
+	return c
+}
@@ -0,0 +1,99 @@
+package catalog
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+)
+
+func TestBestSubset_KnownCase(t *testing.T) {
+	// Every amount is a multiple of 250, so {250} alone ships no overage
+	res, err := BestSubset([]int{250, 300, 700}, 1, []int{250, 500, 750, 1000})
+	if err != nil {
+		t.Fatalf("BestSubset failed: %v", err)
+	}
+	if len(res.Sizes) != 1 || res.Sizes[0] != 250 || res.AverageOverage != 0 {
+		t.Errorf("Expected [250] with no overage, got %+v", res)
+	}
+}
+
+func TestBestSubset_MatchesBruteForce(t *testing.T) {
+	candidates := []int{23, 31, 53, 100, 250, 500, 1000, 2000}
+	rng := rand.New(rand.NewSource(1))
+	amounts := make([]int, 300)
+	for i := range amounts {
+		amounts[i] = 1 + rng.Intn(5000)
+	}
+
+	for k := 1; k <= 4; k++ {
+		res, err := BestSubset(candidates, k, amounts)
+		if err != nil {
+			t.Fatalf("k=%d: BestSubset failed: %v", k, err)
+		}
+		if res.Evaluated+res.Pruned == 0 || res.Subsets != binomial(len(candidates), k) {
+			t.Errorf("k=%d: unexpected search counters %+v", k, res)
+		}
+
+		// Score every subset directly and compare against the search result
+		bestOverage := -1
+		var subsets func(from int, chosen []int)
+		subsets = func(from int, chosen []int) {
+			if len(chosen) == k {
+				overage := 0
+				for _, a := range amounts {
+					overage += calculator.Compute(a, append([]int(nil), chosen...)).TotalItems - a
+				}
+				if bestOverage < 0 || overage < bestOverage {
+					bestOverage = overage
+				}
+				return
+			}
+			for i := from; i < len(candidates); i++ {
+				subsets(i+1, append(chosen, candidates[i]))
+			}
+		}
+		subsets(0, nil)
+
+		if want := float64(bestOverage) / float64(len(amounts)); res.AverageOverage != want {
+			t.Errorf("k=%d: expected average overage %.3f, got %.3f (%v)", k, want, res.AverageOverage, res.Sizes)
+		}
+	}
+}
+
+func TestBestSubset_Limits(t *testing.T) {
+	universe := make([]int, 30)
+	for i := range universe {
+		universe[i] = 10 * (i + 1)
+	}
+
+	tests := []struct {
+		name       string
+		candidates []int
+		k          int
+		amounts    []int
+		tooLarge   bool
+	}{
+		{"universe too large", universe, 2, []int{100}, true},
+		{"k too large", universe[:20], 9, []int{100}, true},
+		{"too many subsets", universe[:24], 8, []int{100}, true},
+		{"too much DP work", universe[:20], 5, []int{100, MaxAmount}, true},
+		{"k above universe", []int{250, 500}, 3, []int{100}, false},
+		{"no candidates", []int{0, -5}, 1, []int{100}, false},
+		{"no amounts", []int{250}, 1, nil, false},
+		{"amount out of range", []int{250}, 1, []int{0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BestSubset(tt.candidates, tt.k, tt.amounts)
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+			if errors.Is(err, ErrSearchTooLarge) != tt.tooLarge {
+				t.Errorf("Expected ErrSearchTooLarge=%v, got %v", tt.tooLarge, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Package events fans pack set change events out to in-process subscribers, such as
+// dashboards streaming GET /packs/events.
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before it's dropped.
+const subscriberBuffer = 16
+
+// Broker implements the domain.Publisher and domain.PackEventSubscriber ports in memory.
+// Publishing never blocks: a subscriber whose buffer is full is unsubscribed and its channel
+// closed, so a stalled client reconnects and catches up rather than holding up everyone else.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[chan domain.PackSetChanged]struct{}
+	closed bool // Set by Close; new subscriptions end immediately
+}
+
+// NewBroker creates a broker with no subscribers.
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[chan domain.PackSetChanged]struct{})}
+}
+
+// Publish delivers an event to every current subscriber.
+func (b *Broker) Publish(ctx context.Context, event domain.PackSetChanged) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving events published from now on, and a function ending
+// the subscription. The channel is closed when the subscription ends, by either side.
+func (b *Broker) Subscribe() (<-chan domain.PackSetChanged, func()) {
+	ch := make(chan domain.PackSetChanged, subscriberBuffer)
+	b.mu.Lock()
+	if b.closed {
+		close(ch)
+	} else {
+		b.subs[ch] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends every subscription, and any made later, so streaming clients disconnect and
+// the server can shut down without waiting for them.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// Subscribers returns the number of current subscribers.
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+func TestBroker_FanOut(t *testing.T) {
+	b := NewBroker()
+	first, unsubFirst := b.Subscribe()
+	second, unsubSecond := b.Subscribe()
+	defer unsubSecond()
+
+	event := domain.PackSetChanged{Version: 7, Sizes: []int{250, 500}}
+	_ = b.Publish(context.Background(), event)
+	for i, ch := range []<-chan domain.PackSetChanged{first, second} {
+		if got := <-ch; got.Version != 7 {
+			t.Errorf("Subscriber %d: expected version 7, got %+v", i, got)
+		}
+	}
+
+	// Unsubscribing closes the channel once; calling it again is harmless
+	unsubFirst()
+	unsubFirst()
+	if _, ok := <-first; ok {
+		t.Errorf("Expected the channel closed after unsubscribing")
+	}
+	if n := b.Subscribers(); n != 1 {
+		t.Errorf("Expected 1 subscriber left, got %d", n)
+	}
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	b := NewBroker()
+	slow, unsubscribe := b.Subscribe()
+	defer unsubscribe()
+
+	// Publishing never blocks; the subscriber is dropped once its buffer is full
+	for v := int64(1); v <= subscriberBuffer+1; v++ {
+		_ = b.Publish(context.Background(), domain.PackSetChanged{Version: v})
+	}
+	if n := b.Subscribers(); n != 0 {
+		t.Fatalf("Expected the slow subscriber dropped, got %d subscribers", n)
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("Expected the %d buffered events before the close, got %d", subscriberBuffer, received)
+	}
+}
+
+func TestBroker_Close(t *testing.T) {
+	b := NewBroker()
+	ch, unsubscribe := b.Subscribe()
+
+	b.Close()
+	if _, ok := <-ch; ok {
+		t.Errorf("Expected Close to end the subscription")
+	}
+	unsubscribe() // Already closed; must not panic
+
+	late, _ := b.Subscribe()
+	if _, ok := <-late; ok {
+		t.Errorf("Expected subscriptions after Close to end immediately")
+	}
+}
@@ -0,0 +1,317 @@
+// Package jobs implements asynchronous batch calculations.
+// Jobs are accepted immediately, processed by a background worker, and their state
+// (progress and results) is stored through the domain.Cache port so any instance can serve polls.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+const (
+	// keyPrefix namespaces job state in the cache.
+	keyPrefix = "job:v1:"
+
+	// defaultStaleAfter is how long a queued/running job may go without a heartbeat
+	// before it is considered abandoned (e.g. the worker process crashed).
+	defaultStaleAfter = 2 * time.Minute
+
+	// progressEvery controls how often (in processed amounts) progress is persisted
+	// between heartbeats.
+	progressEvery = 500
+
+	// defaultShutdownWait bounds how long Close waits for interrupted workers to record their
+	// failure, so a stuck calculation can't hold up shutdown.
+	defaultShutdownWait = 5 * time.Second
+)
+
+// ErrClosed is returned by Submit once the service has been closed.
+var ErrClosed = errors.New("job service is closed")
+
+// Service implements the domain.JobService port.
+type Service struct {
+	cache      domain.Cache
+	calc       domain.Calculator
+	logger     *slog.Logger
+	ttl        int           // Job state time-to-live in seconds
+	staleAfter time.Duration // Heartbeat age after which an unfinished job is marked failed
+	heartbeat  time.Duration // How often a running job saves a heartbeat; well within staleAfter
+	now        func() time.Time
+
+	ctx          context.Context    // Parent of every worker's context; cancelled by Close
+	stop         context.CancelFunc // Cancels ctx
+	mu           sync.Mutex         // Orders worker registration against Close
+	workers      sync.WaitGroup     // Running workers
+	shutdownWait time.Duration      // Longest Close waits for workers
+}
+
+// NewService creates a new job service that stores job state in the given cache.
+// ttlSeconds controls how long finished (and unfinished) jobs remain pollable.
+func NewService(cache domain.Cache, calc domain.Calculator, logger *slog.Logger, ttlSeconds int) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	return &Service{
+		cache:      cache,
+		calc:       calc,
+		logger:     logger,
+		ttl:        ttlSeconds,
+		staleAfter: defaultStaleAfter,
+		heartbeat:  defaultStaleAfter / 4,
+		now:        time.Now,
+
+		ctx:          ctx,
+		stop:         stop,
+		shutdownWait: defaultShutdownWait,
+	}
+}
+
+// Close stops the running jobs, marking them failed, and waits for their workers to finish,
+// for at most the shutdown wait or until ctx ends. Jobs submitted afterwards are refused.
+func (s *Service) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.stop()
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, s.shutdownWait)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for job workers: %w", ctx.Err())
+	}
+}
+
+// Submit implements domain.JobService.
+// The job is persisted as queued before returning, then processed in a background goroutine.
+// The amounts and sizes slices are copied so the caller may reuse them.
+func (s *Service) Submit(ctx context.Context, amounts []int, sizes []int) (domain.Job, error) {
+	// Registering the worker under mu means Close either refuses the job or waits for it
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return domain.Job{}, ErrClosed
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return domain.Job{}, err
+	}
+
+	now := s.now().UTC()
+	job := domain.Job{
+		ID:        id,
+		Status:    domain.JobQueued,
+		Total:     len(amounts),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.save(job); err != nil {
+		return domain.Job{}, err
+	}
+
+	amounts = append([]int(nil), amounts...)
+	sizes = append([]int(nil), sizes...)
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		s.run(job, amounts, sizes)
+	}()
+
+	return job, nil
+}
+
+// Get implements domain.JobService.
+// Unfinished jobs whose heartbeat is older than the stale threshold are marked failed,
+// which covers workers that died mid-job.
+func (s *Service) Get(ctx context.Context, id string) (domain.Job, bool, error) {
+	job, ok, err := s.load(id)
+	if err != nil || !ok {
+		return job, ok, err
+	}
+
+	if s.isStale(job) {
+		job.Status = domain.JobFailed
+		job.Error = "job worker stopped responding"
+		job.UpdatedAt = s.now().UTC()
+		if err := s.save(job); err != nil {
+			return domain.Job{}, false, err
+		}
+		s.logger.Warn("marked stale job as failed", "job_id", job.ID, "processed", job.Processed, "total", job.Total)
+	}
+
+	return job, true, nil
+}
+
+// run processes all amounts for a job. Progress is saved every progressEvery amounts, and a
+// heartbeat every s.heartbeat however long each amount takes, so Get never mistakes a live job
+// for an abandoned one. If the job turns out to have been marked failed meanwhile (e.g. after the
+// process was paused past staleAfter), the worker stops instead of overwriting the failure.
+// Closing the service stops the worker too, and marks the job failed.
+func (s *Service) run(job domain.Job, amounts []int, sizes []int) {
+	ctx, stop := context.WithCancel(s.ctx)
+	defer stop()
+	w := &worker{svc: s, job: job}
+
+	// A panic in the worker must not take down the server; record it on the job instead
+	defer func() {
+		if rec := recover(); rec != nil {
+			w.fail(fmt.Sprintf("job worker panicked: %v", rec))
+		}
+	}()
+
+	if !w.checkpoint(func(j *domain.Job) { j.Status = domain.JobRunning }) {
+		return
+	}
+	go w.beat(ctx, stop)
+
+	results := make([]domain.CalculationResult, 0, len(amounts))
+	for i, amount := range amounts {
+		res, err := s.calc.Compute(ctx, amount, sizes)
+		if s.ctx.Err() != nil {
+			w.fail("job interrupted by server shutdown")
+			return
+		}
+		if ctx.Err() != nil {
+			return // The heartbeat found the job failed
+		}
+		if err != nil {
+			w.fail(fmt.Sprintf("calculation failed for amount %d: %v", amount, err))
+			return
+		}
+		results = append(results, res)
+
+		w.advance(i + 1)
+		if (i+1)%progressEvery == 0 && !w.checkpoint(func(*domain.Job) {}) {
+			return
+		}
+	}
+
+	completed := w.checkpoint(func(j *domain.Job) {
+		j.Status = domain.JobCompleted
+		j.Results = results
+	})
+	if completed {
+		s.logger.Info("job completed", "job_id", job.ID, "total", job.Total)
+	}
+}
+
+// worker is the state of a running job, shared by its calculation loop and its heartbeat.
+type worker struct {
+	svc *Service
+	mu  sync.Mutex // Serializes checkpoints and guards job
+	job domain.Job
+}
+
+// advance records that the first processed amounts are done; the next checkpoint saves it.
+func (w *worker) advance(processed int) {
+	w.mu.Lock()
+	w.job.Processed = processed
+	w.mu.Unlock()
+}
+
+// checkpoint applies update to the job and saves it with a fresh heartbeat. If the stored job
+// has been marked failed meanwhile, nothing is saved and checkpoint reports false: the worker
+// must stop, since a failed job is final.
+func (w *worker) checkpoint(update func(*domain.Job)) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if stored, ok, err := w.svc.load(w.job.ID); err == nil && ok && stored.Status == domain.JobFailed {
+		w.svc.logger.Warn("job was marked failed, stopping its worker", "job_id", w.job.ID, "processed", w.job.Processed, "error", stored.Error)
+		return false
+	}
+	update(&w.job)
+	w.job.UpdatedAt = w.svc.now().UTC()
+	if err := w.svc.save(w.job); err != nil {
+		w.svc.logger.Error("failed to save job state", "job_id", w.job.ID, "status", w.job.Status, "error", err)
+	}
+	return true
+}
+
+// beat saves a heartbeat every svc.heartbeat until ctx ends. If the job was marked failed
+// meanwhile it calls stop, so the calculation loop ends too.
+func (w *worker) beat(ctx context.Context, stop context.CancelFunc) {
+	ticker := time.NewTicker(w.svc.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !w.checkpoint(func(*domain.Job) {}) {
+				stop()
+				return
+			}
+		}
+	}
+}
+
+// fail marks the job as failed with the given reason, unless it already is.
+func (w *worker) fail(reason string) {
+	failed := w.checkpoint(func(j *domain.Job) {
+		j.Status = domain.JobFailed
+		j.Error = reason
+		j.Results = nil
+	})
+	if failed {
+		w.svc.logger.Error("job failed", "job_id", w.job.ID, "reason", reason)
+	}
+}
+
+// isStale reports whether an unfinished job has gone without a heartbeat for too long.
+func (s *Service) isStale(job domain.Job) bool {
+	if job.Status != domain.JobQueued && job.Status != domain.JobRunning {
+		return false
+	}
+	return s.now().Sub(job.UpdatedAt) > s.staleAfter
+}
+
+// save stores the job state in the cache.
+func (s *Service) save(job domain.Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(keyPrefix+job.ID, b, s.ttl)
+}
+
+// load reads the job state from the cache.
+func (s *Service) load(id string) (domain.Job, bool, error) {
+	b, err := s.cache.Get(keyPrefix + id)
+	if err != nil {
+		return domain.Job{}, false, err
+	}
+	if b == nil {
+		return domain.Job{}, false, nil
+	}
+	var job domain.Job
+	if err := json.Unmarshal(b, &job); err != nil {
+		return domain.Job{}, false, err
+	}
+	return job, true, nil
+}
+
+// newJobID generates a random 128-bit hex job identifier.
+func newJobID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,290 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// memoryCache implements domain.Cache in memory for testing.
+type memoryCache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{data: map[string][]byte{}}
+}
+
+func (c *memoryCache) Get(key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.data[key], nil
+}
+
+func (c *memoryCache) Set(key string, value []byte, ttlSeconds int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = value
+	return nil
+}
+
+func (c *memoryCache) GetMany(keys []string) ([][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = c.data[key]
+	}
+	return values, nil
+}
+
+func (c *memoryCache) SetMany(entries map[string][]byte, ttlSeconds int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, value := range entries {
+		c.data[key] = value
+	}
+	return nil
+}
+
+func (c *memoryCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.data, key)
+	return nil
+}
+
+func (c *memoryCache) DeleteByPrefix(prefix string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := 0
+	for k := range c.data {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.data, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// waitForJob polls until the job leaves the queued/running states or the deadline passes.
+func waitForJob(t *testing.T, svc *Service, id string) domain.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok, err := svc.Get(context.Background(), id)
+		if err != nil || !ok {
+			t.Fatalf("Get(%s): ok=%v err=%v", id, ok, err)
+		}
+		if job.Status == domain.JobCompleted || job.Status == domain.JobFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish in time", id)
+	return domain.Job{}
+}
+
+func TestSubmit_ProcessesInBackground(t *testing.T) {
+	svc := NewService(newMemoryCache(), calculator.NewService(), nil, 60)
+
+	amounts := []int{1, 251, 501, 12001}
+	job, err := svc.Submit(context.Background(), amounts, []int{250, 500, 1000, 2000, 5000})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if job.ID == "" || job.Status != domain.JobQueued || job.Total != 4 {
+		t.Fatalf("Unexpected submitted job: %+v", job)
+	}
+
+	done := waitForJob(t, svc, job.ID)
+	if done.Status != domain.JobCompleted {
+		t.Fatalf("Expected completed job, got %s (%s)", done.Status, done.Error)
+	}
+	if done.Processed != 4 || len(done.Results) != 4 {
+		t.Fatalf("Expected 4 processed results, got processed=%d results=%d", done.Processed, len(done.Results))
+	}
+
+	expectedItems := []int{250, 500, 750, 12250}
+	for i, res := range done.Results {
+		if res.Amount != amounts[i] || res.TotalItems != expectedItems[i] {
+			t.Errorf("Result %d: expected amount %d / items %d, got %d / %d", i, amounts[i], expectedItems[i], res.Amount, res.TotalItems)
+		}
+	}
+}
+
+func TestGet_UnknownJob(t *testing.T) {
+	svc := NewService(newMemoryCache(), calculator.NewService(), nil, 60)
+
+	_, ok, err := svc.Get(context.Background(), "missing")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ok {
+		t.Errorf("Expected unknown job to be reported as missing")
+	}
+}
+
+func TestGet_MarksStaleJobFailed(t *testing.T) {
+	svc := NewService(newMemoryCache(), calculator.NewService(), nil, 60)
+
+	// Simulate a job whose worker died: running, with a heartbeat far in the past
+	stale := domain.Job{
+		ID:        "stale",
+		Status:    domain.JobRunning,
+		Total:     10,
+		Processed: 3,
+		CreatedAt: time.Now().Add(-time.Hour),
+		UpdatedAt: time.Now().Add(-time.Hour),
+	}
+	if err := svc.save(stale); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	job, ok, err := svc.Get(context.Background(), "stale")
+	if err != nil || !ok {
+		t.Fatalf("Get: ok=%v err=%v", ok, err)
+	}
+	if job.Status != domain.JobFailed || job.Error == "" {
+		t.Errorf("Expected stale job to be failed with a reason, got %+v", job)
+	}
+
+	// The failure is persisted, not just reported
+	persisted, _, _ := svc.load("stale")
+	if persisted.Status != domain.JobFailed {
+		t.Errorf("Expected failed status to be persisted, got %s", persisted.Status)
+	}
+}
+
+// slowCalculator takes delay per amount, or blocks until release is closed when it's set.
+type slowCalculator struct {
+	domain.Calculator
+	delay   time.Duration
+	release chan struct{}
+	mu      sync.Mutex
+	calls   int
+}
+
+func (c *slowCalculator) Compute(ctx context.Context, amount int, sizes []int) (domain.CalculationResult, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	wait := time.After(c.delay)
+	if c.release != nil {
+		wait = nil
+	}
+	select {
+	case <-wait:
+	case <-c.release:
+	case <-ctx.Done():
+		return domain.CalculationResult{}, ctx.Err()
+	}
+	return c.Calculator.Compute(ctx, amount, sizes)
+}
+
+func TestRun_HeartbeatKeepsSlowJobAlive(t *testing.T) {
+	calc := &slowCalculator{Calculator: calculator.NewService(), delay: 60 * time.Millisecond}
+	svc := NewService(newMemoryCache(), calc, nil, 60)
+	svc.staleAfter = 40 * time.Millisecond
+	svc.heartbeat = 10 * time.Millisecond
+
+	// Every amount takes longer than staleAfter, far fewer than progressEvery of them
+	job, err := svc.Submit(context.Background(), []int{251, 501, 12001}, []int{250, 500, 1000, 2000, 5000})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	done := waitForJob(t, svc, job.ID)
+	if done.Status != domain.JobCompleted || len(done.Results) != 3 {
+		t.Fatalf("Expected the slow job to complete, got %s (%s)", done.Status, done.Error)
+	}
+}
+
+func TestRun_StopsWhenMarkedFailed(t *testing.T) {
+	calc := &slowCalculator{Calculator: calculator.NewService(), release: make(chan struct{})}
+	svc := NewService(newMemoryCache(), calc, nil, 60)
+	svc.heartbeat = 5 * time.Millisecond
+
+	job, err := svc.Submit(context.Background(), []int{251, 501, 751}, []int{250, 500})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	// Mark the job failed while its worker is stuck in the first amount, as Get does for a stale job
+	deadline := time.Now().Add(time.Second)
+	for {
+		stored, _, _ := svc.load(job.ID)
+		if stored.Status == domain.JobRunning {
+			stored.Status = domain.JobFailed
+			stored.Error = "job worker stopped responding"
+			if err := svc.save(stored); err != nil {
+				t.Fatalf("save failed: %v", err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job never started running")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond) // Several heartbeats
+	close(calc.release)
+	time.Sleep(20 * time.Millisecond)
+
+	// The failure stands, and the worker didn't go on to the other amounts
+	stored, _, _ := svc.load(job.ID)
+	if stored.Status != domain.JobFailed || stored.Error != "job worker stopped responding" {
+		t.Errorf("Expected the job to stay failed, got %s (%s)", stored.Status, stored.Error)
+	}
+	calc.mu.Lock()
+	defer calc.mu.Unlock()
+	if calc.calls != 1 {
+		t.Errorf("Expected the worker to stop after its first amount, got %d calculations", calc.calls)
+	}
+}
+
+func TestClose_StopsRunningJobs(t *testing.T) {
+	calc := &slowCalculator{Calculator: calculator.NewService(), release: make(chan struct{})}
+	defer close(calc.release)
+	svc := NewService(newMemoryCache(), calc, nil, 60)
+
+	job, err := svc.Submit(context.Background(), []int{251, 501}, []int{250, 500})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	// The worker is blocked in its first amount; Close cancels it and waits for it to record that
+	if err := svc.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	stored, _, _ := svc.load(job.ID)
+	if stored.Status != domain.JobFailed || stored.Error != "job interrupted by server shutdown" {
+		t.Errorf("Expected the job to be failed by shutdown, got %s (%s)", stored.Status, stored.Error)
+	}
+
+	if _, err := svc.Submit(context.Background(), []int{251}, []int{250}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestClose_BoundsTheWait(t *testing.T) {
+	svc := NewService(newMemoryCache(), calculator.NewService(), nil, 60)
+	svc.shutdownWait = 20 * time.Millisecond
+
+	// A worker that ignores cancellation must not hold Close past the shutdown wait
+	svc.workers.Add(1)
+	defer svc.workers.Done()
+	start := time.Now()
+	if err := svc.Close(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close waited %s", elapsed)
+	}
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// RequiredPackSizesError is returned when an update would remove pack sizes that policy
+// requires to always be present in the active set.
+type RequiredPackSizesError struct {
+	Missing []int // Required sizes absent from the proposed set, sorted ascending
+}
+
+// Error implements the error interface.
+func (e *RequiredPackSizesError) Error() string {
+	return fmt.Sprintf("required pack sizes missing: %v", e.Missing)
+}
+
+// ValidationError reports input that breaks a business rule. It carries no transport details;
+// adapters translate it into their own error format (e.g. an HTTP 400 response).
+type ValidationError struct {
+	Field   string         // Input field at fault, e.g. "amount" or "sizes"
+	Reason  string         // The broken rule, phrased for API clients
+	Details map[string]any // Extra context such as "index", "value" or "minimum"
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// NoSolutionError is returned when no combination of whole packs can fulfill an amount, e.g. when
+// no usable pack sizes remain. It is distinct from empty input, which has a trivial zero-pack result.
+type NoSolutionError struct {
+	Amount int    // Amount that couldn't be fulfilled
+	Reason string // Why no combination works, phrased for API clients
+}
+
+// Error implements the error interface.
+func (e *NoSolutionError) Error() string {
+	return fmt.Sprintf("no solution for amount %d: %s", e.Amount, e.Reason)
+}
+
+// UnavailableError is returned when a dependency is known to be down, e.g. while its circuit
+// breaker is open, so the call failed fast instead of reaching it.
+type UnavailableError struct {
+	Dependency string        // The unavailable dependency, e.g. "database"
+	RetryAfter time.Duration // Time until the dependency is tried again
+}
+
+// Error implements the error interface.
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s unavailable, retry in %s", e.Dependency, e.RetryAfter)
+}
+
+// VersionConflictError is returned by a conditional pack set update when the active set is no
+// longer at any of the versions the caller expected, so nothing was written.
+type VersionConflictError struct {
+	Expected []int64 // Versions the caller was prepared to replace
+	Current  int64   // Version actually active when the update was refused
+}
+
+// Error implements the error interface.
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("pack set is at version %d, expected one of %v", e.Current, e.Expected)
+}
+
+// LockedError is returned by a pack set write refused because pack size changes are locked.
+// Repositories check the lock as part of the write, so a lock taken concurrently can't be missed.
+type LockedError struct{}
+
+// Error implements the error interface.
+func (e *LockedError) Error() string {
+	return "pack sizes are locked"
+}
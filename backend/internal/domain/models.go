@@ -3,15 +3,165 @@
 // It defines contracts that adapters must implement, following the Dependency Inversion Principle.
 package domain
 
-import "context"
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
 
 // CalculationResult represents the result of a pack calculation.
+// Overage and Shortfall are both unsigned amounts, at most one of them non-zero, so a solution
+// that falls short of the amount never shows up as a negative overage.
 type CalculationResult struct {
 	Amount     int         `json:"amount"`     // Original requested amount
 	TotalItems int         `json:"totalItems"` // Total items in solution (may exceed amount)
-	Overage    int         `json:"overage"`    // Difference between totalItems and amount
+	Overage    int         `json:"overage"`    // Items beyond amount (over-fulfillment), never negative
+	Shortfall  int         `json:"shortfall"`  // Items of amount left unfulfilled, never negative; 0 whenever packs round up
 	TotalPacks int         `json:"totalPacks"` // Total number of packs needed
 	Breakdown  map[int]int `json:"breakdown"`  // Map of pack size -> quantity needed
+	// The same counts as Breakdown, ordered by descending size, for clients that iterate them
+	BreakdownList []PackCount `json:"breakdownList,omitempty"`
+}
+
+// PackCount is the number of packs of one size in a solution.
+type PackCount struct {
+	Size  int `json:"size"`  // Pack size
+	Count int `json:"count"` // Packs of this size
+}
+
+// NewBreakdownList converts a size -> quantity map into a list ordered by descending size.
+// It returns nil for a nil map, i.e. a result without a breakdown.
+func NewBreakdownList(counts map[int]int) []PackCount {
+	if counts == nil {
+		return nil
+	}
+	out := make([]PackCount, 0, len(counts))
+	for s, c := range counts {
+		out = append(out, PackCount{Size: s, Count: c})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Size > out[j].Size })
+	return out
+}
+
+// TieBreak selects which objective the calculator minimizes first.
+type TieBreak string
+
+const (
+	TieBreakItemsFirst TieBreak = "ItemsFirst" // Minimize total items, then number of packs (default)
+	TieBreakPacksFirst TieBreak = "PacksFirst" // Minimize number of packs, then total items
+)
+
+// ParseTieBreak parses a tie-break policy name, ignoring case.
+// Returns false if the name isn't a known policy.
+func ParseTieBreak(s string) (TieBreak, bool) {
+	switch {
+	case strings.EqualFold(s, string(TieBreakItemsFirst)):
+		return TieBreakItemsFirst, true
+	case strings.EqualFold(s, string(TieBreakPacksFirst)):
+		return TieBreakPacksFirst, true
+	}
+	return "", false
+}
+
+// CalcOptions adjusts how a single calculation chooses between valid solutions.
+type CalcOptions struct {
+	TieBreak  TieBreak // Objective order; empty uses the calculator's default
+	Preferred []int    // Sizes favored when items and packs are otherwise tied
+	
+	SummaryOnly bool // Skip building the breakdown; results carry totals only, with a nil Breakdown
+	
+	MinOnePerSize bool // Include at least one pack of every size; the rest of the amount is optimized as usual
+	
+	MaxPacks int // Use at most this many packs in total (0 means no limit); fewer may leave no solution
+	
+	// Smallest fraction (0-1) of any pack that must hold ordered items, 0 for no minimum.
+	// Packs are filled largest first, so only the last one is partly used, e.g. a 250 pack
+	// opened for 1 remaining item is 0.4% utilized. Too high a minimum may leave no solution.
+	MinUtilization float64
+	
+	// Pack set version the sizes were taken from, 0 for custom sizes. Calculators ignore it;
+	// it lets a result cache tie results to a version instead of the sizes alone.
+	Version int64
+	
+	// Calculate on one-off DP tables, bypassing the calculator's table cache and any result
+	// cache, for sizes unlikely to be asked for again, such as candidate sets being compared.
+	Uncached bool
+}
+
+// Pack represents a pack size with an optional SKU/label used by the warehouse system.
+type Pack struct {
+	Size int    `json:"size"`          // Number of items in the pack
+	SKU  string `json:"sku,omitempty"` // Optional SKU or label for the pack
+}
+
+// PackSetMeta describes the active pack set version, for correlating changes with deployments.
+type PackSetMeta struct {
+	Version   int64     `json:"version"`   // Version number of the active pack set
+	UpdatedAt time.Time `json:"updatedAt"` // When the active pack set was created
+}
+
+// CustomPackSet is a saved set of pack sizes that calculations can reference by ID
+// instead of sending the sizes with every request. It is separate from the active set.
+type CustomPackSet struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"` // Optional label chosen by the client
+	Sizes     []int     `json:"sizes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CacheStats reports pack-sizes cache effectiveness since startup or the last reset.
+type CacheStats struct {
+	Hits     uint64  `json:"hits"`     // Lookups answered from the cache
+	Misses   uint64  `json:"misses"`   // Lookups that fell through to the repository
+	HitRatio float64 `json:"hitRatio"` // Hits / (hits + misses), 0 when there were no lookups
+}
+
+// PoolStats reports how busy the calculator's worker pool is.
+type PoolStats struct {
+	Workers   int    `json:"workers"`   // Calculations that may run at once
+	Busy      int    `json:"busy"`      // Calculations running now
+	Waiting   int    `json:"waiting"`   // Calculations queued for a free worker
+	Saturated bool   `json:"saturated"` // Every worker is busy
+	Waits     uint64 `json:"waits"`     // Calculations since startup that had to queue
+}
+
+// DependencyHealth is the outcome of the latest background check of external dependencies.
+type DependencyHealth struct {
+	DatabaseOK bool      // The database answered the last ping
+	CacheOK    bool      // The cache answered the last ping (false when it isn't checked)
+	CheckedAt  time.Time // When the last check finished (zero before the first)
+
+	DatabaseRetryAt time.Time // When the open database breaker lets a trial call through (zero while closed)
+}
+
+// PackSetChanged is published after the active pack set is replaced, so other systems can react.
+type PackSetChanged struct {
+	Version   int64     `json:"version"`   // Version number of the new active pack set
+	Sizes     []int     `json:"sizes"`     // Pack sizes in the new active set
+	ChangedAt time.Time `json:"changedAt"` // When the change was made
+}
+
+// JobStatus represents the lifecycle state of an asynchronous calculation job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"    // Accepted, waiting for a worker
+	JobRunning   JobStatus = "running"   // Being processed
+	JobCompleted JobStatus = "completed" // All amounts processed
+	JobFailed    JobStatus = "failed"    // Aborted (error or stale worker)
+)
+
+// Job represents an asynchronous batch calculation and its progress.
+type Job struct {
+	ID        string              `json:"id"`
+	Status    JobStatus           `json:"status"`
+	Total     int                 `json:"total"`             // Number of amounts submitted
+	Processed int                 `json:"processed"`         // Number of amounts computed so far
+	Results   []CalculationResult `json:"results,omitempty"` // Results in submission order (once completed)
+	Error     string              `json:"error,omitempty"`   // Failure reason (failed jobs only)
+	CreatedAt time.Time           `json:"createdAt"`
+	UpdatedAt time.Time           `json:"updatedAt"` // Last heartbeat from the worker
 }
 
 // Ports (hexagonal architecture)
@@ -25,12 +175,41 @@ type PackRepository interface {
 	GetAllActive() ([]int, error)
 	
 	// ReplaceActive replaces all pack sizes with a new set.
-	// Returns the normalized (sorted, deduplicated) sizes.
-	ReplaceActive(sizes []int) ([]int, error)
+	// Returns the normalized (sorted, deduplicated) sizes and the version created for them.
+	ReplaceActive(sizes []int) ([]int, int64, error)
+	
+	// GetActivePacks returns the current active pack sizes along with their SKUs.
+	GetActivePacks() ([]Pack, error)
+	
+	// ReplaceActivePacks replaces all pack sizes and their SKUs with a new set.
+	// Returns the normalized (sorted by size, deduplicated) packs and the version created for them.
+	// While pack size changes are locked nothing is written and a *LockedError is returned; the
+	// lock is checked in the same step as the write.
+	ReplaceActivePacks(packs []Pack) ([]Pack, int64, error)
+	
+	// ReplaceActivePacksIfVersion is ReplaceActivePacks, but only writes while the active set is
+	// at one of the expected versions, checked and written in one step. Otherwise nothing is
+	// written and a *VersionConflictError names the current version. Locking applies as for
+	// ReplaceActivePacks.
+	ReplaceActivePacksIfVersion(packs []Pack, expected []int64) ([]Pack, int64, error)
 	
 	// CurrentVersion returns the highest version number.
 	// Used for cache key generation in versioned storage.
 	CurrentVersion() (int64, error)
+	
+	// GetPacksByVersion returns the pack sizes and SKUs stored in a specific version.
+	// Returns false if the version doesn't exist.
+	GetPacksByVersion(version int64) ([]Pack, bool, error)
+	
+	// LatestCreatedAt returns when the latest pack set version was created.
+	// Returns the zero time if no versions exist.
+	LatestCreatedAt() (time.Time, error)
+	
+	// IsLocked reports whether pack size changes are currently locked.
+	IsLocked() (bool, error)
+	
+	// SetLocked persists the pack size lock state.
+	SetLocked(locked bool) error
 }
 
 // Cache is the port for caching operations.
@@ -43,9 +222,34 @@ type Cache interface {
 	// Set stores a value in cache with a time-to-live.
 	Set(key string, value []byte, ttlSeconds int) error
 	
-	// DeleteByPrefix removes all keys matching the given prefix.
+	// GetMany retrieves the values of several keys in one round trip.
+	// Returns one entry per key, in the order of keys; a missing key's entry is nil.
+	GetMany(keys []string) ([][]byte, error)
+	
+	// SetMany stores several key/value pairs, all with the same time-to-live, in one round trip.
+	SetMany(entries map[string][]byte, ttlSeconds int) error
+	
+	// Delete removes a single key; removing a key that doesn't exist is not an error.
+	Delete(key string) error
+	
+	// DeleteByPrefix removes all keys matching the given prefix and returns how many were removed.
 	// Used for cache invalidation when data changes.
-	DeleteByPrefix(prefix string) error
+	DeleteByPrefix(prefix string) (int, error)
+}
+
+// Publisher is the port for announcing pack set changes to downstream consumers.
+// Implementations can use Redis Streams, Kafka, or any other log or message broker.
+type Publisher interface {
+	// Publish appends a pack set change event.
+	Publish(ctx context.Context, event PackSetChanged) error
+}
+
+// PackEventSubscriber is the port for receiving pack set change events as they happen.
+type PackEventSubscriber interface {
+	// Subscribe returns a channel of events published from now on and a function ending the
+	// subscription. The channel is closed when the subscription ends, including when the
+	// implementation drops a subscriber that falls too far behind.
+	Subscribe() (<-chan PackSetChanged, func())
 }
 
 // PacksService is the port for pack size management operations.
@@ -56,7 +260,52 @@ type PacksService interface {
 	GetActiveSizes(ctx context.Context) ([]int, error)
 	
 	// ReplaceActive replaces all pack sizes with a new set.
-	ReplaceActive(ctx context.Context, sizes []int) ([]int, error)
+	// Returns the stored sizes and the version created for them.
+	ReplaceActive(ctx context.Context, sizes []int) ([]int, int64, error)
+	
+	// GetActivePacks returns the current active pack sizes along with their SKUs.
+	GetActivePacks(ctx context.Context) ([]Pack, error)
+	
+	// ReplaceActivePacks replaces all pack sizes and their SKUs with a new set.
+	// Returns the stored packs and the version created for them.
+	ReplaceActivePacks(ctx context.Context, packs []Pack) ([]Pack, int64, error)
+	
+	// ReplaceActivePacksIfVersion replaces the active set like ReplaceActivePacks, but only if it
+	// is still at one of the expected versions; otherwise it returns a *VersionConflictError.
+	ReplaceActivePacksIfVersion(ctx context.Context, packs []Pack, expected []int64) ([]Pack, int64, error)
+	
+	// GetActivePacksWithVersion returns the active packs with the version they belong to,
+	// so a calculation can be repeated later against exactly the same set.
+	GetActivePacksWithVersion(ctx context.Context) ([]Pack, int64, error)
+	
+	// GetPacksAtVersion returns the pack sizes and SKUs of a historical pack set version.
+	// Returns false if the version doesn't exist.
+	GetPacksAtVersion(ctx context.Context, version int64) ([]Pack, bool, error)
+	
+	// GetMeta returns the version and last change time of the active pack set.
+	GetMeta(ctx context.Context) (PackSetMeta, error)
+	
+	// IsLocked reports whether pack size changes are locked (e.g. during a maintenance window).
+	IsLocked(ctx context.Context) (bool, error)
+	
+	// SetLocked locks or unlocks pack size changes.
+	SetLocked(ctx context.Context, locked bool) error
+	
+	// SaveCustomSet stores a custom pack set, assigning its ID and creation time.
+	// Saved sets expire after a configured time-to-live.
+	SaveCustomSet(ctx context.Context, set CustomPackSet) (CustomPackSet, error)
+	
+	// GetCustomSet returns a saved custom pack set.
+	// Returns false if the ID doesn't exist or the set has expired.
+	GetCustomSet(ctx context.Context, id string) (CustomPackSet, bool, error)
+	
+	// CacheStats returns the pack-sizes cache hit and miss counters.
+	// With reset, the counters are zeroed after being read.
+	CacheStats(ctx context.Context, reset bool) CacheStats
+	
+	// FlushCache removes every cached entry derived from the pack sets (pack lists and calculations)
+	// and returns how many keys were removed. Saved custom sets and jobs are kept.
+	FlushCache(ctx context.Context) (int, error)
 }
 
 // Calculator is the port for pack calculation operations.
@@ -64,6 +313,61 @@ type PacksService interface {
 type Calculator interface {
 	// Compute calculates the optimal pack distribution for a given amount.
 	// Uses the provided pack sizes, or active sizes if not specified.
-	// Returns a result with breakdown showing how many packs of each size are needed.
+	// Returns a result with breakdown showing how many packs of each size are needed,
+	// or a *NoSolutionError when no combination of packs fulfills the amount.
 	Compute(ctx context.Context, amount int, sizes []int) (CalculationResult, error)
+	
+	// ComputeWithOptions works like Compute but applies per-request options:
+	// a tie-break policy overriding the default, and preferred sizes used as a final tie-breaker.
+	ComputeWithOptions(ctx context.Context, amount int, sizes []int, opts CalcOptions) (CalculationResult, error)
+	
+	// ComputeBatch calculates optimal pack distributions for several amounts with the same pack sizes.
+	// Results are returned in the same order as amounts.
+	ComputeBatch(ctx context.Context, amounts []int, sizes []int) ([]CalculationResult, error)
+	
+	// ComputeBatchWithOptions works like ComputeBatch but applies per-request options to every amount.
+	ComputeBatchWithOptions(ctx context.Context, amounts []int, sizes []int, opts CalcOptions) ([]CalculationResult, error)
+	
+	// ExactFit reports whether amount can be fulfilled exactly, with no overage, from the pack sizes.
+	ExactFit(ctx context.Context, amount int, sizes []int) (bool, error)
+	
+	// PoolStats reports the occupancy of the worker pool bounding concurrent calculations.
+	PoolStats() PoolStats
+}
+
+// Validator is the port for input validation rules.
+// Every transport applies the same rules through it; failures are *ValidationError values.
+type Validator interface {
+	// ValidateAmount checks an order amount against the minimum order amount and the maximum.
+	ValidateAmount(amount int) error
+	
+	// ValidateSizes checks that every pack size is positive and within the maximum pack size.
+	ValidateSizes(sizes []int) error
+	
+	// ValidatePackSet checks that a resolved set of pack sizes can be calculated with.
+	ValidatePackSet(sizes []int) error
+	
+	// ValidatePackCount checks that a set to be stored doesn't exceed the maximum number of distinct sizes.
+	ValidatePackCount(sizes []int) error
+	
+	// ValidatePackSizesWithinAmount checks that no pack size is larger than the maximum order amount.
+	ValidatePackSizesWithinAmount(sizes []int) error
+	
+	// ValidateSKUs checks that every pack's SKU fits within the maximum length.
+	ValidateSKUs(packs []Pack) error
+	
+	// Limits returns the bounds the validator enforces.
+	Limits() ValidationLimits
+}
+
+// JobService is the port for asynchronous batch calculations.
+// Jobs are processed in the background; clients poll for progress and results.
+type JobService interface {
+	// Submit queues a batch of amounts for calculation with the given pack sizes.
+	// Returns the queued job immediately; processing continues in the background.
+	Submit(ctx context.Context, amounts []int, sizes []int) (Job, error)
+	
+	// Get returns the current state of a job.
+	// Returns false if the job doesn't exist or has expired.
+	Get(ctx context.Context, id string) (Job, bool, error)
 }
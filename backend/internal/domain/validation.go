@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"math"
+	"strconv"
+)
+
+// Default validation limits, matching the bounds the API has always documented.
+const (
+	DefaultMaxPackSize  = 10_000    // Largest pack size accepted
+	DefaultMaxAmount    = 1_000_000 // Largest order amount accepted
+	DefaultMaxPackCount = 100       // Most distinct pack sizes an active set may hold
+	DefaultMaxSKULength = 64        // Longest SKU/label accepted for a pack size or line item
+)
+
+// MaxLimit caps the configurable amount and pack size limits. An optimal solution totals less
+// than the amount plus the largest pack size, and every size × count of its breakdown is part
+// of that total, so with both limits at most MaxLimit no total or product can overflow an int,
+// even a 32-bit one.
+const MaxLimit = math.MaxInt32 / 2
+
+// MaxElevatedAmount caps the order amount limits, elevated ones included. A calculation fills a
+// DP table of about amount plus the largest pack size cells at 24 bytes each, so one at this
+// bound takes about 1.2 GB; MaxLimit alone would allow tables of about 24 GB.
+const MaxElevatedAmount = 50_000_000
+
+// ValidationLimits holds the configurable bounds applied by RuleValidator.
+// Zero values fall back to the defaults noted on each field.
+type ValidationLimits struct {
+	MinAmount    int // Smallest order amount accepted, the minimum order policy (default 1)
+	MaxAmount    int // Largest order amount accepted (default DefaultMaxAmount)
+	MaxPackSize  int // Largest pack size accepted (default DefaultMaxPackSize)
+	MaxPackCount int // Most distinct sizes in the active set (default DefaultMaxPackCount)
+	MaxSKULength int // Longest SKU accepted, in bytes (default DefaultMaxSKULength)
+}
+
+// RuleValidator implements the Validator port with configurable limits.
+type RuleValidator struct {
+	limits ValidationLimits
+}
+
+// NewValidator creates a validator for the given limits, filling in defaults for zero values.
+// Amount and pack size limits above MaxLimit are lowered to it.
+func NewValidator(limits ValidationLimits) *RuleValidator {
+	if limits.MinAmount <= 0 {
+		limits.MinAmount = 1
+	}
+	if limits.MaxAmount <= 0 {
+		limits.MaxAmount = DefaultMaxAmount
+	}
+	if limits.MaxPackSize <= 0 {
+		limits.MaxPackSize = DefaultMaxPackSize
+	}
+	if limits.MaxPackCount <= 0 {
+		limits.MaxPackCount = DefaultMaxPackCount
+	}
+	if limits.MaxSKULength <= 0 {
+		limits.MaxSKULength = DefaultMaxSKULength
+	}
+	limits.MaxAmount = min(limits.MaxAmount, MaxLimit)
+	limits.MaxPackSize = min(limits.MaxPackSize, MaxLimit)
+	return &RuleValidator{limits: limits}
+}
+
+// Limits returns the bounds this validator enforces.
+func (v *RuleValidator) Limits() ValidationLimits { return v.limits }
+
+// ValidateAmount checks that an order amount is positive, meets the minimum order amount
+// and doesn't exceed the maximum.
+func (v *RuleValidator) ValidateAmount(amount int) error {
+	switch {
+	case amount <= 0:
+		return &ValidationError{Field: "amount", Reason: "amount must be positive", Details: map[string]any{"value": amount}}
+	case amount < v.limits.MinAmount:
+		return &ValidationError{Field: "amount", Reason: "amount is below the minimum order amount",
+			Details: map[string]any{"value": amount, "minimum": v.limits.MinAmount}}
+	case amount > v.limits.MaxAmount:
+		return &ValidationError{Field: "amount", Reason: "amount cannot exceed " + groupDigits(v.limits.MaxAmount) + " items",
+			Details: map[string]any{"value": amount}}
+	}
+	return nil
+}
+
+// ValidateSizes checks that every pack size is positive and within the maximum pack size,
+// reporting the first offending index. An empty list is valid; see ValidatePackSet.
+func (v *RuleValidator) ValidateSizes(sizes []int) error {
+	for i, s := range sizes {
+		if s <= 0 {
+			return &ValidationError{Field: "sizes", Reason: "pack sizes must be positive", Details: map[string]any{"index": i, "value": s}}
+		}
+		if s > v.limits.MaxPackSize {
+			return &ValidationError{Field: "sizes", Reason: "pack sizes cannot exceed " + groupDigits(v.limits.MaxPackSize) + " items",
+				Details: map[string]any{"index": i, "value": s}}
+		}
+	}
+	return nil
+}
+
+// ValidatePackSet checks that a set of pack sizes can be calculated with, i.e. that it isn't empty.
+func (v *RuleValidator) ValidatePackSet(sizes []int) error {
+	if len(sizes) == 0 {
+		return &ValidationError{Field: "sizes", Reason: "no pack sizes configured"}
+	}
+	return nil
+}
+
+// ValidatePackCount checks that a set to be stored as the active set doesn't hold more distinct sizes
+// than the maximum. Duplicates are counted once, since they're removed when the set is stored.
+func (v *RuleValidator) ValidatePackCount(sizes []int) error {
+	distinct := make(map[int]struct{}, len(sizes))
+	for _, s := range sizes {
+		distinct[s] = struct{}{}
+	}
+	if len(distinct) > v.limits.MaxPackCount {
+		return &ValidationError{Field: "sizes", Reason: "cannot have more than " + groupDigits(v.limits.MaxPackCount) + " distinct pack sizes",
+			Details: map[string]any{"count": len(distinct), "maximum": v.limits.MaxPackCount}}
+	}
+	return nil
+}
+
+// ValidateSKUs checks that every pack's SKU fits within the maximum length,
+// reporting the first offending index.
+func (v *RuleValidator) ValidateSKUs(packs []Pack) error {
+	for i, p := range packs {
+		if len(p.SKU) > v.limits.MaxSKULength {
+			return &ValidationError{Field: "sizes", Reason: "SKU cannot exceed " + groupDigits(v.limits.MaxSKULength) + " characters",
+				Details: map[string]any{"index": i, "value": p.SKU}}
+		}
+	}
+	return nil
+}
+
+// ValidatePackSizesWithinAmount checks that no pack size exceeds the maximum order amount.
+// Such a size can still be stored and calculated with, but it can never be needed without
+// overage for an accepted order, so callers decide whether to reject it or only warn.
+func (v *RuleValidator) ValidatePackSizesWithinAmount(sizes []int) error {
+	for i, s := range sizes {
+		if s > v.limits.MaxAmount {
+			return &ValidationError{Field: "sizes", Reason: "pack sizes cannot exceed the maximum order amount of " + groupDigits(v.limits.MaxAmount) + " items",
+				Details: map[string]any{"index": i, "value": s, "maximum": v.limits.MaxAmount}}
+		}
+	}
+	return nil
+}
+
+// groupDigits formats n with comma thousands separators, e.g. 1000000 -> "1,000,000".
+func groupDigits(n int) string {
+	s := strconv.Itoa(n)
+	if n < 0 {
+		return "-" + groupDigits(-n)
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
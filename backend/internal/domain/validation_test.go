@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestRuleValidator_ValidateAmount(t *testing.T) {
+	v := NewValidator(ValidationLimits{MinAmount: 100, MaxAmount: 5000})
+
+	tests := []struct {
+		amount int
+		reason string
+	}{
+		{250, ""},
+		{100, ""},
+		{5000, ""},
+		{0, "amount must be positive"},
+		{-3, "amount must be positive"},
+		{99, "amount is below the minimum order amount"},
+		{5001, "amount cannot exceed 5,000 items"},
+	}
+
+	for _, tt := range tests {
+		err := v.ValidateAmount(tt.amount)
+		if tt.reason == "" {
+			if err != nil {
+				t.Errorf("amount %d: expected valid, got %v", tt.amount, err)
+			}
+			continue
+		}
+		var ve *ValidationError
+		if !errors.As(err, &ve) || ve.Field != "amount" || ve.Reason != tt.reason {
+			t.Errorf("amount %d: expected %q, got %v", tt.amount, tt.reason, err)
+		}
+	}
+}
+
+func TestRuleValidator_ValidateSizes(t *testing.T) {
+	v := NewValidator(ValidationLimits{})
+
+	if err := v.ValidateSizes(nil); err != nil {
+		t.Errorf("Expected an empty list to be valid, got %v", err)
+	}
+	if err := v.ValidateSizes([]int{250, DefaultMaxPackSize}); err != nil {
+		t.Errorf("Expected sizes up to the default maximum to be valid, got %v", err)
+	}
+
+	var ve *ValidationError
+	err := v.ValidateSizes([]int{250, 500, 10_001})
+	if !errors.As(err, &ve) || ve.Details["index"] != 2 || ve.Reason != "pack sizes cannot exceed 10,000 items" {
+		t.Errorf("Expected the oversized entry at index 2 to be reported, got %v", err)
+	}
+	err = v.ValidateSizes([]int{0})
+	if !errors.As(err, &ve) || ve.Reason != "pack sizes must be positive" {
+		t.Errorf("Expected a non-positive size to be rejected, got %v", err)
+	}
+}
+
+func TestRuleValidator_ValidatePackSet(t *testing.T) {
+	v := NewValidator(ValidationLimits{})
+	if err := v.ValidatePackSet(nil); err == nil {
+		t.Errorf("Expected an empty pack set to be rejected")
+	}
+	if err := v.ValidatePackSet([]int{250}); err != nil {
+		t.Errorf("Expected a non-empty pack set to be valid, got %v", err)
+	}
+}
+
+func TestRuleValidator_ValidatePackCount(t *testing.T) {
+	v := NewValidator(ValidationLimits{MaxPackCount: 2})
+
+	if err := v.ValidatePackCount([]int{250, 500}); err != nil {
+		t.Errorf("Expected a set at the limit to be valid, got %v", err)
+	}
+	if err := v.ValidatePackCount([]int{250, 500, 250}); err != nil {
+		t.Errorf("Expected duplicates to count once, got %v", err)
+	}
+
+	err := v.ValidatePackCount([]int{250, 500, 1000})
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("Expected a ValidationError above the limit, got %v", err)
+	}
+	if ve.Details["count"] != 3 || ve.Details["maximum"] != 2 {
+		t.Errorf("Unexpected details: %v", ve.Details)
+	}
+}
+
+func TestRuleValidator_ValidatePackSizesWithinAmount(t *testing.T) {
+	v := NewValidator(ValidationLimits{MaxAmount: 1000})
+
+	if err := v.ValidatePackSizesWithinAmount([]int{250, 1000}); err != nil {
+		t.Errorf("Expected sizes up to the maximum amount to be valid, got %v", err)
+	}
+
+	err := v.ValidatePackSizesWithinAmount([]int{250, 2000})
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("Expected a ValidationError above the maximum amount, got %v", err)
+	}
+	if ve.Details["index"] != 1 || ve.Details["value"] != 2000 || ve.Details["maximum"] != 1000 {
+		t.Errorf("Unexpected details: %v", ve.Details)
+	}
+}
+
+func TestRuleValidator_ValidateSKUs(t *testing.T) {
+	v := NewValidator(ValidationLimits{MaxSKULength: 4})
+
+	if err := v.ValidateSKUs([]Pack{{Size: 250, SKU: "ABCD"}, {Size: 500}}); err != nil {
+		t.Errorf("Expected SKUs up to the maximum length to be valid, got %v", err)
+	}
+
+	err := v.ValidateSKUs([]Pack{{Size: 250, SKU: "A"}, {Size: 500, SKU: "ABCDE"}})
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Reason != "SKU cannot exceed 4 characters" {
+		t.Fatalf("Expected a ValidationError above the maximum length, got %v", err)
+	}
+	if ve.Details["index"] != 1 || ve.Details["value"] != "ABCDE" {
+		t.Errorf("Unexpected details: %v", ve.Details)
+	}
+}
+
+func TestNewValidator_Defaults(t *testing.T) {
+	limits := NewValidator(ValidationLimits{}).Limits()
+	if limits.MinAmount != 1 || limits.MaxAmount != DefaultMaxAmount || limits.MaxPackSize != DefaultMaxPackSize || limits.MaxPackCount != DefaultMaxPackCount ||
+		limits.MaxSKULength != DefaultMaxSKULength {
+		t.Errorf("Unexpected default limits: %+v", limits)
+	}
+}
+
+func TestNewValidator_CapsLimits(t *testing.T) {
+	// The largest total is below amount + largest size, which must still fit a 32-bit int
+	if int64(MaxLimit)*2 > math.MaxInt32 {
+		t.Fatalf("MaxLimit %d lets totals exceed a 32-bit int", MaxLimit)
+	}
+
+	limits := NewValidator(ValidationLimits{MaxAmount: math.MaxInt, MaxPackSize: MaxLimit + 1}).Limits()
+	if limits.MaxAmount != MaxLimit || limits.MaxPackSize != MaxLimit {
+		t.Errorf("Expected both limits lowered to %d, got %+v", MaxLimit, limits)
+	}
+	if limits := NewValidator(ValidationLimits{MaxAmount: MaxLimit}).Limits(); limits.MaxAmount != MaxLimit {
+		t.Errorf("Expected a limit of exactly MaxLimit to be kept, got %d", limits.MaxAmount)
+	}
+}
@@ -4,13 +4,19 @@ package integration
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	pg "github.com/temo/pack-optimizer/backend/internal/adapters/postgres"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+	"github.com/temo/pack-optimizer/backend/internal/platform"
 )
 
 func TestPostgresRepository(t *testing.T) {
@@ -48,10 +54,11 @@ func TestPostgresRepository(t *testing.T) {
 	// create schema
 	_, _ = db.Exec(context.Background(), `
 CREATE EXTENSION IF NOT EXISTS pgcrypto;
-CREATE TABLE IF NOT EXISTS pack_sets (version BIGSERIAL PRIMARY KEY, sizes INTEGER[] NOT NULL, created_at TIMESTAMPTZ NOT NULL DEFAULT now());
+CREATE TABLE IF NOT EXISTS pack_sets (version BIGSERIAL PRIMARY KEY, sizes INTEGER[] NOT NULL, skus JSONB NOT NULL DEFAULT '{}'::jsonb, created_at TIMESTAMPTZ NOT NULL DEFAULT now());
+CREATE TABLE IF NOT EXISTS pack_lock (id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id), locked BOOLEAN NOT NULL DEFAULT FALSE, updated_at TIMESTAMPTZ NOT NULL DEFAULT now());
 `)
 	repo := pg.New(db)
-	_, err = repo.ReplaceActive([]int{10, 20, 50})
+	_, _, err = repo.ReplaceActive([]int{10, 20, 50})
 	if err != nil {
 		t.Fatalf("replace: %v", err)
 	}
@@ -62,6 +69,103 @@ CREATE TABLE IF NOT EXISTS pack_sets (version BIGSERIAL PRIMARY KEY, sizes INTEG
 	if len(out) != 3 || out[0] != 10 || out[2] != 50 {
 		t.Fatalf("unexpected sizes: %+v", out)
 	}
+	// SKU round-trip; each replace reports the version its INSERT created
+	_, first, err := repo.ReplaceActive([]int{10, 20, 50})
+	if err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	_, second, err := repo.ReplaceActivePacks([]domain.Pack{{Size: 500, SKU: "BOX-500"}, {Size: 250}})
+	if err != nil {
+		t.Fatalf("replace packs: %v", err)
+	}
+	if second != first+1 {
+		t.Fatalf("expected version %d after %d, got %d", first+1, first, second)
+	}
+	packs, err := repo.GetActivePacks()
+	if err != nil {
+		t.Fatalf("get packs: %v", err)
+	}
+	if len(packs) != 2 || packs[0] != (domain.Pack{Size: 250}) || packs[1] != (domain.Pack{Size: 500, SKU: "BOX-500"}) {
+		t.Fatalf("unexpected packs: %+v", packs)
+	}
+	// historical version lookup
+	ver, err := repo.CurrentVersion()
+	if err != nil {
+		t.Fatalf("current version: %v", err)
+	}
+	if old, ok, err := repo.GetPacksByVersion(ver - 1); err != nil || !ok || len(old) != 3 {
+		t.Fatalf("previous version: packs=%+v ok=%v err=%v", old, ok, err)
+	}
+	if _, ok, err := repo.GetPacksByVersion(ver + 100); err != nil || ok {
+		t.Fatalf("expected missing version: ok=%v err=%v", ok, err)
+	}
+	// conditional replace: of several writers expecting the same version, exactly one wins
+	var wg sync.WaitGroup
+	results := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(size int) {
+			defer wg.Done()
+			_, _, err := repo.ReplaceActivePacksIfVersion([]domain.Pack{{Size: size}}, []int64{ver})
+			results <- err
+		}(100 + i)
+	}
+	wg.Wait()
+	close(results)
+	won := 0
+	for err := range results {
+		var conflict *domain.VersionConflictError
+		switch {
+		case err == nil:
+			won++
+		case errors.As(err, &conflict) && conflict.Current == ver+1:
+		default:
+			t.Fatalf("conditional replace: unexpected error %v", err)
+		}
+	}
+	if won != 1 {
+		t.Fatalf("expected exactly one conditional replace to succeed, got %d", won)
+	}
+	if now, err := repo.CurrentVersion(); err != nil || now != ver+1 {
+		t.Fatalf("expected version %d after conditional replaces, got %d (err %v)", ver+1, now, err)
+	}
+	// latest version timestamp
+	if ts, err := repo.LatestCreatedAt(); err != nil || ts.IsZero() {
+		t.Fatalf("latest created_at: ts=%v err=%v", ts, err)
+	}
+	// lock round-trip
+	if locked, err := repo.IsLocked(); err != nil || locked {
+		t.Fatalf("expected unlocked by default: locked=%v err=%v", locked, err)
+	}
+	if err := repo.SetLocked(true); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	if locked, err := repo.IsLocked(); err != nil || !locked {
+		t.Fatalf("expected locked: locked=%v err=%v", locked, err)
+	}
+	var lockedErr *domain.LockedError
+	if _, _, err := repo.ReplaceActive([]int{250}); !errors.As(err, &lockedErr) {
+		t.Fatalf("expected a locked error while locked, got %v", err)
+	}
+	// statement timeout: a slow query is cancelled server-side
+	poolCfg, err := platform.NewPoolConfig(dsn, platform.PoolSettings{StatementTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("pool config: %v", err)
+	}
+	limited, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
+	if err != nil {
+		t.Fatalf("limited pool: %v", err)
+	}
+	defer limited.Close()
+	start := time.Now()
+	_, err = limited.Exec(context.Background(), "SELECT pg_sleep(5)")
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "57014" { // query_canceled
+		t.Fatalf("expected the statement to be cancelled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("statement ran for %v despite the timeout", elapsed)
+	}
 }
 
 
@@ -4,34 +4,66 @@ package platform
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"log/slog"
+	"sort"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 	httpad "github.com/temo/pack-optimizer/backend/internal/adapters/http"
 	pg "github.com/temo/pack-optimizer/backend/internal/adapters/postgres"
 	redisad "github.com/temo/pack-optimizer/backend/internal/adapters/redis"
 	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+	"github.com/temo/pack-optimizer/backend/internal/app/events"
+	"github.com/temo/pack-optimizer/backend/internal/app/jobs"
 	"github.com/temo/pack-optimizer/backend/internal/domain"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // App represents the fully configured application with all its dependencies.
 type App struct {
 	PacksSvc domain.PacksService // Service for managing pack sizes (with caching)
 	Calc     domain.Calculator   // Service for calculating optimal pack distributions
+	Jobs     domain.JobService   // Service for asynchronous batch calculations
+	
+	Health   *HealthMonitor      // Live database and cache health, checked in the background
+	Events   domain.PackEventSubscriber // Pack set changes, for streaming to clients
+	
+	CacheDegraded bool // True when Redis was unavailable at startup and caching is disabled
+	CacheDisabled bool // True when caching is turned off by configuration (CACHE_BACKEND=none)
+	TracingEnabled bool // True when spans are exported, so requests get server spans
+	
+	broker *events.Broker // Backs Events; closed by CloseStreams
+}
+
+// CloseStreams ends every open event stream (GET /packs/events). Register it with
+// http.Server.RegisterOnShutdown so graceful shutdown doesn't wait for streaming clients.
+func (a *App) CloseStreams() {
+	if a.broker != nil {
+		a.broker.Close()
+	}
 }
 
 // Bootstrap initializes the application by:
-// 1. Connecting to PostgreSQL with retry logic and circuit breaker
-// 2. Connecting to Redis with retry logic and circuit breaker
+// 1. Connecting to PostgreSQL (and the optional read replica) with retry logic and circuit breaker
+// 2. Connecting to Redis with retry logic and circuit breaker (optional unless REDIS_REQUIRED)
 // 3. Creating repository and cache adapters
-// 4. Wrapping repository with caching layer
-// 5. Creating calculator service
-// 6. Returning configured App and cleanup function
+// 4. Wrapping repository with caching layer (and pack set change events, if enabled)
+// 5. Warming up the pack-sizes cache in the background
+// 6. Creating calculator and async job services
+// 7. Starting the background health check of the database and cache
+// 8. Installing the OpenTelemetry tracer provider, if tracing is enabled
+// 9. Returning configured App and cleanup function
 //
 // Uses exponential backoff retry and circuit breaker pattern for resilience.
+// The circuit breakers keep guarding the dependencies after startup through the health check.
 func Bootstrap(cfg Config, logger *slog.Logger) (*App, func(context.Context) error) {
 	ctx := context.Background()
 	
@@ -52,47 +84,197 @@ func Bootstrap(cfg Config, logger *slog.Logger) (*App, func(context.Context) err
 	redisCircuitBreaker := NewCircuitBreaker(logger, 5, 30*time.Second)
 	
 	// Connect to PostgreSQL with retry logic and circuit breaker
-	pool, err := ConnectPostgresWithRetry(ctx, logger, cfg.PostgresURL, retryConfig, dbCircuitBreaker)
+	pool, err := ConnectPostgresWithRetry(ctx, logger, cfg.PostgresURL, cfg.DBPool, retryConfig, dbCircuitBreaker)
 	if err != nil {
 		logger.Error("postgres not ready after retries", "error", err)
 		panic(err)
 	}
 
-	// Connect to Redis with retry logic and circuit breaker
-	rdb, err := ConnectRedisWithRetry(ctx, logger, cfg.RedisAddr, cfg.RedisPass, retryConfig, redisCircuitBreaker)
-	if err != nil {
-		logger.Error("redis not ready after retries", "error", err)
-		panic(err)
+	// Connect to the read replica if configured; a replica outage shouldn't stop startup,
+	// so fall back to reading from the primary instead of failing
+	var readPool *pgxpool.Pool
+	if cfg.PostgresReadURL != "" {
+		replicaCircuitBreaker := NewCircuitBreaker(logger, 5, 30*time.Second)
+		readPool, err = ConnectPostgresWithRetry(ctx, logger, cfg.PostgresReadURL, cfg.DBPool, retryConfig, replicaCircuitBreaker)
+		if err != nil {
+			logger.Error("postgres read replica not ready, reading from primary", "error", err)
+			readPool = nil
+		}
+	}
+
+	// Select the cache backend. With CACHE_BACKEND=none Redis isn't contacted at all.
+	// Otherwise connect with retry logic and circuit breaker; the cache is optional, so unless
+	// Redis is required, start in a degraded no-cache mode instead of failing.
+	var cache domain.Cache = noopCache{}
+	var rdb *redis.Client
+	degraded := false
+	if cfg.CacheBackend != CacheBackendRedis && cfg.CacheBackend != CacheBackendNone {
+		logger.Warn("unknown CACHE_BACKEND, using redis", "value", cfg.CacheBackend)
+	}
+	if cfg.CacheBackend == CacheBackendNone {
+		logger.Info("caching disabled (async jobs unavailable)")
+	} else {
+		rdb, err = ConnectRedisWithRetry(ctx, logger, cfg.RedisAddr, cfg.RedisPass, retryConfig, redisCircuitBreaker)
+		if err != nil {
+			if cfg.RedisRequired {
+				logger.Error("redis not ready after retries", "error", err)
+				panic(err)
+			}
+			logger.Warn("redis not ready after retries, running without cache (async jobs unavailable)", "error", err)
+			degraded = true
+		} else {
+			rc := redisad.New(rdb)
+			if cfg.CacheCompression {
+				rc.WithCompression(cfg.CacheCompressionMinBytes)
+			}
+			cache = rc
+		}
 	}
 
 	// Create adapters
-	repo := pg.New(pool)              // PostgreSQL repository
-	cache := redisad.New(rdb)         // Redis cache adapter
+	repo := pg.NewWithReplica(pool, readPool) // PostgreSQL repository (reads from replica if connected)
 	
 	// Wrap repository with caching layer
-	ps := &packsService{repo: repo, cache: cache, ttl: cfg.CacheTTLSecs}
+	ps := &packsService{repo: repo, cache: cache, ttl: cfg.CacheTTLSecs, customTTL: cfg.CustomSetTTLSecs, required: cfg.RequiredPackSizes, logger: logger}
+	
+	// Pack set changes reach this replica's subscribers (GET /packs/events) through a broker.
+	// With events enabled they go to a Redis stream instead, using the cache's Redis connection,
+	// and every replica feeds its broker from the stream so changes made anywhere reach everyone.
+	broker := events.NewBroker()
+	ps.publisher = broker
+	var streamSub *redisad.StreamSubscriber
+	if cfg.PackEventsEnabled {
+		if rdb != nil {
+			ps.publisher = redisad.NewStreamPublisher(rdb, cfg.PackEventsStream, int64(cfg.PackEventsMaxLen))
+			streamSub = redisad.NewStreamSubscriber(rdb, cfg.PackEventsStream)
+			logger.Info("publishing pack set changes", "stream", cfg.PackEventsStream)
+		} else {
+			logger.Warn("redis unavailable, pack set change events disabled", "stream", cfg.PackEventsStream)
+		}
+	}
+	
+	// Warm up the pack-sizes cache in the background so the first request doesn't miss
+	go warmPackSizesCache(ctx, logger, ps)
+	
+	// Create calculator service with the configured tie-break policy and worker pool size
+	tieBreak, ok := domain.ParseTieBreak(cfg.TieBreak)
+	if !ok {
+		logger.Warn("unknown TIE_BREAK, using ItemsFirst", "value", cfg.TieBreak)
+		tieBreak = domain.TieBreakItemsFirst
+	}
+	calc := calculator.NewServiceWithTieBreak(tieBreak).WithWorkers(cfg.CalcWorkers)
+	
+	// Optionally cache calculation results in Redis, for workloads repeating the same requests
+	var apiCalc domain.Calculator = calc
+	if cfg.CalcCacheEnabled && rdb != nil {
+		apiCalc = newCachingCalculator(calc, cache, cfg.CacheTTLSecs, tieBreak)
+	}
+	
+	// Create async job service (job state lives in Redis via the cache port)
+	jobSvc := jobs.NewService(cache, calc, logger, cfg.JobTTLSecs)
+	
+	// Ping the database and cache through their breakers until cleanup, so readiness stays live
+	health := NewHealthMonitor(logger, cfg.HealthCheckInterval, dbCircuitBreaker, pool.Ping)
+	if rdb != nil {
+		health.WithCache(redisCircuitBreaker, func(ctx context.Context) error { return rdb.Ping(ctx).Err() })
+	}
+	ps.dbHealth = health
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		health.Run(bgCtx)
+	}()
 	
-	// Create calculator service
-	calc := calculator.NewService()
+	// Optionally re-cache the active pack set shortly before each expiry, so reads never miss
+	if interval := cfg.CacheRefreshInterval(); interval > 0 && rdb != nil {
+		refresher := newCacheRefresher(logger, ps, interval)
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			refresher.Run(bgCtx)
+		}()
+		logger.Info("pack sizes cache refresh enabled", "interval", interval)
+	}
+	
+	// Follow the pack events stream, forwarding changes from every replica to local subscribers
+	if streamSub != nil {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			streamSub.Run(bgCtx, func(e domain.PackSetChanged) { _ = broker.Publish(bgCtx, e) }, func(err error) {
+				logger.Warn("reading pack events stream failed", "stream", cfg.PackEventsStream, "error", err)
+			})
+		}()
+	}
+
+	// Export spans if tracing is enabled; tracing is diagnostics only, so a bad exporter
+	// configuration is logged and the service keeps running untraced
+	shutdownTracing, err := setupTracing(ctx, cfg.Tracing)
+	tracingEnabled := cfg.Tracing.Enabled
+	if err != nil {
+		logger.Warn("tracing setup failed, running without tracing", "error", err)
+		shutdownTracing = func(context.Context) error { return nil }
+		tracingEnabled = false
+	} else if tracingEnabled {
+		logger.Info("tracing enabled", "service", cfg.Tracing.ServiceName, "sample_ratio", cfg.Tracing.SampleRatio)
+	}
 
 	// Return configured app and cleanup function
-	return &App{PacksSvc: ps, Calc: calc}, func(ctx context.Context) error {
-		rdb.Close()
+	app := &App{PacksSvc: ps, Calc: apiCalc, Jobs: jobSvc, Health: health, Events: broker, broker: broker, CacheDegraded: degraded, CacheDisabled: cfg.CacheBackend == CacheBackendNone, TracingEnabled: tracingEnabled}
+	return app, func(ctx context.Context) error {
+		// Stop the job workers and background goroutines before closing the connections they use
+		if err := jobSvc.Close(ctx); err != nil {
+			logger.Warn("job workers did not stop in time", "error", err)
+		}
+		stopBackground()
+		background.Wait()
+		if rdb != nil {
+			rdb.Close()
+		}
+		if readPool != nil {
+			readPool.Close()
+		}
 		pool.Close()
-		return nil
+		// Flush the spans still buffered for export
+		return shutdownTracing(ctx)
+	}
+}
+
+// warmPackSizesCache populates the pack-sizes cache by fetching the active sizes once.
+// Failures are logged and ignored - the cache will be filled on the first real request instead.
+func warmPackSizesCache(ctx context.Context, logger *slog.Logger, svc domain.PacksService) {
+	sizes, err := svc.GetActiveSizes(ctx)
+	if err != nil {
+		logger.Warn("pack sizes cache warm-up failed", "error", err)
+		return
 	}
+	logger.Info("pack sizes cache warmed up", "sizes", len(sizes))
 }
 
 // MountRoutes registers all API routes on the provided router.
 // Routes are mounted under the /api/v1 prefix.
-func MountRoutes(r *chi.Mux, app *App, errorHandler *httpad.ErrorHandler) {
+func MountRoutes(r *chi.Mux, app *App, errorHandler *httpad.ErrorHandler, handlerCfg httpad.HandlerConfig) {
+	// Readiness reports the degraded or disabled no-cache modes and live dependency health
+	handlerCfg.CacheDegraded = app.CacheDegraded
+	handlerCfg.CacheDisabled = app.CacheDisabled
+	if app.Health != nil {
+		handlerCfg.Health = app.Health.Status
+	}
+	handlerCfg.PackEvents = app.Events
+	
 	r.Route("/api/v1", func(api chi.Router) {
+		// Trace every request when tracing is enabled (outermost, so the span covers recovery too)
+		if app.TracingEnabled {
+			api.Use(httpad.TracingMiddleware)
+		}
+		// Add request ID middleware for tracing (first, so recovered panics carry the ID too)
+		api.Use(httpad.NewRequestIDMiddleware(handlerCfg.RequestIDs))
 		// Add recovery middleware to catch panics
 		api.Use(httpad.RecoveryMiddleware(errorHandler))
-		// Add request ID middleware for tracing
-		api.Use(httpad.RequestIDMiddleware)
 		// Mount API routes
-		api.Mount("/", httpad.NewRouter(app.PacksSvc, app.Calc, errorHandler))
+		api.Mount("/", httpad.NewRouter(app.PacksSvc, app.Calc, app.Jobs, errorHandler, handlerCfg))
 	})
 }
 
@@ -102,34 +284,68 @@ func MountRoutes(r *chi.Mux, app *App, errorHandler *httpad.ErrorHandler) {
 type packsService struct {
 	repo  interface {
 		GetAllActive() ([]int, error)
-		ReplaceActive(sizes []int) ([]int, error)
+		ReplaceActive(sizes []int) ([]int, int64, error)
+		GetActivePacks() ([]domain.Pack, error)
+		ReplaceActivePacks(packs []domain.Pack) ([]domain.Pack, int64, error)
+		ReplaceActivePacksIfVersion(packs []domain.Pack, expected []int64) ([]domain.Pack, int64, error)
 		CurrentVersion() (int64, error)
+		GetActivePacksWithVersion() ([]domain.Pack, int64, error)
+		GetPacksByVersion(version int64) ([]domain.Pack, bool, error)
+		LatestCreatedAt() (time.Time, error)
+		IsLocked() (bool, error)
+		SetLocked(locked bool) error
 	}
-	cache interface {
-		Get(key string) ([]byte, error)
-		Set(key string, value []byte, ttlSeconds int) error
-		DeleteByPrefix(prefix string) error
-	}
-	ttl int // Cache time-to-live in seconds
+	cache     domain.Cache
+	ttl       int   // Cache time-to-live in seconds
+	customTTL int   // Custom pack set time-to-live in seconds
+	required  []int // Pack sizes that must always remain in the active set
+	
+	publisher domain.Publisher // Announces pack set changes (nil disables events)
+	logger    *slog.Logger     // Reports failures to publish events
+	
+	// dbHealth reports how long the database breaker stays open; nil never fails fast
+	dbHealth interface{ DatabaseRetryAfter() time.Duration }
+	
+	hits   atomic.Uint64 // GetActiveSizes lookups answered from the cache
+	misses atomic.Uint64 // GetActiveSizes lookups that went to the repository
 }
 
 // GetActiveSizes retrieves pack sizes with caching.
 // First checks cache using version-based key, falls back to repository if cache miss.
 // Caches the result for future requests.
+// An entry that doesn't decode (corrupted, or written in an older format) is logged, deleted and
+// treated as a miss, so the cache repairs itself instead of returning empty sizes.
+// Fails fast while the database circuit breaker is open (see dbUnavailable).
 func (p *packsService) GetActiveSizes(ctx context.Context) ([]int, error) {
+	ctx, span := tracer().Start(ctx, "packs.GetActiveSizes")
+	defer span.End()
+	if err := p.dbUnavailable(); err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	
 	// Get current version for cache key
-	ver, _ := p.repo.CurrentVersion()
+	ver, _ := p.currentVersion(ctx)
+	span.SetAttributes(attribute.Int64("pack.version", ver))
 	key := "packlist:v1:" + strconv.FormatInt(ver, 10)
 	
 	// Try cache first
-	if b, _ := p.cache.Get(key); b != nil {
+	if b := tracedCacheGet(ctx, p.cache, key); b != nil {
 		var out []int
-		_ = json.Unmarshal(b, &out)
-		return out, nil
+		err := json.Unmarshal(b, &out)
+		if err == nil {
+			p.hits.Add(1)
+			return out, nil
+		}
+		p.logger.Warn("discarding undecodable cache entry", "key", key, "error", err)
+		_ = p.cache.Delete(key)
 	}
 	
 	// Cache miss - fetch from repository
+	p.misses.Add(1)
+	querySpan := startQuerySpan(ctx, "GetAllActive")
 	sizes, err := p.repo.GetAllActive()
+	endQuerySpan(querySpan, err)
 	if err != nil {
 		return nil, err
 	}
@@ -142,19 +358,378 @@ func (p *packsService) GetActiveSizes(ctx context.Context) ([]int, error) {
 	return sizes, nil
 }
 
+// dbUnavailable returns a domain.UnavailableError while the database circuit breaker is open,
+// carrying the time until the breaker's half-open trial, so reads fail fast instead of waiting
+// on a database known to be down. Returns nil otherwise.
+func (p *packsService) dbUnavailable() error {
+	if p.dbHealth == nil {
+		return nil
+	}
+	if wait := p.dbHealth.DatabaseRetryAfter(); wait > 0 {
+		return &domain.UnavailableError{Dependency: "database", RetryAfter: wait}
+	}
+	return nil
+}
+
+// CacheStats reports GetActiveSizes cache hits and misses, optionally resetting them.
+// Each counter is read (and reset) atomically; the pair is not a single snapshot, so under
+// concurrent traffic a lookup may be counted in the next period instead of this one.
+func (p *packsService) CacheStats(ctx context.Context, reset bool) domain.CacheStats {
+	var hits, misses uint64
+	if reset {
+		hits, misses = p.hits.Swap(0), p.misses.Swap(0)
+	} else {
+		hits, misses = p.hits.Load(), p.misses.Load()
+	}
+	
+	stats := domain.CacheStats{Hits: hits, Misses: misses}
+	if total := hits + misses; total > 0 {
+		stats.HitRatio = float64(hits) / float64(total)
+	}
+	return stats
+}
+
 // ReplaceActive updates pack sizes and invalidates related cache entries.
 // After updating the repository, it clears all pack list and calculation caches
 // to ensure consistency.
-func (p *packsService) ReplaceActive(ctx context.Context, sizes []int) ([]int, error) {
+func (p *packsService) ReplaceActive(ctx context.Context, sizes []int) ([]int, int64, error) {
+	// Enforce the required pack sizes policy
+	if err := p.checkRequired(sizes); err != nil {
+		return nil, 0, err
+	}
+	
 	// Update repository (creates new version)
-	out, err := p.repo.ReplaceActive(sizes)
+	out, ver, err := p.repo.ReplaceActive(sizes)
 	if err != nil {
+		return nil, 0, err
+	}
+	
+	// Invalidate all related caches
+	p.invalidate()
+	
+	p.publishChange(ctx, ver, out)
+	return out, ver, nil
+}
+
+// GetActivePacks retrieves pack sizes with their SKUs, cached like GetActiveSizes.
+// An undecodable entry is logged, deleted and treated as a miss, as in GetActiveSizes.
+func (p *packsService) GetActivePacks(ctx context.Context) ([]domain.Pack, error) {
+	ctx, span := tracer().Start(ctx, "packs.GetActivePacks")
+	defer span.End()
+	if err := p.dbUnavailable(); err != nil {
+		recordSpanError(span, err)
 		return nil, err
 	}
 	
+	// Get current version for cache key
+	ver, _ := p.currentVersion(ctx)
+	span.SetAttributes(attribute.Int64("pack.version", ver))
+	key := "packs:v1:" + strconv.FormatInt(ver, 10)
+	
+	// Try cache first
+	if b := tracedCacheGet(ctx, p.cache, key); b != nil {
+		var out []domain.Pack
+		err := json.Unmarshal(b, &out)
+		if err == nil {
+			return out, nil
+		}
+		p.logger.Warn("discarding undecodable cache entry", "key", key, "error", err)
+		_ = p.cache.Delete(key)
+	}
+	
+	// Cache miss - fetch from repository
+	querySpan := startQuerySpan(ctx, "GetActivePacks")
+	packs, err := p.repo.GetActivePacks()
+	endQuerySpan(querySpan, err)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Cache the result for future requests
+	if b, err := json.Marshal(packs); err == nil {
+		_ = p.cache.Set(key, b, p.ttl)
+	}
+	
+	return packs, nil
+}
+
+// ReplaceActivePacks updates pack sizes with their SKUs and invalidates related cache entries.
+func (p *packsService) ReplaceActivePacks(ctx context.Context, packs []domain.Pack) ([]domain.Pack, int64, error) {
+	return p.replacePacks(ctx, packs, p.repo.ReplaceActivePacks)
+}
+
+// ReplaceActivePacksIfVersion is ReplaceActivePacks, but the repository only writes the new set
+// while the active one is at an expected version; otherwise a *domain.VersionConflictError is
+// returned and the caches are left alone.
+func (p *packsService) ReplaceActivePacksIfVersion(ctx context.Context, packs []domain.Pack, expected []int64) ([]domain.Pack, int64, error) {
+	return p.replacePacks(ctx, packs, func(packs []domain.Pack) ([]domain.Pack, int64, error) {
+		return p.repo.ReplaceActivePacksIfVersion(packs, expected)
+	})
+}
+
+// replacePacks enforces the required sizes policy, stores packs with write, then invalidates the
+// caches and announces the change.
+func (p *packsService) replacePacks(ctx context.Context, packs []domain.Pack, write func([]domain.Pack) ([]domain.Pack, int64, error)) ([]domain.Pack, int64, error) {
+	// Enforce the required pack sizes policy
+	sizes := make([]int, len(packs))
+	for i, pk := range packs {
+		sizes[i] = pk.Size
+	}
+	if err := p.checkRequired(sizes); err != nil {
+		return nil, 0, err
+	}
+	
+	// Update repository (creates new version)
+	out, ver, err := write(packs)
+	if err != nil {
+		return nil, 0, err
+	}
+	
 	// Invalidate all related caches
-	_ = p.cache.DeleteByPrefix("packlist:v1:")
-	_ = p.cache.DeleteByPrefix("calc:v1:")
+	p.invalidate()
+	
+	stored := make([]int, len(out))
+	for i, pk := range out {
+		stored[i] = pk.Size
+	}
+	p.publishChange(ctx, ver, stored)
+	return out, ver, nil
+}
+
+// publishChange announces a new active pack set if a publisher is configured.
+// The change is already stored, so a failure to publish is logged rather than returned.
+func (p *packsService) publishChange(ctx context.Context, ver int64, sizes []int) {
+	if p.publisher == nil {
+		return
+	}
+	err := p.publisher.Publish(ctx, domain.PackSetChanged{Version: ver, Sizes: sizes, ChangedAt: time.Now().UTC()})
+	if err != nil {
+		p.logger.Warn("failed to publish pack set change", "version", ver, "error", err)
+	}
+}
+
+// SaveCustomSet stores a custom pack set in the cache under a random ID.
+// The sizes slice is stored as given; callers validate and normalize it first.
+func (p *packsService) SaveCustomSet(ctx context.Context, set domain.CustomPackSet) (domain.CustomPackSet, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return domain.CustomPackSet{}, err
+	}
+	set.ID = hex.EncodeToString(id[:])
+	set.CreatedAt = time.Now().UTC()
+	
+	b, err := json.Marshal(set)
+	if err != nil {
+		return domain.CustomPackSet{}, err
+	}
+	if err := p.cache.Set("packset:v1:"+set.ID, b, p.customTTL); err != nil {
+		return domain.CustomPackSet{}, err
+	}
+	return set, nil
+}
+
+// GetCustomSet loads a custom pack set saved by SaveCustomSet.
+// Returns false if the ID is unknown or the set has expired from the cache.
+func (p *packsService) GetCustomSet(ctx context.Context, id string) (domain.CustomPackSet, bool, error) {
+	b, err := p.cache.Get("packset:v1:" + id)
+	if err != nil || b == nil {
+		return domain.CustomPackSet{}, false, err
+	}
+	var set domain.CustomPackSet
+	if err := json.Unmarshal(b, &set); err != nil {
+		return domain.CustomPackSet{}, false, err
+	}
+	return set, true, nil
+}
+
+// GetActivePacksWithVersion retrieves the active packs and their version, cached like GetActivePacks.
+// A version's contents never change, so a cache hit for the current version is exact; on a miss
+// a single repository query reads both, so they can't straddle a concurrent update.
+// An undecodable entry is logged, deleted and treated as a miss, as in GetActivePacks.
+func (p *packsService) GetActivePacksWithVersion(ctx context.Context) ([]domain.Pack, int64, error) {
+	ctx, span := tracer().Start(ctx, "packs.GetActivePacksWithVersion")
+	defer span.End()
+	if err := p.dbUnavailable(); err != nil {
+		recordSpanError(span, err)
+		return nil, 0, err
+	}
+	
+	// Try cache first, keyed by the current version
+	if ver, err := p.currentVersion(ctx); err == nil {
+		key := "packs:v1:" + strconv.FormatInt(ver, 10)
+		if b := tracedCacheGet(ctx, p.cache, key); b != nil {
+			var out []domain.Pack
+			err := json.Unmarshal(b, &out)
+			if err == nil {
+				span.SetAttributes(attribute.Int64("pack.version", ver))
+				return out, ver, nil
+			}
+			p.logger.Warn("discarding undecodable cache entry", "key", key, "error", err)
+			_ = p.cache.Delete(key)
+		}
+	}
+	
+	// Cache miss - fetch packs and version together from the repository
+	querySpan := startQuerySpan(ctx, "GetActivePacksWithVersion")
+	packs, ver, err := p.repo.GetActivePacksWithVersion()
+	endQuerySpan(querySpan, err)
+	if err != nil {
+		return nil, 0, err
+	}
+	span.SetAttributes(attribute.Int64("pack.version", ver))
+	
+	// Cache the result under the version it was read at
+	if b, err := json.Marshal(packs); err == nil {
+		_ = p.cache.Set("packs:v1:"+strconv.FormatInt(ver, 10), b, p.ttl)
+	}
+	
+	return packs, ver, nil
+}
+
+// RefreshActivePacks reads the active pack set from the repository and writes it to the cache
+// under the keys GetActiveSizes and GetActivePacks read, restarting their TTL whether or not
+// they were cached. Fails fast while the database circuit breaker is open, leaving the entries
+// to expire as usual.
+func (p *packsService) RefreshActivePacks(ctx context.Context) error {
+	ctx, span := tracer().Start(ctx, "packs.RefreshActivePacks")
+	defer span.End()
+	if err := p.dbUnavailable(); err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	
+	// A single query, so the packs and version can't straddle a concurrent update
+	querySpan := startQuerySpan(ctx, "GetActivePacksWithVersion")
+	packs, ver, err := p.repo.GetActivePacksWithVersion()
+	endQuerySpan(querySpan, err)
+	if err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	span.SetAttributes(attribute.Int64("pack.version", ver))
 	
-	return out, nil
+	sizes := make([]int, len(packs))
+	for i, pk := range packs {
+		sizes[i] = pk.Size
+	}
+	suffix := strconv.FormatInt(ver, 10)
+	for key, v := range map[string]any{"packlist:v1:" + suffix: sizes, "packs:v1:" + suffix: packs} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if err := p.cache.Set(key, b, p.ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPacksAtVersion retrieves the packs of a historical version.
+// Uses the same cache key as GetActivePacks since a version's contents never change, and like it
+// discards an undecodable entry and reads the repository instead.
+func (p *packsService) GetPacksAtVersion(ctx context.Context, version int64) ([]domain.Pack, bool, error) {
+	key := "packs:v1:" + strconv.FormatInt(version, 10)
+	
+	// Try cache first
+	if b, _ := p.cache.Get(key); b != nil {
+		var out []domain.Pack
+		err := json.Unmarshal(b, &out)
+		if err == nil {
+			return out, true, nil
+		}
+		p.logger.Warn("discarding undecodable cache entry", "key", key, "error", err)
+		_ = p.cache.Delete(key)
+	}
+	
+	// Cache miss - fetch from repository
+	packs, ok, err := p.repo.GetPacksByVersion(version)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	
+	// Cache the result for future requests
+	if b, err := json.Marshal(packs); err == nil {
+		_ = p.cache.Set(key, b, p.ttl)
+	}
+	
+	return packs, true, nil
+}
+
+// GetMeta returns the active pack set version and when it was created.
+// Read straight from the repository; both queries hit the primary key index.
+func (p *packsService) GetMeta(ctx context.Context) (domain.PackSetMeta, error) {
+	ver, err := p.repo.CurrentVersion()
+	if err != nil {
+		return domain.PackSetMeta{}, err
+	}
+	updatedAt, err := p.repo.LatestCreatedAt()
+	if err != nil {
+		return domain.PackSetMeta{}, err
+	}
+	return domain.PackSetMeta{Version: ver, UpdatedAt: updatedAt}, nil
+}
+
+// checkRequired returns a *domain.RequiredPackSizesError if any required size is missing from sizes.
+// Both replacing and deleting pack sizes go through this check, so the policy holds for every caller.
+func (p *packsService) checkRequired(sizes []int) error {
+	present := make(map[int]bool, len(sizes))
+	for _, s := range sizes {
+		present[s] = true
+	}
+	
+	var missing []int
+	for _, s := range p.required {
+		if !present[s] {
+			missing = append(missing, s)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Ints(missing)
+	return &domain.RequiredPackSizesError{Missing: missing}
+}
+
+// versionedCachePrefixes are the key prefixes of cache entries that depend on the stored pack sets.
+var versionedCachePrefixes = []string{"packlist:v1:", "packs:v1:", calcCachePrefix}
+
+// derivedCachePrefixes are the key prefixes of every cache entry that can be rebuilt: the versioned
+// entries plus custom-size calculations. Saved custom sets ("packset:v1:") and jobs are the only
+// copy, so they're not included.
+var derivedCachePrefixes = append(append([]string(nil), versionedCachePrefixes...), customCalcCachePrefix)
+
+// invalidate clears all pack list, labeled pack and calculation caches tied to pack set versions.
+// Custom-size calculation results don't depend on the active set, so they're kept.
+func (p *packsService) invalidate() {
+	for _, prefix := range versionedCachePrefixes {
+		_, _ = p.cache.DeleteByPrefix(prefix)
+	}
+}
+
+// FlushCache clears every derived cache entry on demand, including custom-size calculation results,
+// reporting how many keys were removed. Every prefix is attempted even if one fails; the first error
+// is returned with the total so far.
+func (p *packsService) FlushCache(ctx context.Context) (int, error) {
+	removed := 0
+	var firstErr error
+	for _, prefix := range derivedCachePrefixes {
+		n, err := p.cache.DeleteByPrefix(prefix)
+		removed += n
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return removed, firstErr
+}
+
+// IsLocked reports whether pack size changes are locked.
+// The lock is read straight from the repository so it is never stale.
+func (p *packsService) IsLocked(ctx context.Context) (bool, error) {
+	return p.repo.IsLocked()
+}
+
+// SetLocked locks or unlocks pack size changes.
+func (p *packsService) SetLocked(ctx context.Context, locked bool) error {
+	return p.repo.SetLocked(locked)
 }
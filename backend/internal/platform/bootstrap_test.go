@@ -0,0 +1,562 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// fakeRepo is an in-memory pack repository for testing the packsService.
+type fakeRepo struct {
+	packs   []domain.Pack
+	version int64
+}
+
+func (f *fakeRepo) GetAllActive() ([]int, error) {
+	sizes := make([]int, len(f.packs))
+	for i, p := range f.packs {
+		sizes[i] = p.Size
+	}
+	return sizes, nil
+}
+
+func (f *fakeRepo) ReplaceActive(sizes []int) ([]int, int64, error) {
+	packs := make([]domain.Pack, len(sizes))
+	for i, s := range sizes {
+		packs[i] = domain.Pack{Size: s}
+	}
+	_, version, err := f.ReplaceActivePacks(packs)
+	if err != nil {
+		return nil, 0, err
+	}
+	return sizes, version, nil
+}
+
+func (f *fakeRepo) GetActivePacks() ([]domain.Pack, error) { return f.packs, nil }
+
+func (f *fakeRepo) ReplaceActivePacks(packs []domain.Pack) ([]domain.Pack, int64, error) {
+	f.packs = packs
+	f.version++
+	return packs, f.version, nil
+}
+
+func (f *fakeRepo) ReplaceActivePacksIfVersion(packs []domain.Pack, expected []int64) ([]domain.Pack, int64, error) {
+	if !slices.Contains(expected, f.version) {
+		return nil, 0, &domain.VersionConflictError{Expected: expected, Current: f.version}
+	}
+	return f.ReplaceActivePacks(packs)
+}
+
+func (f *fakeRepo) GetActivePacksWithVersion() ([]domain.Pack, int64, error) {
+	return f.packs, f.version, nil
+}
+
+// GetPacksByVersion only knows the current version.
+func (f *fakeRepo) GetPacksByVersion(version int64) ([]domain.Pack, bool, error) {
+	if version != f.version {
+		return nil, false, nil
+	}
+	return f.packs, true, nil
+}
+
+func (f *fakeRepo) CurrentVersion() (int64, error)      { return f.version, nil }
+func (f *fakeRepo) LatestCreatedAt() (time.Time, error) { return time.Time{}, nil }
+func (f *fakeRepo) IsLocked() (bool, error)             { return false, nil }
+func (f *fakeRepo) SetLocked(locked bool) error         { return nil }
+
+// fakeCache is an in-memory cache for testing the packsService.
+type fakeCache struct {
+	data map[string][]byte
+}
+
+func (c *fakeCache) Get(key string) ([]byte, error) { return c.data[key], nil }
+
+func (c *fakeCache) Set(key string, value []byte, ttlSeconds int) error {
+	c.data[key] = value
+	return nil
+}
+
+func (c *fakeCache) GetMany(keys []string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = c.data[key]
+	}
+	return values, nil
+}
+
+func (c *fakeCache) SetMany(entries map[string][]byte, ttlSeconds int) error {
+	for key, value := range entries {
+		c.data[key] = value
+	}
+	return nil
+}
+
+func (c *fakeCache) Delete(key string) error {
+	delete(c.data, key)
+	return nil
+}
+
+func (c *fakeCache) DeleteByPrefix(prefix string) (int, error) {
+	removed := 0
+	for k := range c.data {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.data, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func TestPacksService_RequiredPackSizes(t *testing.T) {
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}, {Size: 500}, {Size: 1000}}, version: 1}
+	ps := &packsService{repo: repo, cache: &fakeCache{data: map[string][]byte{}}, ttl: 60, required: []int{500, 250}}
+	ctx := context.Background()
+
+	// Removing required sizes is rejected, naming every missing one
+	_, _, err := ps.ReplaceActive(ctx, []int{1000, 2000})
+	var reqErr *domain.RequiredPackSizesError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("Expected RequiredPackSizesError, got %v", err)
+	}
+	if !reflect.DeepEqual(reqErr.Missing, []int{250, 500}) {
+		t.Errorf("Expected missing [250 500], got %v", reqErr.Missing)
+	}
+	if repo.version != 1 {
+		t.Errorf("Expected repository to be untouched, got version %d", repo.version)
+	}
+
+	// Deletes go through ReplaceActivePacks and are covered too
+	_, _, err = ps.ReplaceActivePacks(ctx, []domain.Pack{{Size: 250}, {Size: 1000}})
+	if !errors.As(err, &reqErr) || !reflect.DeepEqual(reqErr.Missing, []int{500}) {
+		t.Errorf("Expected missing [500], got %v", err)
+	}
+
+	// Keeping the required sizes is allowed
+	if _, _, err := ps.ReplaceActive(ctx, []int{250, 500, 2000}); err != nil {
+		t.Errorf("Expected update keeping required sizes to succeed, got %v", err)
+	}
+}
+
+func TestPacksService_WorksWithoutCache(t *testing.T) {
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}, {Size: 500}}, version: 1}
+	ps := &packsService{repo: repo, cache: noopCache{}, ttl: 60}
+
+	sizes, err := ps.GetActiveSizes(context.Background())
+	if err != nil || !reflect.DeepEqual(sizes, []int{250, 500}) {
+		t.Fatalf("Expected sizes from repository, got %v (err %v)", sizes, err)
+	}
+
+	if _, _, err := ps.ReplaceActive(context.Background(), []int{1000}); err != nil {
+		t.Fatalf("ReplaceActive failed: %v", err)
+	}
+	sizes, _ = ps.GetActiveSizes(context.Background())
+	if !reflect.DeepEqual(sizes, []int{1000}) {
+		t.Errorf("Expected updated sizes without caching, got %v", sizes)
+	}
+}
+
+func TestPacksService_CustomSets(t *testing.T) {
+	cache := &fakeCache{data: map[string][]byte{}}
+	ps := &packsService{repo: &fakeRepo{}, cache: cache, ttl: 60, customTTL: 3600}
+	ctx := context.Background()
+
+	saved, err := ps.SaveCustomSet(ctx, domain.CustomPackSet{Name: "pallets", Sizes: []int{23, 31, 53}})
+	if err != nil {
+		t.Fatalf("SaveCustomSet failed: %v", err)
+	}
+	if len(saved.ID) != 32 || saved.CreatedAt.IsZero() {
+		t.Errorf("Expected a generated ID and creation time, got %+v", saved)
+	}
+
+	got, ok, err := ps.GetCustomSet(ctx, saved.ID)
+	if err != nil || !ok {
+		t.Fatalf("Expected saved set to be found, got ok=%v err=%v", ok, err)
+	}
+	if got.Name != "pallets" || !reflect.DeepEqual(got.Sizes, []int{23, 31, 53}) || !got.CreatedAt.Equal(saved.CreatedAt) {
+		t.Errorf("Expected %+v, got %+v", saved, got)
+	}
+
+	// Unknown (or expired) IDs aren't found
+	if _, ok, err := ps.GetCustomSet(ctx, "missing"); ok || err != nil {
+		t.Errorf("Expected unknown ID not to be found, got ok=%v err=%v", ok, err)
+	}
+
+	// Replacing the active set doesn't drop saved custom sets
+	if _, _, err := ps.ReplaceActive(ctx, []int{250}); err != nil {
+		t.Fatalf("ReplaceActive failed: %v", err)
+	}
+	if _, ok, _ := ps.GetCustomSet(ctx, saved.ID); !ok {
+		t.Errorf("Expected custom set to survive cache invalidation")
+	}
+}
+
+func TestNoopCache(t *testing.T) {
+	var c domain.Cache = noopCache{}
+
+	if err := c.Set("packlist:v1:1", []byte("[250]"), 60); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if b, err := c.Get("packlist:v1:1"); b != nil || err != nil {
+		t.Errorf("Expected a miss after Set, got %q (err %v)", b, err)
+	}
+	if err := c.Delete("packlist:v1:1"); err != nil {
+		t.Errorf("Expected Delete to succeed, got %v", err)
+	}
+	if n, err := c.DeleteByPrefix("packlist:v1:"); n != 0 || err != nil {
+		t.Errorf("Expected nothing to delete, got %d (err %v)", n, err)
+	}
+}
+
+func TestPacksService_FlushCache(t *testing.T) {
+	cache := &fakeCache{data: map[string][]byte{
+		"packlist:v1:1":  []byte("[250]"),
+		"packs:v1:1":     []byte(`[{"size":250}]`),
+		"calc:v1:1:500":  []byte("{}"),
+		"packset:v1:abc": []byte(`{"id":"abc"}`),
+		"job:v1:xyz":     []byte(`{"id":"xyz"}`),
+
+		"calc:custom:v1:500:abc": []byte("{}"),
+	}}
+	ps := &packsService{repo: &fakeRepo{version: 1}, cache: cache, ttl: 60}
+
+	removed, err := ps.FlushCache(context.Background())
+	if err != nil {
+		t.Fatalf("FlushCache failed: %v", err)
+	}
+	if removed != 4 {
+		t.Errorf("Expected 4 keys removed, got %d", removed)
+	}
+	for _, key := range []string{"packlist:v1:1", "packs:v1:1", "calc:v1:1:500", "calc:custom:v1:500:abc"} {
+		if _, ok := cache.data[key]; ok {
+			t.Errorf("Expected %s to be flushed", key)
+		}
+	}
+	// Saved custom sets and jobs aren't derived data, so they survive
+	if len(cache.data) != 2 {
+		t.Errorf("Expected the custom set and job to remain, got %v", cache.data)
+	}
+}
+
+func TestPacksService_CacheStats(t *testing.T) {
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}, {Size: 500}}, version: 1}
+	ps := &packsService{repo: repo, cache: &fakeCache{data: map[string][]byte{}}, ttl: 60}
+	ctx := context.Background()
+
+	if got := ps.CacheStats(ctx, false); got != (domain.CacheStats{}) {
+		t.Fatalf("Expected zero stats before any lookup, got %+v", got)
+	}
+
+	// First lookup misses and fills the cache, the next three hit it
+	for i := 0; i < 4; i++ {
+		if _, err := ps.GetActiveSizes(ctx); err != nil {
+			t.Fatalf("GetActiveSizes failed: %v", err)
+		}
+	}
+	want := domain.CacheStats{Hits: 3, Misses: 1, HitRatio: 0.75}
+	if got := ps.CacheStats(ctx, true); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if got := ps.CacheStats(ctx, false); got != (domain.CacheStats{}) {
+		t.Errorf("Expected zero stats after reset, got %+v", got)
+	}
+}
+
+func TestPacksService_GetActiveSizes_CorruptCacheEntry(t *testing.T) {
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}, {Size: 500}}, version: 1}
+	cache := &fakeCache{data: map[string][]byte{
+		"packlist:v1:1":  []byte(`{"sizes":[250`),
+		"packlist:v1:10": []byte("[1000]"), // Shares the bad key as a prefix
+	}}
+	var logs bytes.Buffer
+	ps := &packsService{repo: repo, cache: cache, ttl: 60, logger: slog.New(slog.NewTextHandler(&logs, nil))}
+	ctx := context.Background()
+
+	// The bad entry counts as a miss and the repository's sizes are returned
+	sizes, err := ps.GetActiveSizes(ctx)
+	if err != nil {
+		t.Fatalf("GetActiveSizes failed: %v", err)
+	}
+	if !reflect.DeepEqual(sizes, []int{250, 500}) {
+		t.Errorf("Expected the repository's sizes, got %v", sizes)
+	}
+	if got := ps.CacheStats(ctx, false); got.Misses != 1 || got.Hits != 0 {
+		t.Errorf("Expected one miss, got %+v", got)
+	}
+	if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "packlist:v1:1") {
+		t.Errorf("Expected a warning naming the key, got %q", logs.String())
+	}
+
+	// Only the bad entry is discarded
+	if string(cache.data["packlist:v1:10"]) != "[1000]" {
+		t.Errorf("Expected the entry for version 10 to survive, got %q", cache.data["packlist:v1:10"])
+	}
+
+	// The entry was replaced, so the next lookup hits
+	if string(cache.data["packlist:v1:1"]) != "[250,500]" {
+		t.Errorf("Expected the entry to be rewritten, got %q", cache.data["packlist:v1:1"])
+	}
+	if sizes, _ := ps.GetActiveSizes(ctx); !reflect.DeepEqual(sizes, []int{250, 500}) {
+		t.Errorf("Expected cached sizes, got %v", sizes)
+	}
+	if got := ps.CacheStats(ctx, false); got.Hits != 1 {
+		t.Errorf("Expected a hit after repair, got %+v", got)
+	}
+}
+
+func TestPacksService_GetPacks_CorruptCacheEntry(t *testing.T) {
+	want := []domain.Pack{{Size: 250}, {Size: 500}}
+	lookups := []struct {
+		name string
+		get  func(ps *packsService) ([]domain.Pack, error)
+	}{
+		{"GetActivePacks", func(ps *packsService) ([]domain.Pack, error) {
+			return ps.GetActivePacks(context.Background())
+		}},
+		{"GetPacksAtVersion", func(ps *packsService) ([]domain.Pack, error) {
+			packs, ok, err := ps.GetPacksAtVersion(context.Background(), 1)
+			if err == nil && !ok {
+				err = errors.New("version not found")
+			}
+			return packs, err
+		}},
+		{"GetActivePacksWithVersion", func(ps *packsService) ([]domain.Pack, error) {
+			packs, _, err := ps.GetActivePacksWithVersion(context.Background())
+			return packs, err
+		}},
+	}
+
+	for _, tt := range lookups {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &fakeCache{data: map[string][]byte{"packs:v1:1": []byte(`{"packs":[`)}}
+			var logs bytes.Buffer
+			ps := &packsService{repo: &fakeRepo{packs: want, version: 1}, cache: cache, ttl: 60, logger: slog.New(slog.NewTextHandler(&logs, nil))}
+
+			// The bad entry is treated as a miss and the repository's packs are returned
+			packs, err := tt.get(ps)
+			if err != nil {
+				t.Fatalf("%s failed: %v", tt.name, err)
+			}
+			if !reflect.DeepEqual(packs, want) {
+				t.Errorf("Expected the repository's packs, got %v", packs)
+			}
+			if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "packs:v1:1") {
+				t.Errorf("Expected a warning naming the key, got %q", logs.String())
+			}
+
+			// The entry was replaced with the repository's packs
+			if string(cache.data["packs:v1:1"]) != `[{"size":250},{"size":500}]` {
+				t.Errorf("Expected the entry to be rewritten, got %q", cache.data["packs:v1:1"])
+			}
+		})
+	}
+}
+
+func TestPacksService_GetActivePacksWithVersion(t *testing.T) {
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}, {Size: 500}}, version: 4}
+	ps := &packsService{repo: repo, cache: &fakeCache{data: map[string][]byte{}}, ttl: 60}
+	ctx := context.Background()
+
+	// The miss fills the cache; the hit must return the same packs and version
+	for i := 0; i < 2; i++ {
+		packs, ver, err := ps.GetActivePacksWithVersion(ctx)
+		if err != nil {
+			t.Fatalf("GetActivePacksWithVersion failed: %v", err)
+		}
+		if ver != 4 || !reflect.DeepEqual(packs, repo.packs) {
+			t.Errorf("Lookup %d: expected version 4 with %v, got %d with %v", i, repo.packs, ver, packs)
+		}
+	}
+
+	// A replacement moves both to the new version
+	if _, _, err := ps.ReplaceActive(ctx, []int{100}); err != nil {
+		t.Fatalf("ReplaceActive failed: %v", err)
+	}
+	packs, ver, _ := ps.GetActivePacksWithVersion(ctx)
+	if ver != 5 || len(packs) != 1 || packs[0].Size != 100 {
+		t.Errorf("Expected version 5 with [100], got %d with %v", ver, packs)
+	}
+}
+
+func TestPacksService_CacheStatsConcurrent(t *testing.T) {
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}}, version: 1}
+	ps := &packsService{repo: repo, cache: noopCache{}, ttl: 60}
+	ctx := context.Background()
+
+	const workers, lookups = 8, 100
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < lookups; i++ {
+				_, _ = ps.GetActiveSizes(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := ps.CacheStats(ctx, false)
+	if got.Misses != workers*lookups || got.Hits != 0 || got.HitRatio != 0 {
+		t.Errorf("Expected %d misses and no hits, got %+v", workers*lookups, got)
+	}
+}
+
+// fakePublisher records published pack set changes.
+type fakePublisher struct {
+	events []domain.PackSetChanged
+	err    error
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, event domain.PackSetChanged) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.events = append(f.events, event)
+	return nil
+}
+
+func TestPacksService_ReplaceReturnsVersion(t *testing.T) {
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}}, version: 1}
+	ps := &packsService{repo: repo, cache: &fakeCache{data: map[string][]byte{}}, ttl: 60}
+	ctx := context.Background()
+
+	_, v1, err := ps.ReplaceActive(ctx, []int{250, 500})
+	if err != nil {
+		t.Fatalf("ReplaceActive failed: %v", err)
+	}
+	_, v2, err := ps.ReplaceActivePacks(ctx, []domain.Pack{{Size: 500, SKU: "BOX-500"}})
+	if err != nil {
+		t.Fatalf("ReplaceActivePacks failed: %v", err)
+	}
+	_, v3, err := ps.ReplaceActive(ctx, []int{1000})
+	if err != nil {
+		t.Fatalf("ReplaceActive failed: %v", err)
+	}
+	if v1 != 2 || v2 != 3 || v3 != 4 {
+		t.Errorf("Expected versions 2, 3, 4, got %d, %d, %d", v1, v2, v3)
+	}
+
+	// The returned version is the one reads now report
+	if _, ver, err := ps.GetActivePacksWithVersion(ctx); err != nil || ver != v3 {
+		t.Errorf("Expected active version %d, got %d (err %v)", v3, ver, err)
+	}
+}
+
+func TestPacksService_ReplaceIfVersion(t *testing.T) {
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}}, version: 4}
+	pub := &fakePublisher{}
+	cache := &fakeCache{data: map[string][]byte{"packlist:v1:4": []byte("[250]")}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ps := &packsService{repo: repo, cache: cache, ttl: 60, publisher: pub, logger: logger}
+	ctx := context.Background()
+
+	// A stale version writes nothing, keeps the caches and announces nothing
+	_, _, err := ps.ReplaceActivePacksIfVersion(ctx, []domain.Pack{{Size: 500}}, []int64{3})
+	var conflict *domain.VersionConflictError
+	if !errors.As(err, &conflict) || conflict.Current != 4 {
+		t.Fatalf("Expected a version conflict at 4, got %v", err)
+	}
+	if repo.version != 4 || cache.data["packlist:v1:4"] == nil || len(pub.events) != 0 {
+		t.Fatalf("Expected no write on conflict, got version %d, cache %v, %d events", repo.version, cache.data, len(pub.events))
+	}
+
+	// The current version goes through like an unconditional replace
+	out, ver, err := ps.ReplaceActivePacksIfVersion(ctx, []domain.Pack{{Size: 500}}, []int64{3, 4})
+	if err != nil {
+		t.Fatalf("ReplaceActivePacksIfVersion failed: %v", err)
+	}
+	if ver != 5 || !reflect.DeepEqual(out, []domain.Pack{{Size: 500}}) || len(pub.events) != 1 {
+		t.Errorf("Expected version 5 with [500] announced, got %d, %v, %d events", ver, out, len(pub.events))
+	}
+}
+
+func TestPacksService_PublishesChanges(t *testing.T) {
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}}, version: 1}
+	pub := &fakePublisher{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ps := &packsService{repo: repo, cache: noopCache{}, ttl: 60, publisher: pub, logger: logger}
+	ctx := context.Background()
+
+	if _, _, err := ps.ReplaceActive(ctx, []int{250, 500}); err != nil {
+		t.Fatalf("ReplaceActive failed: %v", err)
+	}
+	if _, _, err := ps.ReplaceActivePacks(ctx, []domain.Pack{{Size: 1000, SKU: "BOX-1000"}}); err != nil {
+		t.Fatalf("ReplaceActivePacks failed: %v", err)
+	}
+
+	if len(pub.events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(pub.events))
+	}
+	if e := pub.events[0]; e.Version != 2 || !reflect.DeepEqual(e.Sizes, []int{250, 500}) || e.ChangedAt.IsZero() {
+		t.Errorf("Unexpected first event: %+v", e)
+	}
+	if e := pub.events[1]; e.Version != 3 || !reflect.DeepEqual(e.Sizes, []int{1000}) {
+		t.Errorf("Unexpected second event: %+v", e)
+	}
+
+	// Rejected changes aren't published
+	ps.required = []int{1000}
+	if _, _, err := ps.ReplaceActive(ctx, []int{250}); err == nil {
+		t.Fatalf("Expected ReplaceActive without a required size to fail")
+	}
+	if len(pub.events) != 2 {
+		t.Errorf("Expected no event for a rejected change, got %d events", len(pub.events))
+	}
+}
+
+func TestPacksService_PublishFailureKeepsChange(t *testing.T) {
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}}, version: 1}
+	pub := &fakePublisher{err: errors.New("stream unavailable")}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ps := &packsService{repo: repo, cache: noopCache{}, ttl: 60, publisher: pub, logger: logger}
+
+	sizes, _, err := ps.ReplaceActive(context.Background(), []int{500})
+	if err != nil {
+		t.Fatalf("Expected the change to succeed despite the publish failure, got %v", err)
+	}
+	if !reflect.DeepEqual(sizes, []int{500}) || repo.version != 2 {
+		t.Errorf("Expected the change to be stored, got %v at version %d", sizes, repo.version)
+	}
+}
+
+// fixedRetryAfter reports a constant wait until the database breaker's trial.
+type fixedRetryAfter time.Duration
+
+func (f fixedRetryAfter) DatabaseRetryAfter() time.Duration { return time.Duration(f) }
+
+func TestPacksService_FailsFastWhileDatabaseBreakerOpen(t *testing.T) {
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}}, version: 1}
+	ps := &packsService{repo: repo, cache: &fakeCache{data: map[string][]byte{}}, ttl: 60, dbHealth: fixedRetryAfter(12 * time.Second)}
+	ctx := context.Background()
+
+	reads := map[string]func() error{
+		"GetActiveSizes":            func() error { _, err := ps.GetActiveSizes(ctx); return err },
+		"GetActivePacks":            func() error { _, err := ps.GetActivePacks(ctx); return err },
+		"GetActivePacksWithVersion": func() error { _, _, err := ps.GetActivePacksWithVersion(ctx); return err },
+	}
+	for name, read := range reads {
+		var unavailable *domain.UnavailableError
+		if err := read(); !errors.As(err, &unavailable) || unavailable.RetryAfter != 12*time.Second || unavailable.Dependency != "database" {
+			t.Errorf("%s: expected an UnavailableError with the breaker's wait, got %v", name, err)
+		}
+	}
+	if got := ps.CacheStats(ctx, false); got.Misses != 0 {
+		t.Errorf("Expected the repository not to be consulted, got %+v", got)
+	}
+
+	// Once the breaker's trial is due, reads go through again
+	ps.dbHealth = fixedRetryAfter(0)
+	if sizes, err := ps.GetActiveSizes(ctx); err != nil || !reflect.DeepEqual(sizes, []int{250}) {
+		t.Errorf("Expected the repository's sizes, got %v, %v", sizes, err)
+	}
+}
@@ -0,0 +1,30 @@
+package platform
+
+// Cache backends selectable via CACHE_BACKEND.
+const (
+	CacheBackendRedis = "redis" // Redis via the redisad adapter (default)
+	CacheBackendNone  = "none"  // No caching; every read goes to the repository
+)
+
+// noopCache implements domain.Cache without storing anything: Get and GetMany always miss,
+// Set, SetMany, Delete and DeleteByPrefix do nothing. It lets packsService run without a cache
+// (disabled by config, or Redis unavailable) with no nil checks on the caching path.
+type noopCache struct{}
+
+// Get implements domain.Cache; it always reports a miss.
+func (noopCache) Get(key string) ([]byte, error) { return nil, nil }
+
+// Set implements domain.Cache; the value is discarded.
+func (noopCache) Set(key string, value []byte, ttlSeconds int) error { return nil }
+
+// GetMany implements domain.Cache; every key misses.
+func (noopCache) GetMany(keys []string) ([][]byte, error) { return make([][]byte, len(keys)), nil }
+
+// SetMany implements domain.Cache; the values are discarded.
+func (noopCache) SetMany(entries map[string][]byte, ttlSeconds int) error { return nil }
+
+// Delete implements domain.Cache; there is nothing to delete.
+func (noopCache) Delete(key string) error { return nil }
+
+// DeleteByPrefix implements domain.Cache; there is nothing to delete.
+func (noopCache) DeleteByPrefix(prefix string) (int, error) { return 0, nil }
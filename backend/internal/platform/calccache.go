@@ -0,0 +1,161 @@
+package platform
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// Cache key prefixes for calculation results.
+const (
+	calcCachePrefix       = "calc:v1:"        // Results for a stored pack set version; cleared on pack set changes
+	customCalcCachePrefix = "calc:custom:v1:" // Results for custom sizes; independent of the active set
+)
+
+// cachingCalculator caches Compute, ComputeWithOptions, ComputeBatch and ComputeBatchWithOptions
+// results through the cache port; Uncached calculations go straight to the calculator.
+// Results for sizes taken from a stored pack set (CalcOptions.Version > 0) are keyed by that
+// version, and packsService.invalidate clears them when the active set changes. Results for
+// custom sizes depend on nothing but the sizes, so they're keyed by a hash of the sizes and
+// survive pack set changes until they expire. Failed calculations are never cached.
+type cachingCalculator struct {
+	domain.Calculator
+	cache    domain.Cache
+	ttl      int             // Cache time-to-live in seconds
+	tieBreak domain.TieBreak // The calculator's default, so keys record the policy actually applied
+}
+
+// newCachingCalculator wraps calc with a result cache. tieBreak must be calc's default policy.
+func newCachingCalculator(calc domain.Calculator, cache domain.Cache, ttl int, tieBreak domain.TieBreak) *cachingCalculator {
+	return &cachingCalculator{Calculator: calc, cache: cache, ttl: ttl, tieBreak: tieBreak}
+}
+
+// Compute implements domain.Calculator; sizes are treated as custom sizes.
+func (c *cachingCalculator) Compute(ctx context.Context, amount int, sizes []int) (domain.CalculationResult, error) {
+	return c.cached(ctx, calcCacheKey(amount, sizes, domain.CalcOptions{TieBreak: c.tieBreak}), func() (domain.CalculationResult, error) {
+		return c.Calculator.Compute(ctx, amount, sizes)
+	})
+}
+
+// ComputeWithOptions implements domain.Calculator.
+func (c *cachingCalculator) ComputeWithOptions(ctx context.Context, amount int, sizes []int, opts domain.CalcOptions) (domain.CalculationResult, error) {
+	if opts.Uncached {
+		return c.Calculator.ComputeWithOptions(ctx, amount, sizes, opts)
+	}
+	return c.cached(ctx, calcCacheKey(amount, sizes, c.keyOptions(opts)), func() (domain.CalculationResult, error) {
+		return c.Calculator.ComputeWithOptions(ctx, amount, sizes, opts)
+	})
+}
+
+// ComputeBatch implements domain.Calculator; sizes are treated as custom sizes, as by Compute,
+// so a batch shares its entries with single calculations. All amounts are looked up with one
+// GetMany, only the misses are calculated (still as one batch), and their results are stored
+// with one SetMany. If any miss has no solution the batch fails as the calculator's does.
+func (c *cachingCalculator) ComputeBatch(ctx context.Context, amounts []int, sizes []int) ([]domain.CalculationResult, error) {
+	return c.cachedBatch(ctx, amounts, sizes, domain.CalcOptions{TieBreak: c.tieBreak}, func(missing []int) ([]domain.CalculationResult, error) {
+		return c.Calculator.ComputeBatch(ctx, missing, sizes)
+	})
+}
+
+// ComputeBatchWithOptions implements domain.Calculator, caching each amount as ComputeWithOptions does.
+func (c *cachingCalculator) ComputeBatchWithOptions(ctx context.Context, amounts []int, sizes []int, opts domain.CalcOptions) ([]domain.CalculationResult, error) {
+	if opts.Uncached {
+		return c.Calculator.ComputeBatchWithOptions(ctx, amounts, sizes, opts)
+	}
+	return c.cachedBatch(ctx, amounts, sizes, c.keyOptions(opts), func(missing []int) ([]domain.CalculationResult, error) {
+		return c.Calculator.ComputeBatchWithOptions(ctx, missing, sizes, opts)
+	})
+}
+
+// keyOptions returns opts with the tie-break policy the calculator will actually apply.
+func (c *cachingCalculator) keyOptions(opts domain.CalcOptions) domain.CalcOptions {
+	if opts.TieBreak == "" {
+		opts.TieBreak = c.tieBreak
+	}
+	return opts
+}
+
+// cachedBatch looks up the amounts under the keys for opts and calculates only the misses,
+// in one call to compute.
+func (c *cachingCalculator) cachedBatch(ctx context.Context, amounts []int, sizes []int, opts domain.CalcOptions, compute func(missing []int) ([]domain.CalculationResult, error)) ([]domain.CalculationResult, error) {
+	keys := make([]string, len(amounts))
+	for i, amount := range amounts {
+		keys[i] = calcCacheKey(amount, sizes, opts)
+	}
+
+	results := make([]domain.CalculationResult, len(amounts))
+	var missing []int // Indexes of the amounts to calculate
+	for i, b := range tracedCacheGetMany(ctx, c.cache, keys) {
+		if b == nil || json.Unmarshal(b, &results[i]) != nil {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return results, nil
+	}
+
+	missAmounts := make([]int, len(missing))
+	for j, i := range missing {
+		missAmounts[j] = amounts[i]
+	}
+	computed, err := compute(missAmounts)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string][]byte, len(missing))
+	for j, i := range missing {
+		results[i] = computed[j]
+		if b, err := json.Marshal(computed[j]); err == nil {
+			entries[keys[i]] = b
+		}
+	}
+	_ = c.cache.SetMany(entries, c.ttl)
+	return results, nil
+}
+
+// cached returns the result stored under key, or computes and stores it.
+// Cache failures only cost the lookup; the calculation still runs.
+func (c *cachingCalculator) cached(ctx context.Context, key string, compute func() (domain.CalculationResult, error)) (domain.CalculationResult, error) {
+	if b := tracedCacheGet(ctx, c.cache, key); b != nil {
+		var res domain.CalculationResult
+		if json.Unmarshal(b, &res) == nil {
+			return res, nil
+		}
+	}
+
+	res, err := compute()
+	if err != nil {
+		return res, err
+	}
+	if b, err := json.Marshal(res); err == nil {
+		_ = c.cache.Set(key, b, c.ttl)
+	}
+	return res, nil
+}
+
+// calcCacheKey builds the cache key for a calculation. The sizes and preferred sizes go in
+// through calculator.SizesKey, the calculator's own canonical form, and are hashed together with
+// every other option that affects the result, so equivalent requests share an entry.
+func calcCacheKey(amount int, sizes []int, opts domain.CalcOptions) string {
+	var sb strings.Builder
+	sb.WriteString(calculator.SizesKey(sizes))
+	sb.WriteString("|" + string(opts.TieBreak) + "|")
+	sb.WriteString(calculator.SizesKey(opts.Preferred))
+	sb.WriteString("|" + strconv.FormatBool(opts.SummaryOnly))
+	sb.WriteString("|" + strconv.FormatBool(opts.MinOnePerSize))
+	sb.WriteString("|" + strconv.Itoa(opts.MaxPacks))
+	sb.WriteString("|" + strconv.FormatFloat(opts.MinUtilization, 'g', -1, 64))
+	sum := sha256.Sum256([]byte(sb.String()))
+	hash := hex.EncodeToString(sum[:16])
+
+	if opts.Version > 0 {
+		return calcCachePrefix + strconv.FormatInt(opts.Version, 10) + ":" + strconv.Itoa(amount) + ":" + hash
+	}
+	return customCalcCachePrefix + strconv.Itoa(amount) + ":" + hash
+}
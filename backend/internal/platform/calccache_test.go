@@ -0,0 +1,154 @@
+package platform
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/app/calculator"
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// countingCalculator counts the calculations that reach the real calculator.
+type countingCalculator struct {
+	domain.Calculator
+	calls   int
+	batched []int // Amounts passed to ComputeBatch
+}
+
+func (c *countingCalculator) ComputeBatch(ctx context.Context, amounts []int, sizes []int) ([]domain.CalculationResult, error) {
+	c.batched = append(c.batched, amounts...)
+	return c.Calculator.ComputeBatch(ctx, amounts, sizes)
+}
+
+func (c *countingCalculator) ComputeWithOptions(ctx context.Context, amount int, sizes []int, opts domain.CalcOptions) (domain.CalculationResult, error) {
+	c.calls++
+	return c.Calculator.ComputeWithOptions(ctx, amount, sizes, opts)
+}
+
+func TestCachingCalculator_SurvivesPackReplaceForCustomSizesOnly(t *testing.T) {
+	cache := &fakeCache{data: map[string][]byte{}}
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}, {Size: 500}}, version: 1}
+	ps := &packsService{repo: repo, cache: cache, ttl: 60}
+	inner := &countingCalculator{Calculator: calculator.NewService()}
+	calc := newCachingCalculator(inner, cache, 60, domain.TieBreakItemsFirst)
+	ctx := context.Background()
+
+	active := domain.CalcOptions{Version: 1}
+	custom := domain.CalcOptions{}
+	for i := 0; i < 2; i++ {
+		if _, err := calc.ComputeWithOptions(ctx, 251, []int{250, 500}, active); err != nil {
+			t.Fatalf("active calculation failed: %v", err)
+		}
+		if _, err := calc.ComputeWithOptions(ctx, 251, []int{23, 31}, custom); err != nil {
+			t.Fatalf("custom calculation failed: %v", err)
+		}
+	}
+	if inner.calls != 2 {
+		t.Fatalf("Expected repeated calculations to be cached, got %d calculations", inner.calls)
+	}
+
+	// Replacing the active set drops version-keyed results but not custom-size ones
+	if _, _, err := ps.ReplaceActive(ctx, []int{250, 500, 1000}); err != nil {
+		t.Fatalf("ReplaceActive failed: %v", err)
+	}
+	res, err := calc.ComputeWithOptions(ctx, 251, []int{23, 31}, custom)
+	if err != nil || res.TotalItems != 253 {
+		t.Fatalf("Unexpected custom result %+v (err %v)", res, err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("Expected the custom-size result to survive the replace, got %d calculations", inner.calls)
+	}
+	if _, err := calc.ComputeWithOptions(ctx, 251, []int{250, 500}, active); err != nil {
+		t.Fatalf("active calculation failed: %v", err)
+	}
+	if inner.calls != 3 {
+		t.Errorf("Expected the active-size result to be recalculated, got %d calculations", inner.calls)
+	}
+}
+
+func TestCachingCalculator_ComputeBatchCalculatesMissesOnly(t *testing.T) {
+	cache := &fakeCache{data: map[string][]byte{}}
+	inner := &countingCalculator{Calculator: calculator.NewService()}
+	calc := newCachingCalculator(inner, cache, 60, domain.TieBreakItemsFirst)
+	ctx := context.Background()
+	sizes := []int{250, 500, 1000}
+
+	// A single calculation fills the entry the batch looks up for the same amount
+	if _, err := calc.Compute(ctx, 501, sizes); err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+
+	amounts := []int{1001, 501, 1, 1001}
+	for round := 0; round < 2; round++ {
+		results, err := calc.ComputeBatch(ctx, amounts, sizes)
+		if err != nil {
+			t.Fatalf("Round %d: ComputeBatch failed: %v", round, err)
+		}
+		if len(results) != len(amounts) {
+			t.Fatalf("Round %d: expected %d results, got %d", round, len(amounts), len(results))
+		}
+		for i, want := range []int{1250, 750, 250, 1250} {
+			if results[i].Amount != amounts[i] || results[i].TotalItems != want {
+				t.Errorf("Round %d, result %d: expected %d -> %d items, got %+v", round, i, amounts[i], want, results[i])
+			}
+		}
+	}
+
+	// Only the first round's misses reach the calculator, in the order they were asked for
+	if want := []int{1001, 1, 1001}; !slices.Equal(inner.batched, want) {
+		t.Errorf("Expected the calculator to batch %v, got %v", want, inner.batched)
+	}
+}
+
+func TestCachingCalculator_UncachedBypassesCache(t *testing.T) {
+	cache := &fakeCache{data: map[string][]byte{}}
+	calc := newCachingCalculator(calculator.NewService(), cache, 60, domain.TieBreakItemsFirst)
+	ctx := context.Background()
+	sizes := []int{250, 500, 1000}
+
+	if _, err := calc.ComputeBatchWithOptions(ctx, []int{1, 501}, sizes, domain.CalcOptions{Uncached: true}); err != nil {
+		t.Fatalf("ComputeBatchWithOptions failed: %v", err)
+	}
+	if _, err := calc.ComputeWithOptions(ctx, 1001, sizes, domain.CalcOptions{Uncached: true}); err != nil {
+		t.Fatalf("ComputeWithOptions failed: %v", err)
+	}
+	if len(cache.data) != 0 {
+		t.Errorf("Expected uncached calculations not to be stored, got %d entries", len(cache.data))
+	}
+
+	if _, err := calc.ComputeBatchWithOptions(ctx, []int{1, 501}, sizes, domain.CalcOptions{}); err != nil {
+		t.Fatalf("ComputeBatchWithOptions failed: %v", err)
+	}
+	if len(cache.data) != 2 {
+		t.Errorf("Expected both amounts to be stored, got %d entries", len(cache.data))
+	}
+}
+
+func TestCalcCacheKey(t *testing.T) {
+	base := calcCacheKey(1200, []int{500, 250, 250}, domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst})
+	if !strings.HasPrefix(base, customCalcCachePrefix+"1200:") {
+		t.Errorf("Expected a custom-size key, got %q", base)
+	}
+	if same := calcCacheKey(1200, []int{250, 500}, domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst}); same != base {
+		t.Errorf("Expected equivalent sizes to share a key, got %q and %q", base, same)
+	}
+	if versioned := calcCacheKey(1200, []int{250, 500}, domain.CalcOptions{TieBreak: domain.TieBreakItemsFirst, Version: 7}); !strings.HasPrefix(versioned, calcCachePrefix+"7:1200:") {
+		t.Errorf("Expected a version-keyed key, got %q", versioned)
+	}
+
+	// Every option that changes the result changes the key
+	for _, opts := range []domain.CalcOptions{
+		{TieBreak: domain.TieBreakPacksFirst},
+		{TieBreak: domain.TieBreakItemsFirst, Preferred: []int{250}},
+		{TieBreak: domain.TieBreakItemsFirst, SummaryOnly: true},
+		{TieBreak: domain.TieBreakItemsFirst, MinOnePerSize: true},
+		{TieBreak: domain.TieBreakItemsFirst, MaxPacks: 3},
+		{TieBreak: domain.TieBreakItemsFirst, MinUtilization: 0.5},
+	} {
+		if key := calcCacheKey(1200, []int{250, 500}, opts); key == base {
+			t.Errorf("Expected options %+v to change the key", opts)
+		}
+	}
+}
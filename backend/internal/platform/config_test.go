@@ -0,0 +1,217 @@
+package platform
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+func TestLoadPoolSettings(t *testing.T) {
+	t.Setenv("DB_MAX_CONNS", "20")
+	t.Setenv("DB_MIN_CONNS", "2")
+	t.Setenv("DB_MAX_CONN_LIFETIME", "45m")
+	t.Setenv("DB_MAX_CONN_IDLE_TIME", "5m")
+	t.Setenv("DB_STATEMENT_TIMEOUT", "5s")
+
+	ps := loadPoolSettings(os.Getenv)
+	if ps.MaxConns != 20 || ps.MinConns != 2 {
+		t.Errorf("Expected max 20 / min 2 connections, got %+v", ps)
+	}
+	if ps.MaxConnLifetime.Minutes() != 45 || ps.MaxConnIdleTime.Minutes() != 5 || ps.StatementTimeout.Seconds() != 5 {
+		t.Errorf("Unexpected durations: %+v", ps)
+	}
+}
+
+func TestLoadPoolSettings_InvalidValuesFallBack(t *testing.T) {
+	t.Setenv("DB_MAX_CONNS", "not-a-number")
+	t.Setenv("DB_MIN_CONNS", "500")
+	t.Setenv("DB_MAX_CONN_LIFETIME", "-1h")
+	t.Setenv("DB_MAX_CONN_IDLE_TIME", "soon")
+	t.Setenv("DB_STATEMENT_TIMEOUT", "0s")
+
+	ps := loadPoolSettings(os.Getenv)
+	if ps.MaxConns != defaultDBMaxConns {
+		t.Errorf("MaxConns: expected default %d, got %d", defaultDBMaxConns, ps.MaxConns)
+	}
+	if ps.MinConns != defaultDBMinConns {
+		t.Errorf("MinConns: expected default %d when above max, got %d", defaultDBMinConns, ps.MinConns)
+	}
+	if ps.MaxConnLifetime != defaultDBMaxConnLifetime {
+		t.Errorf("MaxConnLifetime: expected default, got %v", ps.MaxConnLifetime)
+	}
+	if ps.MaxConnIdleTime != defaultDBMaxConnIdleTime {
+		t.Errorf("MaxConnIdleTime: expected default, got %v", ps.MaxConnIdleTime)
+	}
+	if ps.StatementTimeout != defaultDBStatementTimeout {
+		t.Errorf("StatementTimeout: expected default, got %v", ps.StatementTimeout)
+	}
+}
+
+func TestLoadConfig_MaxPackCount(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  int
+		valid bool
+	}{
+		{"", domain.DefaultMaxPackCount, true},
+		{"25", 25, true},
+		{"1", 1, true},
+		{"0", 0, false},
+		{"-3", -3, false},
+		{"many", 0, false},
+	} {
+		t.Setenv("MAX_PACK_COUNT", tc.value)
+		cfg := LoadConfig()
+		if cfg.MaxPackCount != tc.want {
+			t.Errorf("MAX_PACK_COUNT=%q: expected %d, got %d", tc.value, tc.want, cfg.MaxPackCount)
+		}
+		err := cfg.Validate()
+		if tc.valid && err != nil {
+			t.Errorf("MAX_PACK_COUNT=%q: unexpected error %v", tc.value, err)
+		}
+		if !tc.valid && (err == nil || !strings.Contains(err.Error(), "MAX_PACK_COUNT")) {
+			t.Errorf("MAX_PACK_COUNT=%q: expected a MAX_PACK_COUNT error, got %v", tc.value, err)
+		}
+	}
+}
+
+func TestLoadConfig_PackEventsMaxLen(t *testing.T) {
+	for value, want := range map[string]int{"": 10000, "500": 500, "0": 0, "-1": 10000, "lots": 10000} {
+		t.Setenv("PACK_EVENTS_MAXLEN", value)
+		if got := LoadConfig().PackEventsMaxLen; got != want {
+			t.Errorf("PACK_EVENTS_MAXLEN=%q: expected %d, got %d", value, want, got)
+		}
+	}
+}
+
+func TestGetenvFactorMap(t *testing.T) {
+	t.Setenv("UNIT_CONVERSIONS", " Cases = 12, kg=2.5,bad,pallets=-4,empty=,=3")
+
+	got := lookupFunc(os.Getenv).getenvFactorMap("UNIT_CONVERSIONS")
+	if len(got) != 2 || got["cases"] != 12 || got["kg"] != 2.5 {
+		t.Errorf("Expected cases=12 and kg=2.5 only, got %v", got)
+	}
+}
+
+func TestConfig_TLSEnabled(t *testing.T) {
+	tests := []struct {
+		cert, key string
+		enabled   bool
+		wantErr   bool
+	}{
+		{cert: "", key: "", enabled: false},
+		{cert: "server.crt", key: "server.key", enabled: true},
+		{cert: "server.crt", key: "", wantErr: true},
+		{cert: "", key: "server.key", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		enabled, err := Config{TLSCertFile: tt.cert, TLSKeyFile: tt.key}.TLSEnabled()
+		if (err != nil) != tt.wantErr || enabled != tt.enabled {
+			t.Errorf("cert=%q key=%q: expected enabled=%v err=%v, got enabled=%v err=%v",
+				tt.cert, tt.key, tt.enabled, tt.wantErr, enabled, err)
+		}
+	}
+}
+
+func TestConfig_RequestIDGenerator(t *testing.T) {
+	for _, format := range []string{"", "chi", "CHI"} {
+		if gen, err := (Config{RequestIDFormat: format}).RequestIDGenerator(); err != nil || gen != nil {
+			t.Errorf("Format %q: expected chi's generator (nil), got err=%v", format, err)
+		}
+	}
+	if gen, err := (Config{RequestIDFormat: "uuidv7"}).RequestIDGenerator(); err != nil || gen == nil || len(gen()) != 36 {
+		t.Errorf("Expected a UUIDv7 generator, got err=%v", err)
+	}
+	if _, err := (Config{RequestIDFormat: "ulid"}).RequestIDGenerator(); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
+
+func TestLoadConfig_SuspiciousRequestBlocking(t *testing.T) {
+	for _, tc := range []struct {
+		ddos, blocking string
+		want           bool
+	}{
+		{"", "", true},
+		{"false", "", false}, // Follows DDoS protection, as before the split
+		{"true", "false", false},
+		{"false", "true", true},
+	} {
+		t.Setenv("DDOS_PROTECTION_ENABLED", tc.ddos)
+		t.Setenv("SUSPICIOUS_REQUEST_BLOCKING_ENABLED", tc.blocking)
+		if got := LoadConfig().SuspiciousRequestBlockingEnabled; got != tc.want {
+			t.Errorf("DDOS_PROTECTION_ENABLED=%q SUSPICIOUS_REQUEST_BLOCKING_ENABLED=%q: expected %v, got %v", tc.ddos, tc.blocking, tc.want, got)
+		}
+	}
+}
+
+func TestLoadConfig_ElevatedLimits(t *testing.T) {
+	t.Setenv("ELEVATED_API_KEYS", " key-a, ,key-b ")
+	t.Setenv("ELEVATED_MAX_ORDER_AMOUNT", "")
+
+	cfg := LoadConfig()
+	if len(cfg.ElevatedAPIKeys) != 2 || cfg.ElevatedAPIKeys[0] != "key-a" || cfg.ElevatedAPIKeys[1] != "key-b" {
+		t.Errorf("Expected keys [key-a key-b], got %q", cfg.ElevatedAPIKeys)
+	}
+	if cfg.ElevatedMaxOrderAmount != defaultElevatedMaxOrderAmount {
+		t.Errorf("Expected hard maximum %d, got %d", defaultElevatedMaxOrderAmount, cfg.ElevatedMaxOrderAmount)
+	}
+}
+
+func TestConfig_IPFilter(t *testing.T) {
+	if f, err := (Config{}).IPFilter(); err != nil || f != nil {
+		t.Errorf("Expected no filter when both lists are empty, got %v, %v", f, err)
+	}
+	f, err := (Config{IPAllowlist: []string{"10.0.0.0/8"}, IPDenylist: []string{"10.0.0.1"}}).IPFilter()
+	if err != nil || f == nil || !f.Allowed("10.0.0.2") || f.Allowed("10.0.0.1") {
+		t.Errorf("Expected a filter from both lists, got %v, %v", f, err)
+	}
+	if _, err := (Config{IPDenylist: []string{"10.0.0.0/99"}}).IPFilter(); err == nil {
+		t.Errorf("Expected an error for an invalid CIDR")
+	}
+}
+
+func TestConfig_Redactor(t *testing.T) {
+	if r, err := (Config{LogIPMode: "plain"}).Redactor(); err != nil || r != nil {
+		t.Errorf("Expected no redactor by default, got %v, %v", r, err)
+	}
+	r, err := (Config{LogIPMode: "truncate"}).Redactor()
+	if err != nil || r == nil || r.RedactIP("192.0.2.77:1") != "192.0.2.0/24" {
+		t.Errorf("Expected a truncating redactor, got %v, %v", r, err)
+	}
+	r, err = (Config{LogIPMode: "plain", LogRedactKeys: []string{"email"}}).Redactor()
+	if err != nil || r == nil || r.RedactDetails(map[string]interface{}{"email": "x"})["email"] == "x" {
+		t.Errorf("Expected a redactor masking email, got %v, %v", r, err)
+	}
+	if _, err := (Config{LogIPMode: "scramble"}).Redactor(); err == nil {
+		t.Errorf("Expected an error for an unknown LOG_IP_MODE")
+	}
+}
+
+func TestConfig_CacheRefreshInterval(t *testing.T) {
+	valid := Config{HTTPPort: "8080", LogIPMode: "plain", CacheTTLSecs: 600, MaxPackCount: 100}
+	for ahead, want := range map[int]time.Duration{0: 0, 60: 9 * time.Minute, 599: time.Second} {
+		cfg := valid
+		cfg.CacheRefreshAheadSecs = ahead
+		if got := cfg.CacheRefreshInterval(); got != want {
+			t.Errorf("Ahead %ds: expected interval %v, got %v", ahead, want, got)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Ahead %ds: unexpected error %v", ahead, err)
+		}
+	}
+	for _, ahead := range []int{-1, 600, 900} {
+		cfg := valid
+		cfg.CacheRefreshAheadSecs = ahead
+		if got := cfg.CacheRefreshInterval(); got != 0 {
+			t.Errorf("Ahead %ds: expected refreshing disabled, got %v", ahead, got)
+		}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "CACHE_REFRESH_AHEAD_SECS") {
+			t.Errorf("Ahead %ds: expected a CACHE_REFRESH_AHEAD_SECS error, got %v", ahead, err)
+		}
+	}
+}
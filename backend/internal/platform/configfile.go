@@ -0,0 +1,137 @@
+// Package platform provides dependency injection and application bootstrapping.
+// This file contains loading settings from a YAML or JSON config file.
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile holds the settings read from a config file. The file is a flat mapping of
+// environment variable names to values, in YAML (.yaml, .yml) or JSON (.json). Values are
+// scalars or lists; lists become the comma-separated form the environment uses, e.g.
+// IP_ALLOWLIST: [10.0.0.0/8, 192.0.2.7].
+type configFile struct {
+	path     string
+	settings map[string]string // Values by upper-case setting name
+	used     map[string]bool   // Settings the configuration asked for through lookup
+}
+
+// readConfigFile reads and parses the config file at path.
+func readConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("CONFIG_FILE: %w", err)
+	}
+
+	var raw map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("CONFIG_FILE: unsupported file type %q, expected .yaml, .yml or .json", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CONFIG_FILE %s: %w", path, err)
+	}
+
+	settings, err := flattenSettings(raw)
+	if err != nil {
+		return nil, fmt.Errorf("CONFIG_FILE %s: %w", path, err)
+	}
+	return &configFile{path: path, settings: settings, used: make(map[string]bool)}, nil
+}
+
+// lookup returns a lookupFunc that reads a setting from env and falls back to the file when env
+// doesn't set it, so environment variables take precedence over the file. Every setting asked
+// for is recorded for checkUnknown.
+func (f *configFile) lookup(env lookupFunc) lookupFunc {
+	return func(key string) string {
+		f.used[key] = true
+		if v := env(key); v != "" {
+			return v
+		}
+		return f.settings[key]
+	}
+}
+
+// checkUnknown returns an error naming the file's settings that loading the configuration never
+// asked for, which are most likely misspelled. It is only meaningful once the configuration has
+// been loaded through lookup.
+func (f *configFile) checkUnknown() error {
+	var unknown []string
+	for key := range f.settings {
+		if !f.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("CONFIG_FILE %s: unknown settings %s", f.path, strings.Join(unknown, ", "))
+}
+
+// flattenSettings converts a decoded settings mapping to the string form the environment uses.
+// Keys are upper-cased; strings, numbers and booleans become their string form, lists their
+// comma-separated items, and null leaves the setting unset. Nested mappings are rejected rather
+// than guessed at, as is a setting given twice in different case.
+func flattenSettings(raw map[string]any) (map[string]string, error) {
+	settings := make(map[string]string, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for key, v := range raw {
+		name := strings.ToUpper(key)
+		if seen[name] {
+			return nil, fmt.Errorf("%s: set more than once", name)
+		}
+		seen[name] = true
+		if v == nil {
+			continue
+		}
+		var value string
+		if list, ok := v.([]any); ok {
+			parts := make([]string, len(list))
+			for i, item := range list {
+				s, ok := scalarSetting(item)
+				if !ok {
+					return nil, fmt.Errorf("%s: list items must be strings, numbers or booleans", key)
+				}
+				parts[i] = s
+			}
+			value = strings.Join(parts, ",")
+		} else if s, ok := scalarSetting(v); ok {
+			value = s
+		} else {
+			return nil, fmt.Errorf("%s: value must be a string, number, boolean or list", key)
+		}
+		settings[name] = value
+	}
+	return settings, nil
+}
+
+// scalarSetting returns the string form of a decoded string, number or boolean. JSON numbers
+// decode as float64; YAML integers as int, or uint64 beyond int's range.
+func scalarSetting(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case int:
+		return strconv.Itoa(v), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
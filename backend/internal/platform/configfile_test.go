@@ -0,0 +1,199 @@
+package platform
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// writeConfigFile writes content to a config file named name in a temporary directory and
+// points CONFIG_FILE at it. keys are cleared for the test, since the environment would
+// otherwise override the file, and t.Setenv restores them afterwards.
+func writeConfigFile(t *testing.T, name, content string, keys ...string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	for _, key := range keys {
+		t.Setenv(key, "")
+	}
+}
+
+func TestLoad_ConfigFilePrecedence(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.yaml": `
+# Batch and policy settings
+MAX_BATCH_SIZE: 500
+TIE_BREAK: PacksFirst   # fewest packs first
+IP_ALLOWLIST:
+  - 10.0.0.0/8
+  - "192.0.2.7"
+REQUIRED_PACK_SIZES: [250, 500]
+`,
+		"config.json": `{
+  "MAX_BATCH_SIZE": 500,
+  "TIE_BREAK": "PacksFirst",
+  "IP_ALLOWLIST": ["10.0.0.0/8", "192.0.2.7"],
+  "REQUIRED_PACK_SIZES": [250, 500]
+}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			writeConfigFile(t, name, content, "MAX_BATCH_SIZE", "TIE_BREAK", "IP_ALLOWLIST", "REQUIRED_PACK_SIZES")
+			t.Setenv("MAX_BATCH_SIZE", "700") // The environment wins over the file
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cfg.MaxBatchSize != 700 {
+				t.Errorf("Expected MAX_BATCH_SIZE from the environment (700), got %d", cfg.MaxBatchSize)
+			}
+			if cfg.TieBreak != "PacksFirst" {
+				t.Errorf("Expected TIE_BREAK from the file, got %q", cfg.TieBreak)
+			}
+			if want := []string{"10.0.0.0/8", "192.0.2.7"}; !reflect.DeepEqual(cfg.IPAllowlist, want) {
+				t.Errorf("Expected IP_ALLOWLIST %v from the file, got %v", want, cfg.IPAllowlist)
+			}
+			if want := []int{250, 500}; !reflect.DeepEqual(cfg.RequiredPackSizes, want) {
+				t.Errorf("Expected REQUIRED_PACK_SIZES %v from the file, got %v", want, cfg.RequiredPackSizes)
+			}
+		})
+	}
+}
+
+func TestLoad_WithoutConfigFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("MAX_BATCH_SIZE", "250")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.MaxBatchSize != 250 {
+		t.Errorf("Expected MAX_BATCH_SIZE from the environment, got %d", cfg.MaxBatchSize)
+	}
+}
+
+func TestLoad_InvalidConfigFile(t *testing.T) {
+	for name, content := range map[string]string{
+		"missing.yaml":  "",
+		"config.toml":   "MAX_BATCH_SIZE = 500",
+		"nested.yaml":   "SERVER:\n  port: 8080\n",
+		"broken.json":   `{"MAX_BATCH_SIZE": `,
+		"object.json":   `{"UNIT_CONVERSIONS": {"cases": 12}}`,
+		"invalid.yaml":  "HTTP_PORT: 99999\n", // Parses, but fails validation
+		"unquoted.yaml": `TLS_CERT_FILE: "cert.pem` + "\n",
+		"twice.yaml":    "HTTP_PORT: 8080\nhttp_port: 9090\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			writeConfigFile(t, name, content, "HTTP_PORT", "TLS_CERT_FILE")
+			if name == "missing.yaml" {
+				t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), name))
+			}
+			if _, err := Load(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestReadConfigFile_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `---
+http_port: "9090"
+REDIS_PASSWORD: 'it''s#secret'
+LOG_IP_HASH_KEY: "a#b" # The # inside quotes is kept
+RATE_LIMIT_BURST: ~
+DEFAULT_PACK_SIZES: []
+MAX_BATCH_SIZE: 500
+TRACING_SAMPLE_RATIO: 0.25
+HTTP2_ENABLED: false
+IP_DENYLIST:
+  - 192.0.2.1   # one
+  - 192.0.2.2
+CORS_ORIGIN: http://localhost:5173
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	file, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"HTTP_PORT":            "9090",
+		"REDIS_PASSWORD":       "it's#secret",
+		"LOG_IP_HASH_KEY":      "a#b",
+		"DEFAULT_PACK_SIZES":   "",
+		"MAX_BATCH_SIZE":       "500",
+		"TRACING_SAMPLE_RATIO": "0.25",
+		"HTTP2_ENABLED":        "false",
+		"IP_DENYLIST":          "192.0.2.1,192.0.2.2",
+		"CORS_ORIGIN":          "http://localhost:5173",
+	}
+	if !reflect.DeepEqual(file.settings, want) {
+		t.Errorf("Expected %v, got %v", want, file.settings)
+	}
+}
+
+func TestLoad_ConfigFileLeavesEnvironmentAlone(t *testing.T) {
+	writeConfigFile(t, "config.yaml", "MAX_BATCH_SIZE: 500\nLOG_LEVEL: warn\n", "MAX_BATCH_SIZE", "LOG_LEVEL")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.MaxBatchSize != 500 || cfg.Log.Level != slog.LevelWarn {
+		t.Errorf("Expected MAX_BATCH_SIZE 500 and LOG_LEVEL warn from the file, got %d and %v", cfg.MaxBatchSize, cfg.Log.Level)
+	}
+	if v := os.Getenv("MAX_BATCH_SIZE"); v != "" {
+		t.Errorf("Expected the environment to be left alone, got MAX_BATCH_SIZE=%q", v)
+	}
+}
+
+func TestLoad_ConfigFileRejectsUnknownSettings(t *testing.T) {
+	writeConfigFile(t, "config.yaml", "MAX_BATCH_SIZE: 500\nMAX_BATHC_SIZE: 600\nlog_levle: debug\n", "MAX_BATCH_SIZE")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Expected an error for unknown settings")
+	}
+	if !strings.Contains(err.Error(), "unknown settings LOG_LEVLE, MAX_BATHC_SIZE") || strings.Contains(err.Error(), "MAX_BATCH_SIZE") {
+		t.Errorf("Expected the error to name just the unknown settings, got %v", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := Config{HTTPPort: "8080", MinOrderAmount: 1, MaxOrderAmount: 1000, MaxPackCount: 100, LogIPMode: "plain"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+
+	invalid := valid
+	invalid.HTTPPort = "http"
+	invalid.MinOrderAmount = 2000
+	invalid.TLSCertFile = "cert.pem"
+	invalid.RequestIDFormat = "uuidv4"
+	invalid.MaxPackSize = domain.MaxLimit + 1
+	invalid.ElevatedMaxOrderAmount = domain.MaxElevatedAmount + 1
+	invalid.MaxPackCount = -1
+	invalid.UnitRounding = "down"
+	invalid.TrustedProxies = []string{"lb.internal"}
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, want := range []string{"HTTP_PORT", "MIN_ORDER_AMOUNT", "TLS_CERT_FILE", "REQUEST_ID_FORMAT", "MAX_PACK_SIZE", "ELEVATED_MAX_ORDER_AMOUNT", "MAX_PACK_COUNT", "UNIT_ROUNDING", "TRUSTED_PROXIES"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to mention %s, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package platform provides dependency injection and application bootstrapping.
+// This file contains the background health check of external dependencies.
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// defaultHealthCheckInterval is how often dependencies are pinged when HEALTH_CHECK_INTERVAL is unset.
+const defaultHealthCheckInterval = 10 * time.Second
+
+// maxHealthPingTimeout caps how long a single health ping may take.
+const maxHealthPingTimeout = 2 * time.Second
+
+// dependencyCheck pings one dependency through its circuit breaker.
+type dependencyCheck struct {
+	name    string
+	breaker *CircuitBreaker
+	ping    func(ctx context.Context) error
+	healthy bool
+}
+
+// HealthMonitor periodically pings the database and cache through their circuit breakers, so
+// readiness reflects live dependency health and an outage trips the breaker at runtime too.
+// While a breaker is open its dependency is reported unavailable without being pinged, until
+// the breaker's reset timeout lets a probe through again.
+type HealthMonitor struct {
+	logger   *slog.Logger
+	interval time.Duration
+	db       *dependencyCheck
+	cache    *dependencyCheck // nil when there is no cache to check
+
+	mu     sync.RWMutex
+	status domain.DependencyHealth
+}
+
+// NewHealthMonitor creates a monitor pinging db (and cache, if not nil) every interval.
+// Both dependencies start out healthy, since Bootstrap only continues once they've connected.
+func NewHealthMonitor(logger *slog.Logger, interval time.Duration, dbBreaker *CircuitBreaker, dbPing func(context.Context) error) *HealthMonitor {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	return &HealthMonitor{
+		logger:   logger,
+		interval: interval,
+		db:       &dependencyCheck{name: "database", breaker: dbBreaker, ping: dbPing, healthy: true},
+		status:   domain.DependencyHealth{DatabaseOK: true},
+	}
+}
+
+// WithCache adds the cache to the checked dependencies.
+func (m *HealthMonitor) WithCache(breaker *CircuitBreaker, ping func(context.Context) error) *HealthMonitor {
+	m.cache = &dependencyCheck{name: "cache", breaker: breaker, ping: ping, healthy: true}
+	m.status.CacheOK = true
+	return m
+}
+
+// Status returns the outcome of the latest check.
+func (m *HealthMonitor) Status() domain.DependencyHealth {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// DatabaseRetryAfter reports how long until the open database breaker lets a trial call
+// through, or zero when the breaker is closed or its reset timeout has passed.
+func (m *HealthMonitor) DatabaseRetryAfter() time.Duration {
+	return max(time.Until(m.Status().DatabaseRetryAt), 0)
+}
+
+// Run checks the dependencies every interval until ctx is done.
+func (m *HealthMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkOnce(ctx)
+		}
+	}
+}
+
+// checkOnce pings every dependency once and publishes the result.
+func (m *HealthMonitor) checkOnce(ctx context.Context) {
+	status := domain.DependencyHealth{DatabaseOK: m.check(ctx, m.db)}
+	if wait := m.db.breaker.RemainingUntilReset(); wait > 0 {
+		status.DatabaseRetryAt = time.Now().Add(wait)
+	}
+	if m.cache != nil {
+		status.CacheOK = m.check(ctx, m.cache)
+	}
+	status.CheckedAt = time.Now().UTC()
+
+	m.mu.Lock()
+	m.status = status
+	m.mu.Unlock()
+}
+
+// check pings one dependency through its breaker, logging when its health changes.
+// The breakers aren't safe for concurrent use; after startup only this goroutine uses them.
+func (m *HealthMonitor) check(ctx context.Context, dc *dependencyCheck) bool {
+	pingCtx, cancel := context.WithTimeout(ctx, min(m.interval, maxHealthPingTimeout))
+	defer cancel()
+	err := dc.breaker.Execute(func() error { return dc.ping(pingCtx) })
+
+	healthy := err == nil
+	switch {
+	case dc.healthy && !healthy:
+		m.logger.Warn("dependency unavailable", "dependency", dc.name, "error", err)
+	case !dc.healthy && healthy:
+		m.logger.Info("dependency recovered", "dependency", dc.name)
+	}
+	dc.healthy = healthy
+	return healthy
+}
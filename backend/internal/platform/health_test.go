@@ -0,0 +1,93 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestHealthMonitor_TracksDependencies(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var dbErr, cacheErr error
+	dbPings := 0
+	m := NewHealthMonitor(logger, time.Second, NewCircuitBreaker(logger, 2, time.Hour), func(context.Context) error {
+		dbPings++
+		return dbErr
+	}).WithCache(NewCircuitBreaker(logger, 2, time.Hour), func(context.Context) error { return cacheErr })
+
+	if s := m.Status(); !s.DatabaseOK || !s.CacheOK || !s.CheckedAt.IsZero() {
+		t.Fatalf("Expected healthy dependencies before the first check, got %+v", s)
+	}
+
+	ctx := context.Background()
+	cacheErr = errors.New("connection refused")
+	m.checkOnce(ctx)
+	if s := m.Status(); !s.DatabaseOK || s.CacheOK || s.CheckedAt.IsZero() {
+		t.Errorf("Expected only the cache unavailable, got %+v", s)
+	}
+
+	// Once the breaker opens, the database is reported down without being pinged
+	dbErr = errors.New("connection refused")
+	for i := 0; i < 3; i++ {
+		m.checkOnce(ctx)
+	}
+	if s := m.Status(); s.DatabaseOK {
+		t.Errorf("Expected the database unavailable, got %+v", s)
+	}
+	if dbPings != 3 {
+		t.Errorf("Expected the open breaker to stop pings after 2 failures, got %d pings", dbPings)
+	}
+}
+
+func TestHealthMonitor_RunStopsWithContext(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	pinged := make(chan struct{}, 1)
+	m := NewHealthMonitor(logger, time.Millisecond, NewCircuitBreaker(logger, 5, time.Second), func(context.Context) error {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+
+	<-pinged
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return after the context was canceled")
+	}
+	if m.Status().CheckedAt.IsZero() {
+		t.Errorf("Expected a completed check")
+	}
+}
+
+func TestHealthMonitor_DatabaseRetryAfter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dbErr := errors.New("connection refused")
+	m := NewHealthMonitor(logger, time.Second, NewCircuitBreaker(logger, 1, time.Minute), func(context.Context) error { return dbErr })
+
+	ctx := context.Background()
+	m.checkOnce(ctx)
+	if got := m.DatabaseRetryAfter(); got != 0 {
+		t.Errorf("Expected no wait before the breaker opens, got %v", got)
+	}
+
+	m.checkOnce(ctx) // Opens the breaker
+	if got := m.DatabaseRetryAfter(); got <= 50*time.Second || got > time.Minute {
+		t.Errorf("Expected about a minute until the breaker's trial, got %v", got)
+	}
+	if s := m.Status(); s.DatabaseRetryAt.IsZero() {
+		t.Errorf("Expected the status to carry the retry time, got %+v", s)
+	}
+}
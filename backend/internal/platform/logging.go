@@ -0,0 +1,67 @@
+package platform
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// LogSettings holds the structured logger configuration.
+type LogSettings struct {
+	Level  slog.Level // Minimum level to log
+	Format string     // "json" or "text"
+}
+
+// loadLogSettings reads LOG_LEVEL and LOG_FORMAT through env.
+// Defaults depend on ENVIRONMENT: JSON+info in production, text+debug otherwise.
+// Invalid values fall back to those defaults; the returned warnings describe what was ignored
+// so they can be logged once the logger exists.
+func loadLogSettings(env lookupFunc) (LogSettings, []string) {
+	ls := LogSettings{Level: slog.LevelDebug, Format: "text"}
+	if env("ENVIRONMENT") == "production" {
+		ls = LogSettings{Level: slog.LevelInfo, Format: "json"}
+	}
+
+	var warnings []string
+	if v := env("LOG_LEVEL"); v != "" {
+		switch strings.ToLower(v) {
+		case "debug":
+			ls.Level = slog.LevelDebug
+		case "info":
+			ls.Level = slog.LevelInfo
+		case "warn", "warning":
+			ls.Level = slog.LevelWarn
+		case "error":
+			ls.Level = slog.LevelError
+		default:
+			warnings = append(warnings, "invalid LOG_LEVEL "+v+", using "+ls.Level.String())
+		}
+	}
+	if v := env("LOG_FORMAT"); v != "" {
+		switch f := strings.ToLower(v); f {
+		case "json", "text":
+			ls.Format = f
+		default:
+			warnings = append(warnings, "invalid LOG_FORMAT "+v+", using "+ls.Format)
+		}
+	}
+	return ls, warnings
+}
+
+// NewLogger builds the application logger from the LOG_LEVEL, LOG_FORMAT and ENVIRONMENT settings
+// loaded into the configuration. Invalid settings are reported as warnings through the new logger
+// instead of failing startup.
+func (c Config) NewLogger(w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: c.Log.Level}
+	var logger *slog.Logger
+	if c.Log.Format == "json" {
+		logger = slog.New(slog.NewJSONHandler(w, opts))
+	} else {
+		logger = slog.New(slog.NewTextHandler(w, opts))
+	}
+
+	for _, msg := range c.LogWarnings {
+		logger.Warn(msg)
+	}
+	return logger
+}
@@ -0,0 +1,59 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadLogSettings_Defaults(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("LOG_FORMAT", "")
+
+	t.Setenv("ENVIRONMENT", "production")
+	if ls, _ := loadLogSettings(os.Getenv); ls.Level != slog.LevelInfo || ls.Format != "json" {
+		t.Errorf("Expected json/info in production, got %+v", ls)
+	}
+
+	t.Setenv("ENVIRONMENT", "development")
+	if ls, _ := loadLogSettings(os.Getenv); ls.Level != slog.LevelDebug || ls.Format != "text" {
+		t.Errorf("Expected text/debug in development, got %+v", ls)
+	}
+}
+
+func TestLoadLogSettings_Overrides(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "production")
+	t.Setenv("LOG_LEVEL", "DEBUG")
+	t.Setenv("LOG_FORMAT", "text")
+
+	ls, warnings := loadLogSettings(os.Getenv)
+	if ls.Level != slog.LevelDebug || ls.Format != "text" {
+		t.Errorf("Expected text/debug override, got %+v", ls)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+}
+
+func TestNewLogger_InvalidValuesWarnAndFallBack(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "production")
+	t.Setenv("LOG_LEVEL", "verbose")
+	t.Setenv("LOG_FORMAT", "xml")
+
+	var buf bytes.Buffer
+	logger := LoadConfig().NewLogger(&buf)
+
+	if logger.Enabled(context.Background(), slog.LevelDebug) || !logger.Enabled(context.Background(), slog.LevelInfo) {
+		t.Errorf("Expected production default level info")
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "{") {
+		t.Errorf("Expected JSON output, got %q", out)
+	}
+	if !strings.Contains(out, "invalid LOG_LEVEL verbose") || !strings.Contains(out, "invalid LOG_FORMAT xml") {
+		t.Errorf("Expected warnings for invalid values, got %q", out)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -75,7 +76,13 @@ const (
 	CircuitBreakerHalfOpen                          // Testing if service recovered
 )
 
+// latencyEMAAlpha is the weight of the newest sample in the call latency moving average.
+// With 0.3, a few slow calls move the average past the threshold while one outlier among fast calls does not.
+const latencyEMAAlpha = 0.3
+
 // CircuitBreaker implements the circuit breaker pattern for external dependencies.
+// Besides consecutive failures, it can open on slow calls: with a latency threshold set,
+// an exponential moving average of call durations above the threshold trips it too.
 type CircuitBreaker struct {
 	logger          *slog.Logger
 	maxFailures     int
@@ -85,6 +92,9 @@ type CircuitBreaker struct {
 	lastFailureTime time.Time
 	successCount    int // For half-open state
 	halfOpenRequests int // Number of requests to test in half-open state
+	latencyThreshold time.Duration // Average latency that opens the circuit (0 disables)
+	latencyEMA       time.Duration // Moving average of call durations
+	now              func() time.Time // Clock for reset timeouts and call durations (default time.Now)
 }
 
 // NewCircuitBreaker creates a new circuit breaker.
@@ -95,9 +105,24 @@ func NewCircuitBreaker(logger *slog.Logger, maxFailures int, resetTimeout time.D
 		resetTimeout:    resetTimeout,
 		state:           CircuitBreakerClosed,
 		halfOpenRequests: 3, // Test with 3 requests in half-open state
+		now:             time.Now,
 	}
 }
 
+// WithClock replaces the clock the breaker reads for reset timeouts and call durations, so
+// tests can move time forward instead of sleeping through it.
+func (cb *CircuitBreaker) WithClock(now func() time.Time) *CircuitBreaker {
+	cb.now = now
+	return cb
+}
+
+// WithLatencyThreshold makes the breaker open when the moving average of call durations exceeds threshold,
+// even if the calls succeed. Zero (the default) keeps the failure-count behavior only.
+func (cb *CircuitBreaker) WithLatencyThreshold(threshold time.Duration) *CircuitBreaker {
+	cb.latencyThreshold = threshold
+	return cb
+}
+
 // Execute executes a function through the circuit breaker.
 func (cb *CircuitBreaker) Execute(fn func() error) error {
 	cb.updateState()
@@ -115,11 +140,18 @@ func (cb *CircuitBreaker) Execute(fn func() error) error {
 		}
 		fallthrough
 	case CircuitBreakerClosed:
+		start := cb.now()
 		err := fn()
+		cb.recordLatency(cb.now().Sub(start))
 		if err != nil {
 			cb.recordFailure()
 			return err
 		}
+		if cb.tooSlow() {
+			// The call succeeded, so its result is returned; later calls are rejected
+			cb.recordSlow()
+			return nil
+		}
 		cb.recordSuccess()
 		return nil
 	}
@@ -127,9 +159,18 @@ func (cb *CircuitBreaker) Execute(fn func() error) error {
 	return nil
 }
 
+// RemainingUntilReset reports how long an open breaker keeps rejecting calls before it lets a
+// half-open trial through. It is zero unless the breaker is open.
+func (cb *CircuitBreaker) RemainingUntilReset() time.Duration {
+	if cb.state != CircuitBreakerOpen {
+		return 0
+	}
+	return max(cb.resetTimeout-cb.now().Sub(cb.lastFailureTime), 0)
+}
+
 // updateState updates the circuit breaker state based on time and failure count.
 func (cb *CircuitBreaker) updateState() {
-	now := time.Now()
+	now := cb.now()
 
 	switch cb.state {
 	case CircuitBreakerOpen:
@@ -137,6 +178,7 @@ func (cb *CircuitBreaker) updateState() {
 		if now.Sub(cb.lastFailureTime) >= cb.resetTimeout {
 			cb.state = CircuitBreakerHalfOpen
 			cb.successCount = 0
+			cb.latencyEMA = 0 // Judge recovery on fresh samples only
 			cb.logger.Info("circuit breaker half-open - testing service recovery")
 		}
 	case CircuitBreakerHalfOpen:
@@ -157,7 +199,7 @@ func (cb *CircuitBreaker) updateState() {
 // recordFailure records a failure in the circuit breaker.
 func (cb *CircuitBreaker) recordFailure() {
 	cb.failureCount++
-	cb.lastFailureTime = time.Now()
+	cb.lastFailureTime = cb.now()
 
 	if cb.state == CircuitBreakerHalfOpen {
 		// If we fail in half-open, go back to open
@@ -166,6 +208,32 @@ func (cb *CircuitBreaker) recordFailure() {
 	}
 }
 
+// recordLatency folds a call duration into the moving average; the first sample seeds it.
+func (cb *CircuitBreaker) recordLatency(d time.Duration) {
+	if cb.latencyEMA == 0 {
+		cb.latencyEMA = d
+		return
+	}
+	cb.latencyEMA = time.Duration(latencyEMAAlpha*float64(d) + (1-latencyEMAAlpha)*float64(cb.latencyEMA))
+}
+
+// tooSlow reports whether the latency average exceeds the configured threshold.
+func (cb *CircuitBreaker) tooSlow() bool {
+	return cb.latencyThreshold > 0 && cb.latencyEMA > cb.latencyThreshold
+}
+
+// recordSlow opens the circuit because calls are succeeding too slowly.
+func (cb *CircuitBreaker) recordSlow() {
+	cb.state = CircuitBreakerOpen
+	cb.lastFailureTime = cb.now()
+	cb.successCount = 0
+	cb.logger.Error(
+		"circuit breaker opened - latency above threshold",
+		"latency_ema", cb.latencyEMA,
+		"threshold", cb.latencyThreshold,
+	)
+}
+
 // recordSuccess records a success in the circuit breaker.
 func (cb *CircuitBreaker) recordSuccess() {
 	if cb.state == CircuitBreakerHalfOpen {
@@ -176,15 +244,51 @@ func (cb *CircuitBreaker) recordSuccess() {
 	}
 }
 
+// NewPoolConfig parses the DSN and applies the configured pool settings.
+// Zero values in settings leave the corresponding pgxpool default untouched.
+func NewPoolConfig(dsn string, settings PoolSettings) (*pgxpool.Config, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.MaxConns > 0 {
+		poolCfg.MaxConns = settings.MaxConns
+	}
+	if settings.MinConns > 0 && settings.MinConns <= poolCfg.MaxConns {
+		poolCfg.MinConns = settings.MinConns
+	}
+	if settings.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = settings.MaxConnLifetime
+	}
+	if settings.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = settings.MaxConnIdleTime
+	}
+	if settings.StatementTimeout > 0 {
+		// Sent as a startup parameter, so Postgres applies it to every statement on the connection.
+		// Postgres counts in milliseconds, and 0 would disable it, so round up.
+		ms := (settings.StatementTimeout + time.Millisecond - 1) / time.Millisecond
+		poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(int64(ms), 10)
+	}
+
+	return poolCfg, nil
+}
+
 // ConnectPostgresWithRetry connects to PostgreSQL with retry logic and circuit breaker.
-func ConnectPostgresWithRetry(ctx context.Context, logger *slog.Logger, dsn string, retryConfig RetryConfig, cb *CircuitBreaker) (*pgxpool.Pool, error) {
+// The pool is created from the DSN with the given pool settings applied.
+func ConnectPostgresWithRetry(ctx context.Context, logger *slog.Logger, dsn string, settings PoolSettings, retryConfig RetryConfig, cb *CircuitBreaker) (*pgxpool.Pool, error) {
+	// Parse once up front - a malformed DSN won't fix itself on retry
+	poolCfg, err := NewPoolConfig(dsn, settings)
+	if err != nil {
+		return nil, err
+	}
+
 	var pool *pgxpool.Pool
-	var err error
 
 	err = RetryWithBackoff(ctx, logger, retryConfig, func() error {
 		return cb.Execute(func() error {
 			var e error
-			pool, e = pgxpool.New(ctx, dsn)
+			pool, e = pgxpool.NewWithConfig(ctx, poolCfg)
 			if e != nil {
 				return e
 			}
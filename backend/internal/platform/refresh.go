@@ -0,0 +1,57 @@
+// Package platform provides dependency injection and application bootstrapping.
+// This file contains the background refresh of the cached active pack set.
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// packSetRefresher rewrites the cached active pack set; packsService implements it.
+type packSetRefresher interface {
+	RefreshActivePacks(ctx context.Context) error
+}
+
+// cacheRefresher re-caches the active pack set every interval. With an interval shorter than
+// the cache TTL the entries are rewritten before they expire, so reads of a rarely changing
+// but frequently read value never fall through to the database. A failed refresh is logged and
+// retried at the next tick; until then a request that misses refills the cache as usual.
+type cacheRefresher struct {
+	logger   *slog.Logger
+	svc      packSetRefresher
+	interval time.Duration
+	failing  bool // Whether the last refresh failed, so only changes are logged
+}
+
+// newCacheRefresher creates a refresher calling svc.RefreshActivePacks every interval.
+func newCacheRefresher(logger *slog.Logger, svc packSetRefresher, interval time.Duration) *cacheRefresher {
+	return &cacheRefresher{logger: logger, svc: svc, interval: interval}
+}
+
+// Run refreshes the cache every interval until ctx is done. The first refresh is one interval
+// after startup, since the startup warm-up has just filled the cache.
+func (r *cacheRefresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.refreshOnce(ctx)
+		}
+	}
+}
+
+// refreshOnce refreshes the cache once, logging when refreshing starts or stops failing.
+func (r *cacheRefresher) refreshOnce(ctx context.Context) {
+	err := r.svc.RefreshActivePacks(ctx)
+	switch {
+	case err != nil && !r.failing:
+		r.logger.Warn("pack sizes cache refresh failed", "error", err)
+	case err == nil && r.failing:
+		r.logger.Info("pack sizes cache refresh recovered")
+	}
+	r.failing = err != nil
+}
@@ -0,0 +1,181 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+)
+
+// expiringCache is an in-memory cache whose entries expire after their TTL on a fake clock.
+type expiringCache struct {
+	fakeCache
+	now     time.Time
+	expires map[string]time.Time
+}
+
+func newExpiringCache() *expiringCache {
+	return &expiringCache{fakeCache: fakeCache{data: map[string][]byte{}}, now: time.Unix(0, 0), expires: map[string]time.Time{}}
+}
+
+func (c *expiringCache) Get(key string) ([]byte, error) {
+	if !c.now.Before(c.expires[key]) {
+		return nil, nil
+	}
+	return c.fakeCache.Get(key)
+}
+
+func (c *expiringCache) Set(key string, value []byte, ttlSeconds int) error {
+	c.expires[key] = c.now.Add(time.Duration(ttlSeconds) * time.Second)
+	return c.fakeCache.Set(key, value, ttlSeconds)
+}
+
+// countingRepo counts the reads of pack set contents (not of its version) reaching fakeRepo.
+type countingRepo struct {
+	*fakeRepo
+	reads int
+}
+
+func (r *countingRepo) GetAllActive() ([]int, error) {
+	r.reads++
+	return r.fakeRepo.GetAllActive()
+}
+
+func (r *countingRepo) GetActivePacks() ([]domain.Pack, error) {
+	r.reads++
+	return r.fakeRepo.GetActivePacks()
+}
+
+func (r *countingRepo) GetActivePacksWithVersion() ([]domain.Pack, int64, error) {
+	r.reads++
+	return r.fakeRepo.GetActivePacksWithVersion()
+}
+
+func TestCacheRefresher_KeepsCacheWarm(t *testing.T) {
+	const ttl = 600
+	interval := Config{CacheTTLSecs: ttl, CacheRefreshAheadSecs: 60}.CacheRefreshInterval()
+	ctx := context.Background()
+
+	for _, refresh := range []bool{false, true} {
+		repo := &countingRepo{fakeRepo: &fakeRepo{packs: []domain.Pack{{Size: 250}, {Size: 500}}, version: 1}}
+		cache := newExpiringCache()
+		ps := &packsService{repo: repo, cache: cache, ttl: ttl}
+		r := newCacheRefresher(slog.New(slog.NewTextHandler(io.Discard, nil)), ps, interval)
+
+		// Fill the cache as the first requests would, then serve requests every 10 seconds for an hour
+		if _, err := ps.GetActiveSizes(ctx); err != nil {
+			t.Fatalf("Warm-up failed: %v", err)
+		}
+		if _, err := ps.GetActivePacks(ctx); err != nil {
+			t.Fatalf("Warm-up failed: %v", err)
+		}
+		ps.CacheStats(ctx, true)
+		repo.reads = 0
+		refreshes := 0
+		start := cache.now
+		for elapsed := 10 * time.Second; elapsed <= time.Hour; elapsed += 10 * time.Second {
+			cache.now = start.Add(elapsed)
+			if refresh && elapsed%interval == 0 {
+				r.refreshOnce(ctx)
+				refreshes++
+			}
+			if sizes, err := ps.GetActiveSizes(ctx); err != nil || len(sizes) != 2 {
+				t.Fatalf("GetActiveSizes: %v, %v", sizes, err)
+			}
+			if packs, err := ps.GetActivePacks(ctx); err != nil || len(packs) != 2 {
+				t.Fatalf("GetActivePacks: %v, %v", packs, err)
+			}
+		}
+
+		misses := ps.CacheStats(ctx, false).Misses
+		if !refresh {
+			// Every expiry sends a request to the repository
+			if misses != 6 {
+				t.Errorf("Without refreshing, expected a miss every 10 minutes, got %d", misses)
+			}
+			continue
+		}
+		if misses != 0 {
+			t.Errorf("Expected no misses with refreshing, got %d", misses)
+		}
+		if refreshes != 6 || repo.reads != refreshes {
+			t.Errorf("Expected the repository to be read by the %d refreshes only, got %d reads", refreshes, repo.reads)
+		}
+	}
+}
+
+// countingRefresher counts refreshes, failing while err is set.
+type countingRefresher struct {
+	calls atomic.Int64
+	err   error
+}
+
+func (c *countingRefresher) RefreshActivePacks(ctx context.Context) error {
+	c.calls.Add(1)
+	return c.err
+}
+
+func TestCacheRefresher_RunStopsOnCancel(t *testing.T) {
+	svc := &countingRefresher{}
+	r := newCacheRefresher(slog.New(slog.NewTextHandler(io.Discard, nil)), svc, 5*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for svc.calls.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected periodic refreshes, got %d", svc.calls.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return once its context is canceled")
+	}
+}
+
+func TestCacheRefresher_LogsFailureChanges(t *testing.T) {
+	var logs bytes.Buffer
+	svc := &countingRefresher{err: errors.New("connection refused")}
+	r := newCacheRefresher(slog.New(slog.NewTextHandler(&logs, nil)), svc, time.Minute)
+	ctx := context.Background()
+
+	r.refreshOnce(ctx)
+	r.refreshOnce(ctx)
+	svc.err = nil
+	r.refreshOnce(ctx)
+
+	out := logs.String()
+	if n := strings.Count(out, "pack sizes cache refresh failed"); n != 1 {
+		t.Errorf("Expected the failure to be logged once, got %d times:\n%s", n, out)
+	}
+	if n := strings.Count(out, "pack sizes cache refresh recovered"); n != 1 {
+		t.Errorf("Expected the recovery to be logged once, got %d times:\n%s", n, out)
+	}
+}
+
+func TestPacksService_RefreshActivePacksFailsFast(t *testing.T) {
+	repo := &countingRepo{fakeRepo: &fakeRepo{packs: []domain.Pack{{Size: 250}}, version: 1}}
+	ps := &packsService{repo: repo, cache: newExpiringCache(), ttl: 60, dbHealth: fixedRetryAfter(5 * time.Second)}
+
+	var unavailable *domain.UnavailableError
+	if err := ps.RefreshActivePacks(context.Background()); !errors.As(err, &unavailable) {
+		t.Fatalf("Expected an UnavailableError while the breaker is open, got %v", err)
+	}
+	if repo.reads != 0 {
+		t.Errorf("Expected the repository not to be read, got %d reads", repo.reads)
+	}
+}
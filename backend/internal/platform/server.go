@@ -0,0 +1,151 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ServerSettings holds HTTP transport tuning values.
+type ServerSettings struct {
+	ReadTimeout       time.Duration // Maximum time to read a request, including the body
+	ReadHeaderTimeout time.Duration // Maximum time to read request headers
+	WriteTimeout      time.Duration // Maximum time to write a response
+	IdleTimeout       time.Duration // How long an idle keep-alive connection stays open
+	KeepAlives        bool          // Whether HTTP/1.1 keep-alive connections are reused
+
+	RequestTimeout   time.Duration // Handler deadline for every API route (0 disables)
+	CalculateTimeout time.Duration // Tighter handler deadline for POST /calculate (0 disables)
+
+	HTTP2                bool // Serve HTTP/2: via ALPN with TLS, as h2c (prior knowledge) without
+	MaxConcurrentStreams int  // HTTP/2 streams allowed per connection
+}
+
+// Default HTTP server settings, used when the environment doesn't provide valid values.
+// Handler deadlines stay below WriteTimeout so a timed-out request still gets its 503.
+// WriteTimeout covers the largest synchronous responses (a full /calculate/batch); longer
+// work goes through async jobs, and a future streaming handler should extend its own
+// deadline with http.ResponseController rather than raising the server-wide limit.
+const (
+	defaultReadTimeout          = 15 * time.Second
+	defaultReadHeaderTimeout    = 5 * time.Second
+	defaultWriteTimeout         = 15 * time.Second
+	defaultIdleTimeout          = 60 * time.Second
+	defaultRequestTimeout       = 10 * time.Second
+	defaultCalculateTimeout     = 2 * time.Second
+	defaultMaxConcurrentStreams = 250
+)
+
+// loadServerSettings loads HTTP transport settings from environment variables.
+func loadServerSettings(env lookupFunc) ServerSettings {
+	ss := ServerSettings{
+		ReadTimeout:          env.getenvDuration("HTTP_READ_TIMEOUT", defaultReadTimeout),
+		ReadHeaderTimeout:    env.getenvDuration("HTTP_READ_HEADER_TIMEOUT", defaultReadHeaderTimeout),
+		WriteTimeout:         env.getenvDuration("HTTP_WRITE_TIMEOUT", defaultWriteTimeout),
+		IdleTimeout:          env.getenvDuration("HTTP_IDLE_TIMEOUT", defaultIdleTimeout),
+		KeepAlives:           env.getenvBool("HTTP_KEEPALIVES_ENABLED", true),
+		RequestTimeout:       env.getenvOptionalDuration("REQUEST_TIMEOUT", defaultRequestTimeout),
+		CalculateTimeout:     env.getenvOptionalDuration("CALCULATE_TIMEOUT", defaultCalculateTimeout),
+		HTTP2:                env.getenvBool("HTTP2_ENABLED", true),
+		MaxConcurrentStreams: env.getenvInt("HTTP2_MAX_CONCURRENT_STREAMS", defaultMaxConcurrentStreams),
+	}
+	if ss.MaxConcurrentStreams <= 0 {
+		ss.MaxConcurrentStreams = defaultMaxConcurrentStreams
+	}
+	if ss.ReadHeaderTimeout > ss.ReadTimeout {
+		ss.ReadHeaderTimeout = ss.ReadTimeout
+	}
+	return ss
+}
+
+// NewHTTPServer builds the API server for addr with the given transport settings.
+// HTTP/1.1 is always served. With HTTP/2 enabled, TLS listeners negotiate h2 and plaintext
+// listeners also accept h2c, which is what a gateway in front of the service typically speaks.
+func NewHTTPServer(addr string, handler http.Handler, ss ServerSettings) *http.Server {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	if ss.HTTP2 {
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       ss.ReadTimeout,
+		ReadHeaderTimeout: ss.ReadHeaderTimeout,
+		WriteTimeout:      ss.WriteTimeout,
+		IdleTimeout:       ss.IdleTimeout,
+		Protocols:         protocols,
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams: ss.MaxConcurrentStreams,
+		},
+	}
+	srv.SetKeepAlivesEnabled(ss.KeepAlives)
+	return srv
+}
+
+// ConnTracker counts the server's open connections via http.Server.ConnState, so shutdown
+// can report how many requests were still in flight and whether they drained.
+type ConnTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]http.ConnState
+}
+
+// TrackConnections installs a ConnTracker as srv's ConnState callback and returns it.
+func TrackConnections(srv *http.Server) *ConnTracker {
+	t := &ConnTracker{conns: make(map[net.Conn]http.ConnState)}
+	srv.ConnState = t.track
+	return t
+}
+
+// track records a connection state change; hijacked and closed connections are forgotten.
+func (t *ConnTracker) track(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch state {
+	case http.StateHijacked, http.StateClosed:
+		delete(t.conns, c)
+	default:
+		t.conns[c] = state
+	}
+}
+
+// Counts returns the number of open connections and how many of them are serving a request.
+func (t *ConnTracker) Counts() (open, active int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, state := range t.conns {
+		if state == http.StateActive {
+			active++
+		}
+	}
+	return len(t.conns), active
+}
+
+// Shutdown gracefully stops srv within timeout and logs how the drain went: the connections
+// open when it started, how long draining took, and whether the timeout cut requests off.
+func Shutdown(srv *http.Server, conns *ConnTracker, timeout time.Duration, logger *slog.Logger) error {
+	open, active := conns.Counts()
+	logger.Info("server shutting down", "open_connections", open, "active_connections", active, "timeout", timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	err := srv.Shutdown(ctx)
+	drain := time.Since(start)
+
+	timedOut := errors.Is(err, context.DeadlineExceeded)
+	open, active = conns.Counts()
+	if err != nil {
+		logger.Error("server shutdown incomplete", "error", err, "drain_duration", drain, "timed_out", timedOut,
+			"open_connections", open, "active_connections", active)
+		return err
+	}
+	logger.Info("server shut down", "drain_duration", drain, "timed_out", false)
+	return nil
+}
@@ -0,0 +1,154 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadServerSettings(t *testing.T) {
+	t.Setenv("HTTP_WRITE_TIMEOUT", "2m")
+	t.Setenv("HTTP_READ_HEADER_TIMEOUT", "1m") // Above the read timeout, so it gets clamped
+	t.Setenv("HTTP_KEEPALIVES_ENABLED", "false")
+	t.Setenv("HTTP2_MAX_CONCURRENT_STREAMS", "0")
+	t.Setenv("CALCULATE_TIMEOUT", "500ms")
+
+	ss := loadServerSettings(os.Getenv)
+	if ss.WriteTimeout != 2*time.Minute {
+		t.Errorf("Expected write timeout 2m, got %v", ss.WriteTimeout)
+	}
+	if ss.ReadHeaderTimeout != ss.ReadTimeout {
+		t.Errorf("Expected read header timeout clamped to %v, got %v", ss.ReadTimeout, ss.ReadHeaderTimeout)
+	}
+	if ss.KeepAlives {
+		t.Errorf("Expected keep-alives disabled")
+	}
+	if ss.RequestTimeout != defaultRequestTimeout || ss.CalculateTimeout != 500*time.Millisecond {
+		t.Errorf("Expected handler deadlines %v and 500ms, got %v and %v", defaultRequestTimeout, ss.RequestTimeout, ss.CalculateTimeout)
+	}
+	if !ss.HTTP2 || ss.MaxConcurrentStreams != defaultMaxConcurrentStreams {
+		t.Errorf("Expected HTTP/2 on with default streams, got %+v", ss)
+	}
+}
+
+func TestLoadServerSettings_ZeroTimeoutsDisableDeadlines(t *testing.T) {
+	t.Setenv("REQUEST_TIMEOUT", "0")
+	t.Setenv("CALCULATE_TIMEOUT", "0s")
+
+	ss := loadServerSettings(os.Getenv)
+	if ss.RequestTimeout != 0 || ss.CalculateTimeout != 0 {
+		t.Errorf("Expected handler deadlines disabled, got %v and %v", ss.RequestTimeout, ss.CalculateTimeout)
+	}
+
+	t.Setenv("REQUEST_TIMEOUT", "-1s")
+	t.Setenv("CALCULATE_TIMEOUT", "soon")
+	ss = loadServerSettings(os.Getenv)
+	if ss.RequestTimeout != defaultRequestTimeout || ss.CalculateTimeout != defaultCalculateTimeout {
+		t.Errorf("Expected default handler deadlines for invalid values, got %v and %v", ss.RequestTimeout, ss.CalculateTimeout)
+	}
+}
+
+func TestNewHTTPServer_ServesH2C(t *testing.T) {
+	tests := []struct {
+		name      string
+		http2     bool
+		wantProto int
+	}{
+		{"http2 enabled", true, 2},
+		{"http2 disabled", false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := loadServerSettings(os.Getenv)
+			ss.HTTP2 = tt.http2
+			srv := NewHTTPServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), ss)
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("listen: %v", err)
+			}
+			go srv.Serve(ln)
+			defer srv.Close()
+
+			// Prefer h2c when the server offers it, falling back to HTTP/1.1 otherwise
+			protocols := new(http.Protocols)
+			protocols.SetHTTP1(!tt.http2)
+			protocols.SetUnencryptedHTTP2(tt.http2)
+			client := &http.Client{Transport: &http.Transport{Protocols: protocols}, Timeout: 5 * time.Second}
+
+			resp, err := client.Get("http://" + ln.Addr().String() + "/")
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.ProtoMajor != tt.wantProto {
+				t.Errorf("Expected HTTP/%d, got %s", tt.wantProto, resp.Proto)
+			}
+		})
+	}
+}
+
+func TestShutdown_LogsDrain(t *testing.T) {
+	tests := []struct {
+		name         string
+		handlerDelay time.Duration
+		timeout      time.Duration
+		wantTimedOut bool
+	}{
+		{"drained", 20 * time.Millisecond, time.Second, false},
+		{"timed out", time.Second, 20 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			defer close(release)
+			srv := NewHTTPServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-time.After(tt.handlerDelay):
+				case <-release:
+				}
+			}), loadServerSettings(os.Getenv))
+			conns := TrackConnections(srv)
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("listen: %v", err)
+			}
+			go srv.Serve(ln)
+			defer srv.Close()
+
+			go http.Get("http://" + ln.Addr().String() + "/")
+			<-started
+			if open, active := conns.Counts(); open != 1 || active != 1 {
+				t.Fatalf("Expected 1 open and active connection, got %d and %d", open, active)
+			}
+
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, nil))
+			err = Shutdown(srv, conns, tt.timeout, logger)
+			if tt.wantTimedOut != errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Expected timed out %v, got error %v", tt.wantTimedOut, err)
+			}
+
+			logs := buf.String()
+			if !strings.Contains(logs, "active_connections=1") {
+				t.Errorf("Expected the in-flight connection logged at shutdown start, got:\n%s", logs)
+			}
+			if !strings.Contains(logs, fmt.Sprintf("timed_out=%v", tt.wantTimedOut)) || !strings.Contains(logs, "drain_duration=") {
+				t.Errorf("Expected drain duration and timed_out=%v logged, got:\n%s", tt.wantTimedOut, logs)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Package platform provides dependency injection and application bootstrapping.
+// This file contains the OpenTelemetry tracing setup.
+package platform
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingSettings holds the OpenTelemetry tracing configuration. The OTLP exporter itself is
+// configured with the standard OTEL_EXPORTER_OTLP_* variables (endpoint, headers, TLS).
+type TracingSettings struct {
+	Enabled     bool    // Export spans over OTLP/HTTP; when false no tracer provider is installed
+	ServiceName string  // service.name resource attribute
+	SampleRatio float64 // Fraction of new traces sampled (0-1); incoming sampled traces are always kept
+}
+
+// defaultTracingServiceName is the service.name used when OTEL_SERVICE_NAME is unset.
+const defaultTracingServiceName = "pack-optimizer"
+
+// loadTracingSettings loads tracing settings from environment variables.
+// A sample ratio that doesn't parse or lies outside 0-1 falls back to sampling everything.
+func loadTracingSettings(env lookupFunc) TracingSettings {
+	ts := TracingSettings{
+		Enabled:     env.getenvBool("TRACING_ENABLED", false),
+		ServiceName: env.getenv("OTEL_SERVICE_NAME", defaultTracingServiceName),
+		SampleRatio: 1,
+	}
+	if v := env("TRACING_SAMPLE_RATIO"); v != "" {
+		if ratio, err := strconv.ParseFloat(v, 64); err == nil && ratio >= 0 && ratio <= 1 {
+			ts.SampleRatio = ratio
+		}
+	}
+	return ts
+}
+
+// setupTracing installs a global tracer provider exporting spans over OTLP/HTTP, along with the
+// W3C trace context propagator. It returns a function that flushes and stops the exporter.
+// With tracing disabled nothing is installed: the global provider stays the no-op default, so
+// spans started throughout the application cost next to nothing.
+func setupTracing(ctx context.Context, ts TracingSettings) (func(context.Context) error, error) {
+	if !ts.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", ts.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ts.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// tracer returns the platform's tracer from the global provider.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/temo/pack-optimizer/backend/internal/platform")
+}
+
+// The repository and cache ports take no context, so their callers here trace them, which
+// keeps the spans under the request's trace.
+
+// currentVersion reads the active pack set version inside a query span.
+func (p *packsService) currentVersion(ctx context.Context) (int64, error) {
+	span := startQuerySpan(ctx, "CurrentVersion")
+	ver, err := p.repo.CurrentVersion()
+	endQuerySpan(span, err)
+	return ver, err
+}
+
+// tracedCacheGet looks key up in cache inside a span recording whether it hit.
+// Lookup errors count as misses, as they do for every caller.
+func tracedCacheGet(ctx context.Context, cache domain.Cache, key string) []byte {
+	_, span := tracer().Start(ctx, "cache.get", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("cache.key", key)))
+	defer span.End()
+	b, _ := cache.Get(key)
+	span.SetAttributes(attribute.Bool("cache.hit", b != nil))
+	return b
+}
+
+// tracedCacheGetMany looks keys up in cache with one GetMany inside a span recording how many hit.
+// A lookup error counts as a miss for every key.
+func tracedCacheGetMany(ctx context.Context, cache domain.Cache, keys []string) [][]byte {
+	_, span := tracer().Start(ctx, "cache.get_many", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int("cache.keys", len(keys))))
+	defer span.End()
+	values, err := cache.GetMany(keys)
+	if err != nil || len(values) != len(keys) {
+		values = make([][]byte, len(keys))
+	}
+	hits := 0
+	for _, b := range values {
+		if b != nil {
+			hits++
+		}
+	}
+	span.SetAttributes(attribute.Int("cache.hits", hits))
+	return values
+}
+
+// startQuerySpan starts a span for the repository query named operation.
+func startQuerySpan(ctx context.Context, operation string) trace.Span {
+	_, span := tracer().Start(ctx, "postgres."+operation, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql"), attribute.String("db.operation", operation)))
+	return span
+}
+
+// endQuerySpan records err, if any, on a query span and ends it.
+func endQuerySpan(span trace.Span, err error) {
+	recordSpanError(span, err)
+	span.End()
+}
+
+// recordSpanError marks span as failed with err; a nil err leaves it untouched.
+func recordSpanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
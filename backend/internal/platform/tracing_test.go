@@ -0,0 +1,112 @@
+package platform
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/temo/pack-optimizer/backend/internal/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider recording every span until the test ends.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+// spanAttr returns the value of the attribute key on span, if set.
+func spanAttr(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestLoadTracingSettings(t *testing.T) {
+	ts := loadTracingSettings(os.Getenv)
+	if ts.Enabled || ts.ServiceName != defaultTracingServiceName || ts.SampleRatio != 1 {
+		t.Errorf("Expected tracing off by default, got %+v", ts)
+	}
+
+	t.Setenv("TRACING_ENABLED", "true")
+	t.Setenv("OTEL_SERVICE_NAME", "packs-eu")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	ts = loadTracingSettings(os.Getenv)
+	if !ts.Enabled || ts.ServiceName != "packs-eu" || ts.SampleRatio != 0.25 {
+		t.Errorf("Unexpected settings: %+v", ts)
+	}
+
+	t.Setenv("TRACING_SAMPLE_RATIO", "2")
+	if ts := loadTracingSettings(os.Getenv); ts.SampleRatio != 1 {
+		t.Errorf("Expected an out-of-range ratio to fall back to 1, got %v", ts.SampleRatio)
+	}
+}
+
+func TestSetupTracing_DisabledInstallsNothing(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	shutdown, err := setupTracing(context.Background(), TracingSettings{})
+	if err != nil {
+		t.Fatalf("setupTracing: %v", err)
+	}
+	if otel.GetTracerProvider() != prev {
+		t.Errorf("Expected the global tracer provider to be left alone")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("Expected a no-op shutdown, got %v", err)
+	}
+}
+
+func TestPacksService_TracesCacheAndQueries(t *testing.T) {
+	rec := recordSpans(t)
+	repo := &fakeRepo{packs: []domain.Pack{{Size: 250}, {Size: 500}}, version: 7}
+	ps := &packsService{repo: repo, cache: &fakeCache{data: map[string][]byte{}}, ttl: 60}
+
+	// The first read misses the cache and queries the repository; the second hits the cache
+	for range 2 {
+		if _, err := ps.GetActiveSizes(context.Background()); err != nil {
+			t.Fatalf("GetActiveSizes: %v", err)
+		}
+	}
+
+	var hits []bool
+	var queries []string
+	var parents int
+	for _, span := range rec.Ended() {
+		switch span.Name() {
+		case "cache.get":
+			hit, _ := spanAttr(span, "cache.hit")
+			hits = append(hits, hit.AsBool())
+		case "postgres.CurrentVersion", "postgres.GetAllActive":
+			op, _ := spanAttr(span, "db.operation")
+			queries = append(queries, op.AsString())
+		case "packs.GetActiveSizes":
+			parents++
+			if ver, ok := spanAttr(span, "pack.version"); !ok || ver.AsInt64() != 7 {
+				t.Errorf("Expected pack.version 7 on %s, got %v", span.Name(), ver.Emit())
+			}
+			continue
+		}
+		if !span.Parent().IsValid() {
+			t.Errorf("Expected %s to be a child span", span.Name())
+		}
+	}
+	if parents != 2 {
+		t.Errorf("Expected 2 GetActiveSizes spans, got %d", parents)
+	}
+	if len(hits) != 2 || hits[0] || !hits[1] {
+		t.Errorf("Expected a cache miss then a hit, got %v", hits)
+	}
+	if want := []string{"CurrentVersion", "GetAllActive", "CurrentVersion"}; len(queries) != len(want) || queries[0] != want[0] || queries[1] != want[1] || queries[2] != want[2] {
+		t.Errorf("Expected queries %v, got %v", want, queries)
+	}
+}
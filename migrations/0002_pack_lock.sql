@@ -0,0 +1,10 @@
+-- single-row table holding the pack-size configuration lock
+CREATE TABLE IF NOT EXISTS pack_lock (
+  id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+  locked BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO pack_lock (id, locked)
+SELECT TRUE, FALSE
+WHERE NOT EXISTS (SELECT 1 FROM pack_lock);
@@ -0,0 +1,2 @@
+-- optional SKU/label per pack size, stored alongside each version as {"<size>": "<sku>"}
+ALTER TABLE pack_sets ADD COLUMN IF NOT EXISTS skus JSONB NOT NULL DEFAULT '{}'::jsonb;